@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Capabilities the bot needs from its token in the repository
+const (
+	capabilityIssues   = "issues: write"
+	capabilityPulls    = "pull requests: write"
+	capabilityContents = "contents: write"
+)
+
+// capabilityUses explains what each capability is needed for
+var capabilityUses = map[string]string{
+	capabilityIssues:   "comment on and close issues",
+	capabilityPulls:    "open pull requests",
+	capabilityContents: "push fix branches",
+}
+
+// TokenAccess is what the configured token may do in the repository
+type TokenAccess struct {
+	Scopes  []string // Scopes of a classic token, nil for fine-grained and app tokens
+	Missing []string // Capabilities the token lacks, e.g. capabilityContents
+	SSOURL  string   // Where to authorize the token for the organization's SSO, if it must be
+}
+
+// requiredCapabilities are the capabilities a run with config needs
+func requiredCapabilities(config Config) []string {
+	if config.PatchOnly {
+		return []string{capabilityIssues}
+	}
+	return []string{capabilityIssues, capabilityPulls, capabilityContents}
+}
+
+// checkTokenAccess makes sure the token may do everything the run needs before
+// the pipeline starts, instead of failing with a 403 halfway through a fix
+func checkTokenAccess(ctx context.Context, config Config, provider HostingProvider) error {
+	if !config.TokenCheck {
+		return nil
+	}
+
+	access, err := provider.CheckTokenAccess(ctx)
+	if err != nil {
+		fmt.Printf("Warning: Could not check the token's permissions: %v\n", err)
+		return nil
+	}
+
+	repo := config.RepoOwner + "/" + config.RepoName
+	if access.SSOURL != "" {
+		return fmt.Errorf("the token is not authorized for the SAML single sign-on of %s's organization\n  Authorize it at %s", repo, access.SSOURL)
+	}
+
+	var missing []string
+	for _, capability := range requiredCapabilities(config) {
+		for _, m := range access.Missing {
+			if m == capability {
+				missing = append(missing, fmt.Sprintf("  ✗ %s (to %s)", capability, capabilityUses[capability]))
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	hint := "Grant them under the token's repository permissions"
+	if access.Scopes != nil {
+		hint = fmt.Sprintf("The token has the scopes [%s]; classic tokens need the repo scope (public_repo for public repositories)", strings.Join(access.Scopes, ", "))
+	}
+	return fmt.Errorf("the token lacks permissions the bot needs in %s:\n%s\n  %s, or skip this check with --token-check=false", repo, strings.Join(missing, "\n"), hint)
+}
+
+// accessProbes are writes that GitHub validates only after checking permissions,
+// so an empty payload is answered with 422 when the token may write and with
+// 403 or 404 when it may not. Nothing is created.
+var accessProbes = []struct {
+	capability string
+	path       string
+}{
+	{capabilityIssues, "/issues"},
+	{capabilityPulls, "/pulls"},
+	{capabilityContents, "/git/refs"},
+}
+
+// CheckTokenAccess reports the token's scopes and which of the bot's
+// capabilities it lacks in the repository
+func (g *GitHubClient) CheckTokenAccess(ctx context.Context) (*TokenAccess, error) {
+	repoURL := fmt.Sprintf("%s/repos/%s/%s", g.baseURL, g.owner, g.repo)
+	access := &TokenAccess{}
+
+	resp, body, err := g.probe(ctx, "GET", repoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if url := ssoURL(resp); url != "" {
+		access.SSOURL = url
+		return access, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reading %s/%s: %s - %s", g.owner, g.repo, resp.Status, string(body))
+	}
+	if header, ok := resp.Header["X-Oauth-Scopes"]; ok {
+		access.Scopes = []string{}
+		for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				access.Scopes = append(access.Scopes, scope)
+			}
+		}
+	}
+
+	for _, probe := range accessProbes {
+		resp, body, err := g.probe(ctx, "POST", repoURL+probe.path, map[string]interface{}{})
+		if err != nil {
+			return nil, err
+		}
+		if url := ssoURL(resp); url != "" {
+			access.SSOURL = url
+			return access, nil
+		}
+		switch resp.StatusCode {
+		case http.StatusUnprocessableEntity:
+		case http.StatusForbidden, http.StatusNotFound, http.StatusUnauthorized:
+			access.Missing = append(access.Missing, probe.capability)
+		default:
+			return nil, fmt.Errorf("checking %s: %s - %s", probe.capability, resp.Status, string(body))
+		}
+	}
+	return access, nil
+}
+
+// probe sends a request and returns the response whatever its status, for
+// checks that need the status code and headers
+func (g *GitHubClient) probe(ctx context.Context, method, url string, payload interface{}) (*http.Response, []byte, error) {
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		body = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	return resp, respBody, nil
+}
+
+// ssoURL is the authorization URL GitHub sends when an organization enforces
+// SAML SSO and the token is not authorized for it, as in
+// "X-GitHub-SSO: required; url=https://github.com/orgs/acme/sso?authorization_request=..."
+func ssoURL(resp *http.Response) string {
+	header := resp.Header.Get("X-GitHub-SSO")
+	if !strings.HasPrefix(header, "required") {
+		return ""
+	}
+	if _, url, ok := strings.Cut(header, "url="); ok {
+		return strings.TrimSpace(url)
+	}
+	return "the organization's SSO settings"
+}
+
+// CheckTokenAccess reports which of the bot's capabilities the token's
+// account lacks in the repository. Gitea has no way to list a token's scopes,
+// so only the account's permissions are checked.
+func (g *GiteaClient) CheckTokenAccess(ctx context.Context) (*TokenAccess, error) {
+	var repo struct {
+		Permissions struct {
+			Push bool `json:"push"`
+			Pull bool `json:"pull"`
+		} `json:"permissions"`
+	}
+	if err := g.do(ctx, "GET", fmt.Sprintf("/repos/%s/%s", g.owner, g.repo), nil, &repo); err != nil {
+		return nil, err
+	}
+
+	access := &TokenAccess{}
+	if !repo.Permissions.Pull {
+		access.Missing = append(access.Missing, capabilityIssues)
+	}
+	if !repo.Permissions.Push {
+		access.Missing = append(access.Missing, capabilityPulls, capabilityContents)
+	}
+	return access, nil
+}
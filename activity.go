@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// maxActivityCommits is how far back git history is read to rank files
+const maxActivityCommits = 500
+
+// fileActivity is what recent git history says about one file
+type fileActivity struct {
+	commits    int   // Commits among the recent ones that touched the file
+	lastChange int64 // Unix time of the newest of them
+	keywordHit int   // Those of them whose subject shares a keyword with the issue
+}
+
+// recentActivity reads the last maxActivityCommits commits and records, per
+// file, how often and how recently it changed and how many of those commits
+// mention the issue's keywords. Bugs tend to live in the files that change
+// most. Returns nil if the history can't be read.
+func (g *GitOps) recentActivity(ctx context.Context, keywords []string) (map[string]*fileActivity, int64) {
+	output, err := g.gitOutput(ctx, "log", "--no-merges", "--max-count="+strconv.Itoa(maxActivityCommits), "--name-only", "--format=%x1e%ct %s")
+	if err != nil {
+		return nil, 0
+	}
+
+	wanted := make(map[string]bool, len(keywords))
+	for _, keyword := range keywords {
+		wanted[keyword] = true
+	}
+
+	activity := make(map[string]*fileActivity)
+	var newest int64
+	for _, record := range strings.Split(output, "\x1e") {
+		header, files, _ := strings.Cut(record, "\n")
+		stamp, subject, _ := strings.Cut(header, " ")
+		when, err := strconv.ParseInt(stamp, 10, 64)
+		if err != nil {
+			continue
+		}
+		newest = max(newest, when)
+
+		matches := false
+		for _, word := range extractKeywords(subject) {
+			if wanted[word] {
+				matches = true
+				break
+			}
+		}
+
+		for _, file := range strings.Split(files, "\n") {
+			file = strings.TrimSpace(file)
+			if file == "" {
+				continue
+			}
+			entry := activity[file]
+			if entry == nil {
+				entry = &fileActivity{}
+				activity[file] = entry
+			}
+			entry.commits++
+			entry.lastChange = max(entry.lastChange, when)
+			if matches {
+				entry.keywordHit++
+			}
+		}
+	}
+	return activity, newest
+}
+
+// score weighs a file's history against the path matching of
+// calculateRelevance: a file mentioned by name (100) still wins, but a hot
+// file or one whose commits talk about the issue beats a keyword in a path
+// (10). Recency counts from the newest commit, so dormant repositories are
+// ranked on their own timeline.
+func (a *fileActivity) score(newest int64) int {
+	if a == nil {
+		return 0
+	}
+	const day = 24 * 60 * 60
+
+	score := min(a.commits, 10) / 2
+	switch age := newest - a.lastChange; {
+	case age <= 30*day:
+		score += 4
+	case age <= 90*day:
+		score += 2
+	}
+	score += min(a.keywordHit, 3) * 6
+	return score
+}
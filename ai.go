@@ -2,16 +2,35 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 )
 
+// AIClient calls are cancelled when ctx is done (e.g. on Ctrl-C)
 type AIClient interface {
-	AnalyzeAndFix(issue Issue, context *RepoContext) (*Fix, error)
+	AnalyzeAndFix(ctx context.Context, issue Issue, repoContext *RepoContext) (*Fix, error)
+	Complete(ctx context.Context, systemPrompt, prompt string) (string, error)
+}
+
+const fixSystemPrompt = "You are an expert software developer. Analyze issues and provide fixes in a structured JSON format."
+
+// jsonCompleter is implemented by clients that can constrain replies to valid JSON
+type jsonCompleter interface {
+	CompleteJSON(ctx context.Context, systemPrompt, prompt string) (string, error)
+}
+
+// completeJSON asks for a JSON reply, using the client's JSON mode when it has one
+func completeJSON(ctx context.Context, aiClient AIClient, systemPrompt, prompt string) (string, error) {
+	if client, ok := aiClient.(jsonCompleter); ok {
+		return client.CompleteJSON(ctx, systemPrompt, prompt)
+	}
+	return aiClient.Complete(ctx, systemPrompt, prompt)
 }
 
 type AIService interface {
@@ -24,15 +43,21 @@ type Fix struct {
 	Confidence     string // "high", "medium", "low"
 	NeedsMoreInfo  bool
 	Questions      []string
+	TestFiles      []string       // Paths in FileChanges that are generated tests
+	Commit         *CommitMessage  // Proposed commit message, if asked for
+	Changelog      *ChangelogEntry // Proposed changelog entry, if asked for
 }
 
 // OpenAI/ChatGPT Client
 type OpenAIClient struct {
-	apiKey    string
-	model     string
-	baseURL   string
-	client    *http.Client
-	analytics *SessionAnalytics
+	apiKey     string
+	model      string
+	baseURL    string
+	client     *http.Client
+	analytics  *SessionAnalytics
+	scheduler  *RequestScheduler
+	cache      *ResponseCache
+	structured structuredOutput
 }
 
 func NewOpenAIClient(apiKey, model string) *OpenAIClient {
@@ -51,13 +76,31 @@ func (o *OpenAIClient) SetAnalytics(analytics *SessionAnalytics) {
 	o.analytics = analytics
 }
 
+// SetTimeout overrides the HTTP timeout for requests; 0 disables it
+func (o *OpenAIClient) SetTimeout(timeout time.Duration) {
+	o.client.Timeout = timeout
+}
+
+// SetScheduler routes requests through a shared rate limiter
+func (o *OpenAIClient) SetScheduler(scheduler *RequestScheduler) {
+	o.scheduler = scheduler
+}
+
+// SetCache reuses earlier fixes for unchanged prompts
+func (o *OpenAIClient) SetCache(cache *ResponseCache) {
+	o.cache = cache
+}
+
 // xAI Client (Grok models)
 type XAIClient struct {
-	apiKey    string
-	model     string
-	baseURL   string
-	client    *http.Client
-	analytics *SessionAnalytics
+	apiKey     string
+	model      string
+	baseURL    string
+	client     *http.Client
+	analytics  *SessionAnalytics
+	scheduler  *RequestScheduler
+	cache      *ResponseCache
+	structured structuredOutput
 }
 
 func NewXAIClient(apiKey, model string) *XAIClient {
@@ -76,11 +119,27 @@ func (x *XAIClient) SetAnalytics(analytics *SessionAnalytics) {
 	x.analytics = analytics
 }
 
+// SetTimeout overrides the HTTP timeout for requests; 0 disables it
+func (x *XAIClient) SetTimeout(timeout time.Duration) {
+	x.client.Timeout = timeout
+}
+
+// SetScheduler routes requests through a shared rate limiter
+func (x *XAIClient) SetScheduler(scheduler *RequestScheduler) {
+	x.scheduler = scheduler
+}
+
+// SetCache reuses earlier fixes for unchanged prompts
+func (x *XAIClient) SetCache(cache *ResponseCache) {
+	x.cache = cache
+}
+
 type OpenAIRequest struct {
-	Model       string          `json:"model"`
-	Messages    []OpenAIMessage `json:"messages"`
-	Temperature float64         `json:"temperature"`
-	MaxTokens   int             `json:"max_tokens,omitempty"`
+	Model          string                `json:"model"`
+	Messages       []OpenAIMessage       `json:"messages"`
+	Temperature    float64               `json:"temperature"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	ResponseFormat *OpenAIResponseFormat `json:"response_format,omitempty"`
 }
 
 type OpenAIMessage struct {
@@ -90,68 +149,129 @@ type OpenAIMessage struct {
 
 type OpenAIResponse struct {
 	Choices []struct {
-		Message OpenAIMessage `json:"message"`
+		Message      OpenAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
 	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
 }
 
-func (o *OpenAIClient) AnalyzeAndFix(issue Issue, context *RepoContext) (*Fix, error) {
-	// Track API call
-	if o.analytics != nil {
-		o.analytics.RecordAPICall("chatgpt")
+// tokens returns the reported token usage, estimated from the texts when the API omits it
+func (r *OpenAIResponse) tokens(prompt, reply string) (int, int) {
+	if r.Usage.PromptTokens > 0 {
+		return r.Usage.PromptTokens, r.Usage.CompletionTokens
 	}
+	return estimateTokens(prompt), estimateTokens(reply)
+}
 
-	prompt := o.buildPrompt(issue, context)
+func (o *OpenAIClient) AnalyzeAndFix(ctx context.Context, issue Issue, repoContext *RepoContext) (*Fix, error) {
+	prompt := o.buildPrompt(issue, repoContext)
 
+	response, cached := o.cache.Get(o.baseURL, o.model, systemPrompt(repoContext), prompt)
+	if !cached {
+		var err error
+		response, err = completeFix(ctx, o, systemPrompt(repoContext), prompt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fix, err := o.parseFix(response, repoContext)
+	if err != nil {
+		return nil, err
+	}
+	if !cached {
+		o.cache.Put(o.baseURL, o.model, systemPrompt(repoContext), prompt, response)
+	}
+	return fix, nil
+}
+
+// Complete sends a single system+user prompt and returns the raw model reply
+func (o *OpenAIClient) Complete(ctx context.Context, systemPrompt, prompt string) (string, error) {
+	return o.complete(ctx, systemPrompt, prompt, nil)
+}
+
+// SetStructuredOutput selects how JSON replies are requested (structured_output)
+func (o *OpenAIClient) SetStructuredOutput(mode string) {
+	o.structured.set(mode)
+}
+
+// CompleteJSON is like Complete but asks the API for valid JSON
+func (o *OpenAIClient) CompleteJSON(ctx context.Context, systemPrompt, prompt string) (string, error) {
+	return o.CompleteSchema(ctx, systemPrompt, prompt, nil)
+}
+
+// CompleteSchema is like Complete but constrains the reply to a JSON schema
+func (o *OpenAIClient) CompleteSchema(ctx context.Context, systemPrompt, prompt string, schema *JSONSchema) (string, error) {
+	return o.structured.complete(func(mode string) (string, error) {
+		return o.complete(ctx, systemPrompt, prompt, openAIResponseFormat(mode, schema, systemPrompt+prompt))
+	})
+}
+
+func (o *OpenAIClient) complete(ctx context.Context, systemPrompt, prompt string, format *OpenAIResponseFormat) (string, error) {
 	reqBody := OpenAIRequest{
 		Model: o.model,
 		Messages: []OpenAIMessage{
 			{
 				Role:    "system",
-				Content: "You are an expert software developer. Analyze issues and provide fixes in a structured JSON format.",
+				Content: systemPrompt,
 			},
 			{
 				Role:    "user",
 				Content: prompt,
 			},
 		},
-		Temperature: 0.2,
-		MaxTokens:   8000,
+		Temperature:    0.2,
+		MaxTokens:      8000,
+		ResponseFormat: format,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	req, err := http.NewRequest("POST", o.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+o.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := o.client.Do(req)
+	resp, err := o.scheduler.Do(ctx, o.client, req, estimateTokens(systemPrompt+prompt))
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("OpenAI API error: %s - %s", resp.Status, string(body))
+		err := fmt.Errorf("OpenAI API error: %s - %s", resp.Status, string(body))
+		return "", formatError(err, format != nil, resp.StatusCode, string(body))
 	}
 
 	var openaiResp OpenAIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&openaiResp); err != nil {
-		return nil, err
+		return "", err
 	}
 
 	if len(openaiResp.Choices) == 0 {
-		return nil, fmt.Errorf("no response from AI")
+		return "", fmt.Errorf("no response from AI")
+	}
+	if format != nil && openaiResp.Choices[0].FinishReason == "length" {
+		return "", errReplyTruncated
 	}
 
-	return o.parseFix(openaiResp.Choices[0].Message.Content)
+	// Track API call and its cost
+	if o.analytics != nil {
+		inputTokens, outputTokens := openaiResp.tokens(systemPrompt+prompt, openaiResp.Choices[0].Message.Content)
+		o.analytics.RecordAPICall("chatgpt", o.model, inputTokens, outputTokens)
+	}
+
+	return openaiResp.Choices[0].Message.Content, nil
 }
 
 func (o *OpenAIClient) buildPrompt(issue Issue, context *RepoContext) string {
@@ -159,24 +279,74 @@ func (o *OpenAIClient) buildPrompt(issue Issue, context *RepoContext) string {
 
 	prompt.WriteString(fmt.Sprintf("# Issue to Fix\n\n"))
 	prompt.WriteString(fmt.Sprintf("**Title:** %s\n\n", issue.Title))
-	prompt.WriteString(fmt.Sprintf("**Description:**\n%s\n\n", issue.Body))
+	if fields := parseIssueForm(issue.Body); fields != nil {
+		// Issue forms: hand over the answers as labeled sections instead of one blob
+		prompt.WriteString(formatIssueForm(fields))
+	} else {
+		prompt.WriteString(fmt.Sprintf("**Description:**\n%s\n\n", issue.Body))
+	}
+
+	if context.Memory != "" {
+		prompt.WriteString(context.Memory)
+	}
+
+	prompt.WriteString(instructionsSection(context.Instructions))
+	prompt.WriteString(styleSection(context.StyleGuides))
 
 	prompt.WriteString("# Repository Context\n\n")
-	prompt.WriteString("## Directory Structure\n```\n")
+
+	if len(context.Hints) > 0 {
+		prompt.WriteString("## Maintainer Notes\n")
+		for _, hint := range context.Hints {
+			prompt.WriteString(fmt.Sprintf("- %s\n", hint))
+		}
+		prompt.WriteString("\n")
+	}
+
+	if len(context.References) > 0 {
+		prompt.WriteString(formatReferences(context.References))
+	}
+
+	if len(context.Traces) > 0 {
+		prompt.WriteString("## Stack Trace Locations\n\nThe issue contains a stack trace pointing at this code (> marks the reported line):\n\n")
+		for _, trace := range context.Traces {
+			location := fmt.Sprintf("%s:%d", trace.Path, trace.Line)
+			if trace.Function != "" {
+				location += " in " + trace.Function
+			}
+			prompt.WriteString(fmt.Sprintf("### %s\n```\n%s```\n\n", location, trace.Snippet))
+		}
+	}
+
+	prompt.WriteString("## Repository Structure\n```\n")
 	prompt.WriteString(context.Structure)
 	prompt.WriteString("\n```\n\n")
 
 	if len(context.Files) > 0 {
 		prompt.WriteString("## Key Files\n\n")
 		for path, content := range context.Files {
-			// Limit content size
-			if len(content) > 5000 {
-				content = content[:5000] + "\n... (truncated)"
+			if regions := context.Regions[path]; len(regions) > 0 {
+				// Large file: outline plus the regions the AI asked for
+				prompt.WriteString(fmt.Sprintf("### %s (large file, outline)\n%s\n", path, regionOutline(content, findRegions(path, content))))
+				for _, region := range regions {
+					prompt.WriteString(fmt.Sprintf("#### %s: region %q (lines %d-%d)\n```\n%s\n```\n\n", path, region.Name, region.Start, region.End, region.Text))
+				}
+				continue
 			}
+			// Limit content size
+			content = context.promptContent(content)
 			prompt.WriteString(fmt.Sprintf("### %s\n```\n%s\n```\n\n", path, content))
 		}
 	}
 
+	if len(context.Summaries) > 0 {
+		prompt.WriteString("## Other Relevant Files (summarized)\n\n")
+		prompt.WriteString("These files are only summarized. If you change one, you must still return its complete content, so only modify them when necessary.\n\n")
+		for path, summary := range context.Summaries {
+			prompt.WriteString(fmt.Sprintf("### %s\n%s\n\n", path, summary))
+		}
+	}
+
 	prompt.WriteString(`# Task
 
 Analyze the issue and provide a fix. Your response MUST be in the following JSON format:
@@ -188,8 +358,11 @@ Analyze the issue and provide a fix. Your response MUST be in the following JSON
   "explanation": "Brief explanation of what the fix does",
   "files": [
     {
+      "operation": "create|modify|delete|rename",
       "path": "relative/path/to/file.ext",
-      "content": "complete file content with the fix applied"
+      "new_path": "",
+      "content": "complete file content with the fix applied",
+      "summary": "One line for reviewers on what changed in this file and why"
     }
   ]
 }
@@ -200,22 +373,82 @@ Instructions:
 - Provide COMPLETE file content, not diffs or patches
 - Only include files that need to be modified or created
 - Keep explanations concise but clear
+- Give every file a one-line "summary" of what changed in it; reviewers read these first
 - Ensure the fix actually addresses the issue
 - If you need to create a new file, include its full content
-- Return valid JSON only, no markdown code blocks
+- To remove a file that is dead or replaced, use "delete" with empty "content"
+- To move or rename a file, use "rename" with the destination in "new_path"; leave "content" empty to keep the file as it is, or give the complete new content
+- Only delete or rename files when the fix requires it, and update every reference to them
+- Return valid JSON only, no markdown code blocks`)
+
+	if len(context.Regions) > 0 {
+		prompt.WriteString(`
+
+Large files:
+- Files marked "large file" are only shown as an outline plus some regions; do NOT put them in "files"
+- To change one of those regions, add it to an "edits" array: [{"path": "...", "region": "exact region name", "content": "complete new source of the region"}]
+- The region content replaces the whole region, including its doc comment, and may add new functions next to it`)
+	}
 
-Now provide the fix:`)
+	if context.GenerateTests {
+		prompt.WriteString(`
+
+Tests:
+- Also add or update automated tests that reproduce the issue and verify the fix
+- Use the project's existing test framework, file naming and directory layout
+- Put test files in a separate "tests" array with the same shape as "files": [{"path": "...", "content": "...", "summary": "..."}]
+- Leave "tests" empty only if the change cannot reasonably be tested (e.g. documentation)`)
+	}
+
+	if context.ConventionalCommits {
+		prompt.WriteString(`
+
+Commit message:
+- Also add a "commit" object following Conventional Commits: {"type": "fix", "scope": "parser", "subject": "handle empty input", "body": "Why the change is needed and what it does"}
+- "type" is one of: ` + strings.Join(conventionalTypes, ", ") + `
+- "scope" is a short lowercase name of the affected area, or empty
+- "subject" is imperative, lowercase, without a trailing period and under 70 characters`)
+	}
+
+	if context.Changelog != nil {
+		prompt.WriteString(changelogPrompt(context.Changelog))
+	}
+
+	prompt.WriteString("\n\nNow provide the fix:")
 
 	return prompt.String()
 }
 
-func (o *OpenAIClient) parseFix(response string) (*Fix, error) {
-	// Clean up markdown code blocks if present
+// fileOperation normalizes the operation of a file in a fix reply. Models
+// say "move" as often as "rename", and nothing at all for plain writes.
+func fileOperation(operation string) (string, error) {
+	operation = strings.ToLower(strings.TrimSpace(operation))
+	switch operation {
+	case "", "write", "update":
+		return "", nil
+	case "move":
+		return fileRename, nil
+	case "remove":
+		return fileDelete, nil
+	}
+	if !slices.Contains(fileOperations, operation) {
+		return "", fmt.Errorf("unknown file operation %q", operation)
+	}
+	return operation, nil
+}
+
+// cleanJSONResponse strips markdown code fences models like to wrap JSON in
+func cleanJSONResponse(response string) string {
 	response = strings.TrimSpace(response)
 	response = strings.TrimPrefix(response, "```json")
 	response = strings.TrimPrefix(response, "```")
 	response = strings.TrimSuffix(response, "```")
-	response = strings.TrimSpace(response)
+	return strings.TrimSpace(response)
+}
+
+func (o *OpenAIClient) parseFix(response string, repoContext *RepoContext) (*Fix, error) {
+	// Clean up markdown code blocks if present
+	response = cleanJSONResponse(response)
 
 	var result struct {
 		Confidence    string   `json:"confidence"`
@@ -223,9 +456,24 @@ func (o *OpenAIClient) parseFix(response string) (*Fix, error) {
 		Questions     []string `json:"questions"`
 		Explanation   string   `json:"explanation"`
 		Files         []struct {
+			Path      string `json:"path"`
+			Content   string `json:"content"`
+			Operation string `json:"operation"`
+			NewPath   string `json:"new_path"`
+			Summary   string `json:"summary"`
+		} `json:"files"`
+		Tests []struct {
 			Path    string `json:"path"`
 			Content string `json:"content"`
-		} `json:"files"`
+			Summary string `json:"summary"`
+		} `json:"tests"`
+		Edits []struct {
+			Path    string `json:"path"`
+			Region  string `json:"region"`
+			Content string `json:"content"`
+		} `json:"edits"`
+		Commit    *CommitMessage  `json:"commit"`
+		Changelog *ChangelogEntry `json:"changelog"`
 	}
 
 	if err := json.Unmarshal([]byte(response), &result); err != nil {
@@ -238,12 +486,45 @@ func (o *OpenAIClient) parseFix(response string) (*Fix, error) {
 		Questions:     result.Questions,
 		Explanation:   result.Explanation,
 		FileChanges:   make([]FileChange, len(result.Files)),
+		Commit:        result.Commit,
+		Changelog:     result.Changelog,
 	}
 
 	for i, file := range result.Files {
+		operation, err := fileOperation(file.Operation)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Path, err)
+		}
+		if operation == fileRename && file.NewPath == "" {
+			return nil, fmt.Errorf("%s: rename without a new_path", file.Path)
+		}
 		fix.FileChanges[i] = FileChange{
-			FilePath: file.Path,
-			Content:  file.Content,
+			FilePath:  file.Path,
+			Content:   file.Content,
+			Operation: operation,
+			NewPath:   file.NewPath,
+			Summary:   file.Summary,
+		}
+	}
+
+	// Tests are applied, guarded and reviewed like any other file
+	for _, test := range result.Tests {
+		fix.FileChanges = append(fix.FileChanges, FileChange{
+			FilePath: test.Path,
+			Content:  test.Content,
+			Summary:  test.Summary,
+		})
+		fix.TestFiles = append(fix.TestFiles, test.Path)
+	}
+
+	// Region edits of large files become whole-file changes
+	if len(result.Edits) > 0 && repoContext != nil {
+		edits := make([]RegionEdit, len(result.Edits))
+		for i, edit := range result.Edits {
+			edits[i] = RegionEdit{Path: edit.Path, Region: edit.Region, Content: edit.Content}
+		}
+		if err := spliceRegionEdits(fix, edits, repoContext); err != nil {
+			return nil, fmt.Errorf("failed to apply region edits: %w", err)
 		}
 	}
 
@@ -301,10 +582,15 @@ func (o *OpenAIClient) GetAvailableModels() ([]string, error) {
 
 // Ollama Client (Free local AI: https://ollama.com)
 type OllamaClient struct {
-	baseURL   string
-	model     string
-	client    *http.Client
-	analytics *SessionAnalytics
+	baseURL    string
+	model      string
+	client     *http.Client
+	analytics  *SessionAnalytics
+	scheduler  *RequestScheduler
+	cache      *ResponseCache
+	options    *OllamaOptions
+	keepAlive  string
+	structured structuredOutput
 }
 
 func NewOllamaClient(baseURL, model string) *OllamaClient {
@@ -319,60 +605,149 @@ func (o *OllamaClient) SetAnalytics(analytics *SessionAnalytics) {
 	o.analytics = analytics
 }
 
+// SetTimeout overrides the HTTP timeout for requests; 0 disables it
+func (o *OllamaClient) SetTimeout(timeout time.Duration) {
+	o.client.Timeout = timeout
+}
+
+// SetScheduler routes requests through a shared rate limiter
+func (o *OllamaClient) SetScheduler(scheduler *RequestScheduler) {
+	o.scheduler = scheduler
+}
+
+// SetCache reuses earlier fixes for unchanged prompts
+func (o *OllamaClient) SetCache(cache *ResponseCache) {
+	o.cache = cache
+}
+
+// SetOptions configures model parameters and keep_alive
+func (o *OllamaClient) SetOptions(options *OllamaOptions, keepAlive string) {
+	o.options = options
+	o.keepAlive = keepAlive
+}
+
+// SetStructuredOutput selects how JSON replies are requested (structured_output)
+func (o *OllamaClient) SetStructuredOutput(mode string) {
+	o.structured.set(mode)
+}
+
 type OllamaRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+	Model     string         `json:"model"`
+	System    string         `json:"system,omitempty"`
+	Prompt    string         `json:"prompt"`
+	Images    []string       `json:"images,omitempty"`
+	Format    interface{}    `json:"format,omitempty"` // "json" or a JSON schema
+	KeepAlive string         `json:"keep_alive,omitempty"`
+	Options   *OllamaOptions `json:"options,omitempty"`
+	Stream    bool           `json:"stream"`
+}
+
+// OllamaOptions are the model parameters sent with every generate request
+type OllamaOptions struct {
+	NumCtx      int     `json:"num_ctx,omitempty"`
+	Temperature float64 `json:"temperature"`
+	TopP        float64 `json:"top_p,omitempty"`
 }
 
 type OllamaResponse struct {
-	Response string `json:"response"`
-	Done     bool   `json:"done"`
+	Response   string `json:"response"`
+	Done       bool   `json:"done"`
+	DoneReason string `json:"done_reason"`
 }
 
-func (o *OllamaClient) AnalyzeAndFix(issue Issue, context *RepoContext) (*Fix, error) {
-	// Track API call
-	if o.analytics != nil {
-		o.analytics.RecordAPICall("ollama")
+func (o *OllamaClient) AnalyzeAndFix(ctx context.Context, issue Issue, repoContext *RepoContext) (*Fix, error) {
+	prompt := o.buildPrompt(issue, repoContext)
+
+	response, cached := o.cache.Get(o.baseURL, o.model, systemPrompt(repoContext), prompt)
+	if !cached {
+		var err error
+		response, err = completeFix(ctx, o, systemPrompt(repoContext), prompt)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	prompt := o.buildPrompt(issue, context)
+	fix, err := o.parseFix(response, repoContext)
+	if err != nil {
+		return nil, err
+	}
+	if !cached {
+		o.cache.Put(o.baseURL, o.model, systemPrompt(repoContext), prompt, response)
+	}
+	return fix, nil
+}
 
-	reqBody := OllamaRequest{
-		Model:  o.model,
+// Complete sends a single system+user prompt and returns the raw model reply
+func (o *OllamaClient) Complete(ctx context.Context, systemPrompt, prompt string) (string, error) {
+	return o.generate(ctx, OllamaRequest{
+		System: systemPrompt,
 		Prompt: prompt,
-		Stream: false,
+	})
+}
+
+// CompleteJSON is like Complete but uses Ollama's JSON output mode when enabled,
+// which avoids most parse failures with local models
+func (o *OllamaClient) CompleteJSON(ctx context.Context, systemPrompt, prompt string) (string, error) {
+	return o.CompleteSchema(ctx, systemPrompt, prompt, nil)
+}
+
+// CompleteSchema is like Complete but constrains the reply to a JSON schema
+// (Ollama 0.5 and later; older servers fall back to JSON mode)
+func (o *OllamaClient) CompleteSchema(ctx context.Context, systemPrompt, prompt string, schema *JSONSchema) (string, error) {
+	return o.structured.complete(func(mode string) (string, error) {
+		return o.generate(ctx, OllamaRequest{
+			System: systemPrompt,
+			Prompt: prompt,
+			Format: ollamaFormat(mode, schema),
+		})
+	})
+}
+
+// generate fills in the model and configured options and calls /api/generate
+func (o *OllamaClient) generate(ctx context.Context, reqBody OllamaRequest) (string, error) {
+	// Track API call (local models are free)
+	if o.analytics != nil {
+		o.analytics.RecordAPICall("ollama", o.model, estimateTokens(reqBody.System+reqBody.Prompt), 0)
 	}
 
+	reqBody.Model = o.model
+	reqBody.KeepAlive = o.keepAlive
+	reqBody.Options = o.options
+	reqBody.Stream = false
+
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	req, err := http.NewRequest("POST", o.baseURL+"/api/generate", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/api/generate", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := o.client.Do(req)
+	resp, err := o.scheduler.Do(ctx, o.client, req, estimateTokens(reqBody.System+reqBody.Prompt))
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Ollama API error: %s - %s", resp.Status, string(body))
+		err := fmt.Errorf("Ollama API error: %s - %s", resp.Status, string(body))
+		return "", formatError(err, reqBody.Format != nil, resp.StatusCode, string(body))
 	}
 
 	var ollamaResp OllamaResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		return nil, err
+		return "", err
+	}
+	if reqBody.Format != nil && ollamaResp.DoneReason == "length" {
+		return "", errReplyTruncated
 	}
 
-	return o.parseFix(ollamaResp.Response)
+	return ollamaResp.Response, nil
 }
 
 func (o *OllamaClient) buildPrompt(issue Issue, context *RepoContext) string {
@@ -381,71 +756,119 @@ func (o *OllamaClient) buildPrompt(issue Issue, context *RepoContext) string {
 	return g.buildPrompt(issue, context)
 }
 
-func (o *OllamaClient) parseFix(response string) (*Fix, error) {
+func (o *OllamaClient) parseFix(response string, repoContext *RepoContext) (*Fix, error) {
 	// Same parsing logic as Groq
 	g := &OpenAIClient{}
-	return g.parseFix(response)
+	return g.parseFix(response, repoContext)
 }
 
 // xAI Client methods
-func (x *XAIClient) AnalyzeAndFix(issue Issue, context *RepoContext) (*Fix, error) {
-	// Track API call
-	if x.analytics != nil {
-		x.analytics.RecordAPICall("grok")
+func (x *XAIClient) AnalyzeAndFix(ctx context.Context, issue Issue, repoContext *RepoContext) (*Fix, error) {
+	prompt := x.buildPrompt(issue, repoContext)
+
+	response, cached := x.cache.Get(x.baseURL, x.model, systemPrompt(repoContext), prompt)
+	if !cached {
+		var err error
+		response, err = completeFix(ctx, x, systemPrompt(repoContext), prompt)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	prompt := x.buildPrompt(issue, context)
+	fix, err := x.parseFix(response, repoContext)
+	if err != nil {
+		return nil, err
+	}
+	if !cached {
+		x.cache.Put(x.baseURL, x.model, systemPrompt(repoContext), prompt, response)
+	}
+	return fix, nil
+}
+
+// Complete sends a single system+user prompt and returns the raw model reply
+func (x *XAIClient) Complete(ctx context.Context, systemPrompt, prompt string) (string, error) {
+	return x.complete(ctx, systemPrompt, prompt, nil)
+}
+
+// SetStructuredOutput selects how JSON replies are requested (structured_output)
+func (x *XAIClient) SetStructuredOutput(mode string) {
+	x.structured.set(mode)
+}
+
+// CompleteJSON is like Complete but asks the API for valid JSON
+func (x *XAIClient) CompleteJSON(ctx context.Context, systemPrompt, prompt string) (string, error) {
+	return x.CompleteSchema(ctx, systemPrompt, prompt, nil)
+}
+
+// CompleteSchema is like Complete but constrains the reply to a JSON schema
+func (x *XAIClient) CompleteSchema(ctx context.Context, systemPrompt, prompt string, schema *JSONSchema) (string, error) {
+	return x.structured.complete(func(mode string) (string, error) {
+		return x.complete(ctx, systemPrompt, prompt, openAIResponseFormat(mode, schema, systemPrompt+prompt))
+	})
+}
 
+func (x *XAIClient) complete(ctx context.Context, systemPrompt, prompt string, format *OpenAIResponseFormat) (string, error) {
 	reqBody := OpenAIRequest{ // Uses same structure as Groq (OpenAI-compatible)
 		Model: x.model,
 		Messages: []OpenAIMessage{
 			{
 				Role:    "system",
-				Content: "You are an expert software developer. Analyze issues and provide fixes in a structured JSON format.",
+				Content: systemPrompt,
 			},
 			{
 				Role:    "user",
 				Content: prompt,
 			},
 		},
-		Temperature: 0.2,
-		MaxTokens:   8000,
+		Temperature:    0.2,
+		MaxTokens:      8000,
+		ResponseFormat: format,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	req, err := http.NewRequest("POST", x.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", x.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+x.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := x.client.Do(req)
+	resp, err := x.scheduler.Do(ctx, x.client, req, estimateTokens(systemPrompt+prompt))
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("xAI API error: %s - %s", resp.Status, string(body))
+		err := fmt.Errorf("xAI API error: %s - %s", resp.Status, string(body))
+		return "", formatError(err, format != nil, resp.StatusCode, string(body))
 	}
 
 	var xaiResp OpenAIResponse // Uses same response structure
 	if err := json.NewDecoder(resp.Body).Decode(&xaiResp); err != nil {
-		return nil, err
+		return "", err
 	}
 
 	if len(xaiResp.Choices) == 0 {
-		return nil, fmt.Errorf("no response from AI")
+		return "", fmt.Errorf("no response from AI")
+	}
+	if format != nil && xaiResp.Choices[0].FinishReason == "length" {
+		return "", errReplyTruncated
+	}
+
+	// Track API call and its cost
+	if x.analytics != nil {
+		inputTokens, outputTokens := xaiResp.tokens(systemPrompt+prompt, xaiResp.Choices[0].Message.Content)
+		x.analytics.RecordAPICall("grok", x.model, inputTokens, outputTokens)
 	}
 
-	return x.parseFix(xaiResp.Choices[0].Message.Content)
+	return xaiResp.Choices[0].Message.Content, nil
 }
 
 func (x *XAIClient) buildPrompt(issue Issue, context *RepoContext) string {
@@ -454,10 +877,10 @@ func (x *XAIClient) buildPrompt(issue Issue, context *RepoContext) string {
 	return g.buildPrompt(issue, context)
 }
 
-func (x *XAIClient) parseFix(response string) (*Fix, error) {
+func (x *XAIClient) parseFix(response string, repoContext *RepoContext) (*Fix, error) {
 	// Same parsing logic as Groq
 	g := &OpenAIClient{}
-	return g.parseFix(response)
+	return g.parseFix(response, repoContext)
 }
 
 func (x *XAIClient) GetAvailableModels() ([]string, error) {
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// maxScriptDepth bounds how deeply package scripts calling other scripts are followed
+const maxScriptDepth = 5
+
+// packageManagers run the scripts of package.json
+var packageManagers = []string{"npm", "yarn", "pnpm", "bun"}
+
+// packageRunners execute a package's binary: npx jest, npm exec jest, pnpm
+// dlx jest, ... An empty subcommand is the runner itself.
+var packageRunners = map[string][]string{
+	"npx":  {""},
+	"bunx": {""},
+	"npm":  {"exec", "x"},
+	"pnpm": {"dlx", "exec"},
+	"yarn": {"dlx", "exec"},
+	"bun":  {"x"},
+}
+
+// runnerPackageOptions make a package runner run a shell command, or a binary
+// of another package than the one it names, so what runs can't be checked
+var runnerPackageOptions = []string{"-c", "--call", "--shell-mode", "-p", "--package"}
+
+// scriptSubcommands run a package script of the same name, or the script
+// that follows with run
+var (
+	scriptSubcommands = []string{"test", "start", "stop", "restart"}
+	runSubcommands    = []string{"run", "run-script", "rum", "urn"}
+)
+
+// pythonLaunchers run modules as "python -m pytest", which counts as the module
+var pythonLaunchers = []string{"python", "python3", "py"}
+
+// installCommands of the package managers run the install scripts of every
+// dependency, which can't be checked in advance
+var installCommands = []string{"install", "i", "ci", "add"}
+
+// CommandPlan is what running a command executes: the command itself and,
+// for package scripts, every shell command of the scripts it runs
+type CommandPlan struct {
+	Command string
+	Scripts []string // "name: script" of each package script run, in order
+}
+
+// planCommand resolves what a command executes and, with an allowlist,
+// refuses it if anything in it is not allowed. Package scripts are read from
+// package.json, with their pre and post scripts; a script that uses command
+// substitution or redirection is refused, since what it runs can't be told.
+// So is a command that is not a plain command, even without an allowlist.
+func (t *TestRunner) planCommand(command string) (*CommandPlan, error) {
+	plan := &CommandPlan{Command: command}
+	parts, err := splitCommand(command)
+	if err != nil {
+		return plan, err
+	}
+	if err := t.checkCommand(parts, plan, 0); err != nil {
+		return plan, err
+	}
+	return plan, nil
+}
+
+func (t *TestRunner) checkCommand(parts []string, plan *CommandPlan, depth int) error {
+	// Leading VAR=value assignments, as in scripts. With an allowlist they
+	// are refused: PATH=. or NODE_OPTIONS change what the command runs.
+	for len(parts) > 0 && strings.Contains(parts[0], "=") && !strings.ContainsAny(parts[0], `/\`) {
+		if len(t.Allowed) > 0 {
+			variable, _, _ := strings.Cut(parts[0], "=")
+			return fmt.Errorf("%q sets %s, which allowed_commands doesn't permit since it can change what runs", strings.Join(parts, " "), variable)
+		}
+		parts = parts[1:]
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	name := executableName(parts[0])
+
+	// Only a launcher from the PATH may run an allowed module
+	if len(t.Allowed) > 0 && strings.ContainsAny(parts[0], `/\`) {
+		return t.checkExecutable(parts[0])
+	}
+	if slices.Contains(pythonLaunchers, name) && len(parts) > 2 && parts[1] == "-m" && t.allowed(parts[2]) {
+		return nil
+	}
+	if err := t.checkExecutable(parts[0]); err != nil {
+		return err
+	}
+
+	// What package managers run next: a binary, a script or install scripts
+	if args, ok := runnerArgs(name, parts); ok {
+		for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+			option, _, _ := strings.Cut(args[0], "=")
+			if len(t.Allowed) > 0 && slices.Contains(runnerPackageOptions, option) {
+				return fmt.Errorf("%q uses %s, so what it runs can't be checked", strings.Join(parts, " "), option)
+			}
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			return nil
+		}
+		return t.checkExecutable(args[0])
+	}
+	if !slices.Contains(packageManagers, name) {
+		return nil
+	}
+	// A bare "yarn" installs
+	if len(parts) > 1 && slices.Contains(installCommands, parts[1]) || name == "yarn" && len(parts) == 1 {
+		if len(t.Allowed) > 0 && !slices.Contains(parts, "--ignore-scripts") {
+			return fmt.Errorf("%q would run the install scripts of its dependencies; add --ignore-scripts", strings.Join(parts, " "))
+		}
+		return nil
+	}
+	if len(t.Allowed) == 0 {
+		return nil
+	}
+
+	scripts := t.packageScripts()
+	script, binary, err := packageScriptName(name, parts[1:], scripts)
+	if err != nil {
+		return fmt.Errorf("%q: %w", strings.Join(parts, " "), err)
+	}
+	if binary != "" {
+		return t.checkExecutable(binary)
+	}
+	if script == "" {
+		return nil
+	}
+	if depth >= maxScriptDepth {
+		return fmt.Errorf("package script %q calls scripts more than %d levels deep", script, maxScriptDepth)
+	}
+
+	for _, name := range []string{"pre" + script, script, "post" + script} {
+		body, ok := scripts[name]
+		if !ok {
+			continue
+		}
+		plan.Scripts = append(plan.Scripts, name+": "+body)
+		if len(t.Allowed) == 0 {
+			continue
+		}
+		commands, err := splitShellScript(body)
+		if err != nil {
+			return fmt.Errorf("package script %q: %w", name, err)
+		}
+		for _, command := range commands {
+			if err := t.checkCommand(command, plan, depth+1); err != nil {
+				return fmt.Errorf("package script %q: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkExecutable refuses an executable that is not in the allowlist. With
+// an allowlist, executables are looked up on the PATH only, since a path
+// could point at anything in the clone.
+func (t *TestRunner) checkExecutable(executable string) error {
+	if len(t.Allowed) > 0 && strings.ContainsAny(executable, `/\`) {
+		return fmt.Errorf("%s is a path; allowed_commands only permits commands on the PATH", executable)
+	}
+	if t.allowed(executableName(executable)) {
+		return nil
+	}
+	return fmt.Errorf("%s is not in allowed_commands (%s)", executable, strings.Join(t.Allowed, ", "))
+}
+
+// allowed reports whether the allowlist permits an executable; without an
+// allowlist everything is
+func (t *TestRunner) allowed(name string) bool {
+	return len(t.Allowed) == 0 || slices.Contains(t.Allowed, name)
+}
+
+// executableName is the name an executable is allowed by: "npm.cmd" is "npm"
+func executableName(executable string) string {
+	name := filepath.Base(filepath.FromSlash(executable))
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".exe", ".cmd", ".bat":
+		name = name[:len(name)-4]
+	}
+	return name
+}
+
+// runnerArgs returns the arguments after the runner of a package runner
+// command, and false if the command is not one
+func runnerArgs(name string, parts []string) ([]string, bool) {
+	for _, sub := range packageRunners[name] {
+		if sub == "" {
+			return parts[1:], true
+		}
+		if len(parts) > 1 && parts[1] == sub {
+			return parts[2:], true
+		}
+	}
+	return nil, false
+}
+
+// packageScriptName is the package.json script a package manager command
+// runs: npm test, npm run lint, yarn lint, pnpm test. yarn and bun run a
+// binary when no script has the name, which is returned as binary instead.
+// Any other subcommand is an error, since what it runs is not known; this
+// refuses builtins such as "yarn node x.js" or "npm rebuild".
+func packageScriptName(manager string, args []string, scripts map[string]string) (script, binary string, err error) {
+	// Options without a value, such as npm -s test
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return "", "", fmt.Errorf("no subcommand")
+	}
+	_, isScript := scripts[args[0]]
+
+	switch {
+	case slices.Contains(runSubcommands, args[0]):
+		if len(args) < 2 {
+			return "", "", fmt.Errorf("%s without a script", args[0])
+		}
+		if _, ok := scripts[args[1]]; !ok && (manager == "yarn" || manager == "bun") {
+			return "", args[1], nil
+		}
+		return args[1], "", nil
+	case manager == "bun" && args[0] == "test":
+		// bun's own test runner
+		return "", "", nil
+	case manager == "npm" && (args[0] == "t" || args[0] == "tst"):
+		return "test", "", nil
+	case slices.Contains(scriptSubcommands, args[0]):
+		return args[0], "", nil
+	case manager != "npm" && isScript:
+		// yarn, pnpm and bun also run scripts by name
+		return args[0], "", nil
+	}
+	return "", "", fmt.Errorf("%s %s is not a package script or a subcommand allowed_commands knows", manager, args[0])
+}
+
+// packageScripts reads the scripts of the repository's package.json
+func (t *TestRunner) packageScripts() map[string]string {
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if data, err := os.ReadFile(filepath.Join(t.RepoPath, "package.json")); err == nil {
+		json.Unmarshal(data, &pkg)
+	}
+	return pkg.Scripts
+}
+
+// splitShellScript splits a shell script into its commands at &&, ||, ;, |
+// and & outside quotes, and each command into its words like splitCommand.
+// Scripts whose commands can't be told from the text, through expansions,
+// command substitution, redirection or subshells, are refused, also inside
+// double quotes, where the shell still expands them.
+func splitShellScript(script string) ([][]string, error) {
+	refuse := func(construct string) error {
+		return fmt.Errorf("%q uses %q, so what it runs can't be checked", script, construct)
+	}
+
+	var commands [][]string
+	var current strings.Builder
+	flush := func() error {
+		words, err := splitCommand(current.String())
+		if err != nil {
+			return err
+		}
+		if len(words) > 0 {
+			commands = append(commands, words)
+		}
+		current.Reset()
+		return nil
+	}
+
+	var quote rune
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			}
+		case quote == '"':
+			if r == '$' || r == '`' {
+				return nil, refuse(string(r))
+			}
+			if r == '\\' && i+1 < len(runes) {
+				current.WriteRune(r)
+				i++
+				r = runes[i]
+			} else if r == '"' {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '&' || r == '|' || r == ';':
+			if i+1 < len(runes) && (r == '&' || r == '|') && runes[i+1] == r {
+				i++
+			}
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		case strings.ContainsRune("$`<>()\n", r):
+			return nil, refuse(string(r))
+		}
+		current.WriteRune(r)
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("%q has an unterminated %c quote", script, quote)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
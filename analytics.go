@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 )
@@ -10,36 +11,59 @@ import (
 type SessionAnalytics struct {
 	StartTime      time.Time
 	APICallCount   int
+	InputTokens    int
+	OutputTokens   int
 	EstimatedCost  float64
 	IssuesHandled  int
 	PRsCreated     int
 	QuestionsAsked int
+	PullRequests   []string       // URLs of PRs created this session
+	CostHistory    []CostSample   // Cumulative cost after each API call
+	Changes        []IssueChanges // Size of each committed fix
+	Pricing        *Pricing
 	mutex          sync.Mutex
 }
 
-// Cost estimates per provider (approximate, in SEK/kr)
-var costPerCall = map[string]float64{
-	"chatgpt": 0.02,   // ~0.02 kr per request (gpt-4)
-	"openai":  0.02,
-	"grok":    0.01,   // ~0.01 kr per request
-	"xai":     0.01,
-	"ollama":  0.0,    // Free (local)
+// IssueChanges is the size of the fix committed for an issue
+type IssueChanges struct {
+	Issue int `json:"issue"`
+	ChangeStats
 }
 
-func NewSessionAnalytics() *SessionAnalytics {
+// CostSample is a point on the session's cumulative cost curve
+type CostSample struct {
+	Time time.Time `json:"time"`
+	Cost float64   `json:"cost"`
+}
+
+// estimateTokens approximates the token count of a text (~4 characters per token)
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// Typical size of one issue's AI round trip, used for up-front estimates
+const (
+	typicalIssueInputTokens  = 8000
+	typicalIssueOutputTokens = 2000
+)
+
+func NewSessionAnalytics(pricing *Pricing) *SessionAnalytics {
 	return &SessionAnalytics{
 		StartTime: time.Now(),
+		Pricing:   pricing,
 	}
 }
 
-func (s *SessionAnalytics) RecordAPICall(service string) {
+// RecordAPICall counts a request and adds its cost based on the model's token prices
+func (s *SessionAnalytics) RecordAPICall(service, model string, inputTokens, outputTokens int) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	
 	s.APICallCount++
-	if cost, ok := costPerCall[service]; ok {
-		s.EstimatedCost += cost
-	}
+	s.InputTokens += inputTokens
+	s.OutputTokens += outputTokens
+	s.EstimatedCost += s.Pricing.Cost(service, model, inputTokens, outputTokens)
+	s.CostHistory = append(s.CostHistory, CostSample{Time: time.Now(), Cost: s.EstimatedCost})
 }
 
 func (s *SessionAnalytics) RecordIssueHandled() {
@@ -48,10 +72,18 @@ func (s *SessionAnalytics) RecordIssueHandled() {
 	s.IssuesHandled++
 }
 
-func (s *SessionAnalytics) RecordPRCreated() {
+func (s *SessionAnalytics) RecordPRCreated(url string) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.PRsCreated++
+	s.PullRequests = append(s.PullRequests, url)
+}
+
+// RecordChanges adds the size of a committed fix
+func (s *SessionAnalytics) RecordChanges(issue int, stats ChangeStats) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Changes = append(s.Changes, IssueChanges{Issue: issue, ChangeStats: stats})
 }
 
 func (s *SessionAnalytics) RecordQuestionAsked() {
@@ -60,13 +92,75 @@ func (s *SessionAnalytics) RecordQuestionAsked() {
 	s.QuestionsAsked++
 }
 
-func (s *SessionAnalytics) EstimateCostForIssues(count int, service string) float64 {
-	cost, ok := costPerCall[service]
-	if !ok {
-		cost = 0.001 // Default estimate
+// AnalyticsSnapshot is a point-in-time copy of the session counters
+type AnalyticsSnapshot struct {
+	StartTime      time.Time      `json:"start_time"`
+	APICallCount   int            `json:"api_calls"`
+	InputTokens    int            `json:"input_tokens"`
+	OutputTokens   int            `json:"output_tokens"`
+	EstimatedCost  float64        `json:"estimated_cost"`
+	IssuesHandled  int            `json:"issues_handled"`
+	PRsCreated     int            `json:"prs_created"`
+	QuestionsAsked int            `json:"questions_asked"`
+	PullRequests   []string       `json:"pull_requests"`
+	CostHistory    []CostSample   `json:"cost_history"`
+	Changes        []IssueChanges `json:"changes"`
+	Currency       string         `json:"currency"`
+}
+
+// TotalChanges sums the size of all fixes committed this session
+func (s AnalyticsSnapshot) TotalChanges() ChangeStats {
+	var total ChangeStats
+	for _, changes := range s.Changes {
+		total = total.Add(changes.ChangeStats)
+	}
+	return total
+}
+
+// Snapshot returns a copy of the current counters that is safe to read concurrently
+func (s *SessionAnalytics) Snapshot() AnalyticsSnapshot {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return AnalyticsSnapshot{
+		StartTime:      s.StartTime,
+		APICallCount:   s.APICallCount,
+		InputTokens:    s.InputTokens,
+		OutputTokens:   s.OutputTokens,
+		EstimatedCost:  s.EstimatedCost,
+		IssuesHandled:  s.IssuesHandled,
+		PRsCreated:     s.PRsCreated,
+		QuestionsAsked: s.QuestionsAsked,
+		PullRequests:   append([]string(nil), s.PullRequests...),
+		CostHistory:    append([]CostSample(nil), s.CostHistory...),
+		Changes:        append([]IssueChanges(nil), s.Changes...),
+		Currency:       s.Pricing.Currency,
 	}
+}
+
+// EstimateCostForIssues projects the cost of handling a number of issues with a model
+func (s *SessionAnalytics) EstimateCostForIssues(count int, service, model string) float64 {
+	perIssue := s.Pricing.Cost(service, model, typicalIssueInputTokens, typicalIssueOutputTokens)
 	// Each issue typically requires 1-2 API calls
-	return float64(count) * cost * 1.5
+	return float64(count) * perIssue * 1.5
+}
+
+// boxTitle centers text between the borders of the 64 column boxes around
+// the banner and summary. Emoji take two columns in a terminal.
+func boxTitle(text string) string {
+	width := 0
+	for _, r := range text {
+		switch {
+		case r == 0xFE0F:
+		case r >= 0x1F000:
+			width += 2
+		default:
+			width++
+		}
+	}
+	left := max((64-width)/2, 0)
+	right := max(64-width-left, 0)
+	return "║" + strings.Repeat(" ", left) + text + strings.Repeat(" ", right) + "║"
 }
 
 func (s *SessionAnalytics) PrintSummary() {
@@ -76,29 +170,40 @@ func (s *SessionAnalytics) PrintSummary() {
 	duration := time.Since(s.StartTime)
 	
 	fmt.Println("\n╔════════════════════════════════════════════════════════════════╗")
-	fmt.Println("║                    📊 Session Summary                          ║")
+	fmt.Println(boxTitle(T("summary.title")))
 	fmt.Println("╚════════════════════════════════════════════════════════════════╝")
-	fmt.Printf("\n⏱️  Duration: %s\n", duration.Round(time.Second))
-	fmt.Printf("📞 API Calls: %d\n", s.APICallCount)
-	fmt.Printf("🐛 Issues Handled: %d\n", s.IssuesHandled)
-	fmt.Printf("🔧 Pull Requests Created: %d\n", s.PRsCreated)
-	fmt.Printf("❓ Questions Asked: %d\n", s.QuestionsAsked)
+	fmt.Printf(T("summary.duration"), duration.Round(time.Second))
+	fmt.Printf(T("summary.api_calls"), s.APICallCount)
+	fmt.Printf(T("summary.issues"), s.IssuesHandled)
+	fmt.Printf(T("summary.prs"), s.PRsCreated)
+	fmt.Printf(T("summary.questions"), s.QuestionsAsked)
+	if len(s.Changes) > 0 {
+		var total ChangeStats
+		for _, changes := range s.Changes {
+			total = total.Add(changes.ChangeStats)
+		}
+		fmt.Printf(T("summary.changes"), total)
+		for _, changes := range s.Changes {
+			fmt.Printf("   #%d: %s\n", changes.Issue, changes.ChangeStats)
+		}
+	}
 	
 	if s.EstimatedCost > 0 {
-		fmt.Printf("💰 Estimated Cost: %.4f kr\n", s.EstimatedCost)
+		fmt.Printf(T("summary.cost"), s.Pricing.Format(s.EstimatedCost))
 	} else {
-		fmt.Printf("💰 Cost: Free (local model)\n")
+		fmt.Printf(T("summary.free"))
 	}
 	fmt.Println()
 }
 
-func (s *SessionAnalytics) PrintCostEstimate(issueCount int, service string) {
-	cost := s.EstimateCostForIssues(issueCount, service)
+func (s *SessionAnalytics) PrintCostEstimate(issueCount int, service, model string) {
+	cost := s.EstimateCostForIssues(issueCount, service, model)
 	
 	if cost > 0 {
-		fmt.Printf("\n💰 Estimated cost for %d issue(s): %.4f kr\n", issueCount, cost)
-		if cost > 1.0 {
-			fmt.Println("⚠️  This will cost more than 1 kr - proceed with caution")
+		fmt.Printf(T("estimate.cost"), issueCount, s.Pricing.Format(cost))
+		// Warn above the equivalent of one US dollar
+		if threshold := s.Pricing.rate; cost > threshold {
+			fmt.Printf(T("estimate.warning"), s.Pricing.Format(threshold))
 		}
 	}
 }
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// analysisMarker tags root-cause analysis comments
+const analysisMarker = "<!-- mr-code-fixer:analysis -->"
+
+// RootCause is the AI's read-only analysis of an issue
+type RootCause struct {
+	Summary        string          `json:"summary"`
+	LikelyCause    string          `json:"likely_cause"`
+	SuspectedFiles []SuspectedFile `json:"suspected_files"`
+	Approach       []string        `json:"suggested_approach"`
+	Confidence     string          `json:"confidence"`
+	Questions      []string        `json:"questions"`
+}
+
+// SuspectedFile is a file the analysis points at
+type SuspectedFile struct {
+	Path     string `json:"path"`
+	Location string `json:"location"` // Function, type or section, if known
+	Reason   string `json:"reason"`
+}
+
+// analyzeCommand investigates an issue and posts a root-cause analysis
+// instead of a fix. No code is generated, committed or pushed.
+func analyzeCommand(ctx context.Context, args []string) error {
+	config := loadConfig()
+
+	// Allow the issue number before the flags: analyze 42 --repo foo
+	var issueNumber int
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		number, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+		if err != nil {
+			return fmt.Errorf("invalid issue number %q", args[0])
+		}
+		issueNumber = number
+		args = args[1:]
+	}
+
+	var printOnly bool
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	fs.IntVar(&issueNumber, "issue", issueNumber, "Issue to analyze")
+	fs.BoolVar(&printOnly, "print", false, "Print the analysis instead of posting it")
+	parseFlags(&config, fs, args)
+
+	if issueNumber == 0 && fs.NArg() > 0 {
+		number, err := strconv.Atoi(strings.TrimPrefix(fs.Arg(0), "#"))
+		if err != nil {
+			return fmt.Errorf("invalid issue number %q", fs.Arg(0))
+		}
+		issueNumber = number
+	}
+	if issueNumber <= 0 {
+		return fmt.Errorf("an issue number is required, e.g. mr-code-fixer analyze 42")
+	}
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+
+	analytics := NewSessionAnalytics(NewPricing(config))
+	provider := newHostingProvider(config)
+	aiClient := newAIClient(config, analytics)
+
+	fetched, err := provider.GetIssue(ctx, issueNumber)
+	if err != nil {
+		return fmt.Errorf("failed to fetch issue #%d: %w", issueNumber, err)
+	}
+	issue := prepareIssue(ctx, config, analytics, *fetched)
+
+	gitOps, err := newRepoGitOps(ctx, config, provider)
+	if err != nil {
+		return fmt.Errorf("failed to initialize git: %w", err)
+	}
+	defer gitOps.Cleanup()
+	if err := cloneRepo(ctx, config, gitOps); err != nil {
+		return fmt.Errorf("failed to clone repo: %w", err)
+	}
+
+	memory := loadRepoMemory(config.RepoOwner, config.RepoName)
+	_, repoContext, err := prepareRepoContext(ctx, config, provider, gitOps, aiClient, memory, issue)
+	if err != nil {
+		return err
+	}
+	if config.ContextBudget > 0 {
+		summarizeContext(ctx, newSummaryClient(config, analytics, aiClient), repoContext, config.ContextBudget)
+	}
+
+	fmt.Printf("🔬 Analyzing the root cause of issue #%d...\n", issue.Number)
+	analysis, err := analyzeRootCause(ctx, aiClient, issue, repoContext)
+	if err != nil {
+		return fmt.Errorf("AI analysis failed: %w", err)
+	}
+	dropMissingFiles(analysis, gitOps.repoPath)
+	comment := rootCauseComment(analysis)
+
+	if printOnly {
+		fmt.Println()
+		fmt.Println(comment)
+	} else {
+		if err := provider.AddIssueComment(ctx, issue.Number, comment); err != nil {
+			return fmt.Errorf("failed to post analysis: %w", err)
+		}
+		fmt.Printf("✓ Posted root-cause analysis on issue #%d\n", issue.Number)
+	}
+
+	analytics.RecordIssueHandled()
+	recordHistory(config, FixOutcome{IssueNumber: issue.Number, Title: issue.Title, Result: "analyzed", Confidence: analysis.Confidence},
+		analytics.Snapshot().EstimatedCost, analytics.Pricing.Currency)
+	analytics.PrintSummary()
+	return nil
+}
+
+// analyzeRootCause asks the AI where the problem is, without asking for code
+func analyzeRootCause(ctx context.Context, aiClient AIClient, issue Issue, repoContext *RepoContext) (*RootCause, error) {
+	var prompt strings.Builder
+	prompt.WriteString("# Issue\n\n")
+	prompt.WriteString(fmt.Sprintf("**Title:** %s\n\n", issue.Title))
+	prompt.WriteString(fmt.Sprintf("**Description:**\n%s\n\n", issue.Body))
+	if repoContext.Memory != "" {
+		prompt.WriteString(repoContext.Memory)
+	}
+	prompt.WriteString(instructionsSection(repoContext.Instructions))
+	writeRepoOverview(&prompt, repoContext)
+
+	prompt.WriteString(`
+# Task
+
+Investigate the root cause of this issue. Do NOT write a fix or any code: the maintainers want an analysis they can act on themselves. Respond in this JSON format:
+
+{
+  "summary": "One or two sentences restating the problem in technical terms",
+  "likely_cause": "What in the code causes the problem and why, citing the code involved",
+  "suspected_files": [
+    {"path": "relative/path/to/file.ext", "location": "function, type or section, or empty", "reason": "why this file is involved"}
+  ],
+  "suggested_approach": ["step of a fix, in the order a developer would take them"],
+  "confidence": "high|medium|low",
+  "questions": ["what the reporter or maintainers should clarify, if anything"]
+}
+
+Only list files from the repository above, most likely first. Say so in "likely_cause" if the code shown is not enough to be sure. Return valid JSON only, no markdown code blocks.`)
+
+	response, err := completeJSON(ctx, aiClient, systemPrompt(repoContext), prompt.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var analysis RootCause
+	if err := json.Unmarshal([]byte(cleanJSONResponse(response)), &analysis); err != nil {
+		return nil, fmt.Errorf("failed to parse analysis: %w", err)
+	}
+	return &analysis, nil
+}
+
+// dropMissingFiles removes suspected files that do not exist in the clone, so
+// the comment never points maintainers at invented paths
+func dropMissingFiles(analysis *RootCause, repoPath string) {
+	var files []SuspectedFile
+	for _, file := range analysis.SuspectedFiles {
+		if !filepath.IsLocal(file.Path) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(repoPath, file.Path)); err != nil {
+			fmt.Printf("Warning: Dropping suspected file %s, it does not exist\n", file.Path)
+			continue
+		}
+		files = append(files, file)
+	}
+	analysis.SuspectedFiles = files
+}
+
+// rootCauseComment renders an analysis for the issue
+func rootCauseComment(analysis *RootCause) string {
+	var comment strings.Builder
+	comment.WriteString("## 🔬 Root-Cause Analysis\n\n")
+	if analysis.Summary != "" {
+		comment.WriteString(analysis.Summary + "\n\n")
+	}
+
+	if analysis.LikelyCause != "" {
+		comment.WriteString("### Likely Cause\n\n" + analysis.LikelyCause + "\n\n")
+	}
+
+	if len(analysis.SuspectedFiles) > 0 {
+		comment.WriteString("### Suspected Files\n\n")
+		for _, file := range analysis.SuspectedFiles {
+			location := ""
+			if file.Location != "" {
+				location = fmt.Sprintf(" (`%s`)", file.Location)
+			}
+			comment.WriteString(fmt.Sprintf("- `%s`%s: %s\n", file.Path, location, file.Reason))
+		}
+		comment.WriteString("\n")
+	}
+
+	if len(analysis.Approach) > 0 {
+		comment.WriteString("### Suggested Approach\n\n")
+		for i, step := range analysis.Approach {
+			comment.WriteString(fmt.Sprintf("%d. %s\n", i+1, step))
+		}
+		comment.WriteString("\n")
+	}
+
+	if len(analysis.Questions) > 0 {
+		comment.WriteString("### Open Questions\n\n")
+		for _, question := range analysis.Questions {
+			comment.WriteString(fmt.Sprintf("- %s\n", question))
+		}
+		comment.WriteString("\n")
+	}
+
+	if analysis.Confidence != "" {
+		comment.WriteString(fmt.Sprintf("**Confidence:** %s\n\n", analysis.Confidence))
+	}
+
+	comment.WriteString(fmt.Sprintf("%s\n\n---\n\n%s", analysisMarker, bot.footer("This analysis was generated by "+bot.Name+" without changing any code. Comment `/fix` if you want me to attempt a fix.")))
+	return comment.String()
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxAPIQueue bounds the fixes requested through the API that wait for the
+// serve loop
+const maxAPIQueue = 50
+
+// maxAPIRequestBytes bounds the body of a request to the API
+const maxAPIRequestBytes = 64 << 10
+
+// FixRequest is the body of POST /fix. Owner and repo default to the served
+// repository.
+type FixRequest struct {
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	Issue int    `json:"issue"`
+}
+
+// APIStats is the JSON document served at /stats
+type APIStats struct {
+	Repo        string            `json:"repo"`
+	AIService   string            `json:"ai_service"`
+	AIModel     string            `json:"ai_model"`
+	Analytics   AnalyticsSnapshot `json:"analytics"`
+	Queued      int               `json:"queued"`
+	Running     int               `json:"running"`
+	Succeeded   int               `json:"succeeded"`
+	Failed      int               `json:"failed"`
+	Skipped     int               `json:"skipped"`
+	SuccessRate float64           `json:"success_rate"`
+}
+
+// apiJob is a fix requested through the API
+type apiJob struct {
+	config Config // Pointed at the job's repository
+	job    *DashboardJob
+}
+
+// FixAPI is the REST API of serve mode, for tools and chat bots that trigger
+// fixes. Requests need api_token as a bearer token. Requested fixes are
+// queued and run by the serve loop between polls, so they never run at the
+// same time as the issues it finds itself.
+type FixAPI struct {
+	config    Config
+	dashboard *Dashboard
+	queue     chan apiJob
+	mutex     sync.Mutex // Held while queueing, so a send never blocks
+}
+
+func NewFixAPI(config Config, dashboard *Dashboard) *FixAPI {
+	return &FixAPI{
+		config:    config,
+		dashboard: dashboard,
+		queue:     make(chan apiJob, maxAPIQueue),
+	}
+}
+
+// Jobs is the queue of requested fixes, nil without an API so receiving
+// from it blocks forever
+func (a *FixAPI) Jobs() <-chan apiJob {
+	if a == nil {
+		return nil
+	}
+	return a.queue
+}
+
+// Handler returns the HTTP handler of the API: POST /fix, GET /jobs/{id}
+// and GET /stats
+func (a *FixAPI) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fix", a.handleFix)
+	mux.HandleFunc("/jobs/", a.handleJob)
+	mux.HandleFunc("/stats", a.handleStats)
+	return a.authenticate(mux)
+}
+
+// authenticate refuses requests without "Authorization: Bearer <api_token>"
+func (a *FixAPI) authenticate(next http.Handler) http.Handler {
+	return requireToken(a.config.APIToken, false, next)
+}
+
+// requireToken refuses requests without "Authorization: Bearer <token>" or,
+// with inQuery, a matching token query parameter, for pages opened in a browser
+func requireToken(token string, inQuery bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok && inQuery {
+			given, ok = r.URL.Query().Get("token"), r.URL.Query().Has("token")
+		}
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mr-code-fixer"`)
+			writeAPIError(w, http.StatusUnauthorized, "missing or wrong API token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleFix queues a fix for an issue. A fix already queued or running for
+// the issue is returned instead of queueing another one.
+func (a *FixAPI) handleFix(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeAPIError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	var request FixRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIRequestBytes)).Decode(&request); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if request.Issue <= 0 {
+		writeAPIError(w, http.StatusBadRequest, "issue must be an issue number")
+		return
+	}
+	if request.Owner == "" && request.Repo == "" {
+		request.Owner, request.Repo = a.config.RepoOwner, a.config.RepoName
+	}
+	repo := request.Owner + "/" + request.Repo
+	if !a.servesRepo(repo) {
+		writeAPIError(w, http.StatusForbidden, fmt.Sprintf("%s is not served here; add it to repos", repo))
+		return
+	}
+	config, err := configForRepo(a.config, repo)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if job, ok := a.dashboard.PendingJob(repo, request.Issue); ok {
+		writeAPIJSON(w, http.StatusOK, job)
+		return
+	}
+	if len(a.queue) == cap(a.queue) {
+		writeAPIError(w, http.StatusServiceUnavailable, fmt.Sprintf("%d fixes are already queued, try again later", cap(a.queue)))
+		return
+	}
+	job := a.dashboard.QueueJob(repo, request.Issue)
+	a.queue <- apiJob{config: config, job: job}
+	fmt.Printf("🔌 Fix of %s#%d requested through the API (job %d)\n", repo, request.Issue, job.ID)
+
+	snapshot, _ := a.dashboard.Job(job.ID)
+	w.Header().Set("Location", fmt.Sprintf("/jobs/%d", job.ID))
+	writeAPIJSON(w, http.StatusAccepted, snapshot)
+}
+
+// handleJob reports the state of a job, and the pull requests it created
+func (a *FixAPI) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeAPIError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/jobs/"))
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, "no such job")
+		return
+	}
+	job, ok := a.dashboard.Job(id)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "no such job")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, job)
+}
+
+// handleStats reports the session's analytics and job counts
+func (a *FixAPI) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeAPIError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	status := a.dashboard.Status()
+	stats := APIStats{
+		Repo:        status.Repo,
+		AIService:   status.AIService,
+		AIModel:     status.AIModel,
+		Analytics:   status.Analytics,
+		Succeeded:   status.Succeeded,
+		Failed:      status.Failed,
+		SuccessRate: status.SuccessRate,
+	}
+	for _, job := range status.Jobs {
+		switch job.Status {
+		case "queued":
+			stats.Queued++
+		case "running":
+			stats.Running++
+		case "skipped":
+			stats.Skipped++
+		}
+	}
+	writeAPIJSON(w, http.StatusOK, stats)
+}
+
+// servesRepo reports whether fixes may be requested for a repository: the
+// served one or one listed in repos
+func (a *FixAPI) servesRepo(repo string) bool {
+	if strings.EqualFold(repo, a.config.RepoOwner+"/"+a.config.RepoName) {
+		return true
+	}
+	for _, listed := range a.config.Repos {
+		if isRepoURL(listed) {
+			owner, name, err := parseRepoURL(listed)
+			if err != nil {
+				continue
+			}
+			listed = owner + "/" + name
+		}
+		if strings.EqualFold(repo, strings.Trim(listed, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// run fixes the issue of a job requested through the API, with the serve
+// loop's provider for the served repository. Unlike issues found by polling,
+// the issue skips the handled and trust checks: whoever holds the API token
+// asked for it.
+func (a *FixAPI) run(ctx context.Context, request apiJob, provider HostingProvider, aiClient AIClient, analytics *SessionAnalytics, digest *serveDigest) {
+	config, job := request.config, request.job
+	var err error
+	if !strings.EqualFold(job.Repo, a.config.RepoOwner+"/"+a.config.RepoName) {
+		// Another repository may be on another host, with another account
+		provider = newHostingProvider(config)
+		defer func(login string) { bot.Login = login }(bot.Login)
+		err = identifyBotAccount(ctx, provider)
+	}
+
+	var issue *Issue
+	if err == nil {
+		issue, err = provider.GetIssue(ctx, job.IssueNumber)
+	}
+	if err == nil && issue.PullRequest != nil {
+		err = fmt.Errorf("#%d is a pull request", job.IssueNumber)
+	} else if err == nil && issue.State == "closed" {
+		err = fmt.Errorf("issue #%d is closed", job.IssueNumber)
+	}
+	if err != nil {
+		fmt.Printf("\033[31m✗ API job %d:\033[0m %v\n", job.ID, err)
+		a.dashboard.FinishJob(job, err)
+		return
+	}
+
+	fmt.Printf("\n🔧 Processing Issue %s#%d for API job %d: %s\n", job.Repo, issue.Number, job.ID, issue.Title)
+	a.dashboard.RunJob(job, *issue)
+	before := len(analytics.Snapshot().PullRequests)
+	err = processIssue(ctx, config, provider, aiClient, *issue, analytics)
+	a.dashboard.AddPullRequests(job, analytics.Snapshot().PullRequests[before:])
+	a.dashboard.FinishJob(job, err)
+	digest.record(*issue, err)
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, map[string]string{"error": message})
+}
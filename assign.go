@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// selfAssignee returns the account the bot assigns issues to while working on them
+func selfAssignee(ctx context.Context, config Config, provider HostingProvider) (string, error) {
+	if config.SelfAssignUser != "" {
+		return config.SelfAssignUser, nil
+	}
+	return provider.GetAuthenticatedUser(ctx)
+}
+
+// selfAssign assigns every issue of a group to the bot's account and returns the
+// numbers it newly assigned. Issues already assigned to it are left alone, so they
+// are not unassigned afterwards either.
+func selfAssign(ctx context.Context, config Config, provider HostingProvider, issue Issue) map[int]string {
+	login, err := selfAssignee(ctx, config, provider)
+	if err != nil {
+		fmt.Printf("Warning: Could not determine the account to assign: %v\n", err)
+		return nil
+	}
+
+	assigned := make(map[int]string)
+	for _, member := range groupIssues(issue) {
+		if slices.ContainsFunc(member.Assignees, func(u User) bool { return strings.EqualFold(u.Login, login) }) {
+			continue
+		}
+		if err := provider.AddAssignees(ctx, member.Number, []string{login}); err != nil {
+			fmt.Printf("Warning: Could not assign #%d to @%s: %v\n", member.Number, login, err)
+			continue
+		}
+		assigned[member.Number] = login
+	}
+	if len(assigned) > 0 {
+		fmt.Printf("✓ Assigned %s to @%s\n", issueRefs(issue), login)
+	}
+	return assigned
+}
+
+// selfUnassign removes the assignments made by selfAssign
+func selfUnassign(ctx context.Context, provider HostingProvider, assigned map[int]string) {
+	for number, login := range assigned {
+		if err := provider.RemoveAssignees(ctx, number, []string{login}); err != nil {
+			fmt.Printf("Warning: Could not unassign @%s from #%d: %v\n", login, number, err)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxIssueImages limits how many attached images are sent to the vision model
+const maxIssueImages = 3
+
+// maxImageBytes skips attachments that are too large to send inline
+const maxImageBytes = 5 * 1024 * 1024
+
+var (
+	markdownImagePattern = regexp.MustCompile(`!\[[^\]]*\]\((https?://[^)\s]+)[^)]*\)`)
+	htmlImagePattern     = regexp.MustCompile(`<img[^>]+src=["'](https?://[^"']+)["']`)
+)
+
+// VisionClient is implemented by AI clients that can look at images
+type VisionClient interface {
+	DescribeImages(ctx context.Context, prompt string, images []string) (string, error)
+}
+
+// extractImageURLs finds markdown and HTML images in an issue body
+func extractImageURLs(body string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+
+	for _, pattern := range []*regexp.Regexp{markdownImagePattern, htmlImagePattern} {
+		for _, match := range pattern.FindAllStringSubmatch(body, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				urls = append(urls, match[1])
+			}
+		}
+	}
+
+	return urls
+}
+
+// truncateIssueBody keeps huge issue bodies (pasted logs) within a character budget.
+// Repeated log lines are collapsed first; if still too long, the head and tail are kept.
+func truncateIssueBody(body string, maxChars int) string {
+	if maxChars <= 0 || len(body) <= maxChars {
+		return body
+	}
+
+	// Collapse runs of identical lines
+	var collapsed []string
+	lines := strings.Split(body, "\n")
+	for i := 0; i < len(lines); {
+		j := i + 1
+		for j < len(lines) && lines[j] == lines[i] {
+			j++
+		}
+		collapsed = append(collapsed, lines[i])
+		if j-i > 1 {
+			collapsed = append(collapsed, fmt.Sprintf("... (previous line repeated %d more times)", j-i-1))
+		}
+		i = j
+	}
+	body = strings.Join(collapsed, "\n")
+
+	if len(body) <= maxChars {
+		return body
+	}
+
+	// Keep the start (description) and the end (usually the final error)
+	head := maxChars * 6 / 10
+	tail := maxChars - head
+	omitted := len(body) - head - tail
+	return fmt.Sprintf("%s\n\n... [%d characters omitted] ...\n\n%s",
+		strings.ToValidUTF8(body[:head], ""), omitted, strings.ToValidUTF8(body[len(body)-tail:], ""))
+}
+
+// githubAttachmentHosts are the hosts serving issue attachments on GitHub,
+// the only ones the token is sent to
+var githubAttachmentHosts = map[string]bool{
+	"github.com":                                true,
+	"user-images.githubusercontent.com":         true,
+	"private-user-images.githubusercontent.com": true,
+}
+
+// isGithubAttachmentURL reports whether an image URL is an https URL on one
+// of the GitHub attachment hosts
+func isGithubAttachmentURL(imageURL string) bool {
+	u, err := url.Parse(imageURL)
+	if err != nil || u.Scheme != "https" || u.User != nil {
+		return false
+	}
+	return githubAttachmentHosts[strings.ToLower(u.Hostname())]
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, internal like RFC 1918
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicAddress reports whether an IP address is on the internet, not
+// loopback, private, link-local (such as cloud metadata at 169.254.169.254)
+// or otherwise internal
+func isPublicAddress(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified() && !sharedAddressSpace.Contains(ip)
+}
+
+// refuseInternalAddress is a dialer control that only lets connections to
+// public addresses through
+func refuseInternalAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicAddress(ip) {
+		return fmt.Errorf("refusing to connect to %s: not a public address", host)
+	}
+	return nil
+}
+
+// untrustedImageTransport fetches images from hosts an issue names. It only
+// connects to public addresses, so an issue can't make the bot fetch internal
+// URLs and hand them to the vision model, and it connects directly, since a
+// proxy would hide the address. The offline_ai allowlist still applies.
+func untrustedImageTransport() http.RoundTripper {
+	transport := baseTransport().Clone()
+	transport.Proxy = nil
+	dialer := &net.Dialer{Timeout: 30 * time.Second, Control: refuseInternalAddress}
+	transport.DialContext = dialer.DialContext
+	if allowlist, ok := http.DefaultTransport.(*allowlistTransport); ok {
+		return &allowlistTransport{next: transport, allowed: allowlist.allowed}
+	}
+	return transport
+}
+
+// imageTransport sends requests for the GitHub attachment hosts and the
+// provider's host the usual way and everything else, redirects included,
+// through untrustedImageTransport
+type imageTransport struct {
+	providerHost string
+	untrusted    http.RoundTripper
+}
+
+func (t *imageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if host := strings.ToLower(req.URL.Hostname()); githubAttachmentHosts[host] || host == t.providerHost {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	return t.untrusted.RoundTrip(req)
+}
+
+// downloadImage fetches an attachment and returns it as a data URL.
+// GitHub-hosted attachments of private repos need the token. Only the GitHub
+// attachment hosts and providerHost, the Gitea server, may be internal.
+func downloadImage(ctx context.Context, imageURL, token, providerHost string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if token != "" && isGithubAttachmentURL(imageURL) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &imageTransport{providerHost: providerHost, untrusted: untrustedImageTransport()},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download failed: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxImageBytes {
+		return "", fmt.Errorf("image larger than %d MB", maxImageBytes/1024/1024)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", fmt.Errorf("not an image (%s)", contentType)
+	}
+
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// describeIssueImages asks a vision model what the screenshots attached to an issue show
+func describeIssueImages(ctx context.Context, config Config, analytics *SessionAnalytics, issue Issue) (string, error) {
+	urls := extractImageURLs(issue.Body)
+	if len(urls) == 0 {
+		return "", nil
+	}
+	if len(urls) > maxIssueImages {
+		urls = urls[:maxIssueImages]
+	}
+
+	visionConfig := config
+	visionConfig.AIModel = config.VisionModel
+	vision, ok := newAIClient(visionConfig, analytics).(VisionClient)
+	if !ok {
+		return "", fmt.Errorf("%s does not support images", config.AIService)
+	}
+
+	var providerHost string
+	if u, err := url.Parse(providerBaseURL(config)); err == nil {
+		providerHost = strings.ToLower(u.Hostname())
+	}
+
+	var images []string
+	for _, imageURL := range urls {
+		image, err := downloadImage(ctx, imageURL, config.GithubToken, providerHost)
+		if err != nil {
+			fmt.Printf("Warning: Could not download %s: %v\n", imageURL, err)
+			continue
+		}
+		images = append(images, image)
+	}
+	if len(images) == 0 {
+		return "", nil
+	}
+
+	fmt.Printf("🖼  Describing %d attached image(s) with %s...\n", len(images), config.VisionModel)
+	prompt := fmt.Sprintf(`These images are attached to a bug report titled %q.
+Describe what each image shows that is relevant for fixing the issue: error messages (transcribe them exactly), UI state, stack traces, file names and line numbers. Be concise.`, issue.Title)
+
+	return vision.DescribeImages(ctx, prompt, images)
+}
+
+// prepareIssue enriches and trims an issue before analysis
+func prepareIssue(ctx context.Context, config Config, analytics *SessionAnalytics, issue Issue) Issue {
+	if config.VisionModel != "" {
+		description, err := describeIssueImages(ctx, config, analytics, issue)
+		if err != nil {
+			fmt.Printf("Warning: Could not describe attached images: %v\n", err)
+		} else if description != "" {
+			issue.Body += "\n\n## Attached Images (AI description)\n\n" + description
+		}
+	}
+
+	if len(issue.Body) > config.MaxIssueBodyChars && config.MaxIssueBodyChars > 0 {
+		fmt.Printf("✂️  Issue body is %d characters, truncating to %d\n", len(issue.Body), config.MaxIssueBodyChars)
+		issue.Body = truncateIssueBody(issue.Body, config.MaxIssueBodyChars)
+	}
+
+	return issue
+}
+
+// describeImagesOpenAICompatible sends images to an OpenAI-compatible chat completions endpoint
+func describeImagesOpenAICompatible(ctx context.Context, client *http.Client, scheduler *RequestScheduler, baseURL, apiKey, model, prompt string, images []string) (string, error) {
+	content := []map[string]interface{}{
+		{"type": "text", "text": prompt},
+	}
+	for _, image := range images {
+		content = append(content, map[string]interface{}{
+			"type":      "image_url",
+			"image_url": map[string]string{"url": image},
+		})
+	}
+
+	reqBody := map[string]interface{}{
+		"model": model,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": content},
+		},
+		"max_tokens": 1500,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := scheduler.Do(ctx, client, req, estimateTokens(prompt))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("vision API error: %s - %s", resp.Status, string(body))
+	}
+
+	var visionResp OpenAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&visionResp); err != nil {
+		return "", err
+	}
+
+	if len(visionResp.Choices) == 0 {
+		return "", fmt.Errorf("no response from vision model")
+	}
+
+	return visionResp.Choices[0].Message.Content, nil
+}
+
+func (o *OpenAIClient) DescribeImages(ctx context.Context, prompt string, images []string) (string, error) {
+	if o.analytics != nil {
+		// Image tokens are not known up front; count the prompt and a typical description
+		o.analytics.RecordAPICall("chatgpt", o.model, estimateTokens(prompt), 300)
+	}
+	return describeImagesOpenAICompatible(ctx, o.client, o.scheduler, o.baseURL, o.apiKey, o.model, prompt, images)
+}
+
+func (x *XAIClient) DescribeImages(ctx context.Context, prompt string, images []string) (string, error) {
+	if x.analytics != nil {
+		// Image tokens are not known up front; count the prompt and a typical description
+		x.analytics.RecordAPICall("grok", x.model, estimateTokens(prompt), 300)
+	}
+	return describeImagesOpenAICompatible(ctx, x.client, x.scheduler, x.baseURL, x.apiKey, x.model, prompt, images)
+}
+
+func (o *OllamaClient) DescribeImages(ctx context.Context, prompt string, images []string) (string, error) {
+	// Ollama wants raw base64 without the data URL prefix
+	var rawImages []string
+	for _, image := range images {
+		if comma := strings.Index(image, ","); comma != -1 {
+			image = image[comma+1:]
+		}
+		rawImages = append(rawImages, image)
+	}
+
+	return o.generate(ctx, OllamaRequest{
+		Prompt: prompt,
+		Images: rawImages,
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := map[string]bool{
+		"140.82.112.3":    true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+		"::ffff:10.0.0.1": false,
+	}
+	for address, want := range tests {
+		if got := isPublicAddress(net.ParseIP(address)); got != want {
+			t.Errorf("isPublicAddress(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestDownloadImageRefusesInternalHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG\r\n\x1a\n"))
+	}))
+	defer server.Close()
+
+	if _, err := downloadImage(context.Background(), server.URL+"/x.png", "", "github.com"); err == nil {
+		t.Error("downloaded an image from a loopback address")
+	}
+	// Nor through a redirect from the provider's host
+	redirect := httptest.NewServer(http.RedirectHandler(server.URL+"/x.png", http.StatusFound))
+	defer redirect.Close()
+	if _, err := downloadImage(context.Background(), strings.Replace(redirect.URL, "127.0.0.1", "localhost", 1), "", "localhost"); err == nil {
+		t.Error("followed a redirect to a loopback address")
+	}
+
+	// The provider's own host, e.g. a Gitea server on the local network, may be internal
+	if _, err := downloadImage(context.Background(), server.URL+"/x.png", "", "127.0.0.1"); err != nil {
+		t.Errorf("image from the provider host refused: %v", err)
+	}
+}
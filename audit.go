@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of audited side effects
+const (
+	auditProvider = "provider" // GitHub / Gitea API mutation
+	auditGit      = "git"      // Push to the remote
+	auditAI       = "ai"       // Request to an AI service
+	auditNotify   = "notify"   // Webhook notification
+	auditNetwork  = "network"  // Request blocked by offline_ai
+)
+
+// AuditEntry is one side effect of the bot on the outside world, appended to
+// the audit log as a JSON line whether it succeeded or not
+type AuditEntry struct {
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	Action  string    `json:"action"`           // e.g. "add_comment", "create_pull_request", "push"
+	Repo    string    `json:"repo,omitempty"`   // owner/name
+	Target  string    `json:"target,omitempty"` // Issue or PR number, branch or endpoint
+	Summary string    `json:"summary,omitempty"`
+	Outcome string    `json:"outcome"` // "ok" or "error"
+	Error   string    `json:"error,omitempty"`
+}
+
+// AuditLog is an append-only JSONL file of everything the bot did outside
+// the machine. A nil AuditLog records nothing.
+type AuditLog struct {
+	mu   sync.Mutex
+	path string
+}
+
+// auditLog is the process-wide log, set up by configureAudit like the network settings
+var auditLog *AuditLog
+
+func getAuditPath() string {
+	return filepath.Join(getDataDir(), "audit.jsonl")
+}
+
+// configureAudit opens the audit log from audit_log ("off" disables it)
+func configureAudit(config Config) error {
+	path := config.AuditLog
+	switch path {
+	case "off":
+		auditLog = nil
+		return nil
+	case "":
+		path = getAuditPath()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating audit log directory: %w", err)
+	}
+	auditLog = &AuditLog{path: path}
+	return nil
+}
+
+// Record appends an entry. Failures to write are reported but never stop the pipeline.
+func (a *AuditLog) Record(entry AuditEntry) {
+	if a == nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		fmt.Printf("Warning: Could not write audit log: %v\n", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		fmt.Printf("Warning: Could not write audit log: %v\n", err)
+	}
+}
+
+// recordAudit logs one side effect and its result
+func recordAudit(kind, action, repo, target, summary string, err error) {
+	entry := AuditEntry{Kind: kind, Action: action, Repo: repo, Target: target, Summary: summary, Outcome: "ok"}
+	if err != nil {
+		entry.Outcome = "error"
+		entry.Error = err.Error()
+	}
+	auditLog.Record(entry)
+}
+
+// recordHTTPAudit logs a request whose outcome is an HTTP response. The path
+// of a notification webhook is its secret, so only the host is logged for those.
+func recordHTTPAudit(kind, action, repo string, req *http.Request, summary string, resp *http.Response, err error) {
+	target := req.Method + " " + req.URL.Host + req.URL.Path
+	if kind == auditNotify {
+		target = req.Method + " " + req.URL.Host
+	}
+	if err == nil && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		err = fmt.Errorf("HTTP %s", resp.Status)
+	}
+	recordAudit(kind, action, repo, target, summary, err)
+}
+
+// auditSummary shortens free text such as comment bodies for the log
+func auditSummary(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > 200 {
+		text = text[:200] + "..."
+	}
+	return text
+}
+
+// auditedProvider records every mutating call of a HostingProvider; reads
+// are passed through unrecorded
+type auditedProvider struct {
+	HostingProvider
+	repo string
+}
+
+func (p *auditedProvider) AddIssueComment(ctx context.Context, issueNumber int, comment string) error {
+	err := p.HostingProvider.AddIssueComment(ctx, issueNumber, comment)
+	recordAudit(auditProvider, "add_comment", p.repo, fmt.Sprintf("#%d", issueNumber), auditSummary(comment), err)
+	return err
+}
+
+func (p *auditedProvider) EditIssueComment(ctx context.Context, commentID int, comment string) error {
+	err := p.HostingProvider.EditIssueComment(ctx, commentID, comment)
+	recordAudit(auditProvider, "edit_comment", p.repo, fmt.Sprintf("comment %d", commentID), auditSummary(comment), err)
+	return err
+}
+
+func (p *auditedProvider) CreateGist(ctx context.Context, description, filename, content string) (string, error) {
+	url, err := p.HostingProvider.CreateGist(ctx, description, filename, content)
+	recordAudit(auditProvider, "create_gist", p.repo, url, description, err)
+	return url, err
+}
+
+func (p *auditedProvider) CloseIssue(ctx context.Context, issueNumber int) error {
+	err := p.HostingProvider.CloseIssue(ctx, issueNumber)
+	recordAudit(auditProvider, "close_issue", p.repo, fmt.Sprintf("#%d", issueNumber), "", err)
+	return err
+}
+
+func (p *auditedProvider) ReopenIssue(ctx context.Context, issueNumber int) error {
+	err := p.HostingProvider.ReopenIssue(ctx, issueNumber)
+	recordAudit(auditProvider, "reopen_issue", p.repo, fmt.Sprintf("#%d", issueNumber), "", err)
+	return err
+}
+
+func (p *auditedProvider) CreatePullRequest(ctx context.Context, title, body, head, base string) (*PullRequest, error) {
+	pr, err := p.HostingProvider.CreatePullRequest(ctx, title, body, head, base)
+	target := fmt.Sprintf("%s -> %s", head, base)
+	if err == nil {
+		target = pr.HTMLURL
+	}
+	recordAudit(auditProvider, "create_pull_request", p.repo, target, auditSummary(title), err)
+	return pr, err
+}
+
+func (p *auditedProvider) ClosePullRequest(ctx context.Context, number int) error {
+	err := p.HostingProvider.ClosePullRequest(ctx, number)
+	recordAudit(auditProvider, "close_pull_request", p.repo, fmt.Sprintf("#%d", number), "", err)
+	return err
+}
+
+func (p *auditedProvider) UpdatePullRequest(ctx context.Context, number int, title, body string) error {
+	err := p.HostingProvider.UpdatePullRequest(ctx, number, title, body)
+	recordAudit(auditProvider, "update_pull_request", p.repo, fmt.Sprintf("#%d", number), auditSummary(title), err)
+	return err
+}
+
+func (p *auditedProvider) DeleteBranch(ctx context.Context, branch string) error {
+	err := p.HostingProvider.DeleteBranch(ctx, branch)
+	recordAudit(auditProvider, "delete_branch", p.repo, branch, "", err)
+	return err
+}
+
+func (p *auditedProvider) RequestReviewers(ctx context.Context, prNumber int, reviewers []string) error {
+	err := p.HostingProvider.RequestReviewers(ctx, prNumber, reviewers)
+	recordAudit(auditProvider, "request_reviewers", p.repo, fmt.Sprintf("#%d", prNumber), strings.Join(reviewers, ", "), err)
+	return err
+}
+
+func (p *auditedProvider) AddAssignees(ctx context.Context, issueNumber int, assignees []string) error {
+	err := p.HostingProvider.AddAssignees(ctx, issueNumber, assignees)
+	recordAudit(auditProvider, "add_assignees", p.repo, fmt.Sprintf("#%d", issueNumber), strings.Join(assignees, ", "), err)
+	return err
+}
+
+func (p *auditedProvider) RemoveAssignees(ctx context.Context, issueNumber int, assignees []string) error {
+	err := p.HostingProvider.RemoveAssignees(ctx, issueNumber, assignees)
+	recordAudit(auditProvider, "remove_assignees", p.repo, fmt.Sprintf("#%d", issueNumber), strings.Join(assignees, ", "), err)
+	return err
+}
+
+func (p *auditedProvider) SetMilestone(ctx context.Context, issueNumber int, milestone *Milestone) error {
+	err := p.HostingProvider.SetMilestone(ctx, issueNumber, milestone)
+	summary := ""
+	if milestone != nil {
+		summary = milestone.Title
+	}
+	recordAudit(auditProvider, "set_milestone", p.repo, fmt.Sprintf("#%d", issueNumber), summary, err)
+	return err
+}
+
+func (p *auditedProvider) SetProjectStatus(ctx context.Context, issueNumber int, status string) error {
+	err := p.HostingProvider.SetProjectStatus(ctx, issueNumber, status)
+	recordAudit(auditProvider, "set_project_status", p.repo, fmt.Sprintf("#%d", issueNumber), status, err)
+	return err
+}
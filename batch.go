@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// repoListFlag collects repositories from a flag that may be repeated
+// and/or given comma-separated values
+type repoListFlag []string
+
+func (r *repoListFlag) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *repoListFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*r = append(*r, item)
+		}
+	}
+	return nil
+}
+
+// BatchResult is the per-repository outcome of a batch run
+type BatchResult struct {
+	Repo      string
+	Issues    int
+	Failed    int
+	Skipped   int
+	Failures  []string // "#12 Title: reason" for each failed issue
+	Analytics *SessionAnalytics
+	Err       error
+}
+
+// loadReposFile reads a repos.yaml with a top-level "repos" list
+func loadReposFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := parseSimpleYAML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	repos, ok := values["repos"]
+	if !ok {
+		return nil, fmt.Errorf("%s: missing \"repos\" list", path)
+	}
+	return repos, nil
+}
+
+// configForRepo returns a copy of config pointed at a repository given as
+// owner/repo or a full URL
+func configForRepo(config Config, repo string) (Config, error) {
+	if isRepoURL(repo) {
+		owner, name, err := parseRepoURL(repo)
+		if err != nil {
+			return config, err
+		}
+		config.RepoURL = repo
+		config.RepoOwner = owner
+		config.RepoName = name
+		setRepoHost(&config, repo)
+		return config, nil
+	}
+
+	parts := strings.Split(strings.Trim(repo, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return config, fmt.Errorf("invalid repository %q (expected owner/repo or a URL)", repo)
+	}
+	config.RepoURL = ""
+	config.RepoOwner = parts[0]
+	config.RepoName = parts[1]
+	return config, nil
+}
+
+// orgRepos lists the repositories of --org that may have issues to fix:
+// archived repositories, those without issues and those filtered out by
+// --org-topic or --org-language are left out
+func orgRepos(ctx context.Context, config Config) ([]string, error) {
+	listed, err := newHostingProvider(config).ListOrgRepos(ctx, config.Org)
+	if err != nil {
+		return nil, fmt.Errorf("listing repositories of %s: %w", config.Org, err)
+	}
+
+	var repos []string
+	for _, repo := range listed {
+		if repo.Archived || !repo.HasIssues || repo.OpenIssues == 0 {
+			continue
+		}
+		if config.OrgLanguage != "" && !strings.EqualFold(repo.Language, config.OrgLanguage) {
+			continue
+		}
+		if config.OrgTopic != "" && !slices.ContainsFunc(repo.Topics, func(topic string) bool {
+			return strings.EqualFold(topic, config.OrgTopic)
+		}) {
+			continue
+		}
+		repos = append(repos, repo.FullName)
+	}
+	fmt.Printf("🏢 %s: %d of %d repositories have open issues to look at\n", config.Org, len(repos), len(listed))
+	return repos, nil
+}
+
+// batchCommand runs the fix pipeline over every open, unhandled issue in a list of repositories
+func batchCommand(ctx context.Context, args []string) error {
+	config := loadConfig()
+
+	repos := repoListFlag(config.Repos)
+	var reposFile string
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	fs.Var(&repos, "repos", "Repository to process (owner/repo or URL); repeat or comma-separate for several")
+	fs.StringVar(&reposFile, "repos-file", "", "YAML file with a \"repos\" list")
+	fs.StringVar(&config.Org, "org", config.Org, "Process every repository of this organization or user")
+	fs.StringVar(&config.OrgTopic, "org-topic", config.OrgTopic, "With --org, only repositories with this topic")
+	fs.StringVar(&config.OrgLanguage, "org-language", config.OrgLanguage, "With --org, only repositories in this language")
+	parseFlags(&config, fs, args)
+
+	// A batch runs through many repositories without stopping to ask
+	config.Preflight = false
+
+	if reposFile != "" {
+		fromFile, err := loadReposFile(reposFile)
+		if err != nil {
+			return err
+		}
+		repos = append(repos, fromFile...)
+	}
+
+	if config.Org != "" {
+		fromOrg, err := orgRepos(ctx, config)
+		if err != nil {
+			return err
+		}
+		repos = append(repos, fromOrg...)
+	}
+
+	if len(repos) == 0 {
+		return fmt.Errorf("no repositories given (use --repos, --repos-file or --org)")
+	}
+
+	start := time.Now()
+	var results []BatchResult
+	for i, repo := range repos {
+		if ctx.Err() != nil {
+			fmt.Println("\n⏹  Cancelled, remaining repositories were not processed")
+			break
+		}
+		fmt.Println("\n" + strings.Repeat("═", 66))
+		fmt.Printf("📦 [%d/%d] %s\n", i+1, len(repos), repo)
+		fmt.Println(strings.Repeat("═", 66))
+
+		result := runBatchRepo(ctx, config, repo)
+		if result.Err != nil {
+			fmt.Printf("\033[31m✗ %s:\033[0m %v\n", repo, result.Err)
+		}
+		results = append(results, result)
+	}
+
+	printBatchReport(results)
+	NewEmailer(config).Send(batchEmailReport(results, start))
+	return nil
+}
+
+// runBatchRepo processes all unhandled issues of one repository
+func runBatchRepo(ctx context.Context, base Config, repo string) BatchResult {
+	result := BatchResult{Repo: repo, Analytics: NewSessionAnalytics(NewPricing(base))}
+
+	config, err := configForRepo(base, repo)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if err := validateConfig(config); err != nil {
+		result.Err = err
+		return result
+	}
+
+	if err := checkOllama(ctx, config); err != nil {
+		result.Err = err
+		return result
+	}
+
+	provider := newHostingProvider(config)
+	if err := checkTokenAccess(ctx, config, provider); err != nil {
+		result.Err = err
+		return result
+	}
+	if err := identifyBotAccount(ctx, provider); err != nil {
+		result.Err = err
+		return result
+	}
+	aiClient := newAIClient(config, result.Analytics)
+
+	watchMergedPRs(ctx, config, provider)
+
+	issues, err := provider.GetOpenIssues(ctx, 100)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to fetch issues: %w", err)
+		return result
+	}
+
+	pending := newAuthorTrust(config, provider).trustedIssues(ctx, filterUnhandledIssues(ctx, config, provider, issues))
+	prioritizeIssues(pending, config.LabelPriorities)
+	if len(pending) == 0 {
+		fmt.Println("✓ No new issues")
+		return result
+	}
+
+	if config.GroupIssues {
+		pending = groupRelatedIssues(ctx, aiClient, pending)
+	}
+
+	for _, issue := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		result.Issues++
+		fmt.Printf("\n🔧 Processing Issue %s: \033[1m%s\033[0m\n", issueRefs(issue), issue.Title)
+
+		if err := processIssue(ctx, config, provider, aiClient, issue, result.Analytics); err != nil {
+			if errors.Is(err, errIssueSkipped) {
+				result.Skipped++
+				fmt.Printf("⏭ Skipped issue #%d\n", issue.Number)
+				continue
+			}
+			result.Failed++
+			result.Failures = append(result.Failures, fmt.Sprintf("#%d %s: %v", issue.Number, issue.Title, err))
+			fmt.Printf("Failed to process issue #%d: %v\n", issue.Number, err)
+			continue
+		}
+		fmt.Printf("✓ Successfully processed issue #%d\n", issue.Number)
+	}
+
+	result.Analytics.PrintSummary()
+	return result
+}
+
+// printBatchReport prints one line per repository and the totals
+func printBatchReport(results []BatchResult) {
+	fmt.Println("\n╔════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                    📊 Batch Report                             ║")
+	fmt.Println("╚════════════════════════════════════════════════════════════════╝")
+	currency := "USD"
+	if len(results) > 0 {
+		currency = results[0].Analytics.Pricing.Currency
+	}
+	fmt.Printf("\n%-32s %7s %5s %6s %7s %14s %10s\n", "Repository", "Issues", "PRs", "Asked", "Failed", "Lines +/-", "Cost ("+currency+")")
+
+	var issues, prs, questions, failed int
+	var cost float64
+	var changes ChangeStats
+	for _, result := range results {
+		snapshot := result.Analytics.Snapshot()
+		if result.Err != nil {
+			fmt.Printf("%-32s \033[31merror: %v\033[0m\n", result.Repo, result.Err)
+			continue
+		}
+		total := snapshot.TotalChanges()
+		fmt.Printf("%-32s %7d %5d %6d %7d %14s %10.4f\n", result.Repo, result.Issues,
+			snapshot.PRsCreated, snapshot.QuestionsAsked, result.Failed, lineCounts(total), snapshot.EstimatedCost)
+
+		issues += result.Issues
+		prs += snapshot.PRsCreated
+		questions += snapshot.QuestionsAsked
+		failed += result.Failed
+		cost += snapshot.EstimatedCost
+		changes = changes.Add(total)
+	}
+
+	fmt.Println(strings.Repeat("─", 87))
+	fmt.Printf("%-32s %7d %5d %6d %7d %14s %10.4f\n\n", fmt.Sprintf("Total (%d repos)", len(results)),
+		issues, prs, questions, failed, lineCounts(changes), cost)
+}
+
+// batchEmailReport turns the results of a batch run into a summary email
+func batchEmailReport(results []BatchResult, since time.Time) EmailReport {
+	report := EmailReport{Title: "Batch run", Since: since, Currency: "USD"}
+	for _, result := range results {
+		snapshot := result.Analytics.Snapshot()
+		report.Currency = snapshot.Currency
+		report.Repos = append(report.Repos, ReportRepo{
+			Repo:      result.Repo,
+			Issues:    result.Issues,
+			PRs:       snapshot.PullRequests,
+			Questions: snapshot.QuestionsAsked,
+			Failures:  result.Failures,
+			Cost:      snapshot.EstimatedCost,
+			Err:       result.Err,
+		})
+	}
+	return report
+}
+
+// lineCounts renders insertions and deletions for a report column, e.g. "+120/-30"
+func lineCounts(stats ChangeStats) string {
+	return fmt.Sprintf("+%d/-%d", stats.Insertions, stats.Deletions)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultBedrockModel is used when no model is configured. Newer models may
+// only be reachable through a cross-region inference profile, e.g.
+// "us.anthropic.claude-3-7-sonnet-20250219-v1:0".
+const defaultBedrockModel = "anthropic.claude-3-5-sonnet-20240620-v1:0"
+
+// bedrockFallbackModels are offered when the model list cannot be fetched
+var bedrockFallbackModels = []string{
+	"anthropic.claude-3-5-sonnet-20240620-v1:0",
+	"anthropic.claude-3-5-haiku-20241022-v1:0",
+	"meta.llama3-1-70b-instruct-v1:0",
+	"meta.llama3-1-8b-instruct-v1:0",
+}
+
+// bedrockRegion is bedrock_region, else the region of the AWS environment
+func bedrockRegion(config Config) string {
+	for _, region := range []string{config.BedrockRegion, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")} {
+		if region != "" {
+			return region
+		}
+	}
+	return "us-east-1"
+}
+
+// bedrockBaseModel strips the geography of a cross-region inference profile,
+// so "us.anthropic.claude-..." is priced like "anthropic.claude-..."
+func bedrockBaseModel(model string) string {
+	for _, geo := range []string{"us.", "eu.", "apac.", "us-gov.", "global."} {
+		if strings.HasPrefix(model, geo) {
+			return strings.TrimPrefix(model, geo)
+		}
+	}
+	return model
+}
+
+// BedrockClient talks to models hosted on AWS Bedrock through the Converse
+// API, which accepts the same request for Claude, Llama, Mistral and the
+// other model families. Requests are signed with AWS credentials instead of
+// an API key.
+type BedrockClient struct {
+	model     string
+	region    string
+	profile   string // AWS profile to read credentials from, "" for the environment
+	client    *http.Client
+	analytics *SessionAnalytics
+	scheduler *RequestScheduler
+	cache     *ResponseCache
+}
+
+// BedrockRequest is the body of a Converse call
+type BedrockRequest struct {
+	System          []BedrockContent        `json:"system,omitempty"`
+	Messages        []BedrockMessage        `json:"messages"`
+	InferenceConfig *BedrockInferenceConfig `json:"inferenceConfig,omitempty"`
+}
+
+type BedrockMessage struct {
+	Role    string           `json:"role"`
+	Content []BedrockContent `json:"content"`
+}
+
+type BedrockContent struct {
+	Text string `json:"text"`
+}
+
+type BedrockInferenceConfig struct {
+	MaxTokens   int     `json:"maxTokens,omitempty"`
+	Temperature float64 `json:"temperature"`
+}
+
+// BedrockResponse is the reply of a Converse call
+type BedrockResponse struct {
+	Output struct {
+		Message BedrockMessage `json:"message"`
+	} `json:"output"`
+	StopReason string `json:"stopReason"`
+	Usage      struct {
+		InputTokens  int `json:"inputTokens"`
+		OutputTokens int `json:"outputTokens"`
+	} `json:"usage"`
+}
+
+func NewBedrockClient(region, profile, model string) *BedrockClient {
+	if model == "" {
+		model = defaultBedrockModel
+	}
+	return &BedrockClient{
+		model:   model,
+		region:  region,
+		profile: profile,
+		client:  &http.Client{Timeout: 120 * time.Second},
+	}
+}
+
+func (b *BedrockClient) SetAnalytics(analytics *SessionAnalytics) {
+	b.analytics = analytics
+}
+
+// SetTimeout overrides the HTTP timeout for requests; 0 disables it
+func (b *BedrockClient) SetTimeout(timeout time.Duration) {
+	b.client.Timeout = timeout
+}
+
+// SetScheduler routes requests through a shared rate limiter
+func (b *BedrockClient) SetScheduler(scheduler *RequestScheduler) {
+	b.scheduler = scheduler
+}
+
+// SetCache reuses earlier fixes for unchanged prompts
+func (b *BedrockClient) SetCache(cache *ResponseCache) {
+	b.cache = cache
+}
+
+func (b *BedrockClient) runtimeURL() string {
+	return fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", b.region)
+}
+
+func (b *BedrockClient) AnalyzeAndFix(ctx context.Context, issue Issue, repoContext *RepoContext) (*Fix, error) {
+	g := &OpenAIClient{}
+	prompt := g.buildPrompt(issue, repoContext)
+
+	response, cached := b.cache.Get(b.runtimeURL(), b.model, systemPrompt(repoContext), prompt)
+	if !cached {
+		var err error
+		response, err = b.Complete(ctx, systemPrompt(repoContext), prompt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fix, err := g.parseFix(response, repoContext)
+	if err != nil {
+		return nil, err
+	}
+	if !cached {
+		b.cache.Put(b.runtimeURL(), b.model, systemPrompt(repoContext), prompt, response)
+	}
+	return fix, nil
+}
+
+// Complete sends a single system+user prompt and returns the raw model reply
+func (b *BedrockClient) Complete(ctx context.Context, systemPrompt, prompt string) (string, error) {
+	reqBody := BedrockRequest{
+		Messages: []BedrockMessage{
+			{Role: "user", Content: []BedrockContent{{Text: prompt}}},
+		},
+		InferenceConfig: &BedrockInferenceConfig{MaxTokens: 8000, Temperature: 0.2},
+	}
+	if systemPrompt != "" {
+		reqBody.System = []BedrockContent{{Text: systemPrompt}}
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	// Model IDs contain a colon, which must be escaped in the path
+	url := fmt.Sprintf("%s/model/%s/converse", b.runtimeURL(), awsURIEncode(b.model))
+	req, err := b.signedRequest(ctx, "POST", url, jsonData)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := b.scheduler.Do(ctx, b.client, req, estimateTokens(systemPrompt+prompt))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("Bedrock API error: %s - %s", resp.Status, string(body))
+	}
+
+	var completion BedrockResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return "", err
+	}
+
+	var reply strings.Builder
+	for _, content := range completion.Output.Message.Content {
+		reply.WriteString(content.Text)
+	}
+	if reply.Len() == 0 {
+		return "", fmt.Errorf("no response from AI")
+	}
+
+	if b.analytics != nil {
+		inputTokens, outputTokens := completion.Usage.InputTokens, completion.Usage.OutputTokens
+		if inputTokens == 0 {
+			inputTokens, outputTokens = estimateTokens(systemPrompt+prompt), estimateTokens(reply.String())
+		}
+		b.analytics.RecordAPICall("bedrock", bedrockBaseModel(b.model), inputTokens, outputTokens)
+	}
+
+	return reply.String(), nil
+}
+
+// signedRequest builds a request signed with the current AWS credentials.
+// They are read for every request, so refreshed temporary credentials are
+// picked up without a restart.
+func (b *BedrockClient) signedRequest(ctx context.Context, method, url string, payload []byte) (*http.Request, error) {
+	creds, err := loadAWSCredentials(b.profile)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	signAWSRequest(req, payload, creds, b.region, "bedrock", time.Now())
+	return req, nil
+}
+
+// GetAvailableModels lists the text models of the region that can be called
+// on demand
+func (b *BedrockClient) GetAvailableModels() ([]string, error) {
+	url := fmt.Sprintf("https://bedrock.%s.amazonaws.com/foundation-models?byOutputModality=TEXT&byInferenceType=ON_DEMAND", b.region)
+	req, err := b.signedRequest(context.Background(), "GET", url, nil)
+	if err != nil {
+		return bedrockFallbackModels, nil
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return bedrockFallbackModels, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return bedrockFallbackModels, nil
+	}
+
+	var result struct {
+		ModelSummaries []struct {
+			ModelID string `json:"modelId"`
+		} `json:"modelSummaries"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return bedrockFallbackModels, nil
+	}
+
+	models := make([]string, 0, len(result.ModelSummaries))
+	for _, m := range result.ModelSummaries {
+		models = append(models, m.ModelID)
+	}
+	if len(models) == 0 {
+		return bedrockFallbackModels, nil
+	}
+	return models, nil
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// benchServices are the services a --models entry can name explicitly, as in
+// "ollama:deepseek-coder"
+var benchServices = []string{"chatgpt", "openai", "grok", "mistral", "deepseek", "huggingface", "bedrock", "ollama"}
+
+// serviceKeyEnv is where bench looks for the API key of a service other than
+// the configured one
+var serviceKeyEnv = map[string]string{
+	"chatgpt":     "OPENAI_API_KEY",
+	"openai":      "OPENAI_API_KEY",
+	"grok":        "XAI_API_KEY",
+	"mistral":     "MISTRAL_API_KEY",
+	"deepseek":    "DEEPSEEK_API_KEY",
+	"huggingface": "HF_TOKEN",
+}
+
+// benchModel is one contender of a benchmark
+type benchModel struct {
+	Service string
+	Model   string
+}
+
+func (m benchModel) String() string {
+	return m.Service + "/" + m.Model
+}
+
+// parseBenchModel reads "service:model" or a bare model name, whose service is
+// guessed from the name. Names no hosted service uses go to Ollama.
+func parseBenchModel(spec string) benchModel {
+	if service, model, ok := strings.Cut(spec, ":"); ok && slices.Contains(benchServices, strings.ToLower(service)) {
+		return benchModel{Service: strings.ToLower(service), Model: model}
+	}
+
+	lower := strings.ToLower(spec)
+	hasPrefix := func(prefixes ...string) bool {
+		return slices.ContainsFunc(prefixes, func(prefix string) bool { return strings.HasPrefix(lower, prefix) })
+	}
+	switch {
+	case hasPrefix("anthropic.", "meta.", "mistral.", "amazon.", "cohere.", "ai21.", "us.", "eu.", "apac."):
+		return benchModel{Service: "bedrock", Model: spec}
+	case hasPrefix("gpt-", "chatgpt-", "o1", "o3", "o4"):
+		return benchModel{Service: "chatgpt", Model: spec}
+	case hasPrefix("grok"):
+		return benchModel{Service: "grok", Model: spec}
+	case hasPrefix("mistral", "open-mistral", "codestral", "ministral", "devstral", "pixtral"):
+		return benchModel{Service: "mistral", Model: spec}
+	case hasPrefix("deepseek-chat", "deepseek-reasoner"):
+		return benchModel{Service: "deepseek", Model: spec}
+	}
+	return benchModel{Service: "ollama", Model: spec}
+}
+
+// benchAPIKey is the configured key for the configured service, and the
+// service's usual environment variable for the others. The configured key is
+// never sent to another service, so a missing key is an error.
+func benchAPIKey(config Config, service string) (string, error) {
+	env := serviceKeyEnv[service]
+	if service == config.AIService || env != "" && env == serviceKeyEnv[config.AIService] {
+		return config.AIAPIKey, nil
+	}
+	if key := os.Getenv(env); key != "" {
+		return key, nil
+	}
+	config.AIService = service
+	if needsAPIKey(config) {
+		return "", fmt.Errorf("no API key for %s; set %s", service, env)
+	}
+	return "", nil
+}
+
+// benchResult is what one model made of the issue
+type benchResult struct {
+	Model        benchModel
+	Fix          *Fix
+	Err          error
+	Latency      time.Duration
+	Usage        AnalyticsSnapshot
+	Diff         string
+	Changes      ChangeStats
+	Paths        []string
+	SyntaxErrors int
+}
+
+// outcome summarizes the result for the report table
+func (r benchResult) outcome() string {
+	switch {
+	case r.Err != nil:
+		return "error"
+	case r.Fix.NeedsMoreInfo && len(r.Fix.Questions) > 0:
+		return "questions"
+	case len(r.Fix.FileChanges) == 0:
+		return "no code"
+	case r.SyntaxErrors > 0:
+		return "fix (syntax)"
+	}
+	return "fix"
+}
+
+// benchCommand runs one issue through several models and compares their
+// fixes, usage and speed. Nothing is pushed or posted.
+func benchCommand(ctx context.Context, args []string) error {
+	config := loadConfig()
+
+	var issueNumber int
+	var models []string
+	var outDir string
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	fs.IntVar(&issueNumber, "issue", 0, "Issue to run through every model")
+	fs.Var((*listFlag)(&models), "models", "Comma-separated models to compare, e.g. gpt-4o,grok-beta,llama3 (service:model picks the service)")
+	fs.StringVar(&outDir, "out", "", "Directory to write each model's diff to")
+	parseFlags(&config, fs, args)
+
+	if issueNumber <= 0 {
+		return fmt.Errorf("--issue is required")
+	}
+	if len(models) == 0 {
+		return fmt.Errorf("--models is required, e.g. --models gpt-4o,grok-beta,llama3")
+	}
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+	// Cached replies would be free and instant, which is not what is being measured
+	config.NoCache = true
+
+	analytics := NewSessionAnalytics(NewPricing(config))
+	provider := newHostingProvider(config)
+	aiClient := newAIClient(config, analytics)
+
+	fetched, err := provider.GetIssue(ctx, issueNumber)
+	if err != nil {
+		return fmt.Errorf("failed to fetch issue #%d: %w", issueNumber, err)
+	}
+	issue := prepareIssue(ctx, config, analytics, *fetched)
+
+	gitOps, err := newRepoGitOps(ctx, config, provider)
+	if err != nil {
+		return fmt.Errorf("failed to initialize git: %w", err)
+	}
+	defer gitOps.Cleanup()
+	if err := cloneRepo(ctx, config, gitOps); err != nil {
+		return fmt.Errorf("failed to clone repo: %w", err)
+	}
+
+	// Every model gets the same context, prepared once with the configured model
+	memory := loadRepoMemory(config.RepoOwner, config.RepoName)
+	repoConfig, repoContext, err := prepareRepoContext(ctx, config, provider, gitOps, aiClient, memory, issue)
+	if err != nil {
+		return err
+	}
+	if config.ContextBudget > 0 {
+		summarizeContext(ctx, newSummaryClient(config, analytics, aiClient), repoContext, config.ContextBudget)
+	}
+	if config.RegionEdits {
+		selectRegions(ctx, aiClient, issue, repoContext)
+	}
+	gitOps.guard = NewPathGuard(append(append([]string{}, config.ProtectedPaths...), repoConfig.ProtectedPaths...), nil)
+
+	var results []benchResult
+	for _, spec := range models {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		results = append(results, benchModelRun(ctx, config, gitOps, issue, repoContext, parseBenchModel(spec)))
+	}
+
+	printBenchReport(issue, results, analytics.Pricing)
+	if outDir != "" {
+		writeBenchDiffs(outDir, issue, results)
+	}
+	return nil
+}
+
+// benchModelRun asks one model for a fix and measures the diff it produces in
+// the clone, which is reset afterwards
+func benchModelRun(ctx context.Context, config Config, gitOps *GitOps, issue Issue, repoContext *RepoContext, model benchModel) benchResult {
+	config.AIService = model.Service
+	config.AIModel = model.Model
+	result := benchResult{Model: model}
+	key, err := benchAPIKey(config, model.Service)
+	if err != nil {
+		fmt.Printf("\033[31m✗ %s: %v\033[0m\n", model, err)
+		result.Err = err
+		return result
+	}
+	config.AIAPIKey = key
+	analytics := NewSessionAnalytics(NewPricing(config))
+	client := newAIClient(config, analytics)
+
+	fmt.Printf("\n🏁 %s: analyzing issue #%d...\n", model, issue.Number)
+	start := time.Now()
+	result.Fix, result.Err = client.AnalyzeAndFix(ctx, issue, repoContext)
+	result.Latency = time.Since(start)
+	result.Usage = analytics.Snapshot()
+	if result.Err != nil {
+		fmt.Printf("\033[31m✗ %s: %v\033[0m\n", model, result.Err)
+		return result
+	}
+	if len(result.Fix.FileChanges) == 0 {
+		return result
+	}
+
+	result.SyntaxErrors = len(checkFixSyntax(result.Fix))
+	defer func() {
+		if err := gitOps.ResetChanges(ctx); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}()
+	if err := applyFix(gitOps, result.Fix); err != nil {
+		result.Err = err
+		return result
+	}
+	if result.Diff, result.Err = gitOps.Diff(ctx); result.Err != nil {
+		return result
+	}
+	result.Changes, result.Paths, result.Err = gitOps.DiffStats(ctx)
+	return result
+}
+
+func printBenchReport(issue Issue, results []benchResult, pricing *Pricing) {
+	fmt.Println("\n╔════════════════════════════════════════════════════════════════╗")
+	fmt.Println(boxTitle("🏁 Model Benchmark"))
+	fmt.Println("╚════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("\nIssue #%d: %s\n", issue.Number, issue.Title)
+
+	fmt.Printf("\n%-3s %-30s %-12s %-6s %5s %11s %15s %8s %12s\n", "#", "Model", "Result", "Conf.", "Files", "Lines +/-", "Tokens in/out", "Latency", "Cost ("+pricing.Currency+")")
+	for i, result := range results {
+		confidence := "-"
+		if result.Fix != nil && result.Fix.Confidence != "" {
+			confidence = result.Fix.Confidence
+		}
+		fmt.Printf("%-3d %-30s %-12s %-6s %5d %11s %15s %8s %12.4f\n", i+1, truncateRunes(result.Model.String(), 30), result.outcome(), confidence,
+			result.Changes.Files, lineCounts(result.Changes), fmt.Sprintf("%d/%d", result.Usage.InputTokens, result.Usage.OutputTokens),
+			result.Latency.Round(100*time.Millisecond), result.Usage.EstimatedCost)
+	}
+
+	// Which model touched which file, to see at a glance where they disagree
+	var paths []string
+	for _, result := range results {
+		for _, path := range result.Paths {
+			if !slices.Contains(paths, path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	if len(paths) > 0 {
+		slices.Sort(paths)
+		fmt.Printf("\n%-50s", "Files changed")
+		for i := range results {
+			fmt.Printf(" %3d", i+1)
+		}
+		fmt.Println()
+		for _, path := range paths {
+			fmt.Printf("%-50s", truncateRunes(path, 50))
+			for _, result := range results {
+				mark := "·"
+				if slices.Contains(result.Paths, path) {
+					mark = "✓"
+				}
+				fmt.Printf(" %3s", mark)
+			}
+			fmt.Println()
+		}
+	}
+
+	for i, result := range results {
+		fmt.Printf("\n━━━ %d. %s ━━━\n", i+1, result.Model)
+		switch {
+		case result.Err != nil:
+			fmt.Printf("Error: %v\n", result.Err)
+			continue
+		case result.Fix.NeedsMoreInfo && len(result.Fix.Questions) > 0:
+			fmt.Println("Asked for more information:")
+			for _, question := range result.Fix.Questions {
+				fmt.Printf("  - %s\n", question)
+			}
+			continue
+		}
+		if result.Fix.Explanation != "" {
+			fmt.Println(result.Fix.Explanation)
+		}
+		if result.SyntaxErrors > 0 {
+			fmt.Printf("\033[33m⚠ %d file(s) with syntax errors\033[0m\n", result.SyntaxErrors)
+		}
+		if result.Diff != "" {
+			fmt.Printf("\n%s\n", strings.TrimRight(result.Diff, "\n"))
+		}
+	}
+	fmt.Println()
+}
+
+// writeBenchDiffs saves each model's diff, e.g. to compare them in a diff tool
+func writeBenchDiffs(dir string, issue Issue, results []benchResult) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Printf("Warning: Could not create %s: %v\n", dir, err)
+		return
+	}
+	for i, result := range results {
+		if result.Diff == "" {
+			continue
+		}
+		path := filepath.Join(dir, fmt.Sprintf("issue-%d-%d-%s.diff", issue.Number, i+1, slugify(result.Model.String(), 60)))
+		if err := os.WriteFile(path, []byte(result.Diff), 0644); err != nil {
+			fmt.Printf("Warning: Could not write %s: %v\n", path, err)
+			continue
+		}
+		fmt.Printf("📄 %s → %s\n", result.Model, path)
+	}
+}
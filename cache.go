@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// defaultCacheTTL is how long a cached fix stays valid
+const defaultCacheTTL = 24 * time.Hour
+
+// cachedResponse is one AI reply stored on disk
+type cachedResponse struct {
+	Created  time.Time `json:"created"`
+	Model    string    `json:"model"`
+	Response string    `json:"response"`
+}
+
+// ResponseCache keeps AnalyzeAndFix replies on disk, keyed by a hash of the
+// model and the full prompt. Re-running an unchanged issue (e.g. after a failed
+// push) then reuses the fix instead of paying for another call. A nil cache
+// stores nothing.
+type ResponseCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewResponseCache returns a cache in dir whose entries expire after ttl
+func NewResponseCache(dir string, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{dir: dir, ttl: ttl}
+}
+
+// getCacheDir is where cached AI replies are stored
+func getCacheDir() string {
+	return filepath.Join(getDataDir(), "cache")
+}
+
+func (c *ResponseCache) path(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		// Length-prefixed so moving text between parts changes the key
+		fmt.Fprintf(hash, "%d:%s", len(part), part)
+	}
+	return filepath.Join(c.dir, hex.EncodeToString(hash.Sum(nil))+".json")
+}
+
+// Get returns the cached reply for a prompt, if there is one that has not expired
+func (c *ResponseCache) Get(endpoint, model, systemPrompt, prompt string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+
+	data, err := os.ReadFile(c.path(endpoint, model, systemPrompt, prompt))
+	if err != nil {
+		return "", false
+	}
+	var entry cachedResponse
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return "", false
+	}
+	if c.ttl > 0 && time.Since(entry.Created) > c.ttl {
+		return "", false
+	}
+
+	fmt.Printf("♻️  Reusing cached AI response from %s ago (--no-cache to ask again)\n", time.Since(entry.Created).Round(time.Second))
+	return entry.Response, true
+}
+
+// Put stores a reply. Callers only store replies that parsed into a fix, so a
+// malformed reply is never replayed.
+func (c *ResponseCache) Put(endpoint, model, systemPrompt, prompt, response string) {
+	if c == nil {
+		return
+	}
+
+	data, err := json.MarshalIndent(cachedResponse{Created: time.Now(), Model: model, Response: response}, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		fmt.Printf("Warning: Could not create cache directory: %v\n", err)
+		return
+	}
+	if err := os.WriteFile(c.path(endpoint, model, systemPrompt, prompt), data, 0600); err != nil {
+		fmt.Printf("Warning: Could not cache AI response: %v\n", err)
+	}
+}
+
+// Prune removes expired entries
+func (c *ResponseCache) Prune() {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err == nil && time.Since(info.ModTime()) > c.ttl {
+			os.Remove(filepath.Join(c.dir, entry.Name()))
+		}
+	}
+}
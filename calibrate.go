@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// defaultCloseMinScore is the calibrated score a fix needs before the issue is
+// closed; the AI saying "high" alone does not reach it
+const defaultCloseMinScore = 80
+
+// dependencyFiles are manifests and lockfiles; changing them usually means the
+// fix pulls in or upgrades a dependency
+var dependencyFiles = []string{
+	"go.mod", "go.sum", "package.json", "package-lock.json", "yarn.lock", "pnpm-lock.yaml",
+	"requirements.txt", "pyproject.toml", "poetry.lock", "Pipfile", "Pipfile.lock", "setup.py",
+	"Cargo.toml", "Cargo.lock", "pom.xml", "build.gradle", "build.gradle.kts", "Gemfile",
+	"Gemfile.lock", "composer.json", "composer.lock", "mix.exs", "pubspec.yaml",
+}
+
+// ConfidenceSignals are the objective facts known about a fix once it is committed
+type ConfidenceSignals struct {
+	AIConfidence   string // What the model reported: "high", "medium", "low"
+	SyntaxChecked  bool   // Every changed file parsed
+	ReviewApproved bool   // The self-review pass approved the diff
+	Verified       bool   // A second model agreed that the diff resolves the issue
+	TestsRun       bool
+	TestsPassed    bool
+	SetupFailed    bool // Test setup failed, so nothing was validated
+	LinesChanged   int
+	Dependencies   []string // Dependency files the fix changed
+	OverDiffLimit  bool     // Larger than max_diff_lines / max_diff_files
+	Reproduced     bool     // A reproduction failed before the fix and passes with it
+}
+
+// ConfidenceScore is the calibrated confidence in a fix, 0-100, and how it was reached
+type ConfidenceScore struct {
+	Score   int
+	Factors []string
+}
+
+func (s ConfidenceScore) String() string {
+	return fmt.Sprintf("%d/100 (%s)", s.Score, strings.Join(s.Factors, ", "))
+}
+
+// calibrateConfidence combines the model's self-reported confidence with what
+// was actually verified about the fix
+func calibrateConfidence(signals ConfidenceSignals) ConfidenceScore {
+	var score ConfidenceScore
+	add := func(points int, reason string) {
+		score.Score += points
+		score.Factors = append(score.Factors, fmt.Sprintf("%s %+d", reason, points))
+	}
+
+	switch signals.AIConfidence {
+	case "high":
+		add(60, "AI: high")
+	case "medium":
+		add(35, "AI: medium")
+	default:
+		add(10, "AI: low")
+	}
+
+	switch {
+	case signals.SetupFailed:
+		add(-10, "test setup failed")
+	case signals.TestsRun && signals.TestsPassed:
+		add(20, "tests passed")
+	case !signals.TestsRun:
+		score.Factors = append(score.Factors, "no tests")
+	}
+
+	if signals.Reproduced {
+		add(15, "bug reproduced and fixed")
+	}
+	if signals.SyntaxChecked {
+		add(5, "syntax checked")
+	}
+	if signals.ReviewApproved {
+		add(10, "self-review approved")
+	}
+	if signals.Verified {
+		add(10, "verifier agreed")
+	}
+
+	lines := strconv.Itoa(signals.LinesChanged) + " lines changed"
+	switch {
+	case signals.LinesChanged <= 20:
+		add(5, lines)
+	case signals.LinesChanged <= 100:
+	case signals.LinesChanged <= 300:
+		add(-10, lines)
+	default:
+		add(-20, lines)
+	}
+
+	if signals.OverDiffLimit {
+		add(-15, "over the diff size limit")
+	}
+	if len(signals.Dependencies) > 0 {
+		add(-15, "dependencies changed ("+strings.Join(signals.Dependencies, ", ")+")")
+	}
+
+	score.Score = max(0, min(100, score.Score))
+	return score
+}
+
+// changedDependencyFiles returns the dependency manifests and lockfiles among paths
+func changedDependencyFiles(paths []string) []string {
+	var changed []string
+	for _, p := range paths {
+		for _, name := range dependencyFiles {
+			if path.Base(p) == name {
+				changed = append(changed, p)
+				break
+			}
+		}
+	}
+	return changed
+}
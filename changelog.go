@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// How a repository records its changes
+const (
+	changelogFile       = "file"       // A CHANGELOG.md with an Unreleased section
+	changelogChangesets = "changesets" // .changeset/*.md fragments
+	changelogTowncrier  = "towncrier"  // News fragments named <issue>.<type>
+)
+
+// maxChangelogExample bounds the part of the changelog shown to the AI as a
+// style example
+const maxChangelogExample = 1500
+
+// keepAChangelogTypes are the change types of Keep a Changelog, in order
+var keepAChangelogTypes = []string{"added", "changed", "deprecated", "removed", "fixed", "security"}
+
+// defaultTowncrierTypes are the fragment types towncrier knows without configuration
+var defaultTowncrierTypes = []string{"feature", "bugfix", "doc", "removal", "misc"}
+
+// ChangelogStyle is the changelog convention found in a repository
+type ChangelogStyle struct {
+	Kind      string
+	Path      string   // The changelog file, or the directory fragments go in
+	Types     []string // Change types an entry can have
+	Extension string   // File extension of towncrier fragments
+	Example   string   // Start of the changelog, to match its style
+}
+
+// ChangelogEntry is the changelog line the AI proposes for a fix
+type ChangelogEntry struct {
+	Type  string `json:"type"`
+	Entry string `json:"entry"`
+}
+
+// detectChangelog finds how the repository records changes, or returns nil
+// if it keeps no changelog. Fragment tools win over a changelog file, since
+// they generate that file.
+func detectChangelog(repoPath string) *ChangelogStyle {
+	if _, err := os.Stat(filepath.Join(repoPath, ".changeset", "config.json")); err == nil {
+		return &ChangelogStyle{Kind: changelogChangesets, Path: ".changeset", Types: []string{"patch", "minor", "major"}}
+	}
+	if style := detectTowncrier(repoPath); style != nil {
+		return style
+	}
+
+	entries, err := os.ReadDir(repoPath)
+	if err != nil {
+		return nil
+	}
+	for _, name := range []string{"changelog.md", "changes.md"} {
+		for _, entry := range entries {
+			if entry.IsDir() || strings.ToLower(entry.Name()) != name {
+				continue
+			}
+			content, err := os.ReadFile(filepath.Join(repoPath, entry.Name()))
+			if err != nil {
+				return nil
+			}
+			return &ChangelogStyle{
+				Kind:    changelogFile,
+				Path:    entry.Name(),
+				Types:   keepAChangelogTypes,
+				Example: truncateRunes(string(content), maxChangelogExample),
+			}
+		}
+	}
+	return nil
+}
+
+// detectTowncrier reads the towncrier settings from towncrier.toml or
+// pyproject.toml. Only the keys that decide where fragments go are parsed.
+func detectTowncrier(repoPath string) *ChangelogStyle {
+	var settings map[string]string
+	var types []string
+	for _, name := range []string{"towncrier.toml", "pyproject.toml"} {
+		content, err := os.ReadFile(filepath.Join(repoPath, name))
+		if err != nil {
+			continue
+		}
+		settings, types = parseTowncrierConfig(string(content))
+		if settings != nil {
+			break
+		}
+	}
+	if settings == nil {
+		return nil
+	}
+
+	dir := settings["directory"]
+	if dir == "" {
+		dir = "newsfragments"
+		if pkg := settings["package"]; pkg != "" {
+			dir = path.Join(settings["package_dir"], pkg, "newsfragments")
+		}
+	}
+	if len(types) == 0 {
+		types = defaultTowncrierTypes
+	}
+	extension := ".rst"
+	if strings.HasSuffix(strings.ToLower(settings["filename"]), ".md") {
+		extension = ".md"
+	}
+	return &ChangelogStyle{Kind: changelogTowncrier, Path: dir, Types: types, Extension: extension}
+}
+
+// parseTowncrierConfig returns the [tool.towncrier] settings of a TOML file,
+// nil if it has none, and the fragment types it configures
+func parseTowncrierConfig(content string) (map[string]string, []string) {
+	var settings map[string]string
+	var types []string
+	table := ""
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			table = strings.Trim(line, "[] ")
+			if table == "tool.towncrier" && settings == nil {
+				settings = make(map[string]string)
+			}
+			// Newer configs name each type in its table header
+			if name, ok := strings.CutPrefix(table, "tool.towncrier.fragment."); ok {
+				types = append(types, strings.Trim(name, `"`))
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch {
+		case table == "tool.towncrier":
+			settings[key] = value
+		case table == "tool.towncrier.type" && key == "directory":
+			types = append(types, value)
+		}
+	}
+	return settings, types
+}
+
+// changelogChange builds the file change that records a fix in the
+// repository's changelog. It returns false if the fix already touches the
+// changelog or there is nothing to add.
+func changelogChange(repoPath string, style *ChangelogStyle, issue Issue, fix *Fix) (FileChange, bool) {
+	for _, change := range fix.FileChanges {
+		for _, changed := range change.Paths() {
+			if changed == style.Path || strings.HasPrefix(changed, style.Path+"/") {
+				return FileChange{}, false
+			}
+		}
+	}
+
+	entry := ChangelogEntry{Entry: issue.Title}
+	if fix.Changelog != nil && strings.TrimSpace(fix.Changelog.Entry) != "" {
+		entry = *fix.Changelog
+	}
+	entry.Entry = strings.Join(strings.Fields(entry.Entry), " ")
+	entry.Type = strings.ToLower(strings.TrimSpace(entry.Type))
+	if !slices.Contains(style.Types, entry.Type) {
+		entry.Type = defaultChangelogType(style)
+	}
+
+	switch style.Kind {
+	case changelogFile:
+		content, err := os.ReadFile(filepath.Join(repoPath, style.Path))
+		if err != nil {
+			return FileChange{}, false
+		}
+		return FileChange{FilePath: style.Path, Content: insertChangelogEntry(string(content), entry), Summary: "Changelog entry"}, true
+
+	case changelogChangesets:
+		packages := changesetPackages(repoPath, fix)
+		if len(packages) == 0 {
+			fmt.Println("Warning: No package found for the changeset, leaving the changelog alone")
+			return FileChange{}, false
+		}
+		var content strings.Builder
+		content.WriteString("---\n")
+		for _, pkg := range packages {
+			content.WriteString(fmt.Sprintf("%q: %s\n", pkg, entry.Type))
+		}
+		content.WriteString("---\n\n" + entry.Entry + "\n")
+		return FileChange{FilePath: fmt.Sprintf("%s/fix-issue-%d.md", style.Path, issue.Number), Content: content.String(), Summary: "Changeset for the fix"}, true
+
+	case changelogTowncrier:
+		// <issue>.<type>, numbered if the issue already has a fragment of the type
+		name := fmt.Sprintf("%s/%d.%s%s", style.Path, issue.Number, entry.Type, style.Extension)
+		for n := 1; fileExists(filepath.Join(repoPath, filepath.FromSlash(name))); n++ {
+			name = fmt.Sprintf("%s/%d.%s.%d%s", style.Path, issue.Number, entry.Type, n, style.Extension)
+		}
+		return FileChange{FilePath: name, Content: entry.Entry + "\n", Summary: "News fragment for the fix"}, true
+	}
+	return FileChange{}, false
+}
+
+// defaultChangelogType is the type of an entry the AI gave no valid type for
+func defaultChangelogType(style *ChangelogStyle) string {
+	switch style.Kind {
+	case changelogFile:
+		return "fixed"
+	case changelogChangesets:
+		return "patch"
+	}
+	if slices.Contains(style.Types, "bugfix") {
+		return "bugfix"
+	}
+	return style.Types[0]
+}
+
+// insertChangelogEntry adds an entry to the Unreleased section of a Keep a
+// Changelog file, under the heading of its type. The section and heading are
+// created when missing; changelogs without type headings get a plain bullet.
+func insertChangelogEntry(content string, entry ChangelogEntry) string {
+	lines := strings.Split(content, "\n")
+	bullet := "- "
+	for _, line := range lines {
+		if strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "- ") {
+			bullet = line[:2]
+			break
+		}
+	}
+	heading := "### " + strings.ToUpper(entry.Type[:1]) + entry.Type[1:]
+	item := bullet + entry.Entry
+
+	// Find the Unreleased section, or the first release to put one above
+	start, firstRelease := -1, -1
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "## ") {
+			continue
+		}
+		if strings.Contains(strings.ToLower(line), "unreleased") {
+			start = i
+			break
+		}
+		if firstRelease < 0 {
+			firstRelease = i
+		}
+	}
+	if start < 0 {
+		section := []string{"## [Unreleased]", "", heading, item, ""}
+		at := firstRelease
+		if at < 0 {
+			// No releases yet: below the title, or at the top
+			at = 0
+			if len(lines) > 0 && strings.HasPrefix(lines[0], "# ") {
+				at = 1
+				section = append([]string{""}, section[:len(section)-1]...)
+			}
+		}
+		return strings.Join(slices.Insert(lines, at, section...), "\n")
+	}
+
+	end := len(lines)
+	for i := start + 1; i < len(lines); i++ {
+		if strings.HasPrefix(lines[i], "## ") {
+			end = i
+			break
+		}
+	}
+
+	// Append to the list under the type heading, or under the section itself
+	// if the changelog does not group by type
+	hasHeadings := false
+	listStart := -1
+	for i := start + 1; i < end; i++ {
+		if strings.HasPrefix(lines[i], "### ") {
+			hasHeadings = true
+			if strings.EqualFold(strings.TrimSpace(lines[i]), heading) {
+				listStart = i
+			}
+		}
+	}
+	if !hasHeadings {
+		listStart = start
+	}
+	if listStart < 0 {
+		// New type heading at the end of the section
+		at := end
+		for at > start+1 && strings.TrimSpace(lines[at-1]) == "" {
+			at--
+		}
+		return strings.Join(slices.Insert(lines, at, "", heading, item), "\n")
+	}
+
+	// After the last item, including its continuation lines
+	at := listStart + 1
+	for i := listStart + 1; i < end && !strings.HasPrefix(lines[i], "#"); i++ {
+		if strings.TrimSpace(lines[i]) != "" {
+			at = i + 1
+		}
+	}
+	return strings.Join(slices.Insert(lines, at, item), "\n")
+}
+
+// changesetPackages names the packages a fix changes: the nearest
+// package.json above each changed file
+func changesetPackages(repoPath string, fix *Fix) []string {
+	found := make(map[string]bool)
+	for _, change := range fix.FileChanges {
+		for _, changed := range change.Paths() {
+			for dir := path.Dir(changed); ; dir = path.Dir(dir) {
+				if name := packageName(filepath.Join(repoPath, filepath.FromSlash(dir), "package.json")); name != "" {
+					found[name] = true
+					break
+				}
+				if dir == "." || dir == "/" {
+					break
+				}
+			}
+		}
+	}
+	packages := make([]string, 0, len(found))
+	for name := range found {
+		packages = append(packages, name)
+	}
+	sort.Strings(packages)
+	return packages
+}
+
+// packageName reads the name of a package.json, "" if there is none
+func packageName(file string) string {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(content, &pkg) != nil {
+		return ""
+	}
+	return pkg.Name
+}
+
+// changelogPrompt asks the AI for the changelog entry of a fix
+func changelogPrompt(style *ChangelogStyle) string {
+	var prompt strings.Builder
+	prompt.WriteString(`
+
+Changelog:
+- The project requires a changelog entry with every change; add a "changelog" object: {"type": "...", "entry": "..."}
+- "entry" is one line describing the change for users of the project, not the code
+- Do NOT edit the changelog or add fragment files yourself; the entry is added for you
+- "type" is one of: ` + strings.Join(style.Types, ", "))
+	if style.Example != "" {
+		prompt.WriteString("\n- Match the wording of the existing entries:\n```markdown\n" + style.Example + "\n```")
+	}
+	return prompt.String()
+}
+
+// fileExists reports whether a file exists at the path
+func fileExists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Combined states of the checks on a commit
+const (
+	checksPending = "pending"
+	checksSuccess = "success"
+	checksFailure = "failure"
+	checksNone    = "none" // No checks reported at all
+)
+
+// Timing of wait_for_checks. CI systems need a moment after a push before
+// their checks show up, so "no checks" is only believed after checksGrace.
+const (
+	checksPollInterval   = 30 * time.Second
+	checksGrace          = 2 * time.Minute
+	defaultChecksTimeout = 30 * time.Minute
+)
+
+// BranchProtection is what a protected branch requires before a pull request
+// can be merged into it
+type BranchProtection struct {
+	Protected         bool
+	RequiredChecks    []string // Names of required status checks
+	RequiredApprovals int      // 0 if none or unknown
+}
+
+// CheckRun is one status check or CI check run on a commit
+type CheckRun struct {
+	Name  string
+	State string // checksPending, checksSuccess or checksFailure
+}
+
+// checksState combines the checks on a commit. With required checks only
+// those count, and a required check that has not reported yet is pending.
+func checksState(checks []CheckRun, required []string) string {
+	if len(required) > 0 {
+		var relevant []CheckRun
+		for _, name := range required {
+			i := slices.IndexFunc(checks, func(check CheckRun) bool { return strings.EqualFold(check.Name, name) })
+			if i == -1 {
+				return checksPending
+			}
+			relevant = append(relevant, checks[i])
+		}
+		checks = relevant
+	}
+	if len(checks) == 0 {
+		return checksNone
+	}
+
+	state := checksSuccess
+	for _, check := range checks {
+		switch check.State {
+		case checksFailure:
+			return checksFailure
+		case checksPending:
+			state = checksPending
+		}
+	}
+	return state
+}
+
+// statusState maps the state of a commit status to a check state
+func statusState(state string) string {
+	switch state {
+	case "success", "warning":
+		return checksSuccess
+	case "failure", "error":
+		return checksFailure
+	}
+	return checksPending
+}
+
+// conclusionState maps the conclusion of a completed check run to a check state
+func conclusionState(conclusion string) string {
+	switch conclusion {
+	case "success", "neutral", "skipped":
+		return checksSuccess
+	case "":
+		return checksPending
+	}
+	return checksFailure // failure, cancelled, timed_out, action_required, ...
+}
+
+// protectionSection tells reviewers what the base branch requires before merging
+func protectionSection(protection *BranchProtection, branch string, waitForClose bool) string {
+	if protection == nil || !protection.Protected {
+		return ""
+	}
+
+	var section strings.Builder
+	section.WriteString(fmt.Sprintf("\n### 🛡️ Branch Protection\n\n`%s` is protected. Before this PR can be merged it needs:\n\n", branch))
+	if len(protection.RequiredChecks) > 0 {
+		names := make([]string, len(protection.RequiredChecks))
+		for i, name := range protection.RequiredChecks {
+			names[i] = "`" + name + "`"
+		}
+		section.WriteString(fmt.Sprintf("- Passing required checks: %s\n", strings.Join(names, ", ")))
+	}
+	if protection.RequiredApprovals > 0 {
+		section.WriteString(fmt.Sprintf("- %d approving review(s)\n", protection.RequiredApprovals))
+	}
+	if len(protection.RequiredChecks) == 0 && protection.RequiredApprovals == 0 {
+		section.WriteString("- Whatever the branch rules require; none of them are visible with the bot's token\n")
+	}
+	if waitForClose {
+		section.WriteString("\nThe issue is only closed once the checks on this PR have passed.\n")
+	}
+	return section.String()
+}
+
+// waitForChecks polls the checks on a commit until they are decided or
+// checks_timeout passes, and returns the final state
+func waitForChecks(ctx context.Context, config Config, provider HostingProvider, sha string, required []string) string {
+	timeout := timeoutSetting(config.ChecksTimeout, defaultChecksTimeout)
+	start := time.Now()
+	fmt.Printf("⏳ Waiting for the checks on %.7s...\n", sha)
+
+	for {
+		checks, err := provider.GetChecks(ctx, sha)
+		state := checksPending
+		if err != nil {
+			fmt.Printf("Warning: Could not read checks: %v\n", err)
+		} else {
+			state = checksState(checks, required)
+		}
+
+		switch {
+		case state == checksSuccess || state == checksFailure:
+			return state
+		case state == checksNone && time.Since(start) > checksGrace:
+			return checksNone
+		case timeout > 0 && time.Since(start) > timeout:
+			return checksPending
+		}
+
+		select {
+		case <-ctx.Done():
+			return checksPending
+		case <-time.After(checksPollInterval):
+		}
+	}
+}
+
+// checksAllowClose waits for CI on a new pull request when the issue would be
+// closed right away, and reports whether it may still be closed
+func checksAllowClose(ctx context.Context, config Config, provider HostingProvider, pr *PullRequest, baseBranch string) bool {
+	if !config.WaitForChecks || pr.Head.SHA == "" {
+		return true
+	}
+
+	var required []string
+	if protection, err := provider.GetBranchProtection(ctx, baseBranch); err != nil {
+		fmt.Printf("Warning: Could not read branch protection of %s: %v\n", baseBranch, err)
+	} else {
+		required = protection.RequiredChecks
+	}
+
+	switch waitForChecks(ctx, config, provider, pr.Head.SHA, required) {
+	case checksSuccess:
+		fmt.Println("✓ Checks passed")
+		return true
+	case checksNone:
+		fmt.Println("No checks reported on the pull request")
+		return true
+	case checksFailure:
+		fmt.Println("✗ Checks failed, leaving the issue open")
+	default:
+		fmt.Println("Checks did not finish in time, leaving the issue open")
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// maxClarifyRounds bounds how often the operator is asked before the
+// remaining questions go to the issue
+const maxClarifyRounds = 3
+
+// stdinIsTerminal reports whether someone is at the keyboard to answer prompts
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// clarifyLocally lets the operator answer the AI's questions in the terminal
+// and analyzes the issue again with the answers, saving a round trip through
+// the issue. Leaving every answer blank keeps the questions for the issue.
+// Returns the issue with the answers appended, which the rest of the pipeline
+// works from, and the new fix.
+func clarifyLocally(ctx context.Context, aiClient AIClient, issue Issue, repoContext *RepoContext, fix *Fix) (Issue, *Fix, error) {
+	for round := 0; round < maxClarifyRounds && fix.NeedsMoreInfo && len(fix.Questions) > 0; round++ {
+		fmt.Printf(T("clarify.questions"), issue.Number)
+
+		var answers strings.Builder
+		for i, question := range fix.Questions {
+			fmt.Printf("\n  %d. %s\n", i+1, question)
+			if answer := prompt("  "+T("clarify.answer"), ""); answer != "" {
+				answers.WriteString(fmt.Sprintf("**Q:** %s\n**A:** %s\n\n", question, answer))
+			}
+		}
+		if answers.Len() == 0 {
+			return issue, fix, nil
+		}
+
+		issue.Body += "\n\n## Answers from the maintainer\n\n" + answers.String()
+		fmt.Println(T("clarify.rerunning"))
+		revised, err := aiClient.AnalyzeAndFix(ctx, issue, repoContext)
+		if err != nil {
+			return issue, nil, err
+		}
+		fix = revised
+	}
+	return issue, fix, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxCodeMapChars bounds the code map in the prompt. Files past it are listed
+// by name only, and past twice as much not at all.
+const maxCodeMapChars = 24000
+
+// codeMapSkipDirs are directories whose code is not the project's own
+var codeMapSkipDirs = map[string]bool{
+	"node_modules": true, "vendor": true, "target": true, "dist": true, "build": true, "__pycache__": true,
+}
+
+// buildCodeMap lists every source file with its declarations: package, types
+// and function signatures for Go, definition lines for other languages. It
+// tells the AI which functions exist, where a directory listing only has
+// file names. Files over maxFileSize bytes (0 = no limit) are listed by name.
+func (g *GitOps) buildCodeMap(maxFileSize int) string {
+	var codeMap strings.Builder
+	omitted := 0
+	filepath.WalkDir(g.repoPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != g.repoPath && (strings.HasPrefix(d.Name(), ".") || codeMapSkipDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isSourceFile(filepath.Ext(path)) {
+			return nil
+		}
+		relPath, _ := filepath.Rel(g.repoPath, path)
+		relPath = normalizeRepoPath(relPath)
+
+		if codeMap.Len() >= 2*maxCodeMapChars {
+			omitted++
+			return nil
+		}
+		if codeMap.Len() >= maxCodeMapChars {
+			codeMap.WriteString(relPath + "\n")
+			return nil
+		}
+
+		var symbols []string
+		if info, err := d.Info(); err == nil && (maxFileSize == 0 || info.Size() <= int64(maxFileSize)) {
+			if content, err := os.ReadFile(path); err == nil {
+				symbols = fileSymbols(relPath, string(content))
+			}
+		}
+		codeMap.WriteString(relPath + "\n")
+		for _, symbol := range symbols {
+			codeMap.WriteString("  " + symbol + "\n")
+		}
+		return nil
+	})
+
+	if omitted > 0 {
+		codeMap.WriteString(fmt.Sprintf("... and %d more source files\n", omitted))
+	}
+	return codeMap.String()
+}
+
+// fileSymbols returns the declarations of a source file, one line each
+func fileSymbols(path, content string) []string {
+	if strings.ToLower(filepath.Ext(path)) == ".go" {
+		if symbols, ok := goSymbols(path, content); ok {
+			return symbols
+		}
+	}
+
+	var symbols []string
+	for _, line := range strings.Split(content, "\n") {
+		match := regionDefPattern.FindStringSubmatch(line)
+		if match == nil {
+			match = regionArrowPattern.FindStringSubmatch(line)
+		}
+		if match == nil {
+			continue
+		}
+		indent := strings.Repeat(" ", len(strings.ReplaceAll(match[1], "\t", "    "))/2)
+		signature := strings.TrimRight(strings.TrimSpace(line), " {:")
+		symbols = append(symbols, indent+truncateRunes(signature, maxOutlineLineChars))
+	}
+	return symbols
+}
+
+// goSymbols renders the package, types, exported values and function
+// signatures of a Go file. It reports false if the file does not parse.
+func goSymbols(path, content string) ([]string, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, false
+	}
+
+	symbols := []string{"package " + file.Name.Name}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			signature := *d
+			signature.Body, signature.Doc = nil, nil
+			var buf bytes.Buffer
+			if err := printer.Fprint(&buf, fset, &signature); err == nil {
+				symbols = append(symbols, truncateRunes(strings.Join(strings.Fields(buf.String()), " "), maxOutlineLineChars))
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					symbols = append(symbols, "type "+s.Name.Name+" "+typeKind(s.Type))
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if name.IsExported() {
+							symbols = append(symbols, d.Tok.String()+" "+name.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return symbols, true
+}
+
+// typeKind describes the underlying type of a type declaration briefly
+func typeKind(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StructType:
+		return "struct"
+	case *ast.InterfaceType:
+		return "interface"
+	case *ast.FuncType:
+		return "func"
+	case *ast.MapType:
+		return "map"
+	case *ast.ArrayType:
+		return "slice"
+	case *ast.ChanType:
+		return "chan"
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok {
+			return pkg.Name + "." + t.Sel.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// codeOwnersLocations are checked in order; the first file found is used, as on GitHub
+var codeOwnersLocations = []string{".github/CODEOWNERS", ".gitea/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeOwnersRule is one line of a CODEOWNERS file
+type CodeOwnersRule struct {
+	Pattern string
+	Owners  []string // Users and org/team names without the leading @
+	match   *regexp.Regexp
+}
+
+// loadCodeOwners reads the repository's CODEOWNERS file, if it has one
+func (g *GitOps) loadCodeOwners() []CodeOwnersRule {
+	for _, location := range codeOwnersLocations {
+		content, err := os.ReadFile(filepath.Join(g.repoPath, location))
+		if err == nil {
+			return parseCodeOwners(string(content))
+		}
+	}
+	return nil
+}
+
+// parseCodeOwners parses "pattern @owner @org/team ..." lines. Email owners are
+// skipped since reviews can only be requested from accounts.
+func parseCodeOwners(content string) []CodeOwnersRule {
+	var rules []CodeOwnersRule
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, " #"); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		rule := CodeOwnersRule{Pattern: fields[0], match: codeOwnersPattern(fields[0])}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "@") {
+				rule.Owners = append(rule.Owners, strings.TrimPrefix(owner, "@"))
+			}
+		}
+		// A pattern without owners is kept: it removes ownership from matching paths
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// codeOwnersPattern compiles a gitignore-style CODEOWNERS pattern. Patterns
+// containing a slash are anchored at the repository root, "*" stays within one
+// directory, "**" spans directories, and a match on a directory covers
+// everything below it.
+func codeOwnersPattern(pattern string) *regexp.Regexp {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("(^|/)")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					expr.WriteString("(.*/)?")
+				} else {
+					expr.WriteString(".*")
+				}
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dirOnly {
+		expr.WriteString("/")
+	} else {
+		expr.WriteString("(/|$)")
+	}
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// codeOwnersFor returns the owners of the given paths; for each path the last
+// matching rule wins
+func codeOwnersFor(rules []CodeOwnersRule, paths []string) []string {
+	var owners []string
+	for _, path := range paths {
+		path = filepath.ToSlash(path)
+		var matched *CodeOwnersRule
+		for i := range rules {
+			if rules[i].match != nil && rules[i].match.MatchString(path) {
+				matched = &rules[i]
+			}
+		}
+		if matched == nil {
+			continue
+		}
+		for _, owner := range matched.Owners {
+			if !slices.ContainsFunc(owners, func(o string) bool { return strings.EqualFold(o, owner) }) {
+				owners = append(owners, owner)
+			}
+		}
+	}
+	return owners
+}
+
+// requestCodeOwnerReview requests review from the owners of the files a fix
+// changed. Reviewers configured explicitly were already requested with the PR,
+// and the PR author cannot review their own PR.
+func requestCodeOwnerReview(ctx context.Context, config Config, provider HostingProvider, gitOps *GitOps, pr *PullRequest, fix *Fix) {
+	rules := gitOps.loadCodeOwners()
+	if len(rules) == 0 {
+		return
+	}
+
+	var paths []string
+	for _, change := range fix.FileChanges {
+		paths = append(paths, change.Paths()...)
+	}
+
+	var owners []string
+	for _, owner := range codeOwnersFor(rules, paths) {
+		same := func(login string) bool { return strings.EqualFold(login, owner) }
+		if same(pr.User.Login) || slices.ContainsFunc(config.Reviewers, same) {
+			continue
+		}
+		owners = append(owners, owner)
+	}
+	if len(owners) == 0 {
+		return
+	}
+
+	if err := provider.RequestReviewers(ctx, pr.Number, owners); err != nil {
+		fmt.Printf("Warning: Could not request review from code owners: %v\n", err)
+		return
+	}
+	fmt.Printf("👥 Requested review from code owners: %s\n", strings.Join(owners, ", "))
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Slash commands maintainers can post on an issue while the bot is serving
+const (
+	commandFix     = "fix"
+	commandRetry   = "retry"
+	commandExplain = "explain"
+	commandSkip    = "skip"
+	commandApprove = "approve"
+)
+
+// skipMarker is embedded in the bot's reply to /skip; the issue is ignored until /fix or /retry
+const skipMarker = "<!-- mr-code-fixer:skip -->"
+
+// trustedAssociations are GitHub author associations allowed to command the bot
+// when no command_users are configured
+var trustedAssociations = []string{"OWNER", "MEMBER", "COLLABORATOR"}
+
+// parseCommand returns the slash command a comment starts with, or ""
+func parseCommand(body string) string {
+	line := strings.TrimSpace(body)
+	if i := strings.IndexByte(line, '\n'); i != -1 {
+		line = strings.TrimSpace(line[:i])
+	}
+	if !strings.HasPrefix(line, "/") {
+		return ""
+	}
+
+	fields := strings.Fields(strings.ToLower(line[1:]))
+	if len(fields) == 0 {
+		return ""
+	}
+	switch fields[0] {
+	case commandFix, commandRetry, commandExplain, commandSkip, commandApprove:
+		return fields[0]
+	}
+	return ""
+}
+
+// isCommandAuthorized reports whether the comment author may command the bot
+func isCommandAuthorized(config Config, comment Comment) bool {
+	login := comment.User.Login
+	if len(config.CommandUsers) > 0 {
+		return slices.ContainsFunc(config.CommandUsers, func(user string) bool {
+			return strings.EqualFold(user, login)
+		})
+	}
+	return slices.Contains(trustedAssociations, comment.AuthorAssociation) || strings.EqualFold(login, config.RepoOwner)
+}
+
+// isBotComment reports comments posted by the bot itself: written by its
+// account and carrying its marker, as the account may be a person's too
+func isBotComment(comment Comment) bool {
+	return isBotAccount(comment.User.Login) && (isBotText(comment.Body) || strings.Contains(comment.Body, "🤖"))
+}
+
+// issueCommand returns the newest authorized command posted after the bot's last
+// comment with the comment it was given in, and whether the issue is currently skipped
+func issueCommand(config Config, comments []Comment) (command string, trigger Comment, skipped bool) {
+	for _, comment := range comments {
+		if isBotComment(comment) {
+			command, trigger = "", Comment{}
+			if strings.Contains(comment.Body, skipMarker) {
+				skipped = true
+			}
+			continue
+		}
+
+		cmd := parseCommand(comment.Body)
+		if cmd == "" {
+			continue
+		}
+		if !isCommandAuthorized(config, comment) {
+			fmt.Printf("Ignoring /%s from @%s (not authorized)\n", cmd, comment.User.Login)
+			continue
+		}
+		command, trigger = cmd, comment
+		if cmd == commandFix || cmd == commandRetry {
+			skipped = false
+		}
+	}
+	return command, trigger, skipped
+}
+
+// skipComment acknowledges a /skip command
+func skipComment(login string) string {
+	return fmt.Sprintf(`## ⏭️ Skipped
+
+OK @%s, I'll leave this issue alone. Comment `+"`/fix`"+` if you want me to try after all.
+
+%s
+
+---
+
+%s`, login, skipMarker, bot.signature())
+}
+
+// explainIssue answers /explain: the AI analyzes the issue as usual, but the
+// planned fix is posted as a comment instead of being applied
+func explainIssue(ctx context.Context, config Config, provider HostingProvider, aiClient AIClient, issue Issue, analytics *SessionAnalytics) error {
+	issue = prepareIssue(ctx, config, analytics, issue)
+
+	gitOps, err := newRepoGitOps(ctx, config, provider)
+	if err != nil {
+		return fmt.Errorf("failed to initialize git: %w", err)
+	}
+	defer gitOps.Cleanup()
+
+	if err := cloneRepo(ctx, config, gitOps); err != nil {
+		return fmt.Errorf("failed to clone repo: %w", err)
+	}
+
+	memory := loadRepoMemory(config.RepoOwner, config.RepoName)
+	_, repoContext, err := prepareRepoContext(ctx, config, provider, gitOps, aiClient, memory, issue)
+	if err != nil {
+		return err
+	}
+	if config.ContextBudget > 0 {
+		summarizeContext(ctx, newSummaryClient(config, analytics, aiClient), repoContext, config.ContextBudget)
+	}
+	if config.RegionEdits {
+		selectRegions(ctx, aiClient, issue, repoContext)
+	}
+
+	fmt.Println("Analyzing issue with AI...")
+	fix, err := aiClient.AnalyzeAndFix(ctx, issue, repoContext)
+	if err != nil {
+		return fmt.Errorf("AI analysis failed: %w", err)
+	}
+
+	if err := provider.AddIssueComment(ctx, issue.Number, explainComment(fix)); err != nil {
+		return fmt.Errorf("failed to post explanation: %w", err)
+	}
+	analytics.RecordIssueHandled()
+	fmt.Printf("✓ Posted plan for issue #%d\n", issue.Number)
+	return nil
+}
+
+// explainComment describes what the bot would change without changing it
+func explainComment(fix *Fix) string {
+	var comment strings.Builder
+	comment.WriteString("## 🧭 Plan\n\n")
+
+	if fix.NeedsMoreInfo && len(fix.Questions) > 0 {
+		comment.WriteString("Before I can plan a fix I need to know:\n\n")
+		for i, q := range fix.Questions {
+			comment.WriteString(fmt.Sprintf("%d. %s\n", i+1, q))
+		}
+	} else {
+		if fix.Explanation != "" {
+			comment.WriteString(fix.Explanation + "\n\n")
+		}
+		if len(fix.FileChanges) > 0 {
+			comment.WriteString("### Files I Would Change\n\n")
+			for _, change := range fix.FileChanges {
+				comment.WriteString(fmt.Sprintf("- %s\n", change.Describe()))
+			}
+			comment.WriteString("\n")
+		}
+		if fix.Confidence != "" {
+			comment.WriteString(fmt.Sprintf("**Confidence:** %s\n", fix.Confidence))
+		}
+	}
+
+	comment.WriteString("\nComment `/fix` to have me open a pull request, or `/skip` to leave this issue alone.\n\n---\n\n" + bot.signature())
+	return comment.String()
+}
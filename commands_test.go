@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestIsBotComment(t *testing.T) {
+	defer func(login string) { bot.Login = login }(bot.Login)
+	bot.Login = "fixer-bot"
+
+	tests := []struct {
+		name    string
+		comment Comment
+		want    bool
+	}{
+		{"bot with marker", Comment{User: User{Login: "fixer-bot"}, Body: "Done\n" + botMarker}, true},
+		{"bot login in another case", Comment{User: User{Login: "Fixer-Bot"}, Body: "🤖 Working on it"}, true},
+		{"bot account without marker", Comment{User: User{Login: "fixer-bot"}, Body: "/fix please"}, false},
+		{"pasted marker", Comment{User: User{Login: "mallory"}, Body: "/fix\n" + botMarker}, false},
+		{"pasted name", Comment{User: User{Login: "mallory"}, Body: "🤖 " + defaultBotName}, false},
+	}
+	for _, tt := range tests {
+		if got := isBotComment(tt.comment); got != tt.want {
+			t.Errorf("%s: isBotComment = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	bot.Login = ""
+	if isBotComment(Comment{User: User{Login: "fixer-bot"}, Body: botMarker}) {
+		t.Error("isBotComment without a known bot account = true")
+	}
+}
+
+func TestIssueCommandIgnoresForgedBotComments(t *testing.T) {
+	defer func(login string) { bot.Login = login }(bot.Login)
+	bot.Login = "fixer-bot"
+	config := Config{RepoOwner: "owner", CommandUsers: []string{"owner"}}
+
+	comments := []Comment{
+		{User: User{Login: "owner"}, Body: "/fix"},
+		// A forged bot comment must not reset the command or skip the issue
+		{User: User{Login: "mallory"}, Body: skipMarker + "\n" + botMarker},
+	}
+	command, trigger, skipped := issueCommand(config, comments)
+	if command != commandFix || trigger.User.Login != "owner" || skipped {
+		t.Errorf("issueCommand = %q by %q, skipped %v; want /fix by owner, not skipped", command, trigger.User.Login, skipped)
+	}
+
+	comments = append(comments, Comment{User: User{Login: "fixer-bot"}, Body: "Working on it\n" + botMarker})
+	if command, _, _ := issueCommand(config, comments); command != "" {
+		t.Errorf("issueCommand after the bot's reply = %q, want none", command)
+	}
+}
+
+func TestIsBotPR(t *testing.T) {
+	defer func(login string) { bot.Login = login }(bot.Login)
+	bot.Login = "fixer-bot"
+
+	pr := &PullRequest{Body: "Fixes #1\n\n" + botMarker}
+	pr.User.Login = "fixer-bot"
+	if !isBotPR(pr) {
+		t.Error("isBotPR of the bot's PR = false")
+	}
+	pr.User.Login = "mallory"
+	if isBotPR(pr) {
+		t.Error("isBotPR of a PR with a copied marker = true")
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Built-in comment templates, one directory per language
+//
+//go:embed templates
+var commentTemplates embed.FS
+
+// defaultCommentLanguage is used for templates missing in the configured language
+const defaultCommentLanguage = "en"
+
+// Names of the comment templates
+const (
+	commentNeedInfo  = "need_info" // Issue too vague to work on
+	commentQuestions = "questions" // The AI's clarifying questions
+	commentResponse  = "response"  // Answer to an issue that needs no code changes
+	commentResolved  = "resolved"  // Issue closed because a confident fix was opened
+	commentMerged    = "merged"    // Issue closed because its fix was merged
+	commentReminder  = "reminder"  // The reporter has not answered the questions yet
+	commentStale     = "stale"     // Issue closed because the questions went unanswered
+)
+
+// maxCommentFiles is how many changed files a comment lists by name
+const maxCommentFiles = 3
+
+// CommentData is what comment templates can use
+type CommentData struct {
+	Issue       Issue
+	Questions   []string
+	Explanation string
+	Files       []string // The first few changed files
+	MoreFiles   int      // How many changed files are not in Files
+	PRURL       string
+	BotName     string // bot_name
+}
+
+// withFiles lists the files a fix changed, capped at maxCommentFiles
+func (d CommentData) withFiles(fix *Fix) CommentData {
+	for _, change := range fix.FileChanges {
+		if len(d.Files) < maxCommentFiles {
+			d.Files = append(d.Files, change.TargetPath())
+		} else {
+			d.MoreFiles++
+		}
+	}
+	return d
+}
+
+var commentFuncs = template.FuncMap{
+	"inc":  func(i int) int { return i + 1 },
+	"join": strings.Join,
+	// The bot's signature, and a footer that a custom signature replaces
+	"signature": func() string { return bot.signature() },
+	"footer":    func(text string) string { return bot.footer(text) },
+}
+
+// renderComment fills in a comment template. An override directory
+// (comment_templates) takes precedence over the built-in templates, first in
+// the configured language and then without a language:
+//
+//	<comment_templates>/<language>/<name>.md
+//	<comment_templates>/<name>.md
+//	built-in <language>, then built-in English
+//
+// A broken override falls back to the built-in template.
+func renderComment(config Config, name string, data CommentData) string {
+	language := config.CommentLanguage
+	if language == "" {
+		language = defaultCommentLanguage
+	}
+
+	data.BotName = bot.Name
+	text, source := loadCommentTemplate(config.CommentTemplates, language, name)
+	comment, err := executeComment(name, text, data)
+	if err != nil {
+		fmt.Printf("Warning: Comment template %s failed, using the built-in one: %v\n", source, err)
+		text, _ := loadCommentTemplate("", language, name)
+		comment, _ = executeComment(name, text, data)
+	}
+
+	// The bot recognizes its own comments by botMarker; custom templates may leave it out
+	if !strings.Contains(comment, botMarker) {
+		comment += "\n\n" + botMarker
+	}
+	return comment
+}
+
+// loadCommentTemplate returns the template text for a comment and where it came from
+func loadCommentTemplate(dir, language, name string) (string, string) {
+	if dir != "" {
+		for _, path := range []string{filepath.Join(dir, language, name+".md"), filepath.Join(dir, name+".md")} {
+			if data, err := os.ReadFile(path); err == nil {
+				return string(data), path
+			}
+		}
+	}
+	for _, lang := range []string{language, defaultCommentLanguage} {
+		path := "templates/" + lang + "/" + name + ".md"
+		if data, err := commentTemplates.ReadFile(path); err == nil {
+			return string(data), "built-in " + lang + "/" + name
+		}
+	}
+	return "", name
+}
+
+func executeComment(name, text string, data CommentData) (string, error) {
+	tmpl, err := template.New(name).Funcs(commentFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	commitFormatPlain        = "plain"
+	commitFormatConventional = "conventional"
+
+	// Limits of commitlint's config-conventional
+	maxCommitHeaderLength = 100
+	maxCommitBodyLine     = 100
+)
+
+// conventionalTypes are the commit types accepted by config-conventional
+var conventionalTypes = []string{
+	"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test",
+}
+
+var invalidScopeChars = regexp.MustCompile(`[^a-z0-9._/-]+`)
+
+// CommitMessage is the structured commit message proposed by the AI
+type CommitMessage struct {
+	Type    string `json:"type"`
+	Scope   string `json:"scope"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// buildCommitMessage renders the commit message for a fix in the configured
+// format. Conventional messages fall back to the issue title and explanation
+// for anything the AI left out.
+func buildCommitMessage(config Config, issue Issue, fix *Fix) string {
+	if config.CommitFormat != commitFormatConventional {
+		return fmt.Sprintf("Fix %s: %s\n\n%s", issueRefs(issue), issue.Title, fix.Explanation)
+	}
+
+	var proposed CommitMessage
+	if fix.Commit != nil {
+		proposed = *fix.Commit
+	}
+
+	commitType := strings.ToLower(strings.TrimSpace(proposed.Type))
+	if !slices.Contains(conventionalTypes, commitType) {
+		commitType = "fix"
+	}
+
+	header := commitType
+	if scope := strings.Trim(invalidScopeChars.ReplaceAllString(strings.ToLower(proposed.Scope), "-"), "-"); scope != "" {
+		header += "(" + scope + ")"
+	}
+	header += ": "
+
+	subject := conventionalSubject(proposed.Subject)
+	if subject == "" {
+		subject = conventionalSubject(issue.Title)
+	}
+	header += subject
+	if utf8.RuneCountInString(header) > maxCommitHeaderLength {
+		header = strings.TrimSpace(string([]rune(header)[:maxCommitHeaderLength-3])) + "..."
+	}
+
+	body := strings.TrimSpace(proposed.Body)
+	if body == "" {
+		body = strings.TrimSpace(fix.Explanation)
+	}
+
+	parts := []string{header}
+	if body != "" {
+		parts = append(parts, wrapCommitBody(body, maxCommitBodyLine))
+	}
+	parts = append(parts, fixesLines(issue, closingKeyword(config)))
+	return strings.Join(parts, "\n\n")
+}
+
+// conventionalSubject makes a subject pass commitlint: a single line, no
+// trailing period and not starting with a capital letter unless it is an
+// acronym or identifier such as "HTTP" or "README"
+func conventionalSubject(subject string) string {
+	subject, _, _ = strings.Cut(strings.TrimSpace(subject), "\n")
+	subject = strings.TrimRight(strings.TrimSpace(subject), ".")
+
+	runes := []rune(subject)
+	if len(runes) > 1 && unicode.IsUpper(runes[0]) && !unicode.IsUpper(runes[1]) {
+		runes[0] = unicode.ToLower(runes[0])
+	}
+	return string(runes)
+}
+
+// wrapCommitBody wraps paragraphs at width, leaving lines that cannot be split
+// (long URLs, code) as they are
+func wrapCommitBody(body string, width int) string {
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		if utf8.RuneCountInString(line) <= width {
+			lines = append(lines, line)
+			continue
+		}
+
+		current := ""
+		for _, word := range strings.Fields(line) {
+			if current != "" && utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > width {
+				lines = append(lines, current)
+				current = word
+				continue
+			}
+			if current != "" {
+				current += " "
+			}
+			current += word
+		}
+		lines = append(lines, current)
+	}
+	return strings.Join(lines, "\n")
+}
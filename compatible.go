@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// CompatibleClient talks to hosted APIs that speak the OpenAI chat completions
+// protocol under their own base URL, such as Mistral and DeepSeek
+type CompatibleClient struct {
+	service    string // analytics and pricing key, e.g. "mistral"
+	name       string // shown in errors, e.g. "Mistral"
+	apiKey     string
+	model      string
+	baseURL    string
+	fallback   []string // models offered when the model list cannot be fetched
+	client     *http.Client
+	analytics  *SessionAnalytics
+	scheduler  *RequestScheduler
+	cache      *ResponseCache
+	structured structuredOutput
+}
+
+// NewMistralClient returns a client for the Mistral API. Codestral is the
+// default because it is tuned for code and priced well below mistral-large.
+func NewMistralClient(apiKey, model string) *CompatibleClient {
+	if model == "" {
+		model = "codestral-latest"
+	}
+	return &CompatibleClient{
+		service:  "mistral",
+		name:     "Mistral",
+		apiKey:   apiKey,
+		model:    model,
+		baseURL:  "https://api.mistral.ai/v1",
+		fallback: []string{"codestral-latest", "mistral-large-latest", "mistral-small-latest"},
+		client:   &http.Client{Timeout: 120 * time.Second},
+	}
+}
+
+// NewDeepSeekClient returns a client for the DeepSeek API
+func NewDeepSeekClient(apiKey, model string) *CompatibleClient {
+	if model == "" {
+		model = "deepseek-chat"
+	}
+	return &CompatibleClient{
+		service:  "deepseek",
+		name:     "DeepSeek",
+		apiKey:   apiKey,
+		model:    model,
+		baseURL:  "https://api.deepseek.com/v1",
+		fallback: []string{"deepseek-chat", "deepseek-reasoner"},
+		client:   &http.Client{Timeout: 120 * time.Second},
+	}
+}
+
+func (c *CompatibleClient) SetAnalytics(analytics *SessionAnalytics) {
+	c.analytics = analytics
+}
+
+// SetTimeout overrides the HTTP timeout for requests; 0 disables it
+func (c *CompatibleClient) SetTimeout(timeout time.Duration) {
+	c.client.Timeout = timeout
+}
+
+// SetScheduler routes requests through a shared rate limiter
+func (c *CompatibleClient) SetScheduler(scheduler *RequestScheduler) {
+	c.scheduler = scheduler
+}
+
+// SetCache reuses earlier fixes for unchanged prompts
+func (c *CompatibleClient) SetCache(cache *ResponseCache) {
+	c.cache = cache
+}
+
+func (c *CompatibleClient) AnalyzeAndFix(ctx context.Context, issue Issue, repoContext *RepoContext) (*Fix, error) {
+	prompt := c.buildPrompt(issue, repoContext)
+
+	response, cached := c.cache.Get(c.baseURL, c.model, systemPrompt(repoContext), prompt)
+	if !cached {
+		var err error
+		response, err = completeFix(ctx, c, systemPrompt(repoContext), prompt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fix, err := c.parseFix(response, repoContext)
+	if err != nil {
+		return nil, err
+	}
+	if !cached {
+		c.cache.Put(c.baseURL, c.model, systemPrompt(repoContext), prompt, response)
+	}
+	return fix, nil
+}
+
+// Complete sends a single system+user prompt and returns the raw model reply
+func (c *CompatibleClient) Complete(ctx context.Context, systemPrompt, prompt string) (string, error) {
+	return c.complete(ctx, systemPrompt, prompt, nil)
+}
+
+// SetStructuredOutput selects how JSON replies are requested (structured_output)
+func (c *CompatibleClient) SetStructuredOutput(mode string) {
+	c.structured.set(mode)
+}
+
+// CompleteJSON is like Complete but asks the API for valid JSON
+func (c *CompatibleClient) CompleteJSON(ctx context.Context, systemPrompt, prompt string) (string, error) {
+	return c.CompleteSchema(ctx, systemPrompt, prompt, nil)
+}
+
+// CompleteSchema is like Complete but constrains the reply to a JSON schema
+func (c *CompatibleClient) CompleteSchema(ctx context.Context, systemPrompt, prompt string, schema *JSONSchema) (string, error) {
+	return c.structured.complete(func(mode string) (string, error) {
+		return c.complete(ctx, systemPrompt, prompt, openAIResponseFormat(mode, schema, systemPrompt+prompt))
+	})
+}
+
+func (c *CompatibleClient) complete(ctx context.Context, systemPrompt, prompt string, format *OpenAIResponseFormat) (string, error) {
+	reqBody := OpenAIRequest{
+		Model: c.model,
+		Messages: []OpenAIMessage{
+			{
+				Role:    "system",
+				Content: systemPrompt,
+			},
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+		Temperature:    0.2,
+		MaxTokens:      8000,
+		ResponseFormat: format,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.scheduler.Do(ctx, c.client, req, estimateTokens(systemPrompt+prompt))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		err := fmt.Errorf("%s API error: %s - %s", c.name, resp.Status, string(body))
+		return "", formatError(err, format != nil, resp.StatusCode, string(body))
+	}
+
+	var completion OpenAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return "", err
+	}
+
+	if len(completion.Choices) == 0 {
+		return "", fmt.Errorf("no response from AI")
+	}
+	if format != nil && completion.Choices[0].FinishReason == "length" {
+		return "", errReplyTruncated
+	}
+
+	// Track API call and its cost
+	if c.analytics != nil {
+		inputTokens, outputTokens := completion.tokens(systemPrompt+prompt, completion.Choices[0].Message.Content)
+		c.analytics.RecordAPICall(c.service, c.model, inputTokens, outputTokens)
+	}
+
+	return completion.Choices[0].Message.Content, nil
+}
+
+func (c *CompatibleClient) buildPrompt(issue Issue, context *RepoContext) string {
+	g := &OpenAIClient{}
+	return g.buildPrompt(issue, context)
+}
+
+func (c *CompatibleClient) parseFix(response string, repoContext *RepoContext) (*Fix, error) {
+	g := &OpenAIClient{}
+	return g.parseFix(response, repoContext)
+}
+
+func (c *CompatibleClient) GetAvailableModels() ([]string, error) {
+	req, err := http.NewRequest("GET", c.baseURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.fallback, nil
+	}
+
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return c.fallback, nil
+	}
+
+	models := make([]string, 0, len(result.Data))
+	for _, m := range result.Data {
+		models = append(models, m.ID)
+	}
+
+	if len(models) == 0 {
+		return c.fallback, nil
+	}
+
+	return models, nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Defaults of the context limits, overridable with the context_max_* settings
+const (
+	defaultContextMaxBytes        = 512 * 1024 // File content GetRepoContext reads
+	defaultContextMaxFiles        = 30         // Files included from the relevance ranking
+	defaultContextMaxFileKB       = 100        // Larger source files are skipped
+	defaultContextMaxCharsPerFile = 5000       // Characters of each file shown in the prompt
+)
+
+const contextReadWorkers = 8
+
+// ContextLimits bound what GetRepoContext gathers. A zero limit other than
+// MaxFiles means no limit.
+type ContextLimits struct {
+	MaxFiles        int // Files included from the relevance ranking
+	MaxFileSize     int // Bytes; larger source files are skipped
+	MaxBytes        int // Total file content read
+	MaxCharsPerFile int // Characters of each file shown in the prompt
+}
+
+// contextLimits are the configured context limits
+func contextLimits(config Config) ContextLimits {
+	return ContextLimits{
+		MaxFiles:        config.ContextMaxFiles,
+		MaxFileSize:     config.ContextMaxFileKB * 1024,
+		MaxBytes:        config.ContextMaxBytes,
+		MaxCharsPerFile: config.ContextMaxCharsPerFile,
+	}
+}
+
+// generatedMarkers appear in a comment at the top of generated files
+var generatedMarkers = []string{"code generated", "do not edit", "@generated", "auto-generated"}
+
+// generatedSuffixes are file name endings of generated or bundled code
+var generatedSuffixes = []string{
+	".min.js", ".min.css", ".bundle.js", ".pb.go", ".pb.gw.go", "_pb2.py", ".g.dart",
+	".generated.ts", ".generated.go", "_generated.go", "_gen.go", ".designer.cs",
+}
+
+// ExcludedFile is a candidate context file that was left out, and why
+type ExcludedFile struct {
+	Path   string
+	Reason string // "binary", "minified", "generated", "too large", "byte cap"
+}
+
+// contextFile is the result of reading one candidate file
+type contextFile struct {
+	path    string
+	content string
+	reason  string // Why it must be excluded, empty if usable
+}
+
+// readContextFiles reads and classifies files in parallel, keeping their order
+func readContextFiles(repoPath string, paths []string) []contextFile {
+	files := make([]contextFile, len(paths))
+	workers := min(contextReadWorkers, runtime.NumCPU())
+
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				files[i] = readContextFile(repoPath, paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return files
+}
+
+func readContextFile(repoPath, path string) contextFile {
+	file := contextFile{path: path}
+	data, err := os.ReadFile(filepath.Join(repoPath, path))
+	if err != nil {
+		file.reason = "unreadable"
+		return file
+	}
+	file.content = string(data)
+	file.reason = classifyContent(path, data)
+	return file
+}
+
+// classifyContent tells binary, minified and generated files apart from code
+// worth showing the AI. It returns the reason to skip the file, or "".
+func classifyContent(path string, data []byte) string {
+	head := data[:min(len(data), 8*1024)]
+	if bytes.IndexByte(head, 0) != -1 || !utf8.Valid(head[:validUTF8Prefix(head)]) {
+		return "binary"
+	}
+
+	lower := strings.ToLower(path)
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return "generated"
+		}
+	}
+
+	// Minified: very long lines, e.g. a whole bundle on one line
+	lines := bytes.Count(data, []byte("\n")) + 1
+	if len(data) > 2000 && len(data)/lines > 300 {
+		return "minified"
+	}
+
+	// A generated-code comment in the first few lines
+	top := strings.SplitN(string(data[:min(len(data), 1024)]), "\n", 6)
+	for _, line := range top[:min(len(top), 5)] {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if !isCommentLine(line) {
+			continue
+		}
+		for _, marker := range generatedMarkers {
+			if strings.Contains(line, marker) {
+				return "generated"
+			}
+		}
+	}
+	return ""
+}
+
+func isCommentLine(line string) bool {
+	for _, prefix := range []string{"//", "#", "/*", "*", "<!--", "--", ";"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validUTF8Prefix trims a partial multi-byte character cut off at the end of head
+func validUTF8Prefix(head []byte) int {
+	end := len(head)
+	for i := 0; i < utf8.UTFMax && end > 0; i++ {
+		if utf8.FullRune(head[:end]) {
+			break
+		}
+		end--
+	}
+	return end
+}
+
+// printContextReport shows what went into the context and what was left out
+func printContextReport(repoContext *RepoContext, maxBytes int) {
+	total := 0
+	for _, content := range repoContext.Files {
+		total += len(content)
+	}
+	limit := ""
+	if maxBytes > 0 {
+		limit = fmt.Sprintf(" of %d KB allowed", maxBytes/1024)
+	}
+	fmt.Printf("📂 Context: %d file(s), %d KB%s\n", len(repoContext.Files), total/1024, limit)
+
+	if len(repoContext.Excluded) == 0 {
+		return
+	}
+	byReason := make(map[string][]string)
+	for _, excluded := range repoContext.Excluded {
+		byReason[excluded.Reason] = append(byReason[excluded.Reason], excluded.Path)
+	}
+	reasons := make([]string, 0, len(byReason))
+	for reason := range byReason {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		paths := byReason[reason]
+		shown := strings.Join(paths[:min(len(paths), 5)], ", ")
+		if len(paths) > 5 {
+			shown += fmt.Sprintf(" and %d more", len(paths)-5)
+		}
+		fmt.Printf("   Excluded %d %s: %s\n", len(paths), reason, shown)
+	}
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxDashboardLogLines bounds the in-memory live log buffer
+const maxDashboardLogLines = 500
+
+// maxDashboardJobs bounds the jobs kept in memory; the oldest finished jobs
+// are dropped first
+const maxDashboardJobs = 200
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// DashboardJob is a single issue processing attempt shown on the dashboard
+type DashboardJob struct {
+	ID           int       `json:"id"`
+	Repo         string    `json:"repo,omitempty"` // Set for jobs requested through the API
+	IssueNumber  int       `json:"issue_number"`
+	Title        string    `json:"title"`
+	Status       string    `json:"status"` // "queued", "running", "succeeded", "failed", "skipped"
+	Error        string    `json:"error,omitempty"`
+	PullRequests []string  `json:"pull_requests,omitempty"`
+	Queued       time.Time `json:"queued,omitempty"`
+	Started      time.Time `json:"started"`
+	Finished     time.Time `json:"finished,omitempty"`
+}
+
+// DashboardPR is an open pull request of the bot shown on the dashboard
+type DashboardPR struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+}
+
+// Dashboard collects bot activity for the embedded web UI in serve mode
+type Dashboard struct {
+	repo         string
+	service      string
+	model        string
+	analytics    *SessionAnalytics
+	jobs         []*DashboardJob
+	lastID       int
+	logs         []string
+	pullRequests []DashboardPR // The bot's open PRs as of the last poll
+	mutex        sync.Mutex
+}
+
+func NewDashboard(config Config, analytics *SessionAnalytics) *Dashboard {
+	return &Dashboard{
+		repo:      config.RepoOwner + "/" + config.RepoName,
+		service:   config.AIService,
+		model:     config.AIModel,
+		analytics: analytics,
+	}
+}
+
+// StartJob records that processing of an issue has begun
+func (d *Dashboard) StartJob(issue Issue) *DashboardJob {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.lastID++
+	job := &DashboardJob{
+		ID:          d.lastID,
+		IssueNumber: issue.Number,
+		Title:       issue.Title,
+		Status:      "running",
+		Started:     time.Now(),
+	}
+	d.addJob(job)
+	return job
+}
+
+// QueueJob records a job that waits to be run, as requested through the API
+func (d *Dashboard) QueueJob(repo string, number int) *DashboardJob {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.lastID++
+	job := &DashboardJob{
+		ID:          d.lastID,
+		Repo:        repo,
+		IssueNumber: number,
+		Status:      "queued",
+		Queued:      time.Now(),
+	}
+	d.addJob(job)
+	return job
+}
+
+// addJob appends a job and drops the oldest finished jobs beyond
+// maxDashboardJobs. Queued and running jobs are always kept. The caller
+// holds the mutex.
+func (d *Dashboard) addJob(job *DashboardJob) {
+	d.jobs = append(d.jobs, job)
+	for excess := len(d.jobs) - maxDashboardJobs; excess > 0; excess-- {
+		i := slices.IndexFunc(d.jobs, func(job *DashboardJob) bool {
+			return job.Status != "queued" && job.Status != "running"
+		})
+		if i < 0 {
+			break
+		}
+		d.jobs = slices.Delete(d.jobs, i, i+1)
+	}
+}
+
+// RunJob marks a queued job as running on an issue
+func (d *Dashboard) RunJob(job *DashboardJob, issue Issue) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	job.Title = issue.Title
+	job.Status = "running"
+	job.Started = time.Now()
+}
+
+// AddPullRequests records the pull requests a job created
+func (d *Dashboard) AddPullRequests(job *DashboardJob, urls []string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	job.PullRequests = append(job.PullRequests, urls...)
+}
+
+// RefreshPullRequests looks up the bot's open pull requests, including those
+// of earlier runs. It is called once per poll cycle, so page views never
+// reach the provider; if the lookup fails the previous list is kept.
+func (d *Dashboard) RefreshPullRequests(ctx context.Context, provider HostingProvider) {
+	open, err := provider.GetOpenPullRequests(ctx, 100)
+	if err != nil {
+		fmt.Printf("Warning: Could not list open pull requests for the dashboard: %v\n", err)
+		return
+	}
+	var prs []DashboardPR
+	for _, pr := range open {
+		if isBotPR(&pr) {
+			prs = append(prs, DashboardPR{Number: pr.Number, Title: pr.Title, URL: pr.HTMLURL})
+		}
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.pullRequests = prs
+}
+
+// Job returns a copy of the job with the given ID
+func (d *Dashboard) Job(id int) (DashboardJob, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, job := range d.jobs {
+		if job.ID == id {
+			return *job, true
+		}
+	}
+	return DashboardJob{}, false
+}
+
+// PendingJob returns a copy of the queued or running job for an issue, if any
+func (d *Dashboard) PendingJob(repo string, number int) (DashboardJob, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, job := range d.jobs {
+		if strings.EqualFold(job.Repo, repo) && job.IssueNumber == number && (job.Status == "queued" || job.Status == "running") {
+			return *job, true
+		}
+	}
+	return DashboardJob{}, false
+}
+
+// FinishJob marks a job as succeeded or failed
+func (d *Dashboard) FinishJob(job *DashboardJob, err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	job.Finished = time.Now()
+	if errors.Is(err, errIssueSkipped) {
+		job.Status = "skipped"
+	} else if err != nil {
+		job.Status = "failed"
+		job.Error = err.Error()
+	} else {
+		job.Status = "succeeded"
+	}
+}
+
+func (d *Dashboard) addLog(line string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.logs = append(d.logs, ansiEscape.ReplaceAllString(line, ""))
+	if len(d.logs) > maxDashboardLogLines {
+		d.logs = d.logs[len(d.logs)-maxDashboardLogLines:]
+	}
+}
+
+// CaptureOutput tees everything written to stdout into the live log buffer.
+// The returned function restores the original stdout.
+func (d *Dashboard) CaptureOutput() func() {
+	original := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		return func() {}
+	}
+	os.Stdout = w
+
+	done := make(chan struct{})
+	go func() {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				original.WriteString(line)
+				d.addLog(trimNewline(line))
+			}
+			if err != nil {
+				break
+			}
+		}
+		close(done)
+	}()
+
+	return func() {
+		os.Stdout = original
+		w.Close()
+		<-done
+	}
+}
+
+func trimNewline(line string) string {
+	for len(line) > 0 && (line[len(line)-1] == '\n' || line[len(line)-1] == '\r') {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// DashboardStatus is the JSON document served at /api/status
+type DashboardStatus struct {
+	Repo         string            `json:"repo"`
+	AIService    string            `json:"ai_service"`
+	AIModel      string            `json:"ai_model"`
+	Analytics    AnalyticsSnapshot `json:"analytics"`
+	PullRequests []DashboardPR     `json:"pull_requests"`
+	Jobs         []DashboardJob    `json:"jobs"`
+	Succeeded    int               `json:"succeeded"`
+	Failed       int               `json:"failed"`
+	SuccessRate  float64           `json:"success_rate"`
+	Logs         []string          `json:"logs"`
+}
+
+func (d *Dashboard) Status() DashboardStatus {
+	snapshot := d.analytics.Snapshot()
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	status := DashboardStatus{
+		Repo:         d.repo,
+		AIService:    d.service,
+		AIModel:      d.model,
+		Analytics:    snapshot,
+		Logs:         append([]string(nil), d.logs...),
+		PullRequests: append([]DashboardPR(nil), d.pullRequests...),
+	}
+
+	// Newest jobs first
+	for i := len(d.jobs) - 1; i >= 0; i-- {
+		job := *d.jobs[i]
+		status.Jobs = append(status.Jobs, job)
+		switch job.Status {
+		case "succeeded":
+			status.Succeeded++
+		case "failed":
+			status.Failed++
+		}
+	}
+
+	if finished := status.Succeeded + status.Failed; finished > 0 {
+		status.SuccessRate = float64(status.Succeeded) / float64(finished) * 100
+	}
+
+	return status
+}
+
+// Handler returns the HTTP handler serving the dashboard page and JSON API.
+// With a token, every request needs it, as a bearer token or, for browsers,
+// as the token query parameter.
+func (d *Dashboard) Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(d.Status())
+	})
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := dashboardTemplate.Execute(w, d.Status()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	if token == "" {
+		return mux
+	}
+	return requireToken(token, true, mux)
+}
+
+// dashboardListenAddr is the address the dashboard listens on: a bare port
+// such as ":8080" only listens on the loopback interface
+func dashboardListenAddr(addr string) string {
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		return net.JoinHostPort("127.0.0.1", port)
+	}
+	return addr
+}
+
+// isLoopbackAddr reports whether a listen address only accepts local connections
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// costChartPoints renders the cumulative cost curve as SVG polyline points
+func costChartPoints(samples []CostSample) string {
+	if len(samples) < 2 {
+		return ""
+	}
+
+	const width, height = 600.0, 120.0
+	start := samples[0].Time
+	span := samples[len(samples)-1].Time.Sub(start).Seconds()
+	maxCost := samples[len(samples)-1].Cost
+	if span <= 0 || maxCost <= 0 {
+		return ""
+	}
+
+	points := ""
+	for _, sample := range samples {
+		x := sample.Time.Sub(start).Seconds() / span * width
+		y := height - sample.Cost/maxCost*height
+		points += fmt.Sprintf("%.1f,%.1f ", x, y)
+	}
+	return points
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"costChart": costChartPoints,
+	"since": func(t time.Time) string {
+		return time.Since(t).Round(time.Second).String()
+	},
+	"clock": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("15:04:05")
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="5">
+<title>Mr. Code Fixer - {{.Repo}}</title>
+<style>
+body { font-family: -apple-system, sans-serif; margin: 2em; background: #0d1117; color: #c9d1d9; }
+h1, h2 { color: #58a6ff; }
+.cards { display: flex; gap: 1em; flex-wrap: wrap; }
+.card { background: #161b22; border: 1px solid #30363d; border-radius: 6px; padding: 1em 1.5em; }
+.card b { display: block; font-size: 1.6em; }
+table { border-collapse: collapse; width: 100%; }
+td, th { border-bottom: 1px solid #30363d; padding: 0.4em; text-align: left; }
+.running { color: #d29922; } .succeeded { color: #3fb950; } .failed { color: #f85149; } .skipped, .queued { color: #8b949e; }
+pre { background: #161b22; padding: 1em; max-height: 30em; overflow: auto; }
+a { color: #58a6ff; }
+</style>
+</head>
+<body>
+<h1>🤖 Mr. Code Fixer</h1>
+<p>📦 <b>{{.Repo}}</b> &middot; 🧠 {{.AIService}} ({{.AIModel}}) &middot; ⏱️ up {{since .Analytics.StartTime}}</p>
+
+<div class="cards">
+<div class="card">Issues handled<b>{{.Analytics.IssuesHandled}}</b></div>
+<div class="card">PRs created<b>{{.Analytics.PRsCreated}}</b></div>
+<div class="card">Questions asked<b>{{.Analytics.QuestionsAsked}}</b></div>
+<div class="card">Code changed<b>+{{.Analytics.TotalChanges.Insertions}} -{{.Analytics.TotalChanges.Deletions}}</b>{{.Analytics.TotalChanges.Files}} file(s)</div>
+<div class="card">Success rate<b>{{printf "%.0f" .SuccessRate}}%</b>{{.Succeeded}} ok / {{.Failed}} failed</div>
+<div class="card">API calls<b>{{.Analytics.APICallCount}}</b></div>
+<div class="card">Estimated cost<b>{{printf "%.4f" .Analytics.EstimatedCost}} {{.Analytics.Currency}}</b></div>
+</div>
+
+<h2>💰 Spend over time</h2>
+{{with costChart .Analytics.CostHistory}}<svg width="600" height="120" style="background:#161b22"><polyline fill="none" stroke="#58a6ff" stroke-width="2" points="{{.}}"/></svg>{{else}}<p>Not enough data yet.</p>{{end}}
+
+<h2>🔧 Open pull requests</h2>
+{{if .PullRequests}}<ul>{{range .PullRequests}}<li><a href="{{.URL}}">#{{.Number}} {{.Title}}</a></li>{{end}}</ul>{{else}}<p>None open.</p>{{end}}
+
+<h2>🐛 Processed issues</h2>
+<table>
+<tr><th>Issue</th><th>Title</th><th>Status</th><th>Started</th><th>Finished</th><th>Error</th></tr>
+{{range .Jobs}}<tr><td>{{.Repo}}#{{.IssueNumber}}</td><td>{{.Title}}</td><td class="{{.Status}}">{{.Status}}</td><td>{{clock .Started}}</td><td>{{clock .Finished}}</td><td>{{.Error}}</td></tr>
+{{end}}</table>
+
+<h2>📜 Live log</h2>
+<pre>{{range .Logs}}{{.}}
+{{end}}</pre>
+</body>
+</html>
+`))
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDashboardPrunesFinishedJobs(t *testing.T) {
+	dashboard := NewDashboard(Config{RepoOwner: "owner", RepoName: "repo"}, nil)
+
+	queued := dashboard.QueueJob("owner/repo", 1)
+	for i := 0; i < maxDashboardJobs*2; i++ {
+		job := dashboard.QueueJob("owner/repo", i+2)
+		dashboard.FinishJob(job, nil)
+	}
+
+	if len(dashboard.jobs) > maxDashboardJobs {
+		t.Errorf("%d jobs kept, want at most %d", len(dashboard.jobs), maxDashboardJobs)
+	}
+	if _, ok := dashboard.Job(queued.ID); !ok {
+		t.Error("the queued job was pruned")
+	}
+	if _, ok := dashboard.Job(queued.ID + 1); ok {
+		t.Error("the oldest finished job was kept")
+	}
+}
+
+// openPRsProvider serves a fixed list of open pull requests
+type openPRsProvider struct {
+	HostingProvider
+	prs []PullRequest
+	err error
+}
+
+func (p *openPRsProvider) GetOpenPullRequests(ctx context.Context, maxPRs int) ([]PullRequest, error) {
+	return p.prs, p.err
+}
+
+func TestDashboardRefreshPullRequests(t *testing.T) {
+	defer func(login string) { bot.Login = login }(bot.Login)
+	bot.Login = "fixer-bot"
+
+	pr := func(number int, login, body string) PullRequest {
+		pr := PullRequest{Number: number, Title: "Fix", HTMLURL: fmt.Sprintf("https://example.com/pull/%d", number), Body: body}
+		pr.User.Login = login
+		return pr
+	}
+	provider := &openPRsProvider{prs: []PullRequest{
+		pr(1, "fixer-bot", "Fixes #3\n"+botMarker),
+		pr(2, "mallory", "Copied\n"+botMarker),
+		pr(3, "fixer-bot", "Opened by hand"),
+	}}
+
+	// Nothing is recorded in the analytics, as after a restart
+	dashboard := NewDashboard(Config{RepoOwner: "owner", RepoName: "repo"}, NewSessionAnalytics(NewPricing(Config{})))
+	dashboard.RefreshPullRequests(context.Background(), provider)
+	status := dashboard.Status()
+	if len(status.PullRequests) != 1 || status.PullRequests[0].Number != 1 {
+		t.Fatalf("PullRequests = %+v, want only #1", status.PullRequests)
+	}
+
+	// A failed lookup keeps the previous list
+	provider.err = errors.New("rate limited")
+	dashboard.RefreshPullRequests(context.Background(), provider)
+	if got := dashboard.Status().PullRequests; len(got) != 1 {
+		t.Errorf("PullRequests = %+v after a failed refresh", got)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// maxDuplicateCandidates bounds how many closed issues are compared
+const maxDuplicateCandidates = 50
+
+// duplicateStopWords are ignored when comparing issue texts
+var duplicateStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "when": true, "not": true,
+	"does": true, "doesn": true, "don": true, "can": true, "cannot": true, "this": true,
+	"that": true, "from": true, "are": true, "was": true, "has": true, "have": true,
+	"after": true, "into": true, "on": true, "in": true, "of": true, "to": true,
+	"bug": true, "issue": true, "error": true, "problem": true, "please": true,
+}
+
+// duplicateMarker identifies the bot's duplicate notice in issue comments.
+// Notices from before the marker are recognized by legacyDuplicateMarker.
+const (
+	duplicateMarker       = "<!-- mr-code-fixer:duplicate -->"
+	legacyDuplicateMarker = "Mr. Code Fixer - Possible Duplicate"
+)
+
+// DuplicateMatch is an existing issue that the new one likely duplicates
+type DuplicateMatch struct {
+	Issue Issue
+	Score float64
+}
+
+// issueWords returns the set of significant lowercase words in a text
+func issueWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) < 3 || duplicateStopWords[word] {
+			continue
+		}
+		words[stemWord(word)] = true
+	}
+	return words
+}
+
+// stemWord strips common English suffixes so "crashes" matches "crash"
+func stemWord(word string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 3 {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+// jaccard is the overlap of two word sets, 0 when either is empty
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// issueSimilarity scores two issues between 0 and 1. Titles carry most of the
+// weight; the start of the body is used when both issues have one.
+func issueSimilarity(a, b Issue) float64 {
+	titleScore := jaccard(issueWords(a.Title), issueWords(b.Title))
+
+	bodyA := issueWords(truncateForSimilarity(a.Body))
+	bodyB := issueWords(truncateForSimilarity(b.Body))
+	if len(bodyA) == 0 || len(bodyB) == 0 {
+		return titleScore
+	}
+
+	return 0.7*titleScore + 0.3*jaccard(bodyA, bodyB)
+}
+
+func truncateForSimilarity(body string) string {
+	if len(body) > 1000 {
+		return body[:1000]
+	}
+	return body
+}
+
+// findDuplicate returns the most similar earlier open issue or recently closed
+// issue scoring at least threshold, or nil
+func findDuplicate(issue Issue, open, closed []Issue, threshold float64) *DuplicateMatch {
+	var best *DuplicateMatch
+
+	consider := func(candidate Issue) {
+		if candidate.Number == issue.Number || candidate.PullRequest != nil {
+			return
+		}
+		score := issueSimilarity(issue, candidate)
+		if score >= threshold && (best == nil || score > best.Score) {
+			best = &DuplicateMatch{Issue: candidate, Score: score}
+		}
+	}
+
+	// Only older open issues can be the original
+	for _, candidate := range open {
+		if candidate.Number < issue.Number {
+			consider(candidate)
+		}
+	}
+	for _, candidate := range closed {
+		consider(candidate)
+	}
+
+	return best
+}
+
+// checkDuplicate looks for an existing issue the given one duplicates
+func checkDuplicate(ctx context.Context, config Config, provider HostingProvider, issue Issue) (*DuplicateMatch, error) {
+	// The reporter already replied to our duplicate notice; respect their answer
+	comments, err := provider.GetIssueComments(ctx, issue.Number)
+	if err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		if isBotComment(comment) && (strings.Contains(comment.Body, duplicateMarker) || strings.Contains(comment.Body, legacyDuplicateMarker)) {
+			return nil, nil
+		}
+	}
+
+	open, err := provider.GetOpenIssues(ctx, 100)
+	if err != nil {
+		return nil, fmt.Errorf("fetching open issues: %w", err)
+	}
+
+	closed, err := provider.GetClosedIssues(ctx, maxDuplicateCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("fetching closed issues: %w", err)
+	}
+
+	return findDuplicate(issue, open, closed, config.DuplicateThreshold), nil
+}
+
+// duplicateComment is posted on an issue that was detected as a duplicate
+func duplicateComment(match *DuplicateMatch, closing bool) string {
+	state := "an open issue"
+	if match.Issue.State == "closed" {
+		state = "an already closed issue"
+	}
+
+	comment := fmt.Sprintf(`%s
+🤖 **%s - Possible Duplicate**
+
+This looks like a duplicate of #%d (%s, %.0f%% similar):
+> %s
+
+`, duplicateMarker, bot.Name, match.Issue.Number, state, match.Score*100, match.Issue.Title)
+
+	if closing {
+		comment += "Closing this one so the discussion stays in one place. If this is a different problem, please reopen it and explain what differs. 🙏"
+	} else {
+		comment += "I'm not generating a separate fix for now. If this is a different problem, please add a comment explaining what differs and I'll take another look. 🙏"
+	}
+
+	return comment
+}
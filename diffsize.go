@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// What to do with a fix that exceeds max_diff_lines or max_diff_files
+const (
+	diffLimitShrink    = "shrink"    // Ask the AI for a minimal fix, downgrade if it is still too large
+	diffLimitConfirm   = "confirm"   // Ask the user whether to keep it
+	diffLimitDowngrade = "downgrade" // Keep it with low confidence
+)
+
+// Default size limits of a single fix
+const (
+	defaultMaxDiffLines = 500
+	defaultMaxDiffFiles = 20
+)
+
+// DiffSize is how much an applied fix changes
+type DiffSize struct {
+	Lines int
+	Files int
+}
+
+func (d DiffSize) String() string {
+	return fmt.Sprintf("%d lines in %d files", d.Lines, d.Files)
+}
+
+// exceeds reports whether the diff is over either limit; 0 means no limit
+func (d DiffSize) exceeds(maxLines, maxFiles int) bool {
+	return (maxLines > 0 && d.Lines > maxLines) || (maxFiles > 0 && d.Files > maxFiles)
+}
+
+// diffLimitText describes the configured limits, e.g. "500 lines / 20 files"
+func diffLimitText(config Config) string {
+	var limits []string
+	if config.MaxDiffLines > 0 {
+		limits = append(limits, fmt.Sprintf("%d lines", config.MaxDiffLines))
+	}
+	if config.MaxDiffFiles > 0 {
+		limits = append(limits, fmt.Sprintf("%d files", config.MaxDiffFiles))
+	}
+	return strings.Join(limits, " / ")
+}
+
+func measureDiff(ctx context.Context, gitOps *GitOps) (DiffSize, error) {
+	stats, _, err := gitOps.DiffStats(ctx)
+	if err != nil {
+		return DiffSize{}, err
+	}
+	return DiffSize{Lines: stats.Lines(), Files: stats.Files}, nil
+}
+
+// limitDiffSize enforces the size limits on the applied fix. A 2,000-line
+// change for a typo is a red flag, so depending on diff_limit_policy the AI is
+// asked for a minimal fix, the user is asked, or the fix is kept with low
+// confidence. It returns the fix to continue with and whether it is still over
+// the limits.
+func limitDiffSize(ctx context.Context, config Config, gitOps *GitOps, aiClient AIClient, issue Issue, repoContext *RepoContext, fix *Fix) (*Fix, bool, error) {
+	if config.MaxDiffLines <= 0 && config.MaxDiffFiles <= 0 {
+		return fix, false, nil
+	}
+
+	size, err := measureDiff(ctx, gitOps)
+	if err != nil {
+		return nil, false, err
+	}
+	if !size.exceeds(config.MaxDiffLines, config.MaxDiffFiles) {
+		return fix, false, nil
+	}
+	fmt.Printf(T("diff.too_large"), size, diffLimitText(config))
+
+	switch config.DiffLimitPolicy {
+	case diffLimitConfirm:
+		if !isYes(prompt(T("diff.allow"), T("answer.no"))) {
+			return nil, false, errIssueSkipped
+		}
+		return fix, false, nil
+	case diffLimitShrink:
+		smaller, err := shrinkFix(ctx, config, gitOps, aiClient, issue, repoContext, fix, size)
+		if smaller == nil {
+			return nil, false, err
+		}
+		if err != nil {
+			fmt.Printf("Warning: Could not get a smaller fix: %v\n", err)
+			break
+		}
+		fix = smaller
+		if size, err = measureDiff(ctx, gitOps); err != nil {
+			return nil, false, err
+		}
+		if !size.exceeds(config.MaxDiffLines, config.MaxDiffFiles) {
+			fmt.Printf(T("diff.shrunk"), size)
+			return fix, false, nil
+		}
+		fmt.Printf(T("diff.still_large"), size)
+	}
+
+	fmt.Println(T("diff.downgraded"))
+	fix.Confidence = "low"
+	return fix, true, nil
+}
+
+// shrinkFix asks the AI to redo an oversized fix as the smallest change that
+// resolves the issue and applies it. It returns the fix that is applied
+// afterwards: the original one with the error when no smaller fix could be
+// used, or nil when the working tree could not be restored.
+func shrinkFix(ctx context.Context, config Config, gitOps *GitOps, aiClient AIClient, issue Issue, repoContext *RepoContext, fix *Fix, size DiffSize) (*Fix, error) {
+	diff, err := gitOps.Diff(ctx)
+	if err != nil {
+		return fix, err
+	}
+
+	fmt.Println(T("diff.shrinking"))
+	problem := fmt.Sprintf("The change is too large: it touches %s, more than the limit of %s for one fix. Produce the smallest change that fixes the issue: no refactoring, reformatting, renames or unrelated cleanup, and only the files that must change.", size, diffLimitText(config))
+	smaller, err := reviseFix(ctx, aiClient, issue, repoContext, diff, []string{problem})
+	if err != nil {
+		return fix, err
+	}
+	if len(smaller.FileChanges) == 0 {
+		return fix, fmt.Errorf("AI produced no file changes")
+	}
+	if config.SyntaxCheck {
+		if problems := checkFixSyntax(smaller); len(problems) > 0 {
+			return fix, fmt.Errorf("smaller fix has syntax errors: %s", strings.Join(problems, "; "))
+		}
+	}
+
+	if err := gitOps.ResetChanges(ctx); err != nil {
+		return nil, err
+	}
+	if err := applyFix(gitOps, smaller); err != nil {
+		// Put the original fix back so the pipeline can continue with it
+		if err := gitOps.ResetChanges(ctx); err != nil {
+			return nil, err
+		}
+		if err := applyFix(gitOps, fix); err != nil {
+			return nil, err
+		}
+		return fix, err
+	}
+	return smaller, nil
+}
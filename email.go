@@ -0,0 +1,221 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultEmailInterval is how often serve mails a summary, overridable with email_interval
+const defaultEmailInterval = 24 * time.Hour
+
+// EmailReport is what a summary email covers: the repositories worked on
+// since Since, with what came of their issues
+type EmailReport struct {
+	Title    string // e.g. "Batch run"
+	Since    time.Time
+	Currency string
+	Repos    []ReportRepo
+}
+
+// ReportRepo is one repository's part of an EmailReport
+type ReportRepo struct {
+	Repo      string
+	Issues    int
+	PRs       []string // URLs of the pull requests opened
+	Questions int
+	Failures  []string // "#12 Title: reason"
+	Cost      float64
+	Err       error // Set if the repository could not be processed at all
+}
+
+// Emailer mails summary reports over SMTP
+type Emailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	to       []string
+	network  Config // ca_bundle and client_cert for the TLS connection
+}
+
+// NewEmailer returns nil when no recipients or server are configured; a nil
+// Emailer ignores reports
+func NewEmailer(config Config) *Emailer {
+	if len(config.EmailTo) == 0 || config.SMTPHost == "" {
+		return nil
+	}
+	from := config.EmailFrom
+	if from == "" {
+		from = config.SMTPUsername
+	}
+	return &Emailer{
+		host:     config.SMTPHost,
+		port:     config.SMTPPort,
+		username: config.SMTPUsername,
+		password: config.SMTPPassword,
+		from:     from,
+		to:       config.EmailTo,
+		network:  config,
+	}
+}
+
+// Send mails a report. Failures are reported but never stop the bot.
+func (e *Emailer) Send(report EmailReport) {
+	if e == nil {
+		return
+	}
+
+	subject, body := report.Subject(), report.Body()
+	err := e.send(subject, body)
+	recordAudit(auditNotify, "email", "", strings.Join(e.to, ","), auditSummary(subject), err)
+	if err != nil {
+		fmt.Printf("Warning: Could not send the summary email: %v\n", err)
+		return
+	}
+	fmt.Printf("📧 Summary emailed to %s\n", strings.Join(e.to, ", "))
+}
+
+func (e *Emailer) send(subject, body string) error {
+	var message strings.Builder
+	message.WriteString("From: " + e.from + "\r\n")
+	message.WriteString("To: " + strings.Join(e.to, ", ") + "\r\n")
+	message.WriteString("Subject: " + subject + "\r\n")
+	message.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	addr := net.JoinHostPort(e.host, strconv.Itoa(e.port))
+	var auth smtp.Auth
+	if e.username != "" {
+		auth = smtp.PlainAuth("", e.username, e.password, e.host)
+	}
+
+	tlsConfig, err := clientTLSConfig(e.network)
+	if err != nil {
+		return err
+	}
+	tlsConfig.ServerName = e.host
+
+	// Port 465 speaks TLS from the start; others upgrade with STARTTLS when offered
+	var conn net.Conn
+	if e.port == 465 {
+		conn, err = tls.Dial("tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, 30*time.Second)
+	}
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if e.port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(e.from); err != nil {
+		return err
+	}
+	for _, to := range e.to {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(message.String())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// totals sums the report over its repositories
+func (r EmailReport) totals() (issues, prs, failures int, cost float64) {
+	for _, repo := range r.Repos {
+		issues += repo.Issues
+		prs += len(repo.PRs)
+		failures += len(repo.Failures)
+		if repo.Err != nil {
+			failures++
+		}
+		cost += repo.Cost
+	}
+	return issues, prs, failures, cost
+}
+
+// Subject summarizes the report in one line, e.g.
+// "Mr. Code Fixer: 3 PRs, 1 failure (Batch run, 4 repositories)"
+func (r EmailReport) Subject() string {
+	_, prs, failures, _ := r.totals()
+	return fmt.Sprintf("%s: %s, %s (%s, %s)", bot.Name, plural(prs, "PR"), plural(failures, "failure"), r.Title, plural(len(r.Repos), "repository"))
+}
+
+// Body renders the report as plain text
+func (r EmailReport) Body() string {
+	issues, prs, failures, cost := r.totals()
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s since %s\n\n", r.Title, r.Since.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&body, "Issues processed: %d\n", issues)
+	fmt.Fprintf(&body, "Pull requests:    %d\n", prs)
+	fmt.Fprintf(&body, "Failures:         %d\n", failures)
+	fmt.Fprintf(&body, "Estimated cost:   %.4f %s\n", cost, r.Currency)
+
+	for _, repo := range r.Repos {
+		fmt.Fprintf(&body, "\n== %s ==\n", repo.Repo)
+		if repo.Err != nil {
+			fmt.Fprintf(&body, "Error: %v\n", repo.Err)
+			continue
+		}
+		fmt.Fprintf(&body, "%s processed, %s asked, %.4f %s\n", plural(repo.Issues, "issue"), plural(repo.Questions, "question"), repo.Cost, r.Currency)
+		if len(repo.PRs) > 0 {
+			body.WriteString("\nPull requests:\n")
+			for _, pr := range repo.PRs {
+				body.WriteString("  " + pr + "\n")
+			}
+		}
+		if len(repo.Failures) > 0 {
+			body.WriteString("\nFailures:\n")
+			for _, failure := range repo.Failures {
+				body.WriteString("  " + failure + "\n")
+			}
+		}
+	}
+	return body.String()
+}
+
+// plural renders a count with its noun, e.g. "1 PR" or "3 repositories"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	if strings.HasSuffix(noun, "y") {
+		return fmt.Sprintf("%d %sies", n, strings.TrimSuffix(noun, "y"))
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// What to do when the repository has no commits yet
+const (
+	scaffoldAsk    = "ask"    // Ask before creating an initial commit, when run interactively
+	scaffoldAlways = "always" // Create it without asking
+	scaffoldNever  = "never"  // Stop with an error (default)
+)
+
+// errEmptyRepository is returned by Clone for a repository without commits
+var errEmptyRepository = errors.New("the repository is empty")
+
+// detectDefaultBranch finds the remote's default branch: from the clone's
+// origin/HEAD, then by asking the remote, then from the provider API. "main"
+// is only assumed when all of them fail, and never silently.
+func (g *GitOps) detectDefaultBranch(ctx context.Context) string {
+	if ref, err := g.gitOutput(ctx, "symbolic-ref", "--quiet", "refs/remotes/origin/HEAD"); err == nil {
+		if branch := strings.TrimPrefix(strings.TrimSpace(ref), "refs/remotes/origin/"); branch != "" {
+			return branch
+		}
+	}
+
+	// origin/HEAD is missing after cloning an empty repository and with some servers
+	cmd := g.git(ctx, "remote", "show", "origin")
+	cmd.Dir = g.repoPath
+	cmd.Env = append(os.Environ(), append(g.env, "LC_ALL=C")...)
+	if output, err := cmd.Output(); err == nil {
+		for _, line := range strings.Split(string(output), "\n") {
+			branch, found := strings.CutPrefix(strings.TrimSpace(line), "HEAD branch:")
+			if branch = strings.TrimSpace(branch); found && branch != "" && branch != "(unknown)" {
+				return branch
+			}
+		}
+	}
+
+	if g.branchLookup != nil {
+		branch, err := g.branchLookup(ctx)
+		if err == nil && branch != "" {
+			return branch
+		}
+		if err != nil {
+			fmt.Printf("Warning: Could not look up the default branch: %v\n", err)
+		}
+	}
+
+	fmt.Println("Warning: Could not detect the default branch, assuming \"main\"")
+	return "main"
+}
+
+// ScaffoldInitialCommit pushes a first commit with a README to the default
+// branch of an empty repository, so fix branches have something to start from.
+// It is the only push to the default branch, so it checks that the remote is
+// still empty and is audited as its own action.
+func (g *GitOps) ScaffoldInitialCommit(ctx context.Context) error {
+	heads, err := g.gitOutput(ctx, "ls-remote", "--heads", "origin")
+	if err != nil {
+		return fmt.Errorf("failed to list remote branches: %w", err)
+	}
+	if strings.TrimSpace(heads) != "" {
+		return fmt.Errorf("refusing to push an initial commit: the repository is no longer empty")
+	}
+
+	if err := g.runGitCommand(ctx, "symbolic-ref", "HEAD", "refs/heads/"+g.DefaultBranch); err != nil {
+		return err
+	}
+
+	readme := fmt.Sprintf("# %s\n", g.repo)
+	if err := os.WriteFile(filepath.Join(g.repoPath, "README.md"), []byte(readme), 0644); err != nil {
+		return err
+	}
+	if err := g.runGitCommand(ctx, "add", "README.md"); err != nil {
+		return err
+	}
+	if err := g.runGitCommand(ctx, "commit", "--quiet", "-m", "Initial commit"); err != nil {
+		return err
+	}
+
+	err = g.runGitCommand(ctx, "push", "-u", "origin", g.DefaultBranch)
+	recordAudit(auditGit, "scaffold_push", g.owner+"/"+g.repo, g.DefaultBranch, "initial commit on the default branch", err)
+	if err != nil {
+		return fmt.Errorf("failed to push: %w", err)
+	}
+	return nil
+}
+
+// cloneRepo clones the repository. An empty repository gets an initial commit
+// only when scaffold_empty_repos opts into it, because nothing can be branched
+// from it; "ask" never waits for an answer without a terminal.
+func cloneRepo(ctx context.Context, config Config, gitOps *GitOps) error {
+	err := gitOps.Clone(ctx)
+	if !errors.Is(err, errEmptyRepository) {
+		return err
+	}
+
+	fmt.Printf(T("repo.empty"), config.RepoOwner, config.RepoName)
+	emptyErr := fmt.Errorf("%s/%s has no commits yet; push a first commit or set scaffold_empty_repos", config.RepoOwner, config.RepoName)
+	switch config.ScaffoldEmptyRepos {
+	case scaffoldNever:
+		return emptyErr
+	case scaffoldAsk:
+		if !stdinIsTerminal() || !isYes(prompt(fmt.Sprintf(T("repo.scaffold"), gitOps.DefaultBranch), T("answer.no"))) {
+			return emptyErr
+		}
+	}
+
+	if err := gitOps.ScaffoldInitialCommit(ctx); err != nil {
+		return fmt.Errorf("failed to create the initial commit: %w", err)
+	}
+	fmt.Printf(T("repo.scaffolded"), gitOps.DefaultBranch)
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// envPrefix is prepended to the upper-cased JSON name of every Config field,
+// e.g. "repo_owner" can be set with MRCF_REPO_OWNER
+const envPrefix = "MRCF_"
+
+// envName returns the environment variable for a Config JSON key
+func envName(jsonKey string) string {
+	return envPrefix + strings.ToUpper(jsonKey)
+}
+
+// applyEnvConfig overrides config fields from MRCF_* environment variables.
+// Precedence is defaults < config file < environment < command-line flags.
+// Lists are comma-separated; bools accept anything strconv.ParseBool does.
+func applyEnvConfig(config *Config) error {
+	v := reflect.ValueOf(config).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+
+		name := envName(key)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s: invalid boolean %q", name, value)
+			}
+			field.SetBool(b)
+		case reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s: invalid number %q", name, value)
+			}
+			field.SetInt(int64(n))
+		case reflect.Float64:
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("%s: invalid number %q", name, value)
+			}
+			field.SetFloat(f)
+		case reflect.Slice:
+			var list listFlag
+			list.Set(value)
+			field.Set(reflect.ValueOf([]string(list)))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Markers around the plan in the bot's plan comment, used to find it again
+const (
+	planMarker    = "<!-- mr-code-fixer:plan -->"
+	planEndMarker = "<!-- mr-code-fixer:plan-end -->"
+)
+
+// maxPlanContextChars limits how much file content goes into the planning prompt
+const maxPlanContextChars = 20000
+
+// defaultFeatureLabels mark an issue as a feature request
+var defaultFeatureLabels = []string{"enhancement", "feature", "feature request", "feature-request", "type: feature"}
+
+// featureTitlePrefixes classify unlabeled issues by their title
+var featureTitlePrefixes = []string{"feat:", "feat(", "[feature]", "feature request", "feature:", "[feature request]", "[enhancement]"}
+
+// isFeatureRequest reports issues labeled as a feature request or titled like one
+func isFeatureRequest(config Config, issue Issue) bool {
+	for _, label := range issue.Labels {
+		if slices.ContainsFunc(config.FeatureLabels, func(l string) bool { return strings.EqualFold(l, label.Name) }) {
+			return true
+		}
+	}
+	title := strings.ToLower(strings.TrimSpace(issue.Title))
+	for _, prefix := range featureTitlePrefixes {
+		if strings.HasPrefix(title, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// FeaturePlan is the state of the implementation plan on a feature request
+type FeaturePlan struct {
+	Plan       string   // Empty when no plan was posted yet
+	ApprovedBy string   // Who approved it with 👍 or /approve, if anyone
+	Feedback   []string // Authorized users' comments on the plan, passed to the AI with it
+}
+
+// featurePlanStatus finds the bot's latest plan comment on an issue and whether
+// an authorized user approved it. Only the comments of users who may approve
+// the plan count as feedback, since they end up in the implementation prompt.
+func featurePlanStatus(ctx context.Context, config Config, provider HostingProvider, issue Issue) (*FeaturePlan, error) {
+	comments, err := provider.GetIssueComments(ctx, issue.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &FeaturePlan{}
+	planIndex := -1
+	for i, comment := range comments {
+		if isBotComment(comment) && strings.Contains(comment.Body, planMarker) {
+			planIndex = i
+		}
+	}
+	if planIndex == -1 {
+		return status, nil
+	}
+
+	plan := comments[planIndex]
+	status.Plan = extractPlan(plan.Body)
+
+	for _, comment := range comments[planIndex+1:] {
+		if isBotComment(comment) {
+			continue
+		}
+		if !isCommandAuthorized(config, comment) {
+			continue
+		}
+		if parseCommand(comment.Body) == commandApprove {
+			status.ApprovedBy = comment.User.Login
+			continue
+		}
+		status.Feedback = append(status.Feedback, fmt.Sprintf("@%s: %s", comment.User.Login, strings.TrimSpace(comment.Body)))
+	}
+	if status.ApprovedBy != "" {
+		return status, nil
+	}
+
+	reactions, err := provider.GetCommentReactions(ctx, plan.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, reaction := range reactions {
+		if reaction.Content == "+1" && isApprover(ctx, config, provider, reaction.User.Login) {
+			status.ApprovedBy = reaction.User.Login
+			break
+		}
+	}
+	return status, nil
+}
+
+// isApprover reports whether a 👍 from login counts as approval. Reactions carry
+// no author association, so collaborators are looked up.
+func isApprover(ctx context.Context, config Config, provider HostingProvider, login string) bool {
+	if len(config.CommandUsers) > 0 {
+		return slices.ContainsFunc(config.CommandUsers, func(user string) bool { return strings.EqualFold(user, login) })
+	}
+	if strings.EqualFold(login, config.RepoOwner) {
+		return true
+	}
+	collaborator, err := provider.IsCollaborator(ctx, login)
+	if err != nil {
+		fmt.Printf("Warning: Could not check whether @%s is a collaborator: %v\n", login, err)
+	}
+	return collaborator
+}
+
+// hasPendingPlanApproval reports whether the last bot comment is a plan that got
+// a 👍, which adds no comment and would otherwise go unnoticed
+func hasPendingPlanApproval(ctx context.Context, provider HostingProvider, comment Comment) bool {
+	if !strings.Contains(comment.Body, planMarker) {
+		return false
+	}
+	reactions, err := provider.GetCommentReactions(ctx, comment.ID)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(reactions, func(r Reaction) bool { return r.Content == "+1" })
+}
+
+// extractPlan returns the plan text between the plan markers
+func extractPlan(body string) string {
+	start := strings.Index(body, planMarker)
+	if start == -1 {
+		return ""
+	}
+	plan := body[start+len(planMarker):]
+	if end := strings.Index(plan, planEndMarker); end != -1 {
+		plan = plan[:end]
+	}
+	return strings.TrimSpace(plan)
+}
+
+// generatePlan asks the AI for a plan instead of code: an implementation plan
+// for a feature request, or the analysis and intended changes for a fix
+func generatePlan(ctx context.Context, aiClient AIClient, issue Issue, repoContext *RepoContext, feature bool) (string, error) {
+	var prompt strings.Builder
+	if feature {
+		prompt.WriteString("# Feature Request\n\n")
+	} else {
+		prompt.WriteString("# Issue\n\n")
+	}
+	prompt.WriteString(fmt.Sprintf("**Title:** %s\n\n", issue.Title))
+	prompt.WriteString(fmt.Sprintf("**Description:**\n%s\n\n", issue.Body))
+
+	if repoContext.Memory != "" {
+		prompt.WriteString(repoContext.Memory)
+	}
+	prompt.WriteString(instructionsSection(repoContext.Instructions))
+	writeRepoOverview(&prompt, repoContext)
+
+	prompt.WriteString(`
+# Task
+
+Do NOT write the implementation yet. A maintainer has to approve the approach first. Write a short plan in Markdown with these sections:
+
+`)
+	if feature {
+		prompt.WriteString(`### Summary
+What the feature does and how it fits into the existing code (2-3 sentences).
+`)
+	} else {
+		prompt.WriteString(`### Analysis
+What causes the problem, citing the code involved (2-3 sentences).
+`)
+	}
+	prompt.WriteString(`
+### Changes
+A bulleted list of the files to change or create, with one line each on what changes.
+
+### Open Questions
+Decisions the maintainer should make, if any.
+
+Keep it concise and concrete. Reply with the Markdown only.`)
+
+	plan, err := aiClient.Complete(ctx, systemPrompt(repoContext), prompt.String())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(plan), nil
+}
+
+// writeRepoOverview adds the repository to a prompt that does not ask for
+// code: hints, structure and the most relevant files up to maxPlanContextChars,
+// the rest by name only
+func writeRepoOverview(prompt *strings.Builder, repoContext *RepoContext) {
+	prompt.WriteString("# Repository\n\n")
+	for _, hint := range repoContext.Hints {
+		prompt.WriteString(fmt.Sprintf("- %s\n", hint))
+	}
+	prompt.WriteString("## Repository Structure\n```\n")
+	prompt.WriteString(repoContext.Structure)
+	prompt.WriteString("\n```\n\n## Relevant Files\n\n")
+
+	budget := maxPlanContextChars
+	for _, path := range repoContext.Ranked {
+		content, ok := repoContext.Files[path]
+		if !ok {
+			continue
+		}
+		if budget <= 0 {
+			prompt.WriteString(fmt.Sprintf("- %s\n", path))
+			continue
+		}
+		content = repoContext.promptContent(content)
+		budget -= len(content)
+		prompt.WriteString(fmt.Sprintf("### %s\n```\n%s\n```\n\n", path, content))
+	}
+}
+
+// planComment asks maintainers to approve a plan
+func planComment(plan string, feature bool) string {
+	heading, intro := "Fix Plan", "Before I write any code, here is what I found and what I intend to change, so a maintainer can veto it."
+	if feature {
+		heading, intro = "Implementation Plan", "This looks like a feature request, so I'd like a maintainer to agree on the approach before I write any code."
+	}
+	return fmt.Sprintf(`## 📝 %s
+
+%s
+
+%s
+%s
+%s
+
+---
+
+React with 👍 to this comment or reply `+"`/approve`"+` and I'll implement it. Comments with changes to the plan are taken into account when I do.
+
+%s`, heading, intro, planMarker, plan, planEndMarker, bot.signature())
+}
+
+// approvedPlanSection adds an approved plan and the feedback on it to the issue body
+func approvedPlanSection(plan *FeaturePlan) string {
+	var section strings.Builder
+	section.WriteString(fmt.Sprintf("\n\n## Approved Implementation Plan\n\nApproved by @%s. Implement this plan:\n\n%s\n", plan.ApprovedBy, plan.Plan))
+	if len(plan.Feedback) > 0 {
+		section.WriteString("\n### Maintainer Feedback on the Plan\n\n")
+		for _, feedback := range plan.Feedback {
+			section.WriteString("- " + feedback + "\n")
+		}
+	}
+	return section.String()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultQuestionReminder is how long serve waits for an answer to the bot's
+// questions before reminding the reporter
+const defaultQuestionReminder = 7 * 24 * time.Hour
+
+// Follow-up states of an issue the bot asked questions on
+const (
+	followUpReminded = "reminded" // The reporter was reminded
+	followUpAnswered = "answered" // Someone replied, so the issue is processed again
+	followUpClosed   = "closed"   // The issue was closed, by the bot or anyone else
+)
+
+// followUpQuestions reminds reporters who haven't answered the bot's
+// questions within question_reminder, and with question_close closes the
+// issue as lacking information once the reminder went unanswered that long.
+// Like the merge watcher it works from the fix history, so questions asked by
+// earlier runs are covered too.
+func followUpQuestions(ctx context.Context, config Config, provider HostingProvider) {
+	remindAfter := timeoutSetting(config.QuestionReminder, defaultQuestionReminder)
+	closeAfter := timeoutSetting(config.QuestionClose, 0)
+	if remindAfter == 0 {
+		return
+	}
+
+	records, err := loadHistory()
+	if err != nil {
+		fmt.Printf("Warning: Could not read history to follow up on questions: %v\n", err)
+		return
+	}
+
+	// Only the latest attempt on an issue counts
+	repo := config.RepoOwner + "/" + config.RepoName
+	latest := make(map[int]int)
+	for i, record := range records {
+		if strings.EqualFold(record.Repo, repo) {
+			latest[record.IssueNumber] = i
+		}
+	}
+
+	changed := false
+	for _, i := range latest {
+		record := &records[i]
+		if ctx.Err() != nil {
+			break
+		}
+		if record.Result != "question" || record.FollowUp == followUpAnswered || record.FollowUp == followUpClosed {
+			continue
+		}
+		var waited time.Duration
+		if record.FollowUp == followUpReminded {
+			if closeAfter == 0 || record.RemindedAt == nil {
+				continue
+			}
+			waited = time.Since(*record.RemindedAt)
+			if waited < closeAfter {
+				continue
+			}
+		} else if waited = time.Since(record.Time); waited < remindAfter {
+			continue
+		}
+
+		if state := followUpQuestion(ctx, config, provider, record); state != "" {
+			record.FollowUp = state
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := saveHistory(records); err != nil {
+			fmt.Printf("Warning: Could not update history: %v\n", err)
+		}
+	}
+}
+
+// followUpQuestion reminds the reporter of an issue that is due, or closes
+// it, and returns its new follow-up state ("" if nothing changed)
+func followUpQuestion(ctx context.Context, config Config, provider HostingProvider, record *HistoryRecord) string {
+	issue, err := provider.GetIssue(ctx, record.IssueNumber)
+	if err != nil {
+		fmt.Printf("Warning: Could not check issue #%d: %v\n", record.IssueNumber, err)
+		return ""
+	}
+	if issue.State == "closed" {
+		return followUpClosed
+	}
+	comments, err := provider.GetIssueComments(ctx, issue.Number)
+	if err != nil {
+		fmt.Printf("Warning: Could not check issue #%d: %v\n", issue.Number, err)
+		return ""
+	}
+	if len(comments) > 0 && !isBotComment(comments[len(comments)-1]) {
+		return followUpAnswered
+	}
+
+	if record.FollowUp != followUpReminded {
+		if err := provider.AddIssueComment(ctx, issue.Number, renderComment(config, commentReminder, CommentData{Issue: *issue})); err != nil {
+			fmt.Printf("Warning: Could not remind the reporter of #%d: %v\n", issue.Number, err)
+			return ""
+		}
+		now := time.Now()
+		record.RemindedAt = &now
+		fmt.Printf("⏰ Reminded @%s of the open questions on #%d\n", issue.User.Login, issue.Number)
+		return followUpReminded
+	}
+
+	// Closed first: a failed close is retried next time, and an explanation
+	// posted before it would be posted again
+	if err := provider.CloseIssue(ctx, issue.Number); err != nil {
+		fmt.Printf("Warning: Could not close issue #%d: %v\n", issue.Number, err)
+		return ""
+	}
+	if err := provider.AddIssueComment(ctx, issue.Number, renderComment(config, commentStale, CommentData{Issue: *issue})); err != nil {
+		fmt.Printf("Warning: Could not explain closing issue #%d: %v\n", issue.Number, err)
+	}
+	fmt.Printf("✓ Issue #%d closed for lack of information\n", issue.Number)
+	return followUpClosed
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ghAuthTimeout bounds how long we wait for the gh CLI to answer
+const ghAuthTimeout = 10 * time.Second
+
+// ghTokens caches tokens read from gh per host, so it only runs once per
+// process. The API server and batch jobs look tokens up concurrently, and
+// ghTokensMu is held through the lookup so gh doesn't run twice for a host.
+var (
+	ghTokens   = make(map[string]string)
+	ghTokensMu sync.Mutex
+)
+
+// ghAuthToken returns the token the GitHub CLI is logged in with for host
+// (e.g. "github.com" or a GitHub Enterprise host)
+func ghAuthToken(host string) (string, error) {
+	ghTokensMu.Lock()
+	defer ghTokensMu.Unlock()
+	if token, ok := ghTokens[host]; ok {
+		return token, nil
+	}
+
+	if _, err := exec.LookPath("gh"); err != nil {
+		return "", fmt.Errorf("gh CLI not found")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ghAuthTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "gh", "auth", "token", "--hostname", host).Output()
+	if err != nil {
+		return "", fmt.Errorf("gh is not logged in to %s (run `gh auth login`)", host)
+	}
+
+	token := strings.TrimSpace(string(output))
+	if token == "" {
+		return "", fmt.Errorf("gh returned no token for %s", host)
+	}
+	ghTokens[host] = token
+	return token, nil
+}
+
+// ghHost returns the host gh should look up a token for
+func ghHost(config Config) string {
+	if config.ProviderURL != "" {
+		if u, err := url.Parse(config.ProviderURL); err == nil && u.Host != "" {
+			return u.Host
+		}
+	}
+	return "github.com"
+}
+
+// resolveGithubToken takes the token from the GitHub CLI when use_gh_auth is
+// set, or as a last resort when no token was configured at all
+func resolveGithubToken(config *Config) {
+	if config.Provider != "github" {
+		return
+	}
+	if config.GithubToken != "" && !config.UseGhAuth {
+		return
+	}
+
+	token, err := ghAuthToken(ghHost(*config))
+	if err != nil {
+		if config.UseGhAuth {
+			fmt.Printf("Warning: Could not get a token from the GitHub CLI: %v\n", err)
+		}
+		return
+	}
+	config.GithubToken = token
+}
+
+// withGithubToken returns config with the token resolved from the GitHub CLI
+func withGithubToken(config Config) Config {
+	resolveGithubToken(&config)
+	return config
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type GitOps struct {
@@ -13,28 +18,90 @@ type GitOps struct {
 	repoPath      string
 	owner         string
 	repo          string
-	token         string
+	cloneURL      string
 	DefaultBranch string
+	guard         *PathGuard                            // Protected-path policy enforced by ApplyFileChange
+	env           []string                              // Extra environment for git, e.g. the SSH key to use
+	branchLookup  func(context.Context) (string, error) // Asks the provider for the default branch
+	lock          *RepoLock                             // Held until Cleanup
+	keepClone     bool                                  // Leave the clone in place after Cleanup
+	reuseClone    bool                                  // Update the clone of earlier jobs instead of cloning again
+	runHooks      bool                                  // Run git hooks instead of disabling them
 }
 
-func NewGitOps(workDir, owner, repo, token string) (*GitOps, error) {
-	// Create a unique directory path for this repo
-	repoPath := filepath.Join(workDir, owner, repo)
-	
-	if err := os.MkdirAll(filepath.Dir(repoPath), 0755); err != nil {
+func NewGitOps(workDir, owner, repo, cloneURL string) (*GitOps, error) {
+	if err := os.MkdirAll(filepath.Join(workDir, owner), 0755); err != nil {
 		return nil, fmt.Errorf("failed to create work directory: %w", err)
 	}
 
+	// Every job clones into its own directory, so concurrent runs never share a working tree
+	repoPath, err := os.MkdirTemp(filepath.Join(workDir, owner), repo+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clone directory: %w", err)
+	}
+
 	return &GitOps{
 		workDir:  workDir,
 		repoPath: repoPath,
 		owner:    owner,
 		repo:     repo,
-		token:    token,
+		cloneURL: cloneURL,
+	}, nil
+}
+
+// NewReusedGitOps is NewGitOps with one clone per repository, kept in
+// workDir/owner/repo and brought up to date by every job instead of cloned
+// again. The caller must hold the repository's lock while using it.
+func NewReusedGitOps(workDir, owner, repo, cloneURL string) (*GitOps, error) {
+	if err := os.MkdirAll(filepath.Join(workDir, owner), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	return &GitOps{
+		workDir:    workDir,
+		repoPath:   filepath.Join(workDir, owner, repo),
+		owner:      owner,
+		repo:       repo,
+		cloneURL:   cloneURL,
+		reuseClone: true,
 	}, nil
 }
 
-func (g *GitOps) Clone() error {
+// SetEnv adds environment variables to every git command
+func (g *GitOps) SetEnv(env []string) {
+	g.env = env
+}
+
+// SetDefaultBranchLookup sets where the default branch is looked up when the
+// clone itself does not tell
+func (g *GitOps) SetDefaultBranchLookup(lookup func(context.Context) (string, error)) {
+	g.branchLookup = lookup
+}
+
+// git returns a git command with the configured environment. Hooks are
+// code from the target repository, or from the global git config, and would
+// run on this machine with every commit and push, so they are disabled by
+// pointing core.hooksPath at nothing unless runHooks is set.
+func (g *GitOps) git(ctx context.Context, args ...string) *exec.Cmd {
+	if !g.runHooks {
+		args = append([]string{"-c", "core.hooksPath=" + os.DevNull}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	if len(g.env) > 0 {
+		cmd.Env = append(os.Environ(), g.env...)
+	}
+	return cmd
+}
+
+func (g *GitOps) Clone(ctx context.Context) error {
+	if g.reuseClone && fileExists(filepath.Join(g.repoPath, ".git")) {
+		err := g.updateClone(ctx)
+		if err == nil || !errors.Is(err, errBrokenClone) || ctx.Err() != nil {
+			return err
+		}
+		fmt.Printf("Warning: %v, cloning again\n", err)
+	}
+
 	// Remove existing directory if it exists
 	if _, err := os.Stat(g.repoPath); err == nil {
 		if err := os.RemoveAll(g.repoPath); err != nil {
@@ -42,10 +109,8 @@ func (g *GitOps) Clone() error {
 		}
 	}
 
-	// Clone with token authentication
-	cloneURL := fmt.Sprintf("https://%s@github.com/%s/%s.git", g.token, g.owner, g.repo)
-	
-	cmd := exec.Command("git", "clone", cloneURL, g.repoPath)
+	// Clone with token authentication (passed in the environment) or over SSH
+	cmd := g.git(ctx, "clone", g.cloneURL, g.repoPath)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -54,59 +119,366 @@ func (g *GitOps) Clone() error {
 	}
 
 	// Configure git user for commits
-	g.runGitCommand("config", "user.name", "Mr. Code Fixer")
-	g.runGitCommand("config", "user.email", "code-fixer@automated.bot")
+	g.runGitCommand(ctx, "config", "user.name", bot.Name)
+	g.runGitCommand(ctx, "config", "user.email", bot.Email)
 
-	// Detect default branch
-	cmd = exec.Command("git", "symbolic-ref", "refs/remotes/origin/HEAD")
-	cmd.Dir = g.repoPath
-	output, err := cmd.CombinedOutput()
-	if err == nil {
-		// Output format: refs/remotes/origin/branch-name
-		branch := strings.TrimSpace(string(output))
-		parts := strings.Split(branch, "/")
-		if len(parts) > 0 {
-			g.DefaultBranch = parts[len(parts)-1]
+	g.DefaultBranch = g.detectDefaultBranch(ctx)
+
+	// An empty repository clones fine but has no commit to branch from
+	if _, err := g.gitOutput(ctx, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return errEmptyRepository
+	}
+
+	return nil
+}
+
+// errBrokenClone is returned by updateClone for a clone that can't be
+// updated and has to be cloned again
+var errBrokenClone = errors.New("the existing clone is broken")
+
+// updateClone brings the clone of an earlier job up to date with origin:
+// fetched, reset to the default branch and cleaned of everything the job
+// left behind, its branches included. A failed fetch is only taken for a
+// broken clone if the clone's objects are broken too, so an unreachable
+// remote doesn't throw away a clone that is still good.
+func (g *GitOps) updateClone(ctx context.Context) error {
+	broken := func(step string, err error) error {
+		return fmt.Errorf("%w (%s: %v)", errBrokenClone, step, err)
+	}
+
+	fmt.Printf("Updating the existing clone in %s\n", g.repoPath)
+	// The last job ran the repository's code, which may have written to .git:
+	// a core.fsmonitor or core.sshCommand in .git/config runs with the next
+	// git command, a hook with the next commit. Both are created afresh
+	// before git runs in the clone again.
+	for _, name := range []string{"config", "hooks"} {
+		if err := os.RemoveAll(filepath.Join(g.repoPath, ".git", name)); err != nil {
+			return broken("removing .git/"+name, err)
+		}
+	}
+	if err := g.runGitCommand(ctx, "init", "--quiet"); err != nil {
+		return broken("git init", err)
+	}
+	if err := g.runGitCommand(ctx, "remote", "add", "origin", g.cloneURL); err != nil {
+		return broken("git remote add", err)
+	}
+
+	fetch := g.git(ctx, "fetch", "--prune", "origin")
+	fetch.Dir = g.repoPath
+	fetch.Stdout = os.Stdout
+	fetch.Stderr = os.Stderr
+	if err := fetch.Run(); err != nil {
+		if _, fsckErr := g.gitOutput(ctx, "fsck", "--connectivity-only", "--no-progress"); fsckErr != nil {
+			return broken("git fetch", err)
+		}
+		return fmt.Errorf("git fetch failed: %w", err)
+	}
+
+	// The default branch may have been renamed since the clone was made
+	g.gitOutput(ctx, "remote", "set-head", "origin", "--auto")
+	g.DefaultBranch = g.detectDefaultBranch(ctx)
+	remote := "refs/remotes/origin/" + g.DefaultBranch
+	if _, err := g.gitOutput(ctx, "rev-parse", "--verify", "--quiet", remote); err != nil {
+		// Also an empty repository, which a fresh clone reports as such
+		return broken("no "+remote, err)
+	}
+
+	// Leftovers of the last job: its branch, changes, untracked and ignored files
+	steps := [][]string{
+		{"checkout", "--force", "-B", g.DefaultBranch, remote},
+		{"reset", "--hard", remote},
+		{"clean", "-ffdx"},
+	}
+	for _, args := range steps {
+		if err := g.runGitCommand(ctx, args...); err != nil {
+			return broken("git "+args[0], err)
 		}
 	}
-	if g.DefaultBranch == "" {
-		// Fallback to main
-		g.DefaultBranch = "main"
+	branches, err := g.gitOutput(ctx, "for-each-ref", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return broken("git for-each-ref", err)
+	}
+	for _, branch := range strings.Fields(branches) {
+		if branch != g.DefaultBranch {
+			if err := g.runGitCommand(ctx, "branch", "-D", branch); err != nil {
+				return broken("git branch -D", err)
+			}
+		}
 	}
 
+	g.runGitCommand(ctx, "config", "user.name", bot.Name)
+	g.runGitCommand(ctx, "config", "user.email", bot.Email)
 	return nil
 }
 
-func (g *GitOps) CreateBranch(branchName string) error {
-	if err := g.runGitCommand("checkout", "-b", branchName); err != nil {
+func (g *GitOps) CreateBranch(ctx context.Context, branchName string) error {
+	if g.isDefaultBranch(branchName) {
+		return fmt.Errorf("refusing to work on the default branch %s; check branch_template", branchName)
+	}
+	if err := g.runGitCommand(ctx, "checkout", "-b", branchName); err != nil {
 		return fmt.Errorf("failed to create branch: %w", err)
 	}
 	return nil
 }
 
-func (g *GitOps) CommitChanges(message string) error {
+// RemoteBranchExists reports whether a branch with the given name exists on origin
+func (g *GitOps) RemoteBranchExists(ctx context.Context, branchName string) bool {
+	cmd := g.git(ctx, "ls-remote", "--exit-code", "--heads", "origin", branchName)
+	cmd.Dir = g.repoPath
+	return cmd.Run() == nil
+}
+
+// UniqueBranchName appends a numeric suffix when the branch already exists on origin
+func (g *GitOps) UniqueBranchName(ctx context.Context, base string) string {
+	if !g.RemoteBranchExists(ctx, base) {
+		return base
+	}
+
+	for i := 2; i < 100; i++ {
+		candidate := fmt.Sprintf("%s-%d", base, i)
+		if !g.RemoteBranchExists(ctx, candidate) {
+			fmt.Printf("Branch %s already exists, using %s\n", base, candidate)
+			return candidate
+		}
+	}
+
+	return fmt.Sprintf("%s-%d", base, time.Now().Unix())
+}
+
+// isDefaultBranch reports whether a branch name is the repository's default branch
+func (g *GitOps) isDefaultBranch(branchName string) bool {
+	return g.DefaultBranch != "" && strings.EqualFold(branchName, g.DefaultBranch)
+}
+
+// CurrentBranch returns the checked out branch, or "HEAD" when detached
+func (g *GitOps) CurrentBranch(ctx context.Context) (string, error) {
+	output, err := g.gitOutput(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to read current branch: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// checkWorkBranch makes sure commits and pushes never happen on the default
+// branch, whatever path led here
+func (g *GitOps) checkWorkBranch(ctx context.Context) (string, error) {
+	branch, err := g.CurrentBranch(ctx)
+	if err != nil {
+		return "", err
+	}
+	if branch == "HEAD" {
+		return "", fmt.Errorf("refusing to commit on a detached HEAD")
+	}
+	if g.isDefaultBranch(branch) {
+		return "", fmt.Errorf("refusing to commit or push on the default branch %s", branch)
+	}
+	return branch, nil
+}
+
+func (g *GitOps) CommitChanges(ctx context.Context, message string) error {
+	if _, err := g.checkWorkBranch(ctx); err != nil {
+		return err
+	}
+
 	// Add all changes
-	if err := g.runGitCommand("add", "."); err != nil {
+	if err := g.runGitCommand(ctx, "add", "."); err != nil {
 		return fmt.Errorf("failed to add changes: %w", err)
 	}
 
 	// Commit
-	if err := g.runGitCommand("commit", "-m", message); err != nil {
+	if err := g.runGitCommand(ctx, "commit", "-m", message); err != nil {
 		return fmt.Errorf("failed to commit: %w", err)
 	}
 
 	return nil
 }
 
-func (g *GitOps) Push(branchName string) error {
-	if err := g.runGitCommand("push", "-u", "origin", branchName); err != nil {
+func (g *GitOps) Push(ctx context.Context, branchName string) error {
+	current, err := g.checkWorkBranch(ctx)
+	if err != nil {
+		return err
+	}
+	if current != branchName || g.isDefaultBranch(branchName) {
+		return fmt.Errorf("refusing to push %s while %s is checked out", branchName, current)
+	}
+
+	// Someone else's branch of the same name must not be clobbered or merged into
+	if g.RemoteBranchExists(ctx, branchName) {
+		if err := g.runGitCommand(ctx, "fetch", "--quiet", "origin", "refs/heads/"+branchName); err != nil {
+			return fmt.Errorf("failed to fetch existing remote branch %s: %w", branchName, err)
+		}
+		cmd := g.git(ctx, "merge-base", "--is-ancestor", "FETCH_HEAD", branchName)
+		cmd.Dir = g.repoPath
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("remote branch %s already exists with different history; refusing to push over it", branchName)
+		}
+	}
+
+	err = g.runGitCommand(ctx, "push", "-u", "origin", branchName)
+	recordAudit(auditGit, "push", g.owner+"/"+g.repo, branchName, "", err)
+	if err != nil {
 		return fmt.Errorf("failed to push: %w", err)
 	}
 	return nil
 }
 
-func (g *GitOps) runGitCommand(args ...string) error {
-	cmd := exec.Command("git", args...)
+// ForcePush replaces a branch of an earlier bot PR with the regenerated fix.
+// The lease makes the push fail instead of discarding commits pushed on top
+// of expectedSHA by someone else.
+func (g *GitOps) ForcePush(ctx context.Context, branchName, expectedSHA string) error {
+	current, err := g.checkWorkBranch(ctx)
+	if err != nil {
+		return err
+	}
+	if current != branchName || g.isDefaultBranch(branchName) {
+		return fmt.Errorf("refusing to push %s while %s is checked out", branchName, current)
+	}
+
+	lease := "--force-with-lease=refs/heads/" + branchName
+	if expectedSHA != "" {
+		lease += ":" + expectedSHA
+	}
+	err = g.runGitCommand(ctx, "push", lease, "-u", "origin", branchName)
+	recordAudit(auditGit, "force_push", g.owner+"/"+g.repo, branchName, "", err)
+	if err != nil {
+		return fmt.Errorf("failed to push: %w (was the branch changed since the last revision?)", err)
+	}
+	return nil
+}
+
+// SaveBundle writes the commits of branch that are not on the default branch
+// to a git bundle, so they survive the working copy being replaced
+func (g *GitOps) SaveBundle(ctx context.Context, path, branchName string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := g.runGitCommand(ctx, "bundle", "create", path, "origin/"+g.DefaultBranch+".."+branchName); err != nil {
+		return fmt.Errorf("failed to bundle %s: %w", branchName, err)
+	}
+	return nil
+}
+
+// RestoreBundle recreates a branch saved with SaveBundle in a fresh clone
+func (g *GitOps) RestoreBundle(ctx context.Context, path, branchName string) error {
+	if err := g.runGitCommand(ctx, "fetch", path, branchName+":"+branchName); err != nil {
+		return fmt.Errorf("failed to restore %s from %s: %w", branchName, path, err)
+	}
+	return g.runGitCommand(ctx, "checkout", branchName)
+}
+
+// ChangeStats is the size of a change as counted by git --numstat
+type ChangeStats struct {
+	Files      int `json:"files"`
+	Insertions int `json:"insertions"`
+	Deletions  int `json:"deletions"`
+}
+
+// Lines is the number of lines added plus removed
+func (c ChangeStats) Lines() int {
+	return c.Insertions + c.Deletions
+}
+
+func (c ChangeStats) String() string {
+	return fmt.Sprintf("%d file(s), +%d -%d", c.Files, c.Insertions, c.Deletions)
+}
+
+// Add sums two changes
+func (c ChangeStats) Add(other ChangeStats) ChangeStats {
+	return ChangeStats{Files: c.Files + other.Files, Insertions: c.Insertions + other.Insertions, Deletions: c.Deletions + other.Deletions}
+}
+
+// CommitStats returns the size of the last commit and the paths it touched
+func (g *GitOps) CommitStats(ctx context.Context) (ChangeStats, []string, error) {
+	output, err := g.gitOutput(ctx, "show", "--numstat", "--format=", "HEAD")
+	if err != nil {
+		return ChangeStats{}, nil, fmt.Errorf("failed to read commit stats: %w", err)
+	}
+	stats, paths := parseNumstat(output)
+	return stats, paths, nil
+}
+
+// DiffStats stages all working tree changes and returns their size and the
+// paths touched, like CommitStats before a commit
+func (g *GitOps) DiffStats(ctx context.Context) (ChangeStats, []string, error) {
+	if err := g.runGitCommand(ctx, "add", "-A"); err != nil {
+		return ChangeStats{}, nil, fmt.Errorf("failed to stage changes: %w", err)
+	}
+	output, err := g.gitOutput(ctx, "diff", "--cached", "--numstat")
+	if err != nil {
+		return ChangeStats{}, nil, fmt.Errorf("failed to read diff stats: %w", err)
+	}
+	stats, paths := parseNumstat(output)
+	return stats, paths, nil
+}
+
+// parseNumstat sums git --numstat output
+func parseNumstat(output string) (ChangeStats, []string) {
+	var stats ChangeStats
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		// Binary files show "-" instead of line counts
+		added, _ := strconv.Atoi(fields[0])
+		removed, _ := strconv.Atoi(fields[1])
+		stats.Insertions += added
+		stats.Deletions += removed
+		stats.Files++
+		paths = append(paths, fields[2])
+	}
+	return stats, paths
+}
+
+// ExportPatch writes the last commit as a git format-patch file into dir and
+// returns its path
+func (g *GitOps) ExportPatch(ctx context.Context, dir, name string) (string, error) {
+	patch, err := g.gitOutput(ctx, "format-patch", "-1", "HEAD", "--stdout")
+	if err != nil {
+		return "", fmt.Errorf("failed to format patch: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name+".patch")
+	if err := os.WriteFile(path, []byte(patch), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Diff stages all working tree changes and returns the resulting diff
+func (g *GitOps) Diff(ctx context.Context) (string, error) {
+	if err := g.runGitCommand(ctx, "add", "-A"); err != nil {
+		return "", fmt.Errorf("failed to stage changes: %w", err)
+	}
+	return g.gitOutput(ctx, "diff", "--cached")
+}
+
+// ResetChanges discards all uncommitted changes, including new files
+func (g *GitOps) ResetChanges(ctx context.Context) error {
+	if err := g.runGitCommand(ctx, "reset", "--hard", "--quiet"); err != nil {
+		return fmt.Errorf("failed to reset changes: %w", err)
+	}
+	if err := g.runGitCommand(ctx, "clean", "-fdq"); err != nil {
+		return fmt.Errorf("failed to clean working tree: %w", err)
+	}
+	return nil
+}
+
+func (g *GitOps) gitOutput(ctx context.Context, args ...string) (string, error) {
+	cmd := g.git(ctx, args...)
+	cmd.Dir = g.repoPath
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+func (g *GitOps) runGitCommand(ctx context.Context, args ...string) error {
+	cmd := g.git(ctx, args...)
 	cmd.Dir = g.repoPath
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -114,15 +486,37 @@ func (g *GitOps) runGitCommand(args ...string) error {
 	return cmd.Run()
 }
 
+// Cleanup removes the clone, unless it is kept for inspection or reused, and
+// releases the repository lock
 func (g *GitOps) Cleanup() {
-	// Optional: clean up the cloned repo
-	// os.RemoveAll(g.repoPath)
+	if !g.keepClone && !g.reuseClone {
+		if err := os.RemoveAll(g.repoPath); err != nil {
+			fmt.Printf("Warning: Could not remove %s: %v\n", g.repoPath, err)
+		}
+	}
+	g.lock.Release()
 }
 
 type RepoContext struct {
-	Structure string
-	Files     map[string]string // path -> content
-	FileCount int               // Total files analyzed
+	Structure           string
+	Files               map[string]string       // path -> content
+	FileCount           int                     // Total files analyzed
+	Memory              string                  // Knowledge from previous runs, rendered for the prompt
+	Hints               []string                // Maintainer notes from the repository settings
+	GenerateTests       bool                    // Ask the AI for tests covering the fix
+	ConventionalCommits bool                    // Ask the AI for a Conventional Commits message
+	Changelog           *ChangelogStyle         // Ask the AI for a changelog entry in this style, if set
+	Ranked              []string                // Paths in Files, most relevant first
+	MaxFileChars        int                     // Characters of each file shown in the prompt, 0 for all
+	Summaries           map[string]string       // path -> summary for files too large to include in full
+	Traces              []TraceLocation         // Stack frames from the issue resolved to repository code
+	Regions             map[string][]CodeRegion // path -> regions of large files shown in full
+	Excluded            []ExcludedFile          // Candidate files left out of the context
+	Candidates          int                     // Source files that matched the issue at all
+	References          []Reference             // Issues, pull requests and commits the issue mentions
+	SystemPrompt        string                  // Replaces the built-in system prompt for fixes, if set
+	Instructions        []string                // Project rules from the config and the repository
+	StyleGuides         []StyleGuide            // Contribution guidelines and formatter and linter configs
 }
 
 type fileScore struct {
@@ -130,9 +524,13 @@ type fileScore struct {
 	score int
 }
 
-func (g *GitOps) GetRepoContext(issueTitle, issueBody string) (*RepoContext, error) {
-	ctx := &RepoContext{
-		Files: make(map[string]string),
+// GetRepoContext collects the files most relevant to an issue. Candidates are
+// read in parallel; binary, minified and generated files are skipped, and no
+// more files and content are included than limits allow.
+func (g *GitOps) GetRepoContext(ctx context.Context, issueTitle, issueBody string, limits ContextLimits) (*RepoContext, error) {
+	repoContext := &RepoContext{
+		Files:        make(map[string]string),
+		MaxFileChars: limits.MaxCharsPerFile,
 	}
 
 	// Get directory structure
@@ -140,11 +538,12 @@ func (g *GitOps) GetRepoContext(issueTitle, issueBody string) (*RepoContext, err
 	if err != nil {
 		return nil, err
 	}
-	ctx.Structure = structure
+	repoContext.Structure = structure
 
 	// Extract file mentions and keywords from issue
 	mentionedFiles := extractFileMentions(issueTitle + " " + issueBody)
 	keywords := extractKeywords(issueTitle + " " + issueBody)
+	activity, newest := g.recentActivity(ctx, keywords)
 
 	// Read important files (limit to reasonable size)
 	importantFiles := []string{
@@ -157,46 +556,49 @@ func (g *GitOps) GetRepoContext(issueTitle, issueBody string) (*RepoContext, err
 		"build.gradle",
 	}
 
+	var candidates []string
 	for _, file := range importantFiles {
-		filePath := filepath.Join(g.repoPath, file)
-		if content, err := os.ReadFile(filePath); err == nil {
-			ctx.Files[file] = string(content)
+		if _, err := os.Stat(filepath.Join(g.repoPath, file)); err == nil {
+			candidates = append(candidates, file)
 		}
 	}
 
 	// Collect all source files with relevance scores
 	var scoredFiles []fileScore
 
-	err = filepath.Walk(g.repoPath, func(path string, info os.FileInfo, err error) error {
+	err = filepath.WalkDir(g.repoPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil // Skip errors
 		}
 
 		// Skip hidden directories and common ignore patterns
-		if info.IsDir() {
-			name := info.Name()
-			if strings.HasPrefix(name, ".") || name == "node_modules" || 
+		if d.IsDir() {
+			name := d.Name()
+			if path != g.repoPath && (strings.HasPrefix(name, ".") || name == "node_modules" || 
 			   name == "vendor" || name == "target" || name == "dist" || name == "build" ||
-			   name == "test" || name == "tests" || name == "__pycache__" {
+			   name == "test" || name == "tests" || name == "__pycache__") {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
-		// Only consider source code files up to 100KB
-		if info.Size() > 100*1024 {
-			return nil
-		}
-
 		ext := filepath.Ext(path)
 		if isSourceFile(ext) {
 			relPath, _ := filepath.Rel(g.repoPath, path)
+			relPath = normalizeRepoPath(relPath)
 			
 			// Calculate relevance score
-			score := calculateRelevance(relPath, mentionedFiles, keywords)
-			if score > 0 {
-				scoredFiles = append(scoredFiles, fileScore{relPath, score})
+			score := calculateRelevance(relPath, mentionedFiles, keywords, activity[relPath], newest)
+			if score == 0 {
+				return nil
+			}
+
+			// Only consider source code files up to context_max_file_kb
+			if info, err := d.Info(); err != nil || limits.MaxFileSize > 0 && info.Size() > int64(limits.MaxFileSize) {
+				repoContext.Excluded = append(repoContext.Excluded, ExcludedFile{relPath, "too large"})
+				return nil
 			}
+			scoredFiles = append(scoredFiles, fileScore{relPath, score})
 		}
 
 		return nil
@@ -206,23 +608,41 @@ func (g *GitOps) GetRepoContext(issueTitle, issueBody string) (*RepoContext, err
 		return nil, err
 	}
 
-	// Sort by relevance and take top 30 files
+	// Sort by relevance and read twice as many as needed, as some will be skipped
+	repoContext.Candidates = len(scoredFiles)
 	sortFilesByScore(scoredFiles)
-	maxFiles := 30
-	if len(scoredFiles) > maxFiles {
-		scoredFiles = scoredFiles[:maxFiles]
+	important := len(candidates)
+	for i := 0; i < len(scoredFiles) && i < 2*limits.MaxFiles; i++ {
+		candidates = append(candidates, scoredFiles[i].path)
 	}
 
-	// Read the selected files
-	for _, sf := range scoredFiles {
-		filePath := filepath.Join(g.repoPath, sf.path)
-		if content, err := os.ReadFile(filePath); err == nil {
-			ctx.Files[sf.path] = string(content)
+	// Take the usable files in order until the file count or byte cap is reached
+	total, ranked := 0, 0
+	for i, file := range readContextFiles(g.repoPath, candidates) {
+		if _, ok := repoContext.Files[file.path]; ok {
+			continue
+		}
+		if i >= important && ranked >= limits.MaxFiles {
+			break
+		}
+		if file.reason != "" {
+			repoContext.Excluded = append(repoContext.Excluded, ExcludedFile{file.path, file.reason})
+			continue
 		}
+		if limits.MaxBytes > 0 && total+len(file.content) > limits.MaxBytes {
+			repoContext.Excluded = append(repoContext.Excluded, ExcludedFile{file.path, "byte cap"})
+			continue
+		}
+		total += len(file.content)
+		if i >= important {
+			ranked++
+		}
+		repoContext.Files[file.path] = file.content
+		repoContext.Ranked = append(repoContext.Ranked, file.path)
 	}
 
-	ctx.FileCount = len(ctx.Files)
-	return ctx, nil
+	repoContext.FileCount = len(repoContext.Files)
+	return repoContext, nil
 }
 
 func (g *GitOps) getDirectoryStructure() (string, error) {
@@ -326,8 +746,9 @@ func extractKeywords(text string) []string {
 	return keywords
 }
 
-// calculateRelevance scores a file based on mentions and keywords
-func calculateRelevance(filePath string, mentionedFiles, keywords []string) int {
+// calculateRelevance scores a file based on mentions, keywords and its recent
+// git history (nil if unknown)
+func calculateRelevance(filePath string, mentionedFiles, keywords []string, history *fileActivity, newest int64) int {
 	score := 0
 	lowerPath := strings.ToLower(filePath)
 	
@@ -355,6 +776,9 @@ func calculateRelevance(filePath string, mentionedFiles, keywords []string) int
 		// Give base score to all source files
 		score += 1
 	}
+
+	// Recently and often changed files, and those whose commits mention the issue
+	score += history.score(newest)
 	
 	return score
 }
@@ -370,14 +794,107 @@ func sortFilesByScore(files []fileScore) {
 	}
 }
 
+// File operations of a FileChange. An empty operation writes the file like
+// create and modify do.
+const (
+	fileCreate = "create"
+	fileModify = "modify"
+	fileDelete = "delete"
+	fileRename = "rename" // Move FilePath to NewPath, with new content if Content is set
+)
+
+var fileOperations = []string{fileCreate, fileModify, fileDelete, fileRename}
+
 type FileChange struct {
-	FilePath string
-	Content  string
+	FilePath  string
+	Content   string
+	Operation string // One of fileOperations, empty for a write
+	NewPath   string // Destination of a rename
+	Summary   string // One line on what changed, for the PR description
+}
+
+// Paths returns every path the change touches
+func (c FileChange) Paths() []string {
+	if c.Operation == fileRename {
+		return []string{c.FilePath, c.NewPath}
+	}
+	return []string{c.FilePath}
+}
+
+// Writes reports whether the change produces file content that can be checked
+func (c FileChange) Writes() bool {
+	switch c.Operation {
+	case fileDelete:
+		return false
+	case fileRename:
+		return c.Content != ""
+	}
+	return true
+}
+
+// TargetPath is where the content of the change ends up
+func (c FileChange) TargetPath() string {
+	if c.Operation == fileRename {
+		return c.NewPath
+	}
+	return c.FilePath
+}
+
+// Describe renders the change for file lists in comments and PR descriptions
+func (c FileChange) Describe() string {
+	switch c.Operation {
+	case fileDelete:
+		return fmt.Sprintf("`%s` (deleted)", c.FilePath)
+	case fileRename:
+		return fmt.Sprintf("`%s` → `%s`", c.FilePath, c.NewPath)
+	}
+	return fmt.Sprintf("`%s`", c.FilePath)
 }
 
 func (g *GitOps) ApplyFileChange(change FileChange) error {
+	for _, path := range change.Paths() {
+		if err := g.guard.CheckInRepo(g.repoPath, path); err != nil {
+			return err
+		}
+		if err := checkPlatformPath(path); err != nil {
+			return err
+		}
+	}
+
 	fullPath := filepath.Join(g.repoPath, change.FilePath)
+
+	switch change.Operation {
+	case fileDelete:
+		if err := os.Remove(fullPath); err != nil {
+			return fmt.Errorf("failed to delete file: %w", err)
+		}
+		return nil
+
+	case fileRename:
+		if change.NewPath == "" {
+			return fmt.Errorf("rename without a new path")
+		}
+		newPath := filepath.Join(g.repoPath, change.NewPath)
+		if _, err := os.Stat(newPath); err == nil {
+			return fmt.Errorf("cannot rename to %s: the file already exists", change.NewPath)
+		}
+		if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.Rename(fullPath, newPath); err != nil {
+			return fmt.Errorf("failed to rename file: %w", err)
+		}
+		if change.Content == "" {
+			return nil
+		}
+		fullPath = newPath
+	}
 	
+	// Never write through a link, whatever it points at
+	if info, err := os.Lstat(fullPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("refusing to write %s: it is a symbolic link", change.TargetPath())
+	}
+
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(fullPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
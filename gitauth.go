@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isSSHURL reports ssh:// URLs and the scp-like git@host:owner/repo form
+func isSSHURL(repoURL string) bool {
+	if strings.HasPrefix(repoURL, "ssh://") {
+		return true
+	}
+	if strings.Contains(repoURL, "://") {
+		return false
+	}
+	at, colon := strings.Index(repoURL, "@"), strings.Index(repoURL, ":")
+	return at != -1 && colon > at
+}
+
+// useSSH decides how to clone and push: git_protocol when set, otherwise SSH if
+// a key is configured or the repository was given as an SSH URL
+func useSSH(config Config) bool {
+	switch config.GitProtocol {
+	case "ssh":
+		return true
+	case "https":
+		return false
+	}
+	return config.SSHKey != "" || isSSHURL(config.RepoURL)
+}
+
+// gitCloneURL returns the URL to clone and push with. Over HTTPS it is the
+// provider's URL, with the token passed by gitAuthEnv; over SSH no token is
+// involved.
+func gitCloneURL(config Config, provider HostingProvider) string {
+	if !useSSH(config) {
+		return provider.CloneURL()
+	}
+	if isSSHURL(config.RepoURL) {
+		// Only if it still points at the configured repository
+		if owner, repo, err := parseRepoURL(config.RepoURL); err == nil && owner == config.RepoOwner && repo == config.RepoName {
+			return config.RepoURL
+		}
+	}
+
+	host := "github.com"
+	if config.ProviderURL != "" {
+		if u, err := url.Parse(config.ProviderURL); err == nil && u.Hostname() != "" {
+			host = u.Hostname()
+		}
+	}
+	return fmt.Sprintf("git@%s:%s/%s.git", host, config.RepoOwner, config.RepoName)
+}
+
+// sshKeyPath expands ~ and the {owner} and {repo} placeholders, so each
+// repository of a batch run can use its own deploy key
+func sshKeyPath(config Config) string {
+	key := strings.NewReplacer("{owner}", config.RepoOwner, "{repo}", config.RepoName).Replace(config.SSHKey)
+	if strings.HasPrefix(key, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			key = filepath.Join(home, key[2:])
+		}
+	}
+	return key
+}
+
+// gitEnv returns extra environment for git commands: the SSH key to use, and
+// no interactive prompts that would hang an unattended run
+func gitEnv(config Config) []string {
+	env := append([]string{"GIT_TERMINAL_PROMPT=0"}, networkGitEnv(config)...)
+	if !useSSH(config) {
+		return env
+	}
+	ssh := "ssh -o BatchMode=yes -o StrictHostKeyChecking=accept-new"
+	if config.SSHKey != "" {
+		ssh += fmt.Sprintf(" -i %q -o IdentitiesOnly=yes", sshKeyPath(config))
+	}
+	return append(env, "GIT_SSH_COMMAND="+ssh)
+}
+
+// gitAuthEnv passes the token to git over HTTPS as an Authorization header
+// for the provider's host. GIT_CONFIG_COUNT is git's -c through the
+// environment, so the token is neither written to .git/config, where the
+// commands of a job could read it, nor shown in the process list.
+func gitAuthEnv(config Config, cloneURL string) []string {
+	if useSSH(config) || config.GithubToken == "" {
+		return nil
+	}
+	u, err := url.Parse(cloneURL)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+
+	// GitHub wants the token as the password; Gitea takes it as the user name
+	// with no password
+	credentials := config.GithubToken + ":"
+	if config.Provider != "gitea" {
+		credentials = "x-access-token:" + config.GithubToken
+	}
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http." + u.Scheme + "://" + u.Host + "/.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(credentials)),
+	}
+}
+
+// newRepoGitOps prepares a GitOps for the configured repository with the
+// configured transport and credentials
+func newRepoGitOps(ctx context.Context, config Config, provider HostingProvider) (*GitOps, error) {
+	lock, err := lockRepo(ctx, config.WorkDir, config.RepoOwner, config.RepoName, timeoutSetting(config.LockTimeout, defaultLockTimeout))
+	if err != nil {
+		return nil, err
+	}
+	newGitOps := NewGitOps
+	if config.ReuseClones {
+		newGitOps = NewReusedGitOps
+	}
+	cloneURL := gitCloneURL(config, provider)
+	gitOps, err := newGitOps(config.WorkDir, config.RepoOwner, config.RepoName, cloneURL)
+	if err != nil {
+		lock.Release()
+		return nil, err
+	}
+	gitOps.lock = lock
+	gitOps.keepClone = config.KeepClones
+	gitOps.runHooks = config.RunGitHooks
+	gitOps.SetEnv(append(gitEnv(config), gitAuthEnv(config, cloneURL)...))
+	gitOps.SetDefaultBranchLookup(provider.GetDefaultBranch)
+	return gitOps, nil
+}
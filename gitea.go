@@ -0,0 +1,618 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// GiteaClient talks to the Gitea API. Forgejo is API-compatible and uses the same client.
+type GiteaClient struct {
+	token     string
+	owner     string
+	repo      string
+	webURL    string
+	baseURL   string
+	client    *http.Client
+	reviewers []string
+	assignees []string
+	milestone string
+	assignee  string // Only list issues assigned to this user, "none" or "*"
+	login     string // Account of the token, looked up once
+	sort      string // Issue order: "reactions", "comments", "oldest" or "" for newest
+	label     string // Only list issues with this label
+}
+
+func NewGiteaClient(webURL, token, owner, repo string) *GiteaClient {
+	webURL = strings.TrimSuffix(webURL, "/")
+	return &GiteaClient{
+		token:   token,
+		owner:   owner,
+		repo:    repo,
+		webURL:  webURL,
+		baseURL: webURL + "/api/v1",
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetTimeout overrides the HTTP timeout for API requests; 0 disables it
+func (g *GiteaClient) SetTimeout(timeout time.Duration) {
+	g.client.Timeout = timeout
+}
+
+// SetPRDefaults configures reviewers and assignees applied to every created PR
+func (g *GiteaClient) SetPRDefaults(reviewers, assignees []string) {
+	g.reviewers = reviewers
+	g.assignees = assignees
+}
+
+func (g *GiteaClient) CloneURL() string {
+	return httpsCloneURL(g.webURL, g.owner, g.repo)
+}
+
+// do sends an authenticated request and decodes the JSON response into out (if non-nil)
+func (g *GiteaClient) do(ctx context.Context, method, path string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "token "+g.token)
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Gitea API error: %s - %s", resp.Status, string(respBody))
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// SetMilestoneFilter limits GetOpenIssues to a milestone, given by title or ID
+func (g *GiteaClient) SetMilestoneFilter(milestone string) {
+	g.milestone = milestone
+}
+
+// SetAssigneeFilter limits GetOpenIssues to issues assigned to a user ("me" for
+// the token's account), to unassigned issues ("none") or to assigned ones ("*")
+func (g *GiteaClient) SetAssigneeFilter(assignee string) {
+	g.assignee = assignee
+}
+
+// SetLabelFilter limits GetOpenIssues to issues with a label
+func (g *GiteaClient) SetLabelFilter(label string) {
+	g.label = label
+}
+
+// SetIssueSort orders GetOpenIssues by community signal
+func (g *GiteaClient) SetIssueSort(sort string) {
+	g.sort = sort
+}
+
+func (g *GiteaClient) GetOpenIssues(ctx context.Context, maxIssues int) ([]Issue, error) {
+	issues, err := g.listIssues(ctx, "open", maxIssues)
+	if err != nil {
+		return nil, err
+	}
+
+	// Gitea can't filter on "none" or "*", so filter here for every case
+	if g.assignee != "" {
+		assignee := g.assignee
+		if assignee == "me" {
+			if assignee, err = g.GetAuthenticatedUser(ctx); err != nil {
+				return nil, err
+			}
+		}
+		issues = filterByAssignee(issues, assignee)
+	}
+
+	// Issues come without reaction counts, so look them up when sorting by them
+	if g.sort == "reactions" {
+		for i := range issues {
+			if reactions, err := g.issueReactions(ctx, issues[i].Number); err == nil {
+				issues[i].Reactions = reactions
+			}
+		}
+	}
+	sortIssues(issues, g.sort)
+	return issues, nil
+}
+
+// issueReactions counts the reactions on an issue
+func (g *GiteaClient) issueReactions(ctx context.Context, number int) (*IssueReactions, error) {
+	var reactions []Reaction
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/reactions", g.owner, g.repo, number)
+	if err := g.do(ctx, "GET", path, nil, &reactions); err != nil {
+		return nil, fmt.Errorf("fetching reactions: %w", err)
+	}
+
+	counts := &IssueReactions{TotalCount: len(reactions)}
+	for _, reaction := range reactions {
+		if reaction.Content == "+1" {
+			counts.PlusOne++
+		}
+	}
+	return counts, nil
+}
+
+// GetAuthenticatedUser returns the login of the account the token belongs to
+func (g *GiteaClient) GetAuthenticatedUser(ctx context.Context) (string, error) {
+	if g.login != "" {
+		return g.login, nil
+	}
+
+	var user User
+	if err := g.do(ctx, "GET", "/user", nil, &user); err != nil {
+		return "", fmt.Errorf("fetching authenticated user: %w", err)
+	}
+	g.login = user.Login
+	return g.login, nil
+}
+
+// GetDefaultBranch returns the repository's default branch, which is set even
+// while the repository is still empty
+func (g *GiteaClient) GetDefaultBranch(ctx context.Context) (string, error) {
+	var repo struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := g.do(ctx, "GET", fmt.Sprintf("/repos/%s/%s", g.owner, g.repo), nil, &repo); err != nil {
+		return "", err
+	}
+	return repo.DefaultBranch, nil
+}
+
+// ListOrgRepos lists all repositories of an organization, or of a user when
+// no organization has that name
+func (g *GiteaClient) ListOrgRepos(ctx context.Context, org string) ([]Repository, error) {
+	repos, err := g.listRepos(ctx, "/orgs/"+url.PathEscape(org)+"/repos")
+	if err != nil {
+		if userRepos, userErr := g.listRepos(ctx, "/users/"+url.PathEscape(org)+"/repos"); userErr == nil {
+			return userRepos, nil
+		}
+		return nil, err
+	}
+	return repos, nil
+}
+
+func (g *GiteaClient) listRepos(ctx context.Context, path string) ([]Repository, error) {
+	return giteaList[Repository](ctx, g, path, 0)
+}
+
+// giteaPageSize is the page size of list requests. Gitea caps pages at its
+// MAX_RESPONSE_ITEMS setting, 50 by default, whatever limit is asked for.
+const giteaPageSize = 50
+
+// giteaList fetches a list endpoint page by page until a short page, or
+// until it has max items (0 for all of them)
+func giteaList[T any](ctx context.Context, g *GiteaClient, path string, max int) ([]T, error) {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	var items []T
+	for page := 1; ; page++ {
+		var batch []T
+		if err := g.do(ctx, "GET", fmt.Sprintf("%s%slimit=%d&page=%d", path, separator, giteaPageSize, page), nil, &batch); err != nil {
+			return nil, err
+		}
+		items = append(items, batch...)
+		if max > 0 && len(items) >= max {
+			return items[:max], nil
+		}
+		if len(batch) < giteaPageSize {
+			return items, nil
+		}
+	}
+}
+
+// GetClosedIssues returns the most recently updated closed issues
+func (g *GiteaClient) GetClosedIssues(ctx context.Context, maxIssues int) ([]Issue, error) {
+	return g.listIssues(ctx, "closed", maxIssues)
+}
+
+func (g *GiteaClient) listIssues(ctx context.Context, state string, maxIssues int) ([]Issue, error) {
+	path := fmt.Sprintf("/repos/%s/%s/issues?state=%s&type=issues", g.owner, g.repo, state)
+	if state == "open" && g.milestone != "" {
+		path += "&milestones=" + url.QueryEscape(g.milestone)
+	}
+	if state == "open" && g.label != "" {
+		path += "&labels=" + url.QueryEscape(g.label)
+	}
+	issues, err := giteaList[Issue](ctx, g, path, maxIssues)
+	if err != nil {
+		return nil, err
+	}
+
+	// Gitea filters by type already, but guard against PRs just like GitHub
+	var filteredIssues []Issue
+	for _, issue := range issues {
+		if issue.PullRequest == nil {
+			filteredIssues = append(filteredIssues, issue)
+		}
+	}
+
+	return filteredIssues, nil
+}
+
+func (g *GiteaClient) GetIssue(ctx context.Context, number int) (*Issue, error) {
+	var issue Issue
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d", g.owner, g.repo, number)
+	if err := g.do(ctx, "GET", path, nil, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+func (g *GiteaClient) GetIssueComments(ctx context.Context, issueNumber int) ([]Comment, error) {
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", g.owner, g.repo, issueNumber)
+	comments, err := giteaList[Comment](ctx, g, path, 0)
+	if err != nil {
+		return nil, fmt.Errorf("fetching comments: %w", err)
+	}
+	return comments, nil
+}
+
+func (g *GiteaClient) AddIssueComment(ctx context.Context, issueNumber int, comment string) error {
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", g.owner, g.repo, issueNumber)
+	if err := g.do(ctx, "POST", path, map[string]string{"body": comment}, nil); err != nil {
+		return fmt.Errorf("adding comment: %w", err)
+	}
+	return nil
+}
+
+// EditIssueComment replaces the body of an issue comment
+func (g *GiteaClient) EditIssueComment(ctx context.Context, commentID int, comment string) error {
+	path := fmt.Sprintf("/repos/%s/%s/issues/comments/%d", g.owner, g.repo, commentID)
+	if err := g.do(ctx, "PATCH", path, map[string]string{"body": comment}, nil); err != nil {
+		return fmt.Errorf("editing comment: %w", err)
+	}
+	return nil
+}
+
+func (g *GiteaClient) GetCommentReactions(ctx context.Context, commentID int) ([]Reaction, error) {
+	var reactions []Reaction
+	path := fmt.Sprintf("/repos/%s/%s/issues/comments/%d/reactions", g.owner, g.repo, commentID)
+	if err := g.do(ctx, "GET", path, nil, &reactions); err != nil {
+		return nil, fmt.Errorf("fetching reactions: %w", err)
+	}
+	return reactions, nil
+}
+
+// IsCollaborator reports whether a user is a collaborator on the repository
+func (g *GiteaClient) IsCollaborator(ctx context.Context, login string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", g.baseURL+fmt.Sprintf("/repos/%s/%s/collaborators/%s", g.owner, g.repo, login), nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "token "+g.token)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("Gitea API error checking collaborator: %s", resp.Status)
+}
+
+// IsOrgMember reports whether a user is a member of an organization
+func (g *GiteaClient) IsOrgMember(ctx context.Context, org, login string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", g.baseURL+fmt.Sprintf("/orgs/%s/members/%s", org, login), nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "token "+g.token)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("Gitea API error checking membership of %s: %s", org, resp.Status)
+}
+
+func (g *GiteaClient) CloseIssue(ctx context.Context, issueNumber int) error {
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d", g.owner, g.repo, issueNumber)
+	if err := g.do(ctx, "PATCH", path, map[string]string{"state": "closed"}, nil); err != nil {
+		return fmt.Errorf("closing issue: %w", err)
+	}
+	return nil
+}
+
+func (g *GiteaClient) CreatePullRequest(ctx context.Context, title, body, head, base string) (*PullRequest, error) {
+	prReq := CreatePRRequest{
+		Title: title,
+		Body:  body,
+		Head:  head,
+		Base:  base,
+	}
+
+	var pr PullRequest
+	path := fmt.Sprintf("/repos/%s/%s/pulls", g.owner, g.repo)
+	if err := g.do(ctx, "POST", path, prReq, &pr); err != nil {
+		return nil, fmt.Errorf("creating PR: %w", err)
+	}
+
+	// Reviewers and assignees are best-effort: the PR exists either way
+	if len(g.reviewers) > 0 {
+		if err := g.RequestReviewers(ctx, pr.Number, g.reviewers); err != nil {
+			fmt.Printf("Warning: Could not request reviewers: %v\n", err)
+		}
+	}
+	if len(g.assignees) > 0 {
+		if err := g.AddAssignees(ctx, pr.Number, g.assignees); err != nil {
+			fmt.Printf("Warning: Could not add assignees: %v\n", err)
+		}
+	}
+
+	return &pr, nil
+}
+
+// RequestReviewers requests review on a PR. Entries in "org/team" form are requested as team reviewers.
+func (g *GiteaClient) RequestReviewers(ctx context.Context, prNumber int, reviewers []string) error {
+	reqBody := struct {
+		Reviewers     []string `json:"reviewers,omitempty"`
+		TeamReviewers []string `json:"team_reviewers,omitempty"`
+	}{}
+	for _, reviewer := range reviewers {
+		if slash := strings.Index(reviewer, "/"); slash != -1 {
+			reqBody.TeamReviewers = append(reqBody.TeamReviewers, reviewer[slash+1:])
+		} else {
+			reqBody.Reviewers = append(reqBody.Reviewers, reviewer)
+		}
+	}
+
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/requested_reviewers", g.owner, g.repo, prNumber)
+	if err := g.do(ctx, "POST", path, reqBody, nil); err != nil {
+		return fmt.Errorf("requesting reviewers: %w", err)
+	}
+	return nil
+}
+
+// AddAssignees assigns users to an issue or pull request, keeping existing assignees
+func (g *GiteaClient) AddAssignees(ctx context.Context, issueNumber int, assignees []string) error {
+	current, err := g.assigneeLogins(ctx, issueNumber)
+	if err != nil {
+		return fmt.Errorf("adding assignees: %w", err)
+	}
+	for _, assignee := range assignees {
+		if !slices.Contains(current, assignee) {
+			current = append(current, assignee)
+		}
+	}
+	return g.setAssignees(ctx, issueNumber, current, "adding assignees")
+}
+
+// RemoveAssignees unassigns users from an issue or pull request
+func (g *GiteaClient) RemoveAssignees(ctx context.Context, issueNumber int, assignees []string) error {
+	current, err := g.assigneeLogins(ctx, issueNumber)
+	if err != nil {
+		return fmt.Errorf("removing assignees: %w", err)
+	}
+	kept := []string{}
+	for _, login := range current {
+		if !slices.Contains(assignees, login) {
+			kept = append(kept, login)
+		}
+	}
+	return g.setAssignees(ctx, issueNumber, kept, "removing assignees")
+}
+
+// assigneeLogins returns who an issue is currently assigned to; Gitea's API
+// replaces the whole list on update
+func (g *GiteaClient) assigneeLogins(ctx context.Context, issueNumber int) ([]string, error) {
+	issue, err := g.GetIssue(ctx, issueNumber)
+	if err != nil {
+		return nil, err
+	}
+	var logins []string
+	for _, user := range issue.Assignees {
+		logins = append(logins, user.Login)
+	}
+	return logins, nil
+}
+
+func (g *GiteaClient) setAssignees(ctx context.Context, issueNumber int, assignees []string, action string) error {
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d", g.owner, g.repo, issueNumber)
+	if err := g.do(ctx, "PATCH", path, map[string][]string{"assignees": assignees}, nil); err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	return nil
+}
+
+// GetPullRequest fetches a pull request including its head branch
+func (g *GiteaClient) GetPullRequest(ctx context.Context, number int) (*PullRequest, error) {
+	var pr PullRequest
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d", g.owner, g.repo, number)
+	if err := g.do(ctx, "GET", path, nil, &pr); err != nil {
+		return nil, fmt.Errorf("fetching PR: %w", err)
+	}
+	return &pr, nil
+}
+
+// GetPullRequestDiff fetches the unified diff of a pull request
+func (g *GiteaClient) GetPullRequestDiff(ctx context.Context, number int) (string, error) {
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d.diff", g.owner, g.repo, number)
+	req, err := http.NewRequestWithContext(ctx, "GET", g.baseURL+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "token "+g.token)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("Gitea API error fetching PR diff: %s - %s", resp.Status, string(body))
+	}
+	return string(body), nil
+}
+
+func (g *GiteaClient) ClosePullRequest(ctx context.Context, number int) error {
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d", g.owner, g.repo, number)
+	if err := g.do(ctx, "PATCH", path, map[string]string{"state": "closed"}, nil); err != nil {
+		return fmt.Errorf("closing PR: %w", err)
+	}
+	return nil
+}
+
+// UpdatePullRequest replaces the title and description of a pull request
+func (g *GiteaClient) UpdatePullRequest(ctx context.Context, number int, title, body string) error {
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d", g.owner, g.repo, number)
+	if err := g.do(ctx, "PATCH", path, map[string]string{"title": title, "body": body}, nil); err != nil {
+		return fmt.Errorf("updating PR: %w", err)
+	}
+	return nil
+}
+
+// FindPullRequest returns the open pull request from a branch of the
+// repository, or nil if there is none. Gitea cannot filter by head, so all
+// open pull requests are searched.
+func (g *GiteaClient) FindPullRequest(ctx context.Context, head string) (*PullRequest, error) {
+	path := fmt.Sprintf("/repos/%s/%s/pulls?state=open&sort=recentupdate", g.owner, g.repo)
+	prs, err := giteaList[PullRequest](ctx, g, path, 0)
+	if err != nil {
+		return nil, fmt.Errorf("listing PRs: %w", err)
+	}
+	for i := range prs {
+		if prs[i].Head.Ref == head {
+			return &prs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// GetOpenPullRequests lists open pull requests, most recently updated first
+func (g *GiteaClient) GetOpenPullRequests(ctx context.Context, maxPRs int) ([]PullRequest, error) {
+	path := fmt.Sprintf("/repos/%s/%s/pulls?state=open&sort=recentupdate", g.owner, g.repo)
+	prs, err := giteaList[PullRequest](ctx, g, path, maxPRs)
+	if err != nil {
+		return nil, fmt.Errorf("listing PRs: %w", err)
+	}
+	return prs, nil
+}
+
+func (g *GiteaClient) DeleteBranch(ctx context.Context, branch string) error {
+	path := fmt.Sprintf("/repos/%s/%s/branches/%s", g.owner, g.repo, branch)
+	if err := g.do(ctx, "DELETE", path, nil, nil); err != nil {
+		return fmt.Errorf("deleting branch: %w", err)
+	}
+	return nil
+}
+
+func (g *GiteaClient) ReopenIssue(ctx context.Context, issueNumber int) error {
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d", g.owner, g.repo, issueNumber)
+	if err := g.do(ctx, "PATCH", path, map[string]string{"state": "open"}, nil); err != nil {
+		return fmt.Errorf("reopening issue: %w", err)
+	}
+	return nil
+}
+
+// SetMilestone puts an issue or pull request on a milestone
+func (g *GiteaClient) SetMilestone(ctx context.Context, issueNumber int, milestone *Milestone) error {
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d", g.owner, g.repo, issueNumber)
+	if err := g.do(ctx, "PATCH", path, map[string]int{"milestone": milestone.ID}, nil); err != nil {
+		return fmt.Errorf("setting milestone: %w", err)
+	}
+	return nil
+}
+
+// CreateGist is not available: Gitea has no gists
+func (g *GiteaClient) CreateGist(ctx context.Context, description, filename, content string) (string, error) {
+	return "", fmt.Errorf("gists are not supported by Gitea")
+}
+
+// GetBranchProtection reads the protection rule that applies to a branch
+func (g *GiteaClient) GetBranchProtection(ctx context.Context, branch string) (*BranchProtection, error) {
+	var info struct {
+		Protected           bool     `json:"protected"`
+		RequiredApprovals   int      `json:"required_approvals"`
+		EnableStatusCheck   bool     `json:"enable_status_check"`
+		StatusCheckContexts []string `json:"status_check_contexts"`
+	}
+	path := fmt.Sprintf("/repos/%s/%s/branches/%s", g.owner, g.repo, branch)
+	if err := g.do(ctx, "GET", path, nil, &info); err != nil {
+		return nil, fmt.Errorf("fetching branch: %w", err)
+	}
+
+	protection := &BranchProtection{Protected: info.Protected, RequiredApprovals: info.RequiredApprovals}
+	if info.EnableStatusCheck {
+		protection.RequiredChecks = info.StatusCheckContexts
+	}
+	return protection, nil
+}
+
+// GetChecks lists the commit statuses of a commit, which is how Gitea and
+// Forgejo Actions report CI results
+func (g *GiteaClient) GetChecks(ctx context.Context, ref string) ([]CheckRun, error) {
+	var combined struct {
+		Statuses []struct {
+			Context string `json:"context"`
+			Status  string `json:"status"`
+		} `json:"statuses"`
+	}
+	path := fmt.Sprintf("/repos/%s/%s/commits/%s/status", g.owner, g.repo, ref)
+	if err := g.do(ctx, "GET", path, nil, &combined); err != nil {
+		return nil, fmt.Errorf("fetching commit status: %w", err)
+	}
+
+	var checks []CheckRun
+	for _, status := range combined.Statuses {
+		checks = append(checks, CheckRun{Name: status.Context, State: statusState(status.Status)})
+	}
+	return checks, nil
+}
+
+// SetProjectStatus is not available: the Gitea API cannot move project board cards
+func (g *GiteaClient) SetProjectStatus(ctx context.Context, issueNumber int, status string) error {
+	return fmt.Errorf("moving project board cards is not supported by the Gitea API")
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,14 +19,45 @@ type Issue struct {
 	Body        string                 `json:"body"`
 	State       string                 `json:"state"`
 	HTMLURL     string                 `json:"html_url"`
+	UpdatedAt   string                 `json:"updated_at"`
+	Milestone   *Milestone             `json:"milestone,omitempty"`
+	Labels      []Label                `json:"labels"`
+	Assignees   []User                 `json:"assignees"`
+	User        User                   `json:"user"` // The author
+	Comments    int                    `json:"comments"`
+	CreatedAt   string                 `json:"created_at"`
+	Reactions   *IssueReactions        `json:"reactions,omitempty"`    // GitHub only; Gitea needs a separate call
 	PullRequest map[string]interface{} `json:"pull_request,omitempty"` // Present if it's a PR
+	Related     []Issue                `json:"-"`                      // Issues fixed together with this one
+}
+
+// IssueReactions are the reaction counts GitHub includes with an issue
+type IssueReactions struct {
+	TotalCount int `json:"total_count"`
+	PlusOne    int `json:"+1"`
+}
+
+// Milestone identifies a milestone by number (GitHub) or ID (Gitea)
+type Milestone struct {
+	Number int    `json:"number"`
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+}
+
+type Label struct {
+	Name string `json:"name"`
+}
+
+type User struct {
+	Login string `json:"login"`
 }
 
 type Comment struct {
-	ID        int    `json:"id"`
-	Body      string `json:"body"`
-	CreatedAt string `json:"created_at"`
-	User      struct {
+	ID                int    `json:"id"`
+	Body              string `json:"body"`
+	CreatedAt         string `json:"created_at"`
+	AuthorAssociation string `json:"author_association"` // GitHub only: OWNER, MEMBER, COLLABORATOR, ...
+	User              struct {
 		Login string `json:"login"`
 	} `json:"user"`
 }
@@ -33,6 +68,14 @@ type GitHubClient struct {
 	repo      string
 	baseURL   string
 	client    *http.Client
+	reviewers []string
+	assignees []string
+	milestone string
+	assignee  string // Only list issues assigned to this user, "none" or "*"
+	login     string // Account of the token, looked up once
+	sort      string // Issue order: "reactions", "comments", "oldest" or "" for newest
+	label     string // Only list issues with this label
+	query     string // GitHub search query selecting the issues instead of "all open"
 }
 
 func NewGitHubClient(token, owner, repo string) *GitHubClient {
@@ -45,11 +88,276 @@ func NewGitHubClient(token, owner, repo string) *GitHubClient {
 	}
 }
 
-func (g *GitHubClient) GetOpenIssues(maxIssues int) ([]Issue, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/issues?state=open&per_page=%d", 
-		g.baseURL, g.owner, g.repo, maxIssues)
+// SetTimeout overrides the HTTP timeout for API requests; 0 disables it
+func (g *GitHubClient) SetTimeout(timeout time.Duration) {
+	g.client.Timeout = timeout
+}
+
+// SetPRDefaults configures reviewers and assignees applied to every created PR
+func (g *GitHubClient) SetPRDefaults(reviewers, assignees []string) {
+	g.reviewers = reviewers
+	g.assignees = assignees
+}
+
+func (g *GitHubClient) CloneURL() string {
+	return httpsCloneURL("https://github.com", g.owner, g.repo)
+}
+
+// SetMilestoneFilter limits GetOpenIssues to a milestone, given by title or number
+func (g *GitHubClient) SetMilestoneFilter(milestone string) {
+	g.milestone = milestone
+}
+
+// SetAssigneeFilter limits GetOpenIssues to issues assigned to a user ("me" for
+// the token's account), to unassigned issues ("none") or to assigned ones ("*")
+func (g *GitHubClient) SetAssigneeFilter(assignee string) {
+	g.assignee = assignee
+}
+
+// SetLabelFilter limits GetOpenIssues to issues with a label
+func (g *GitHubClient) SetLabelFilter(label string) {
+	g.label = label
+}
+
+// SetSearchQuery makes GetOpenIssues list the issues a GitHub search query
+// finds, e.g. "label:bug -label:wontfix created:>2024-01-01"
+func (g *GitHubClient) SetSearchQuery(query string) {
+	g.query = strings.TrimSpace(query)
+}
+
+// SetIssueSort orders GetOpenIssues by community signal
+func (g *GitHubClient) SetIssueSort(sort string) {
+	g.sort = sort
+}
+
+func (g *GitHubClient) GetOpenIssues(ctx context.Context, maxIssues int) ([]Issue, error) {
+	if g.query != "" {
+		issues, err := g.searchIssues(ctx, maxIssues)
+		if err != nil {
+			return nil, err
+		}
+		sortIssues(issues, g.sort)
+		return issues, nil
+	}
+
+	query := fmt.Sprintf("state=open&per_page=%d", maxIssues)
+	if g.milestone != "" {
+		milestone, err := g.resolveMilestone(ctx, g.milestone)
+		if err != nil {
+			return nil, err
+		}
+		query += "&milestone=" + milestone
+	}
+	if g.assignee != "" {
+		assignee := g.assignee
+		if assignee == "me" {
+			login, err := g.GetAuthenticatedUser(ctx)
+			if err != nil {
+				return nil, err
+			}
+			assignee = login
+		}
+		query += "&assignee=" + url.QueryEscape(assignee)
+	}
+
+	if g.label != "" {
+		query += "&labels=" + url.QueryEscape(g.label)
+	}
+
+	// Let GitHub pick the top issues where it can; reactions are sorted locally
+	switch g.sort {
+	case "comments":
+		query += "&sort=comments&direction=desc"
+	case "oldest":
+		query += "&sort=created&direction=asc"
+	}
+	issues, err := g.listIssues(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	sortIssues(issues, g.sort)
+	return issues, nil
+}
+
+// maxSearchResults is the most results the search API returns for a query
+const maxSearchResults = 1000
+
+// searchIssues lists up to maxIssues issues of the repository found by the
+// search query, page by page
+func (g *GitHubClient) searchIssues(ctx context.Context, maxIssues int) ([]Issue, error) {
+	params := url.Values{"q": {g.searchQuery()}}
+	// Let GitHub pick the top issues; ties are ordered locally
+	switch g.sort {
+	case "reactions":
+		params.Set("sort", "reactions-+1")
+		params.Set("order", "desc")
+	case "comments":
+		params.Set("sort", "comments")
+		params.Set("order", "desc")
+	case "oldest":
+		params.Set("sort", "created")
+		params.Set("order", "asc")
+	}
+
+	perPage := min(maxIssues, 100)
+	var issues []Issue
+	for page := 1; len(issues) < maxIssues && (page-1)*perPage < maxSearchResults; page++ {
+		params.Set("per_page", strconv.Itoa(perPage))
+		params.Set("page", strconv.Itoa(page))
+		var result struct {
+			Items []Issue `json:"items"`
+		}
+		if err := g.request(ctx, "GET", g.baseURL+"/search/issues?"+params.Encode(), nil, &result, http.StatusOK, "searching issues"); err != nil {
+			return nil, err
+		}
+		for _, issue := range result.Items {
+			if issue.PullRequest == nil && len(issues) < maxIssues {
+				issues = append(issues, issue)
+			}
+		}
+		if len(result.Items) < perPage {
+			break
+		}
+	}
+	return issues, nil
+}
+
+// searchQuery scopes the search query to the repository's open issues,
+// unless it asks for another state, and adds the milestone, assignee and
+// label filters as qualifiers. Milestones are matched by title.
+func (g *GitHubClient) searchQuery() string {
+	terms := []string{g.query, fmt.Sprintf("repo:%s/%s", g.owner, g.repo), "is:issue"}
+	hasState := false
+	for _, term := range strings.Fields(strings.ToLower(g.query)) {
+		term = strings.TrimPrefix(term, "-")
+		if term == "is:open" || term == "is:closed" || strings.HasPrefix(term, "state:") {
+			hasState = true
+		}
+	}
+	if !hasState {
+		terms = append(terms, "is:open")
+	}
+
+	switch g.milestone {
+	case "":
+	case "none":
+		terms = append(terms, "no:milestone")
+	case "*":
+		terms = append(terms, "-no:milestone")
+	default:
+		terms = append(terms, fmt.Sprintf("milestone:%q", g.milestone))
+	}
+	switch g.assignee {
+	case "":
+	case "none":
+		terms = append(terms, "no:assignee")
+	case "*":
+		terms = append(terms, "-no:assignee")
+	case "me":
+		terms = append(terms, "assignee:@me")
+	default:
+		terms = append(terms, "assignee:"+g.assignee)
+	}
+	if g.label != "" {
+		terms = append(terms, fmt.Sprintf("label:%q", g.label))
+	}
+	return strings.Join(terms, " ")
+}
+
+// GetDefaultBranch returns the repository's default branch, which is set even
+// while the repository is still empty
+func (g *GitHubClient) GetDefaultBranch(ctx context.Context) (string, error) {
+	var repo struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s", g.baseURL, g.owner, g.repo)
+	if err := g.request(ctx, "GET", url, nil, &repo, http.StatusOK, "fetching repository"); err != nil {
+		return "", err
+	}
+	return repo.DefaultBranch, nil
+}
+
+// ListOrgRepos lists all repositories of an organization, or of a user when
+// no organization has that name
+func (g *GitHubClient) ListOrgRepos(ctx context.Context, org string) ([]Repository, error) {
+	repos, err := g.listRepos(ctx, fmt.Sprintf("%s/orgs/%s/repos?type=all", g.baseURL, url.PathEscape(org)))
+	if err != nil {
+		if userRepos, userErr := g.listRepos(ctx, fmt.Sprintf("%s/users/%s/repos?type=owner", g.baseURL, url.PathEscape(org))); userErr == nil {
+			return userRepos, nil
+		}
+		return nil, err
+	}
+	return repos, nil
+}
+
+func (g *GitHubClient) listRepos(ctx context.Context, baseURL string) ([]Repository, error) {
+	var repos []Repository
+	for page := 1; ; page++ {
+		var batch []Repository
+		url := fmt.Sprintf("%s&per_page=100&page=%d", baseURL, page)
+		if err := g.request(ctx, "GET", url, nil, &batch, http.StatusOK, "listing repositories"); err != nil {
+			return nil, err
+		}
+		repos = append(repos, batch...)
+		if len(batch) < 100 {
+			return repos, nil
+		}
+	}
+}
+
+// GetAuthenticatedUser returns the login of the account the token belongs to
+func (g *GitHubClient) GetAuthenticatedUser(ctx context.Context) (string, error) {
+	if g.login != "" {
+		return g.login, nil
+	}
+
+	var user User
+	if err := g.request(ctx, "GET", g.baseURL+"/user", nil, &user, http.StatusOK, "fetching authenticated user"); err != nil {
+		return "", err
+	}
+	g.login = user.Login
+	return g.login, nil
+}
+
+// resolveMilestone turns a milestone title into the number the issues API filters on
+func (g *GitHubClient) resolveMilestone(ctx context.Context, milestone string) (string, error) {
+	if _, err := strconv.Atoi(milestone); err == nil || milestone == "*" || milestone == "none" {
+		return milestone, nil
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s/milestones?state=all&per_page=100", 
+		g.baseURL, g.owner, g.repo)
+	
+	var milestones []Milestone
+	if err := g.request(ctx, "GET", url, nil, &milestones, http.StatusOK, "fetching milestones"); err != nil {
+		return "", err
+	}
+	for _, m := range milestones {
+		if strings.EqualFold(m.Title, milestone) {
+			return strconv.Itoa(m.Number), nil
+		}
+	}
+	return "", fmt.Errorf("milestone %q not found", milestone)
+}
+
+// SetMilestone puts an issue or pull request on a milestone
+func (g *GitHubClient) SetMilestone(ctx context.Context, issueNumber int, milestone *Milestone) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d", 
+		g.baseURL, g.owner, g.repo, issueNumber)
 	
-	req, err := http.NewRequest("GET", url, nil)
+	return g.request(ctx, "PATCH", url, map[string]int{"milestone": milestone.Number}, nil, http.StatusOK, "setting milestone")
+}
+
+// GetClosedIssues returns the most recently updated closed issues
+func (g *GitHubClient) GetClosedIssues(ctx context.Context, maxIssues int) ([]Issue, error) {
+	return g.listIssues(ctx, fmt.Sprintf("state=closed&sort=updated&direction=desc&per_page=%d", maxIssues))
+}
+
+func (g *GitHubClient) listIssues(ctx context.Context, query string) ([]Issue, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues?%s", 
+		g.baseURL, g.owner, g.repo, query)
+	
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -85,11 +393,11 @@ func (g *GitHubClient) GetOpenIssues(maxIssues int) ([]Issue, error) {
 	return filteredIssues, nil
 }
 
-func (g *GitHubClient) GetIssue(number int) (*Issue, error) {
+func (g *GitHubClient) GetIssue(ctx context.Context, number int) (*Issue, error) {
 	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d", 
 		g.baseURL, g.owner, g.repo, number)
 	
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -126,9 +434,24 @@ type CreatePRRequest struct {
 type PullRequest struct {
 	Number  int    `json:"number"`
 	HTMLURL string `json:"html_url"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	State   string `json:"state"`
+	Merged  bool   `json:"merged"`
+	Draft   bool   `json:"draft"`
+	Head    struct {
+		Ref  string `json:"ref"`
+		SHA  string `json:"sha"`
+		Repo struct {
+			FullName string `json:"full_name"` // owner/repo the branch lives in, empty for a deleted fork
+		} `json:"repo"`
+	} `json:"head"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
 }
 
-func (g *GitHubClient) CreatePullRequest(title, body, head, base string) (string, error) {
+func (g *GitHubClient) CreatePullRequest(ctx context.Context, title, body, head, base string) (*PullRequest, error) {
 	url := fmt.Sprintf("%s/repos/%s/%s/pulls", 
 		g.baseURL, g.owner, g.repo)
 	
@@ -141,12 +464,12 @@ func (g *GitHubClient) CreatePullRequest(title, body, head, base string) (string
 
 	jsonData, err := json.Marshal(prReq)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+g.token)
@@ -155,24 +478,219 @@ func (g *GitHubClient) CreatePullRequest(title, body, head, base string) (string
 
 	resp, err := g.client.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("GitHub API error creating PR: %s - %s", resp.Status, string(body))
+		return nil, fmt.Errorf("GitHub API error creating PR: %s - %s", resp.Status, string(body))
 	}
 
 	var pr PullRequest
 	if err := json.NewDecoder(resp.Body).Decode(&pr); err != nil {
+		return nil, err
+	}
+
+	// Reviewers and assignees are best-effort: the PR exists either way
+	if len(g.reviewers) > 0 {
+		if err := g.RequestReviewers(ctx, pr.Number, g.reviewers); err != nil {
+			fmt.Printf("Warning: Could not request reviewers: %v\n", err)
+		}
+	}
+	if len(g.assignees) > 0 {
+		if err := g.AddAssignees(ctx, pr.Number, g.assignees); err != nil {
+			fmt.Printf("Warning: Could not add assignees: %v\n", err)
+		}
+	}
+
+	return &pr, nil
+}
+
+// RequestReviewers requests review on a PR. Entries in "org/team" form are requested as team reviewers.
+func (g *GitHubClient) RequestReviewers(ctx context.Context, prNumber int, reviewers []string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/requested_reviewers", 
+		g.baseURL, g.owner, g.repo, prNumber)
+	
+	reqBody := struct {
+		Reviewers     []string `json:"reviewers,omitempty"`
+		TeamReviewers []string `json:"team_reviewers,omitempty"`
+	}{}
+	for _, reviewer := range reviewers {
+		if slash := strings.Index(reviewer, "/"); slash != -1 {
+			reqBody.TeamReviewers = append(reqBody.TeamReviewers, reviewer[slash+1:])
+		} else {
+			reqBody.Reviewers = append(reqBody.Reviewers, reviewer)
+		}
+	}
+
+	return g.post(ctx, url, reqBody, http.StatusCreated, "requesting reviewers")
+}
+
+// AddAssignees assigns users to an issue or pull request
+func (g *GitHubClient) AddAssignees(ctx context.Context, issueNumber int, assignees []string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/assignees", 
+		g.baseURL, g.owner, g.repo, issueNumber)
+	
+	reqBody := map[string][]string{
+		"assignees": assignees,
+	}
+
+	return g.post(ctx, url, reqBody, http.StatusCreated, "adding assignees")
+}
+
+// RemoveAssignees unassigns users from an issue or pull request
+func (g *GitHubClient) RemoveAssignees(ctx context.Context, issueNumber int, assignees []string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/assignees", 
+		g.baseURL, g.owner, g.repo, issueNumber)
+	
+	return g.request(ctx, "DELETE", url, map[string][]string{"assignees": assignees}, nil, http.StatusOK, "removing assignees")
+}
+
+// post sends an authenticated JSON POST and checks for the expected status code
+func (g *GitHubClient) post(ctx context.Context, url string, payload interface{}, expectedStatus int, action string) error {
+	return g.request(ctx, "POST", url, payload, nil, expectedStatus, action)
+}
+
+// request sends an authenticated request with an optional JSON payload, checks the
+// status code and decodes the JSON response into out (if non-nil)
+func (g *GitHubClient) request(ctx context.Context, method, url string, payload interface{}, out interface{}, expectedStatus int, action string) error {
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("GitHub API error %s: %s - %s", action, resp.Status, string(respBody))
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// GetPullRequest fetches a pull request including its head branch
+func (g *GitHubClient) GetPullRequest(ctx context.Context, number int) (*PullRequest, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", 
+		g.baseURL, g.owner, g.repo, number)
+	
+	var pr PullRequest
+	if err := g.request(ctx, "GET", url, nil, &pr, http.StatusOK, "fetching PR"); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// GetPullRequestDiff fetches the unified diff of a pull request
+func (g *GitHubClient) GetPullRequestDiff(ctx context.Context, number int) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", 
+		g.baseURL, g.owner, g.repo, number)
+	
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
 		return "", err
 	}
 
-	return pr.HTMLURL, nil
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("Accept", "application/vnd.github.diff")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub API error fetching PR diff: %s - %s", resp.Status, string(body))
+	}
+	return string(body), nil
+}
+
+func (g *GitHubClient) ClosePullRequest(ctx context.Context, number int) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", 
+		g.baseURL, g.owner, g.repo, number)
+	
+	return g.request(ctx, "PATCH", url, map[string]string{"state": "closed"}, nil, http.StatusOK, "closing PR")
+}
+
+// UpdatePullRequest replaces the title and description of a pull request
+func (g *GitHubClient) UpdatePullRequest(ctx context.Context, number int, title, body string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d",
+		g.baseURL, g.owner, g.repo, number)
+
+	return g.request(ctx, "PATCH", url, map[string]string{"title": title, "body": body}, nil, http.StatusOK, "updating PR")
+}
+
+// FindPullRequest returns the open pull request from a branch of the
+// repository, or nil if there is none
+func (g *GitHubClient) FindPullRequest(ctx context.Context, head string) (*PullRequest, error) {
+	query := fmt.Sprintf("%s/repos/%s/%s/pulls?state=open&head=%s",
+		g.baseURL, g.owner, g.repo, url.QueryEscape(g.owner+":"+head))
+
+	var prs []PullRequest
+	if err := g.request(ctx, "GET", query, nil, &prs, http.StatusOK, "finding PR"); err != nil {
+		return nil, err
+	}
+	if len(prs) == 0 {
+		return nil, nil
+	}
+	return &prs[0], nil
+}
+
+// GetOpenPullRequests lists open pull requests, most recently updated first
+func (g *GitHubClient) GetOpenPullRequests(ctx context.Context, maxPRs int) ([]PullRequest, error) {
+	query := fmt.Sprintf("%s/repos/%s/%s/pulls?state=open&sort=updated&direction=desc&per_page=%d",
+		g.baseURL, g.owner, g.repo, min(maxPRs, 100))
+
+	var prs []PullRequest
+	if err := g.request(ctx, "GET", query, nil, &prs, http.StatusOK, "listing PRs"); err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
+func (g *GitHubClient) DeleteBranch(ctx context.Context, branch string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/git/refs/heads/%s", 
+		g.baseURL, g.owner, g.repo, branch)
+	
+	return g.request(ctx, "DELETE", url, nil, nil, http.StatusNoContent, "deleting branch")
+}
+
+func (g *GitHubClient) ReopenIssue(ctx context.Context, issueNumber int) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d", 
+		g.baseURL, g.owner, g.repo, issueNumber)
+	
+	return g.request(ctx, "PATCH", url, map[string]string{"state": "open"}, nil, http.StatusOK, "reopening issue")
 }
 
-func (g *GitHubClient) AddIssueComment(issueNumber int, comment string) error {
+func (g *GitHubClient) AddIssueComment(ctx context.Context, issueNumber int, comment string) error {
 	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments", 
 		g.baseURL, g.owner, g.repo, issueNumber)
 	
@@ -185,7 +703,7 @@ func (g *GitHubClient) AddIssueComment(issueNumber int, comment string) error {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
@@ -208,11 +726,11 @@ func (g *GitHubClient) AddIssueComment(issueNumber int, comment string) error {
 	return nil
 }
 
-func (g *GitHubClient) GetIssueComments(issueNumber int) ([]Comment, error) {
+func (g *GitHubClient) GetIssueComments(ctx context.Context, issueNumber int) ([]Comment, error) {
 	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments", 
 		g.baseURL, g.owner, g.repo, issueNumber)
 	
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -239,7 +757,163 @@ func (g *GitHubClient) GetIssueComments(issueNumber int) ([]Comment, error) {
 	return comments, nil
 }
 
-func (g *GitHubClient) CloseIssue(issueNumber int) error {
+// EditIssueComment replaces the body of an issue comment
+func (g *GitHubClient) EditIssueComment(ctx context.Context, commentID int, comment string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/comments/%d",
+		g.baseURL, g.owner, g.repo, commentID)
+
+	return g.request(ctx, "PATCH", url, map[string]string{"body": comment}, nil, http.StatusOK, "editing comment")
+}
+
+// CreateGist uploads a secret gist and returns its URL. The token needs the gist scope.
+func (g *GitHubClient) CreateGist(ctx context.Context, description, filename, content string) (string, error) {
+	payload := map[string]interface{}{
+		"description": description,
+		"public":      false,
+		"files":       map[string]map[string]string{filename: {"content": content}},
+	}
+	var gist struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := g.request(ctx, "POST", g.baseURL+"/gists", payload, &gist, http.StatusCreated, "creating gist"); err != nil {
+		return "", err
+	}
+	return gist.HTMLURL, nil
+}
+
+// GetBranchProtection reads whether a branch is protected and which status
+// checks it requires. Required reviews are only visible to admins and are
+// not read.
+func (g *GitHubClient) GetBranchProtection(ctx context.Context, branch string) (*BranchProtection, error) {
+	var info struct {
+		Protected  bool `json:"protected"`
+		Protection struct {
+			RequiredStatusChecks struct {
+				Contexts []string `json:"contexts"`
+			} `json:"required_status_checks"`
+		} `json:"protection"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/branches/%s", g.baseURL, g.owner, g.repo, branch)
+	if err := g.request(ctx, "GET", url, nil, &info, http.StatusOK, "fetching branch"); err != nil {
+		return nil, err
+	}
+	return &BranchProtection{Protected: info.Protected, RequiredChecks: info.Protection.RequiredStatusChecks.Contexts}, nil
+}
+
+// GetChecks lists the commit statuses and check runs of a commit
+func (g *GitHubClient) GetChecks(ctx context.Context, ref string) ([]CheckRun, error) {
+	var combined struct {
+		Statuses []struct {
+			Context string `json:"context"`
+			State   string `json:"state"`
+		} `json:"statuses"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/commits/%s/status", g.baseURL, g.owner, g.repo, ref)
+	if err := g.request(ctx, "GET", url, nil, &combined, http.StatusOK, "fetching commit status"); err != nil {
+		return nil, err
+	}
+
+	var runs struct {
+		CheckRuns []struct {
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+		} `json:"check_runs"`
+	}
+	url = fmt.Sprintf("%s/repos/%s/%s/commits/%s/check-runs?per_page=100", g.baseURL, g.owner, g.repo, ref)
+	if err := g.request(ctx, "GET", url, nil, &runs, http.StatusOK, "fetching check runs"); err != nil {
+		return nil, err
+	}
+
+	var checks []CheckRun
+	for _, status := range combined.Statuses {
+		checks = append(checks, CheckRun{Name: status.Context, State: statusState(status.State)})
+	}
+	for _, run := range runs.CheckRuns {
+		state := checksPending
+		if run.Status == "completed" {
+			state = conclusionState(run.Conclusion)
+		}
+		checks = append(checks, CheckRun{Name: run.Name, State: state})
+	}
+	return checks, nil
+}
+
+// Reaction is an emoji reaction on an issue comment
+type Reaction struct {
+	Content string `json:"content"` // "+1", "-1", "heart", ...
+	User    User   `json:"user"`
+}
+
+// GetCommentReactions lists the reactions on an issue comment
+func (g *GitHubClient) GetCommentReactions(ctx context.Context, commentID int) ([]Reaction, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/comments/%d/reactions",
+		g.baseURL, g.owner, g.repo, commentID)
+
+	var reactions []Reaction
+	if err := g.request(ctx, "GET", url, nil, &reactions, http.StatusOK, "fetching reactions"); err != nil {
+		return nil, err
+	}
+	return reactions, nil
+}
+
+// IsCollaborator reports whether a user is a collaborator on the repository
+func (g *GitHubClient) IsCollaborator(ctx context.Context, login string) (bool, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/collaborators/%s",
+		g.baseURL, g.owner, g.repo, login)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return false, fmt.Errorf("GitHub API error checking collaborator: %s - %s", resp.Status, string(body))
+}
+
+// IsOrgMember reports whether a user is a member of an organization. Unless
+// the token's account is a member itself, only public memberships are seen.
+func (g *GitHubClient) IsOrgMember(ctx context.Context, org, login string) (bool, error) {
+	url := fmt.Sprintf("%s/orgs/%s/members/%s", g.baseURL, org, login)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return false, fmt.Errorf("GitHub API error checking membership of %s: %s - %s", org, resp.Status, string(body))
+}
+
+func (g *GitHubClient) CloseIssue(ctx context.Context, issueNumber int) error {
 	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d", 
 		g.baseURL, g.owner, g.repo, issueNumber)
 	
@@ -252,7 +926,7 @@ func (g *GitHubClient) CloseIssue(issueNumber int) error {
 		return err
 	}
 
-	req, err := http.NewRequest("PATCH", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
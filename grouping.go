@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Limits for grouping related issues into one fix
+const (
+	maxGroupSize          = 5
+	maxGroupingBodyChars  = 600
+	maxGroupingCandidates = 40
+)
+
+// groupRelatedIssues asks the AI which of the selected issues describe the same
+// bug or need changes to the same code. Each group is merged into one issue that
+// is fixed on a single branch; the rest are returned unchanged, in their
+// original order.
+func groupRelatedIssues(ctx context.Context, aiClient AIClient, issues []Issue) []Issue {
+	if len(issues) < 2 || len(issues) > maxGroupingCandidates {
+		return issues
+	}
+
+	fmt.Printf("🧩 Checking %d issues for related ones that can share a fix...\n", len(issues))
+
+	var prompt strings.Builder
+	prompt.WriteString("# Issues\n\n")
+	for _, issue := range issues {
+		body := issue.Body
+		if len(body) > maxGroupingBodyChars {
+			body = body[:maxGroupingBodyChars] + "..."
+		}
+		prompt.WriteString(fmt.Sprintf("## #%d: %s\n%s\n\n", issue.Number, issue.Title, body))
+	}
+	prompt.WriteString(fmt.Sprintf(`# Task
+
+Group issues that describe the same bug, or that would need changes to the same files so that separate fixes would conflict. Only group issues that can reasonably be fixed together in one pull request (at most %d per group). Leave unrelated issues out.
+
+Your response MUST be in the following JSON format:
+
+{
+  "groups": [
+    {"issues": [12, 15], "reason": "Both are caused by the date parsing in utils/date.js"}
+  ]
+}
+
+Return an empty list if no issues are related. Return valid JSON only, no markdown code blocks.`, maxGroupSize))
+
+	response, err := completeJSON(ctx, aiClient, fixSystemPrompt, prompt.String())
+	if err != nil {
+		fmt.Printf("Warning: Could not group issues, fixing them one by one: %v\n", err)
+		return issues
+	}
+
+	var result struct {
+		Groups []struct {
+			Issues []int  `json:"issues"`
+			Reason string `json:"reason"`
+		} `json:"groups"`
+	}
+	if err := json.Unmarshal([]byte(cleanJSONResponse(response)), &result); err != nil {
+		fmt.Printf("Warning: Could not parse issue groups, fixing them one by one: %v\n", err)
+		return issues
+	}
+
+	byNumber := make(map[int]Issue)
+	for _, issue := range issues {
+		byNumber[issue.Number] = issue
+	}
+
+	// Every issue can only be in one group; unknown numbers are dropped
+	merged := make(map[int]Issue) // primary number -> merged issue
+	grouped := make(map[int]bool)
+	for _, group := range result.Groups {
+		var members []Issue
+		for _, number := range group.Issues {
+			issue, ok := byNumber[number]
+			if !ok || grouped[number] || len(members) >= maxGroupSize {
+				continue
+			}
+			if !slices.ContainsFunc(members, func(m Issue) bool { return m.Number == number }) {
+				members = append(members, issue)
+			}
+		}
+		if len(members) < 2 {
+			continue
+		}
+		for _, member := range members {
+			grouped[member.Number] = true
+		}
+		primary := mergeIssues(members, group.Reason)
+		merged[primary.Number] = primary
+		fmt.Printf("✓ Grouped %s: %s\n", issueRefs(primary), group.Reason)
+	}
+
+	if len(merged) == 0 {
+		fmt.Println("✓ No related issues found")
+		return issues
+	}
+
+	// The merged issue takes the place of its first member
+	var out []Issue
+	for _, issue := range issues {
+		if m, ok := merged[issue.Number]; ok {
+			out = append(out, m)
+		} else if !grouped[issue.Number] {
+			out = append(out, issue)
+		}
+	}
+	return out
+}
+
+// mergeIssues combines related issues into one: the first issue keeps its number
+// and title, the description covers all of them and the rest become Related
+func mergeIssues(members []Issue, reason string) Issue {
+	primary := members[0]
+	primary.Related = append([]Issue{}, members[1:]...)
+
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("This is a group of %d related issues that must all be fixed by the same change.", len(members)))
+	if reason != "" {
+		body.WriteString(" " + reason)
+	}
+	body.WriteString("\n\n")
+	for _, member := range members {
+		body.WriteString(fmt.Sprintf("## #%d: %s\n\n%s\n\n", member.Number, member.Title, member.Body))
+	}
+	primary.Body = strings.TrimSpace(body.String())
+	return primary
+}
+
+// groupIssues returns an issue and the issues grouped with it
+func groupIssues(issue Issue) []Issue {
+	return append([]Issue{issue}, issue.Related...)
+}
+
+// issueRefs lists the issue numbers a fix covers, e.g. "#12, #15"
+func issueRefs(issue Issue) string {
+	var refs []string
+	for _, member := range groupIssues(issue) {
+		refs = append(refs, fmt.Sprintf("#%d", member.Number))
+	}
+	return strings.Join(refs, ", ")
+}
+
+// fixesLines links every issue of a group to the PR, e.g. "Fixes #12" so they
+// are closed when it is merged
+func fixesLines(issue Issue, keyword string) string {
+	var lines []string
+	for _, member := range groupIssues(issue) {
+		lines = append(lines, fmt.Sprintf("%s #%d", keyword, member.Number))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// groupedComment is posted on the other issues of a group when the PR does not close them
+func groupedComment(issue Issue, prURL string) string {
+	return fmt.Sprintf(`## 🔗 Fixed Together
+
+This issue is related to #%d and is addressed by the same pull request: %s
+
+Please review the PR; merging it will close all of %s.
+
+---
+
+%s`, issue.Number, prURL, issueRefs(issue), bot.signature())
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultProtectedPaths are files an AI fix should never touch without a human saying so:
+// CI definitions (supply-chain risk), licenses, lockfiles and secrets.
+var defaultProtectedPaths = []string{
+	".github/workflows/",
+	".github/actions/",
+	".gitlab-ci.yml",
+	".circleci/",
+	".travis.yml",
+	"azure-pipelines.yml",
+	"Jenkinsfile",
+	".buildkite/",
+	"LICENSE*",
+	"COPYING*",
+	"package-lock.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"go.sum",
+	"Cargo.lock",
+	"poetry.lock",
+	"Pipfile.lock",
+	"Gemfile.lock",
+	"composer.lock",
+	".env",
+	".env.*",
+	"*.pem",
+	"*.key",
+	"*.p12",
+	"id_rsa*",
+	"id_ed25519*",
+	"secrets.*",
+	".npmrc",
+	".pypirc",
+}
+
+// PathGuard decides whether the AI may write to a repository path
+type PathGuard struct {
+	patterns []string
+	confirm  func(path, pattern string) bool // nil rejects without asking
+	approved map[string]bool
+}
+
+func NewPathGuard(patterns []string, confirm func(path, pattern string) bool) *PathGuard {
+	return &PathGuard{
+		patterns: patterns,
+		confirm:  confirm,
+		approved: make(map[string]bool),
+	}
+}
+
+// Check returns an error if the path is protected and the operator didn't approve it
+func (p *PathGuard) Check(path string) error {
+	// Paths escaping the repository or touching .git are never allowed.
+	// Backslashes count as separators everywhere, as they do on Windows.
+	clean := filepath.ToSlash(filepath.Clean(strings.ReplaceAll(path, `\`, "/")))
+	if filepath.IsAbs(path) || strings.HasPrefix(clean, "/") || filepath.VolumeName(path) != "" || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("refusing to write outside the repository: %s", path)
+	}
+	for _, segment := range strings.Split(clean, "/") {
+		if strings.EqualFold(segment, ".git") {
+			return fmt.Errorf("refusing to write git internals: %s", path)
+		}
+	}
+
+	if p == nil || p.approved[clean] {
+		return nil
+	}
+
+	for _, pattern := range p.patterns {
+		if !matchesPathPattern(clean, pattern) {
+			continue
+		}
+		if p.confirm != nil && p.confirm(clean, pattern) {
+			p.approved[clean] = true
+			return nil
+		}
+		return fmt.Errorf("refusing to modify protected path %s (matches %q)", path, pattern)
+	}
+
+	return nil
+}
+
+// CheckInRepo is Check for a path in the clone at repoPath. Symbolic links
+// already in the clone are followed, so a link can neither lead a write out
+// of the clone nor alias a protected path under another name.
+func (p *PathGuard) CheckInRepo(repoPath, path string) error {
+	if err := p.Check(path); err != nil {
+		return err
+	}
+	if err := checkContained(repoPath, path); err != nil {
+		return err
+	}
+	resolved, err := resolveRepoPath(repoPath, path)
+	if err != nil {
+		return err
+	}
+	if resolved != filepath.ToSlash(filepath.Clean(path)) {
+		if err := p.Check(resolved); err != nil {
+			return fmt.Errorf("refusing %s, a link to %s: %w", path, resolved, err)
+		}
+	}
+	return nil
+}
+
+// checkContained refuses a path that leads out of the clone through a
+// symbolic link, in the path itself or one of its directories. Paths that
+// don't exist yet are checked up to their deepest existing directory.
+func checkContained(repoPath, path string) error {
+	rel, err := resolveRepoPath(repoPath, path)
+	if err != nil {
+		return err
+	}
+	if rel == ".." || strings.HasPrefix(rel, "../") || filepath.IsAbs(rel) {
+		return fmt.Errorf("refusing %s: it leads outside the repository", path)
+	}
+	return nil
+}
+
+// resolveRepoPath returns where path really leads, relative to the clone,
+// after following the symbolic links of its deepest existing part
+func resolveRepoPath(repoPath, path string) (string, error) {
+	root, err := filepath.EvalSymlinks(repoPath)
+	if err != nil {
+		return "", err
+	}
+	existing := filepath.Join(repoPath, filepath.FromSlash(path))
+	missing := ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return filepath.ToSlash(filepath.Clean(path)), nil
+		}
+		missing = filepath.Join(filepath.Base(existing), missing)
+		existing = parent
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, filepath.Join(resolved, missing))
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// confirmProtectedPath asks the operator whether the AI may modify a protected file
+func confirmProtectedPath(path, pattern string) bool {
+	fmt.Printf(T("guard.protected"), path, pattern)
+	return isYes(prompt(T("guard.allow"), T("answer.no")))
+}
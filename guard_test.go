@@ -0,0 +1,214 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPathGuardCheck(t *testing.T) {
+	guard := NewPathGuard(defaultProtectedPaths, nil)
+
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{"main.go", true},
+		{"src/app/main.go", true},
+		{"./src/app.js", true},
+		{"docs/../src/app.js", true},
+		{".github/ISSUE_TEMPLATE/bug.md", true},
+		{"src/.gitignore", true},
+		{"src/.github/notes.md", true},
+		{"legacy/env.go", true},
+
+		// Escaping the repository
+		{"../outside.go", false},
+		{"..", false},
+		{"a/../../x", false},
+		{"a/b/../../../x", false},
+		{"/etc/passwd", false},
+		{`..\outside.go`, false},
+		{`a\..\..\x`, false},
+
+		// Git internals, at the root and in subdirectories
+		{".git", false},
+		{".git/config", false},
+		{".git/hooks/pre-commit", false},
+		{"sub/.git", false},
+		{"sub/.git/config", false},
+		{"vendor/lib/.git/hooks/post-checkout", false},
+		{".GIT/config", false},
+		{`sub\.git\config`, false},
+		{"src/../.git/config", false},
+
+		// Protected patterns
+		{".github/workflows/ci.yml", false},
+		{".github/actions/setup/action.yml", false},
+		{"LICENSE", false},
+		{"LICENSE.md", false},
+		{"package-lock.json", false},
+		{"web/package-lock.json", false},
+		{"go.sum", false},
+		{".env", false},
+		{".env.production", false},
+		{"config/.env.local", false},
+		{"certs/server.pem", false},
+		{"deploy/id_rsa.pub", false},
+		{"Jenkinsfile", false},
+		{"./.travis.yml", false},
+	}
+
+	for _, tt := range tests {
+		err := guard.Check(tt.path)
+		if tt.allowed && err != nil {
+			t.Errorf("Check(%q) = %v, want allowed", tt.path, err)
+		}
+		if !tt.allowed && err == nil {
+			t.Errorf("Check(%q) allowed, want refused", tt.path)
+		}
+	}
+}
+
+func TestPathGuardConfirm(t *testing.T) {
+	asked := 0
+	guard := NewPathGuard([]string{"go.sum"}, func(path, pattern string) bool {
+		asked++
+		return path == "go.sum"
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := guard.Check("go.sum"); err != nil {
+			t.Fatalf("Check(go.sum) = %v after approval", err)
+		}
+	}
+	if asked != 1 {
+		t.Errorf("asked %d times, want once", asked)
+	}
+	if err := guard.Check("sub/go.sum"); err == nil {
+		t.Error("Check(sub/go.sum) allowed although it was declined")
+	}
+
+	// Approval never extends to paths outside the repository or .git
+	guard = NewPathGuard(nil, func(string, string) bool { return true })
+	for _, path := range []string{"../go.sum", ".git/config"} {
+		if err := guard.Check(path); err == nil {
+			t.Errorf("Check(%q) allowed", path)
+		}
+	}
+}
+
+func TestPathGuardCheckInRepo(t *testing.T) {
+	repo := t.TempDir()
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, ".github", "workflows"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(repo, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(".github", "workflows"), filepath.Join(repo, "ci")); err != nil {
+		t.Skipf("symbolic links not available: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(repo, "docs")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("src", filepath.Join(repo, "lib")); err != nil {
+		t.Fatal(err)
+	}
+	guard := NewPathGuard(defaultProtectedPaths, nil)
+
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{"src/main.go", true},
+		{"src/new/dir/file.go", true},
+		{"lib/main.go", true},
+
+		// An alias of a protected directory
+		{"ci/release.yml", false},
+		{"ci/new/release.yml", false},
+		{"ci", false},
+
+		// Escaping the clone
+		{"docs/notes.md", false},
+		{"docs", false},
+	}
+	for _, tt := range tests {
+		err := guard.CheckInRepo(repo, tt.path)
+		if tt.allowed && err != nil {
+			t.Errorf("CheckInRepo(%q) = %v, want allowed", tt.path, err)
+		}
+		if !tt.allowed && err == nil {
+			t.Errorf("CheckInRepo(%q) allowed, want refused", tt.path)
+		}
+	}
+
+	// A rename is refused when its target goes through a link
+	g := &GitOps{repoPath: repo, guard: guard}
+	if err := os.WriteFile(filepath.Join(repo, "src", "a.yml"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{"ci/a.yml", "docs/a.yml"} {
+		change := FileChange{FilePath: "src/a.yml", NewPath: target, Operation: fileRename}
+		if err := g.ApplyFileChange(change); err == nil {
+			t.Errorf("rename to %s allowed", target)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(repo, "src", "a.yml")); err != nil {
+		t.Errorf("refused rename moved the file: %v", err)
+	}
+
+	// Writes never go through a link in the last component
+	if err := os.Symlink("a.yml", filepath.Join(repo, "src", "b.yml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.ApplyFileChange(FileChange{FilePath: "src/b.yml", Content: "b"}); err == nil {
+		t.Error("write through src/b.yml allowed")
+	}
+	if data, _ := os.ReadFile(filepath.Join(repo, "src", "a.yml")); string(data) != "a" {
+		t.Errorf("src/a.yml = %q after refused write", data)
+	}
+}
+
+func TestMatchesPathPattern(t *testing.T) {
+	tests := []struct {
+		path    string
+		pattern string
+		want    bool
+	}{
+		// Directory patterns match everything below them
+		{".github/workflows/ci.yml", ".github/workflows/", true},
+		{".github/workflows", ".github/workflows/", true},
+		{".github/workflows-old/ci.yml", ".github/workflows/", false},
+		{"sub/.github/workflows/ci.yml", ".github/workflows/", false},
+
+		// Patterns without a slash match the file name anywhere
+		{"yarn.lock", "yarn.lock", true},
+		{"web/yarn.lock", "yarn.lock", true},
+		{"web/yarn.lock.bak", "yarn.lock", false},
+		{"LICENSE-MIT", "LICENSE*", true},
+		{"docs/LICENSE", "LICENSE*", true},
+		{"a/b/key.pem", "*.pem", true},
+		{"pem", "*.pem", false},
+
+		// Patterns with a slash are matched against the whole path
+		{"config/secrets.yml", "config/*.yml", true},
+		{"config/sub/secrets.yml", "config/*.yml", false},
+		{"other/config/secrets.yml", "config/*.yml", false},
+		{"docs/api.md", "./docs/api.md", true},
+		{"docs/./api.md", "docs/api.md", true},
+		{"src/x/../main.go", "src/main.go", true},
+
+		// An invalid pattern matches nothing but itself
+		{"a[", "a[", true},
+		{"ab", "a[", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchesPathPattern(tt.path, tt.pattern); got != tt.want {
+			t.Errorf("matchesPathPattern(%q, %q) = %v, want %v", tt.path, tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// handledCheckWorkers bounds how many issues' comments are fetched at once,
+// fast enough for large repositories without tripping secondary rate limits
+const handledCheckWorkers = 8
+
+// handledVerdict is the outcome of checking an issue's comments, valid as
+// long as the issue's updated_at does not change
+type handledVerdict struct {
+	UpdatedAt string `json:"updated_at"`
+	Handled   bool   `json:"handled"`
+}
+
+func getHandledCachePath(owner, repo string) string {
+	return filepath.Join(getDataDir(), "handled", owner, repo+".json")
+}
+
+// loadHandledCache reads the verdicts of earlier runs, keyed by issue number
+func loadHandledCache(owner, repo string) map[string]handledVerdict {
+	verdicts := make(map[string]handledVerdict)
+	if data, err := os.ReadFile(getHandledCachePath(owner, repo)); err == nil {
+		json.Unmarshal(data, &verdicts)
+	}
+	return verdicts
+}
+
+func saveHandledCache(owner, repo string, verdicts map[string]handledVerdict) error {
+	path := getHandledCachePath(owner, repo)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(verdicts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// filterUnhandledIssues drops issues where the bot's comment is still the last one.
+// If there are new comments after the bot's response, the issue is processed again.
+//
+// Any new comment changes an issue's updated_at, so issues unchanged since the
+// last run reuse its verdict and issues without comments need no check at
+// all. The rest are checked in parallel.
+func filterUnhandledIssues(ctx context.Context, config Config, provider HostingProvider, issues []Issue) []Issue {
+	if config.SkipHandledCheck {
+		return issues
+	}
+
+	verdicts := loadHandledCache(config.RepoOwner, config.RepoName)
+	handled := make([]bool, len(issues))
+	cacheable := make([]bool, len(issues))
+
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < handledCheckWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				handled[i], cacheable[i] = isIssueHandled(ctx, provider, issues[i])
+			}
+		}()
+	}
+	for i, issue := range issues {
+		if verdict, ok := verdicts[strconv.Itoa(issue.Number)]; ok && verdict.UpdatedAt == issue.UpdatedAt {
+			handled[i] = verdict.Handled
+			continue
+		}
+		if issue.Comments == 0 {
+			continue
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	// Only open issues are kept, so closed ones drop out of the cache
+	fresh := make(map[string]handledVerdict)
+	var unhandledIssues []Issue
+	for i, issue := range issues {
+		key := strconv.Itoa(issue.Number)
+		if cacheable[i] {
+			fresh[key] = handledVerdict{UpdatedAt: issue.UpdatedAt, Handled: handled[i]}
+		} else if verdict, ok := verdicts[key]; ok && verdict.UpdatedAt == issue.UpdatedAt {
+			fresh[key] = verdict
+		}
+		if !handled[i] {
+			unhandledIssues = append(unhandledIssues, issue)
+		}
+	}
+	saveHandledCache(config.RepoOwner, config.RepoName, fresh)
+	return unhandledIssues
+}
+
+// isIssueHandled reports whether the bot's comment is still the last one on
+// an issue, and whether the answer may be reused until the issue changes
+func isIssueHandled(ctx context.Context, provider HostingProvider, issue Issue) (handled, cacheable bool) {
+	comments, err := provider.GetIssueComments(ctx, issue.Number)
+	if err != nil {
+		// If we can't check, include it to be safe
+		return false, false
+	}
+
+	// Find the last bot comment
+	lastBotCommentIndex := -1
+	for i, comment := range comments {
+		// A rolled back fix should be attempted again
+		if isBotComment(comment) && strings.Contains(comment.Body, rollbackMarker) {
+			continue
+		}
+		if isBotComment(comment) {
+			lastBotCommentIndex = i
+		}
+	}
+	if lastBotCommentIndex == -1 || lastBotCommentIndex != len(comments)-1 {
+		return false, true
+	}
+
+	// A feature plan is approved with a reaction, which leaves updated_at
+	// alone, so it is checked on every run until approved
+	lastComment := comments[lastBotCommentIndex]
+	if strings.Contains(lastComment.Body, planMarker) {
+		return !hasPendingPlanApproval(ctx, provider, lastComment), false
+	}
+	return true, true
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// Set by release builds, see .goreleaser.yaml:
+// -ldflags "-X main.version=1.3.5 -X main.commit=abc1234 -X main.date=2024-01-01T00:00:00Z"
+var (
+	version string
+	commit  string
+	date    string
+)
+
+// CommandInfo documents a subcommand for help output and shell completion
+type CommandInfo struct {
+	Name     string
+	Args     string
+	Summary  string
+	Examples []string
+	NoFlags  bool // Takes no flags, so help is printed without a flag set
+}
+
+var commands = []CommandInfo{
+	{
+		Name:    "serve",
+		Summary: "Run headless, polling for new issues and fixing them as they arrive",
+		Examples: []string{
+			"mr-code-fixer serve --interval 10m --dashboard :8080",
+			"mr-code-fixer serve --commands --require-command",
+			"MRCF_API_TOKEN=... mr-code-fixer serve --api :8081",
+		},
+	},
+	{
+		Name:    "batch",
+		Summary: "Fix every open, unhandled issue in several repositories",
+		Examples: []string{
+			"mr-code-fixer batch --repos owner/api,owner/web",
+			"mr-code-fixer batch --repos-file repos.yml",
+			"mr-code-fixer batch --org my-org --org-language go --label mr-code-fixer",
+		},
+	},
+	{
+		Name:    "rollback",
+		Summary: "Close a bot pull request, delete its branch and reopen the issue",
+		Examples: []string{
+			`mr-code-fixer rollback --pr 42 --reason "Breaks the login flow"`,
+		},
+	},
+	{
+		Name:    "history",
+		Summary: "Show merge rates and costs of past fixes",
+		Examples: []string{
+			"mr-code-fixer history --only owner/repo --days 90",
+		},
+	},
+	{
+		Name:    "stats",
+		Summary: "Show how many fix pull requests were accepted, by model and confidence",
+		Examples: []string{
+			"mr-code-fixer stats --days 30",
+		},
+	},
+	{
+		Name:    "analyze",
+		Args:    "<number>",
+		Summary: "Post a root-cause analysis on an issue without writing any code",
+		Examples: []string{
+			"mr-code-fixer analyze 42",
+			"mr-code-fixer analyze 42 --print",
+		},
+	},
+	{
+		Name:    "review",
+		Args:    "[number]",
+		Summary: "Review open pull requests by people and post the findings as a comment",
+		Examples: []string{
+			"mr-code-fixer review",
+			"mr-code-fixer review 57 --print",
+		},
+	},
+	{
+		Name:    "bench",
+		Summary: "Run one issue through several models and compare their fixes, usage and speed",
+		Examples: []string{
+			"mr-code-fixer bench --issue 42 --models gpt-4o,grok-beta,llama3",
+			"mr-code-fixer bench --issue 42 --models ollama:deepseek-coder,qwen2.5-coder --out bench/",
+		},
+	},
+	{
+		Name:    "resume-issue",
+		Args:    "[number]",
+		Summary: "Finish a fix whose push or pull request creation failed",
+		Examples: []string{
+			"mr-code-fixer resume-issue 123",
+			"mr-code-fixer resume-issue",
+		},
+	},
+	{
+		Name:    "version",
+		NoFlags: true,
+		Summary: "Print the version, commit and build date",
+	},
+	{
+		Name:    "completion",
+		NoFlags: true,
+		Args:    "bash|zsh|fish",
+		Summary: "Print a shell completion script",
+		Examples: []string{
+			"source <(mr-code-fixer completion bash)",
+			"mr-code-fixer completion zsh > \"${fpath[1]}/_mr-code-fixer\"",
+			"mr-code-fixer completion fish > ~/.config/fish/completions/mr-code-fixer.fish",
+		},
+	},
+	{
+		Name:    "help",
+		NoFlags: true,
+		Args:    "[command]",
+		Summary: "Show help for mr-code-fixer or one of its commands",
+	},
+}
+
+var topLevelExamples = []string{
+	"mr-code-fixer                                        # interactive setup and issue picker",
+	"mr-code-fixer --repo-url https://github.com/owner/repo --ai-service chatgpt --ai-model gpt-4o",
+	"MRCF_AI_API_KEY=sk-... mr-code-fixer serve --no-config-write",
+}
+
+// flagChoices are the accepted values of enum-like flags, offered by shell completion
+var flagChoices = map[string][]string{
+	"provider":          {"github", "gitea"},
+	"ai-service":        {"chatgpt", "openai", "grok", "mistral", "deepseek", "huggingface", "bedrock", "ollama", "mock"},
+	"sort":              issueSorts,
+	"close-policy":      {closeNever, closeOnMerge, closeOnHighConfidence},
+	"commit-format":     {commitFormatPlain, commitFormatConventional},
+	"git-protocol":      {"https", "ssh"},
+	"ollama-pull":       {ollamaPullAsk, ollamaPullAlways, ollamaPullNever},
+	"scaffold-empty":    {scaffoldAsk, scaffoldAlways, scaffoldNever},
+	"body-overflow":     {overflowComments, overflowGist},
+	"diff-limit":        {diffLimitShrink, diffLimitConfirm, diffLimitDowngrade},
+	"structured-output": structuredOutputModes,
+	"lang":              strings.Split(availableLanguages(), ", "),
+}
+
+func findCommand(name string) *CommandInfo {
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// versionInfo returns the version, commit and build date. Release builds get
+// them from -ldflags; go install and go build fall back to the VCS stamp.
+func versionInfo() (string, string, string) {
+	ver, rev, built := Version, commit, date
+	if version != "" {
+		ver = "v" + strings.TrimPrefix(version, "v")
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok && (rev == "" || built == "") {
+		modified := false
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if rev == "" {
+					rev = setting.Value
+				}
+			case "vcs.time":
+				if built == "" {
+					built = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && commit == "" && rev != "" {
+			rev += "-dirty"
+		}
+	}
+
+	if len(rev) > 12 && !strings.HasSuffix(rev, "-dirty") {
+		rev = rev[:12]
+	}
+	if rev == "" {
+		rev = "unknown"
+	}
+	if built == "" {
+		built = "unknown"
+	}
+	return ver, rev, built
+}
+
+func printVersion() {
+	ver, rev, built := versionInfo()
+	fmt.Printf("mr-code-fixer %s\n", ver)
+	fmt.Printf("  commit:  %s\n", rev)
+	fmt.Printf("  built:   %s\n", built)
+	fmt.Printf("  go:      %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// printUsage is the -h output of every command: what it does, examples and
+// then its flags
+func printUsage(fs *flag.FlagSet) {
+	out := fs.Output()
+	command := findCommand(fs.Name())
+
+	if command == nil {
+		fmt.Fprintf(out, "Mr. Code Fixer %s - fixes GitHub and Gitea issues with AI\n\n", Version)
+		fmt.Fprintln(out, "Usage:")
+		fmt.Fprintln(out, "  mr-code-fixer                     Interactive setup and issue picker")
+		fmt.Fprintln(out, "  mr-code-fixer [flags]             Pick and fix issues with the given settings")
+		fmt.Fprintln(out, "  mr-code-fixer <command> [flags]")
+		fmt.Fprintln(out, "\nCommands:")
+		for _, info := range commands {
+			fmt.Fprintf(out, "  %-14s %s\n", info.Name, info.Summary)
+		}
+		printExamples(fs, topLevelExamples)
+		fmt.Fprintln(out, "\nSettings are read from ~/.mr-code-fixer.json, then MRCF_* environment variables, then flags.")
+		fmt.Fprintln(out, "Run 'mr-code-fixer help <command>' for the flags of a command.")
+	} else {
+		fmt.Fprintf(out, "Usage: mr-code-fixer %s", command.Name)
+		if command.Args != "" {
+			fmt.Fprintf(out, " %s", command.Args)
+		}
+		fmt.Fprintf(out, " [flags]\n\n%s\n", command.Summary)
+		printExamples(fs, command.Examples)
+	}
+
+	fmt.Fprintln(out, "\nFlags:")
+	fs.PrintDefaults()
+}
+
+func printExamples(fs *flag.FlagSet, examples []string) {
+	if len(examples) == 0 {
+		return
+	}
+	fmt.Fprintln(fs.Output(), "\nExamples:")
+	for _, example := range examples {
+		fmt.Fprintf(fs.Output(), "  %s\n", example)
+	}
+}
+
+// helpCommand prints the overview or the help of a command without flags.
+// Commands with flags print their own help with -h, see main.
+func helpCommand(args []string) error {
+	if len(args) == 0 {
+		fs := flag.NewFlagSet("mr-code-fixer", flag.ContinueOnError)
+		fs.SetOutput(os.Stdout)
+		config := loadConfig()
+		registerFlags(&config, fs)
+		printUsage(fs)
+		return nil
+	}
+
+	command := findCommand(args[0])
+	if command == nil {
+		return fmt.Errorf("unknown command %q (run 'mr-code-fixer help' for a list)", args[0])
+	}
+	fmt.Printf("Usage: %s\n\n%s\n", strings.TrimSpace("mr-code-fixer "+command.Name+" "+command.Args), command.Summary)
+	if len(command.Examples) > 0 {
+		fmt.Println("\nExamples:")
+		for _, example := range command.Examples {
+			fmt.Printf("  %s\n", example)
+		}
+	}
+	return nil
+}
+
+// completionFlag is a flag as offered by shell completion
+type completionFlag struct {
+	Name    string
+	Usage   string
+	IsBool  bool
+	Choices []string
+}
+
+// completionFlags returns the flags shared by every command, sorted by name
+func completionFlags() []completionFlag {
+	fs := flag.NewFlagSet("completion", flag.ContinueOnError)
+	registerFlags(&Config{}, fs)
+
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		usage, _, _ := strings.Cut(f.Usage, "\n")
+		flags = append(flags, completionFlag{
+			Name:    f.Name,
+			Usage:   usage,
+			IsBool:  ok && boolFlag.IsBoolFlag(),
+			Choices: flagChoices[f.Name],
+		})
+	})
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+func completionCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: mr-code-fixer completion bash|zsh|fish")
+	}
+
+	var script string
+	switch args[0] {
+	case "bash":
+		script = bashCompletion()
+	case "zsh":
+		script = zshCompletion()
+	case "fish":
+		script = fishCompletion()
+	default:
+		return fmt.Errorf("unsupported shell %q (supported: bash, zsh, fish)", args[0])
+	}
+	fmt.Print(script)
+	return nil
+}
+
+func commandNames() []string {
+	var names []string
+	for _, info := range commands {
+		names = append(names, info.Name)
+	}
+	return names
+}
+
+func bashCompletion() string {
+	var script strings.Builder
+	script.WriteString("# bash completion for mr-code-fixer\n")
+	script.WriteString("_mr_code_fixer() {\n")
+	script.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	script.WriteString("    case \"$prev\" in\n")
+
+	var flagNames []string
+	for _, f := range completionFlags() {
+		flagNames = append(flagNames, "--"+f.Name)
+		if len(f.Choices) > 0 {
+			script.WriteString(fmt.Sprintf("        --%s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return ;;\n", f.Name, strings.Join(f.Choices, " ")))
+		}
+	}
+
+	script.WriteString("    esac\n")
+	script.WriteString("    if [[ $COMP_CWORD -eq 1 && \"$cur\" != -* ]]; then\n")
+	script.WriteString(fmt.Sprintf("        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return\n", strings.Join(commandNames(), " ")))
+	script.WriteString("    fi\n")
+	script.WriteString("    case \"${COMP_WORDS[1]}\" in\n")
+	script.WriteString("        completion) COMPREPLY=($(compgen -W \"bash zsh fish\" -- \"$cur\")); return ;;\n")
+	script.WriteString(fmt.Sprintf("        help) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return ;;\n", strings.Join(commandNames(), " ")))
+	script.WriteString("    esac\n")
+	script.WriteString(fmt.Sprintf("    COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(flagNames, " ")))
+	script.WriteString("}\n")
+	script.WriteString("complete -F _mr_code_fixer mr-code-fixer\n")
+	return script.String()
+}
+
+func zshCompletion() string {
+	quote := func(text string) string {
+		return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+	}
+
+	var script strings.Builder
+	script.WriteString("#compdef mr-code-fixer\n\n")
+	script.WriteString("_mr_code_fixer() {\n")
+	script.WriteString("  local -a commands flags\n")
+	script.WriteString("  commands=(\n")
+	for _, info := range commands {
+		script.WriteString("    " + quote(info.Name+":"+info.Summary) + "\n")
+	}
+	script.WriteString("  )\n")
+	script.WriteString("  flags=(\n")
+	flags := completionFlags()
+	for _, f := range flags {
+		script.WriteString("    " + quote("--"+f.Name+":"+f.Usage) + "\n")
+	}
+	script.WriteString("  )\n\n")
+	script.WriteString("  case $words[CURRENT-1] in\n")
+	for _, f := range flags {
+		if len(f.Choices) > 0 {
+			script.WriteString(fmt.Sprintf("    --%s) compadd %s; return ;;\n", f.Name, strings.Join(f.Choices, " ")))
+		}
+	}
+	script.WriteString("  esac\n\n")
+	script.WriteString("  if (( CURRENT == 2 )) && [[ $words[CURRENT] != -* ]]; then\n")
+	script.WriteString("    _describe 'command' commands\n")
+	script.WriteString("  elif [[ $words[2] == completion ]]; then\n")
+	script.WriteString("    compadd bash zsh fish\n")
+	script.WriteString("  elif [[ $words[2] == help ]]; then\n")
+	script.WriteString("    _describe 'command' commands\n")
+	script.WriteString("  else\n")
+	script.WriteString("    _describe 'flag' flags\n")
+	script.WriteString("  fi\n")
+	script.WriteString("}\n\n")
+	script.WriteString("compdef _mr_code_fixer mr-code-fixer\n")
+	return script.String()
+}
+
+func fishCompletion() string {
+	quote := func(text string) string {
+		return "'" + strings.ReplaceAll(strings.ReplaceAll(text, `\`, `\\`), "'", `\'`) + "'"
+	}
+
+	var script strings.Builder
+	script.WriteString("# fish completion for mr-code-fixer\n")
+	script.WriteString("complete -c mr-code-fixer -f\n")
+	for _, info := range commands {
+		script.WriteString(fmt.Sprintf("complete -c mr-code-fixer -n __fish_use_subcommand -a %s -d %s\n", info.Name, quote(info.Summary)))
+	}
+	script.WriteString("complete -c mr-code-fixer -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'\n")
+	script.WriteString(fmt.Sprintf("complete -c mr-code-fixer -n '__fish_seen_subcommand_from help' -a %s\n", quote(strings.Join(commandNames(), " "))))
+	for _, f := range completionFlags() {
+		line := fmt.Sprintf("complete -c mr-code-fixer -l %s -d %s", f.Name, quote(f.Usage))
+		switch {
+		case len(f.Choices) > 0:
+			line += " -x -a " + quote(strings.Join(f.Choices, " "))
+		case !f.IsBool:
+			line += " -r"
+		}
+		script.WriteString(line + "\n")
+	}
+	return script.String()
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HistoryRecord is one handled issue, appended to history.jsonl after every attempt
+type HistoryRecord struct {
+	Time         time.Time    `json:"time"`
+	Repo         string       `json:"repo"` // owner/name
+	Provider     string       `json:"provider,omitempty"`
+	ProviderURL  string       `json:"provider_url,omitempty"`
+	IssueNumber  int          `json:"issue_number"`
+	Title        string       `json:"title"`
+	Result       string       `json:"result"` // Same values as FixOutcome.Result
+	PRURL        string       `json:"pr_url,omitempty"`
+	PRState      string       `json:"pr_state,omitempty"`      // "open", "merged" or "closed" as last seen
+	IssuesClosed bool         `json:"issues_closed,omitempty"` // The merge watcher closed the fixed issues
+	FollowUp     string       `json:"follow_up,omitempty"`     // Questions: "reminded", "answered" or "closed"
+	RemindedAt   *time.Time   `json:"reminded_at,omitempty"`   // When the reporter was reminded of the questions
+	Service      string       `json:"service"`
+	Model        string       `json:"model"`
+	Confidence   string       `json:"confidence,omitempty"` // The AI's confidence in the fix, for PRs
+	Score        int          `json:"score,omitempty"`      // Calibrated confidence score, for PRs
+	Changes      *ChangeStats `json:"changes,omitempty"`    // Size of the committed fix
+	Cost         float64      `json:"cost"`
+	Currency     string       `json:"currency"`
+}
+
+var prNumberPattern = regexp.MustCompile(`/pulls?/(\d+)`)
+
+func getHistoryPath() string {
+	return filepath.Join(getDataDir(), "history.jsonl")
+}
+
+// appendHistory adds a record to the history file
+func appendHistory(record HistoryRecord) error {
+	path := getHistoryPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// loadHistory reads all records, skipping lines that cannot be parsed
+func loadHistory() ([]HistoryRecord, error) {
+	f, err := os.Open(getHistoryPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []HistoryRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		var record HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err == nil {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// saveHistory rewrites the history file, used after PR states are refreshed
+func saveHistory(records []HistoryRecord) error {
+	var data []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	return os.WriteFile(getHistoryPath(), data, 0600)
+}
+
+// recordHistory stores the outcome of processing an issue; cost is what the attempt spent
+func recordHistory(config Config, outcome FixOutcome, cost float64, currency string) {
+	record := HistoryRecord{
+		Time:        time.Now(),
+		Repo:        config.RepoOwner + "/" + config.RepoName,
+		Provider:    config.Provider,
+		ProviderURL: config.ProviderURL,
+		IssueNumber: outcome.IssueNumber,
+		Title:       outcome.Title,
+		Result:      outcome.Result,
+		PRURL:       outcome.PRURL,
+		Service:     config.AIService,
+		Model:       config.AIModel,
+		Confidence:  outcome.Confidence,
+		Score:       outcome.Score,
+		Changes:     outcome.Changes,
+		Cost:        cost,
+		Currency:    currency,
+	}
+	if record.PRURL != "" {
+		record.PRState = "open"
+	}
+	if err := appendHistory(record); err != nil {
+		fmt.Printf("Warning: Could not save history: %v\n", err)
+	}
+}
+
+// historyStats aggregates records for one row of the history report
+type historyStats struct {
+	Attempts int
+	PRs      int
+	Merged   int
+	Closed   int
+	Cost     float64
+}
+
+func (s *historyStats) add(record HistoryRecord) {
+	s.Attempts++
+	s.Cost += record.Cost
+	if record.PRURL == "" {
+		return
+	}
+	s.PRs++
+	switch record.PRState {
+	case "merged":
+		s.Merged++
+	case "closed":
+		s.Closed++
+	}
+}
+
+// mergeRate is the share of decided PRs (merged or closed) that were merged
+func (s *historyStats) mergeRate() string {
+	if s.Merged+s.Closed == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", float64(s.Merged)*100/float64(s.Merged+s.Closed))
+}
+
+// costPerMerge is everything spent in the group divided by the merged fixes
+func (s *historyStats) costPerMerge() string {
+	if s.Merged == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.4f", s.Cost/float64(s.Merged))
+}
+
+// historyCommand prints statistics about past fixes: merged vs closed PRs per
+// repository and model, cost per merged fix and the monthly trend
+func historyCommand(ctx context.Context, args []string) error {
+	records, err := selectHistory(ctx, "history", args)
+	if err != nil || len(records) == 0 {
+		return err
+	}
+	printHistoryReport(records)
+	return nil
+}
+
+// statsCommand prints how many of the bot's PRs were accepted, by model and by
+// confidence, to show whether the bot is actually helping
+func statsCommand(ctx context.Context, args []string) error {
+	records, err := selectHistory(ctx, "stats", args)
+	if err != nil || len(records) == 0 {
+		return err
+	}
+	printAcceptanceReport(records)
+	return nil
+}
+
+// selectHistory parses the flags shared by history and stats, brings the state
+// of open PRs up to date and returns the selected records
+func selectHistory(ctx context.Context, name string, args []string) ([]HistoryRecord, error) {
+	config := loadConfig()
+
+	var repo string
+	var days int
+	var offline bool
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(&repo, "only", "", "Only show records for this repository (owner/repo)")
+	fs.IntVar(&days, "days", 0, "Only show records from the last N days (0 = all)")
+	fs.BoolVar(&offline, "offline", false, "Do not look up the current state of open pull requests")
+	parseFlags(&config, fs, args)
+
+	records, err := loadHistory()
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+
+	if !offline && config.GithubToken != "" && refreshPRStates(ctx, config, records) {
+		if err := saveHistory(records); err != nil {
+			fmt.Printf("Warning: Could not update history: %v\n", err)
+		}
+	}
+
+	var selected []HistoryRecord
+	since := time.Time{}
+	if days > 0 {
+		since = time.Now().AddDate(0, 0, -days)
+	}
+	for _, record := range records {
+		if repo != "" && !strings.EqualFold(record.Repo, repo) {
+			continue
+		}
+		if record.Time.Before(since) {
+			continue
+		}
+		selected = append(selected, record)
+	}
+
+	if len(selected) == 0 {
+		fmt.Println("No history yet - it is recorded as issues are processed.")
+	}
+	return selected, nil
+}
+
+// refreshPRStates looks up PRs last seen open and reports whether any changed
+func refreshPRStates(ctx context.Context, config Config, records []HistoryRecord) bool {
+	providers := make(map[string]HostingProvider)
+	changed := false
+
+	for i := range records {
+		record := &records[i]
+		if ctx.Err() != nil {
+			break
+		}
+		if record.PRURL == "" || record.PRState == "merged" || record.PRState == "closed" {
+			continue
+		}
+		match := prNumberPattern.FindStringSubmatch(record.PRURL)
+		if match == nil {
+			continue
+		}
+		number, _ := strconv.Atoi(match[1])
+
+		key := record.Provider + " " + record.ProviderURL + " " + record.Repo
+		provider, ok := providers[key]
+		if !ok {
+			repoConfig, err := configForRepo(config, record.Repo)
+			if err != nil {
+				continue
+			}
+			repoConfig.Provider = record.Provider
+			repoConfig.ProviderURL = record.ProviderURL
+			provider = newHostingProvider(repoConfig)
+			providers[key] = provider
+		}
+
+		pr, err := provider.GetPullRequest(ctx, number)
+		if err != nil {
+			fmt.Printf("Warning: Could not check %s: %v\n", record.PRURL, err)
+			continue
+		}
+		state := pr.State
+		if pr.Merged {
+			state = "merged"
+		}
+		if state != record.PRState {
+			record.PRState = state
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func printHistoryReport(records []HistoryRecord) {
+	fmt.Println("\n╔════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                    📜 Fix History                              ║")
+	fmt.Println("╚════════════════════════════════════════════════════════════════╝")
+
+	currency := records[len(records)-1].Currency
+	for _, record := range records {
+		if record.Currency != currency && record.Cost > 0 {
+			fmt.Printf("\nNote: Costs were recorded in more than one currency; totals mix %s and %s\n", record.Currency, currency)
+			break
+		}
+	}
+	fmt.Printf("\n%d attempt(s) between %s and %s\n", len(records),
+		records[0].Time.Format("2006-01-02"), records[len(records)-1].Time.Format("2006-01-02"))
+
+	group := func(title string, key func(HistoryRecord) string) {
+		stats := make(map[string]*historyStats)
+		var keys []string
+		for _, record := range records {
+			k := key(record)
+			if stats[k] == nil {
+				stats[k] = &historyStats{}
+				keys = append(keys, k)
+			}
+			stats[k].add(record)
+		}
+		sort.Strings(keys)
+
+		fmt.Printf("\n%-32s %8s %5s %7s %7s %7s %12s\n", title, "Attempts", "PRs", "Merged", "Closed", "Merge%", "Cost/merge")
+		total := &historyStats{}
+		for _, k := range keys {
+			s := stats[k]
+			fmt.Printf("%-32s %8d %5d %7d %7d %7s %12s\n", truncateRunes(k, 32), s.Attempts, s.PRs, s.Merged, s.Closed, s.mergeRate(), s.costPerMerge())
+		}
+		for _, record := range records {
+			total.add(record)
+		}
+		fmt.Println(strings.Repeat("─", 84))
+		fmt.Printf("%-32s %8d %5d %7d %7d %7s %12s\n", "Total", total.Attempts, total.PRs, total.Merged, total.Closed, total.mergeRate(), total.costPerMerge())
+	}
+
+	group("Repository", func(r HistoryRecord) string { return r.Repo })
+	group("Model", func(r HistoryRecord) string { return r.Service + "/" + r.Model })
+	group("Month", func(r HistoryRecord) string { return r.Time.Format("2006-01") })
+
+	var cost float64
+	var changes ChangeStats
+	fixes := 0
+	for _, record := range records {
+		cost += record.Cost
+		if record.Changes != nil {
+			changes = changes.Add(*record.Changes)
+			fixes++
+		}
+	}
+	if fixes > 0 {
+		fmt.Printf("\nCode changed: %s in %d fix(es), %d lines per fix on average\n", changes, fixes, changes.Lines()/fixes)
+	}
+	fmt.Printf("\nTotal spend: %.4f %s\n\n", cost, currency)
+}
+
+// scoreBand groups calibrated confidence scores for the acceptance report
+func scoreBand(record HistoryRecord) string {
+	switch {
+	case record.Score == 0 && record.Confidence == "":
+		return "unknown" // Recorded before scores were kept
+	case record.Score >= 80:
+		return "80-100"
+	case record.Score >= 60:
+		return "60-79"
+	case record.Score >= 40:
+		return "40-59"
+	}
+	return "0-39"
+}
+
+// printAcceptanceReport shows the outcome of the bot's PRs: accepted (merged),
+// rejected (closed unmerged) and still open, by model and by confidence
+func printAcceptanceReport(records []HistoryRecord) {
+	// An updated PR counts once, with its latest revision
+	var prs []HistoryRecord
+	seen := make(map[string]int)
+	for _, record := range records {
+		if record.PRURL == "" {
+			continue
+		}
+		if i, ok := seen[record.PRURL]; ok {
+			prs[i] = record
+			continue
+		}
+		seen[record.PRURL] = len(prs)
+		prs = append(prs, record)
+	}
+
+	fmt.Println("\n╔════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                    ✅ Fix Acceptance                           ║")
+	fmt.Println("╚════════════════════════════════════════════════════════════════╝")
+
+	if len(prs) == 0 {
+		fmt.Printf("\nNo pull requests among %d attempt(s) yet.\n\n", len(records))
+		return
+	}
+	fmt.Printf("\n%d pull request(s) from %d attempt(s) between %s and %s\n", len(prs), len(records),
+		records[0].Time.Format("2006-01-02"), records[len(records)-1].Time.Format("2006-01-02"))
+
+	group := func(title string, key func(HistoryRecord) string) {
+		stats := make(map[string]*historyStats)
+		var keys []string
+		for _, record := range prs {
+			k := key(record)
+			if k == "" {
+				k = "unknown"
+			}
+			if stats[k] == nil {
+				stats[k] = &historyStats{}
+				keys = append(keys, k)
+			}
+			stats[k].add(record)
+		}
+		sort.Strings(keys)
+
+		fmt.Printf("\n%-32s %5s %7s %7s %7s %11s\n", title, "PRs", "Merged", "Closed", "Open", "Acceptance")
+		total := &historyStats{}
+		for _, k := range keys {
+			s := stats[k]
+			fmt.Printf("%-32s %5d %7d %7d %7d %11s\n", truncateRunes(k, 32), s.PRs, s.Merged, s.Closed, s.PRs-s.Merged-s.Closed, s.mergeRate())
+		}
+		for _, record := range prs {
+			total.add(record)
+		}
+		fmt.Println(strings.Repeat("─", 73))
+		fmt.Printf("%-32s %5d %7d %7d %7d %11s\n", "Total", total.PRs, total.Merged, total.Closed, total.PRs-total.Merged-total.Closed, total.mergeRate())
+	}
+
+	group("Model", func(r HistoryRecord) string { return r.Service + "/" + r.Model })
+	group("AI confidence", func(r HistoryRecord) string { return r.Confidence })
+	group("Confidence score", scoreBand)
+
+	fmt.Println("\nAcceptance is the share of merged PRs among those merged or closed; open PRs are not counted.")
+	fmt.Println()
+}
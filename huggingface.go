@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// huggingFaceRouterURL serves the chat completions of serverless
+	// inference, routing each model to a provider that hosts it
+	huggingFaceRouterURL = "https://router.huggingface.co/v1"
+
+	// huggingFaceEndpointsAPI manages dedicated Inference Endpoints
+	huggingFaceEndpointsAPI = "https://api.endpoints.huggingface.cloud/v2/endpoint"
+
+	// endpointModelPrefix marks a model that names a dedicated Inference
+	// Endpoint instead of a Hub model, as in "endpoint:acme/coder"
+	endpointModelPrefix = "endpoint:"
+
+	defaultHuggingFaceModel = "Qwen/Qwen2.5-Coder-32B-Instruct"
+)
+
+// HuggingFaceClient talks to Hugging Face inference: serverless models by
+// their Hub ID, the user's dedicated Inference Endpoints by name, or any
+// endpoint URL. All of them speak the OpenAI chat completions protocol.
+type HuggingFaceClient struct {
+	*CompatibleClient
+	resolveOnce sync.Once
+	resolveErr  error
+}
+
+// HuggingFaceEndpoint is a dedicated Inference Endpoint as the endpoints API
+// lists it
+type HuggingFaceEndpoint struct {
+	Name  string `json:"name"`
+	Model struct {
+		Repository string `json:"repository"`
+	} `json:"model"`
+	Status struct {
+		State string `json:"state"` // "running", "scaledToZero", "paused", "failed", ...
+		URL   string `json:"url"`
+	} `json:"status"`
+}
+
+// NewHuggingFaceClient returns a client for model, or for the endpoint at
+// endpointURL when it is set
+func NewHuggingFaceClient(apiKey, model, endpointURL string) *HuggingFaceClient {
+	if model == "" && endpointURL == "" {
+		model = defaultHuggingFaceModel
+	}
+	client := &HuggingFaceClient{CompatibleClient: &CompatibleClient{
+		service:  "huggingface",
+		name:     "Hugging Face",
+		apiKey:   apiKey,
+		model:    model,
+		baseURL:  huggingFaceRouterURL,
+		fallback: []string{defaultHuggingFaceModel, "Qwen/Qwen3-Coder-480B-A35B-Instruct", "meta-llama/Llama-3.3-70B-Instruct"},
+		client:   &http.Client{Timeout: 120 * time.Second},
+	}}
+	if endpointURL != "" {
+		client.baseURL = strings.TrimSuffix(strings.TrimSuffix(endpointURL, "/"), "/v1") + "/v1"
+		if client.model == "" {
+			// Text Generation Inference serves one model and ignores the name
+			client.model = "tgi"
+		}
+	}
+	return client
+}
+
+// resolve looks up the URL of a named dedicated endpoint before the first
+// request. Other models need no lookup.
+func (h *HuggingFaceClient) resolve(ctx context.Context) error {
+	h.resolveOnce.Do(func() {
+		name, ok := strings.CutPrefix(h.model, endpointModelPrefix)
+		if !ok {
+			return
+		}
+		namespace, endpoint, ok := strings.Cut(name, "/")
+		if !ok {
+			h.resolveErr = fmt.Errorf("Hugging Face endpoint %q must be given as %snamespace/name", name, endpointModelPrefix)
+			return
+		}
+
+		var found HuggingFaceEndpoint
+		if err := h.getJSON(ctx, fmt.Sprintf("%s/%s/%s", huggingFaceEndpointsAPI, namespace, endpoint), &found); err != nil {
+			h.resolveErr = fmt.Errorf("looking up Hugging Face endpoint %s: %w", name, err)
+			return
+		}
+		switch found.Status.State {
+		case "paused", "failed":
+			h.resolveErr = fmt.Errorf("Hugging Face endpoint %s is %s; resume it in the Inference Endpoints console", name, found.Status.State)
+			return
+		}
+		if found.Status.URL == "" {
+			h.resolveErr = fmt.Errorf("Hugging Face endpoint %s has no URL yet (state %s)", name, found.Status.State)
+			return
+		}
+
+		// vLLM and TGI endpoints both accept the name of the model they serve
+		h.baseURL = strings.TrimSuffix(found.Status.URL, "/") + "/v1"
+		if found.Model.Repository != "" {
+			h.model = found.Model.Repository
+		}
+	})
+	return h.resolveErr
+}
+
+func (h *HuggingFaceClient) AnalyzeAndFix(ctx context.Context, issue Issue, repoContext *RepoContext) (*Fix, error) {
+	if err := h.resolve(ctx); err != nil {
+		return nil, err
+	}
+	return h.CompatibleClient.AnalyzeAndFix(ctx, issue, repoContext)
+}
+
+// Complete sends a single system+user prompt and returns the raw model reply
+func (h *HuggingFaceClient) Complete(ctx context.Context, systemPrompt, prompt string) (string, error) {
+	if err := h.resolve(ctx); err != nil {
+		return "", err
+	}
+	return h.CompatibleClient.Complete(ctx, systemPrompt, prompt)
+}
+
+// CompleteJSON is like Complete but asks the API for valid JSON
+func (h *HuggingFaceClient) CompleteJSON(ctx context.Context, systemPrompt, prompt string) (string, error) {
+	return h.CompleteSchema(ctx, systemPrompt, prompt, nil)
+}
+
+// CompleteSchema is like Complete but constrains the reply to a JSON schema
+func (h *HuggingFaceClient) CompleteSchema(ctx context.Context, systemPrompt, prompt string, schema *JSONSchema) (string, error) {
+	if err := h.resolve(ctx); err != nil {
+		return "", err
+	}
+	return h.CompatibleClient.CompleteSchema(ctx, systemPrompt, prompt, schema)
+}
+
+// GetAvailableModels lists the dedicated endpoints of the user and their
+// organizations first, as "endpoint:namespace/name", then serverless models.
+// With an endpoint URL only the model it serves is listed.
+func (h *HuggingFaceClient) GetAvailableModels() ([]string, error) {
+	if h.baseURL != huggingFaceRouterURL {
+		return h.CompatibleClient.GetAvailableModels()
+	}
+
+	var models []string
+	for _, namespace := range h.namespaces() {
+		var endpoints struct {
+			Items []HuggingFaceEndpoint `json:"items"`
+		}
+		if err := h.getJSON(context.Background(), huggingFaceEndpointsAPI+"/"+namespace, &endpoints); err != nil {
+			continue
+		}
+		for _, endpoint := range endpoints.Items {
+			if endpoint.Status.State != "failed" {
+				models = append(models, endpointModelPrefix+namespace+"/"+endpoint.Name)
+			}
+		}
+	}
+
+	serverless, _ := h.CompatibleClient.GetAvailableModels()
+	return append(models, serverless...), nil
+}
+
+// namespaces are the user and the organizations the token belongs to, which
+// is where their endpoints live
+func (h *HuggingFaceClient) namespaces() []string {
+	var whoami struct {
+		Name string `json:"name"`
+		Orgs []struct {
+			Name string `json:"name"`
+		} `json:"orgs"`
+	}
+	if err := h.getJSON(context.Background(), "https://huggingface.co/api/whoami-v2", &whoami); err != nil || whoami.Name == "" {
+		return nil
+	}
+	namespaces := []string{whoami.Name}
+	for _, org := range whoami.Orgs {
+		namespaces = append(namespaces, org.Name)
+	}
+	return namespaces
+}
+
+func (h *HuggingFaceClient) getJSON(ctx context.Context, url string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+h.apiKey)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s - %s", resp.Status, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+//go:embed locales
+var localeFiles embed.FS
+
+// defaultLanguage is used for messages missing in the selected language
+const defaultLanguage = "en"
+
+// messages is the active catalog, message ID -> format string
+var messages = loadLocale(defaultLanguage)
+
+// loadLocale reads a built-in message bundle; unknown languages give nil
+func loadLocale(lang string) map[string]string {
+	data, err := localeFiles.ReadFile(path.Join("locales", lang+".json"))
+	if err != nil {
+		return nil
+	}
+
+	var bundle map[string]string
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil
+	}
+	return bundle
+}
+
+// availableLanguages lists the built-in bundles, e.g. "en, sv"
+func availableLanguages() string {
+	entries, _ := localeFiles.ReadDir("locales")
+	var langs []string
+	for _, entry := range entries {
+		langs = append(langs, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	return strings.Join(langs, ", ")
+}
+
+// detectLanguage picks the language from the POSIX locale variables,
+// e.g. LANG=sv_SE.UTF-8 gives "sv"
+func detectLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		lang := strings.ToLower(strings.FieldsFunc(value, func(r rune) bool {
+			return r == '_' || r == '.' || r == '@' || r == '-'
+		})[0])
+		if lang == "c" || lang == "posix" {
+			return defaultLanguage
+		}
+		return lang
+	}
+	return defaultLanguage
+}
+
+// setLanguage switches the CLI messages to lang (empty = from the locale).
+// Messages missing in a bundle fall back to English.
+func setLanguage(lang string) error {
+	explicit := lang != ""
+	if !explicit {
+		lang = detectLanguage()
+	}
+	lang = strings.ToLower(lang)
+
+	bundle := loadLocale(lang)
+	if bundle == nil {
+		messages = loadLocale(defaultLanguage)
+		if explicit {
+			return fmt.Errorf("unsupported language %q (available: %s)", lang, availableLanguages())
+		}
+		return nil
+	}
+
+	merged := loadLocale(defaultLanguage)
+	for id, message := range bundle {
+		merged[id] = message
+	}
+	messages = merged
+	return nil
+}
+
+// T returns the message for id in the current language. Messages with
+// arguments are format strings for fmt.Printf and fmt.Sprintf.
+func T(id string) string {
+	if message, ok := messages[id]; ok {
+		return message
+	}
+	return id
+}
+
+// isYes reports whether a prompt answer means yes, in English or the current language
+func isYes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer == "" {
+		return false
+	}
+	yes := T("answer.yes")
+	return answer == "yes" || answer == "y" || answer == yes || answer == string([]rune(yes)[:1])
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Defaults of bot_name and bot_email
+const (
+	defaultBotName  = "Mr. Code Fixer"
+	defaultBotEmail = "code-fixer@automated.bot"
+)
+
+// botMarker is hidden in every comment and pull request the bot writes, so
+// it recognizes its own work under any name
+const botMarker = "<!-- mr-code-fixer -->"
+
+// BotIdentity is who the bot commits and comments as
+type BotIdentity struct {
+	Name      string
+	Email     string
+	Signature string // Replaces the footer of comments and pull requests, markdown
+	Login     string // Account the token belongs to, set by identifyBotAccount
+}
+
+// bot is the identity of this run, set from the config by parseFlags
+var bot = BotIdentity{Name: defaultBotName, Email: defaultBotEmail}
+
+// configureIdentity sets the bot's identity from bot_name, bot_email and
+// bot_signature, with the defaults for empty settings
+func configureIdentity(config Config) {
+	bot = BotIdentity{Name: defaultBotName, Email: defaultBotEmail, Signature: strings.TrimSpace(config.BotSignature)}
+	if name := strings.TrimSpace(config.BotName); name != "" {
+		bot.Name = name
+	}
+	if email := strings.TrimSpace(config.BotEmail); email != "" {
+		bot.Email = email
+	}
+}
+
+// identifyBotAccount looks up the account the bot posts as. Only comments
+// and pull requests by that account are taken for the bot's own, since anyone
+// can paste the marker into theirs.
+func identifyBotAccount(ctx context.Context, provider HostingProvider) error {
+	login, err := provider.GetAuthenticatedUser(ctx)
+	if err != nil {
+		return fmt.Errorf("could not determine the account the token belongs to: %w", err)
+	}
+	bot.Login = login
+	return nil
+}
+
+// isBotAccount reports whether a login is the account the bot posts as
+func isBotAccount(login string) bool {
+	return bot.Login != "" && strings.EqualFold(login, bot.Login)
+}
+
+// signature is the footer of the bot's comments: bot_signature, or the
+// bot's name, followed by botMarker
+func (b BotIdentity) signature() string {
+	return b.footer(b.Name)
+}
+
+// footer is a footer that says more than the bot's name, such as how a
+// comment came about. A custom signature replaces it.
+func (b BotIdentity) footer(text string) string {
+	if b.Signature != "" {
+		return b.Signature + "\n" + botMarker
+	}
+	return "<sub>🤖 " + text + "</sub>\n" + botMarker
+}
+
+// link is the bot's name, linked to the project for the default name
+func (b BotIdentity) link() string {
+	if b.Name == defaultBotName {
+		return "[" + defaultBotName + "](https://github.com/pefman/Mr-Code-Fixer)"
+	}
+	return b.Name
+}
+
+// isBotText reports whether a comment or pull request description is the
+// bot's: it has botMarker or, if written before the marker, the default name
+func isBotText(text string) bool {
+	return strings.Contains(text, botMarker) || strings.Contains(text, defaultBotName)
+}
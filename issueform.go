@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// minFormFields is how many answered sections make a body count as an issue form
+const minFormFields = 2
+
+// formHeading matches the "### Label" headings GitHub issue forms (and Gitea
+// issue templates) render each field as; "##" is accepted for hand-written templates
+var formHeading = regexp.MustCompile(`^#{2,3}\s+(.+?)\s*#*$`)
+
+// noResponse are the placeholders forms put in fields left empty
+var noResponse = map[string]bool{
+	"_no response_": true,
+	"no response":   true,
+	"n/a":           true,
+	"none":          true,
+}
+
+// IssueField is one labeled section of a structured issue
+type IssueField struct {
+	Label string
+	Value string
+}
+
+// parseIssueForm splits an issue-form body into its answered sections, with a
+// short intro before the first heading kept as "Description". It returns nil
+// for free text, i.e. when fewer than minFormFields sections have an answer or
+// substantial text comes before the first heading.
+func parseIssueForm(body string) []IssueField {
+	var fields []IssueField
+	var preamble, current strings.Builder
+	inCode := false
+
+	flush := func() {
+		if len(fields) > 0 {
+			fields[len(fields)-1].Value = strings.TrimSpace(current.String())
+		}
+		current.Reset()
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+		}
+		if match := formHeading.FindStringSubmatch(line); match != nil && !inCode {
+			flush()
+			fields = append(fields, IssueField{Label: strings.TrimSpace(match[1])})
+			continue
+		}
+		if len(fields) == 0 {
+			preamble.WriteString(line + "\n")
+		} else {
+			current.WriteString(line + "\n")
+		}
+	}
+	flush()
+
+	// A short intro line is fine, a whole free-text report with a heading or two is not
+	if len(strings.TrimSpace(preamble.String())) > 200 {
+		return nil
+	}
+
+	var answered []IssueField
+	for _, field := range fields {
+		if field.Value == "" || noResponse[strings.ToLower(field.Value)] {
+			continue
+		}
+		answered = append(answered, field)
+	}
+	if len(answered) < minFormFields {
+		return nil
+	}
+	if intro := strings.TrimSpace(preamble.String()); intro != "" {
+		answered = append([]IssueField{{Label: "Description", Value: intro}}, answered...)
+	}
+	return answered
+}
+
+// formatIssueForm renders the answered fields as labeled sections for the prompt
+func formatIssueForm(fields []IssueField) string {
+	var sections strings.Builder
+	for _, field := range fields {
+		sections.WriteString(fmt.Sprintf("**%s:**\n%s\n\n", field.Label, field.Value))
+	}
+	return sections.String()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Timing of repository locks
+const (
+	lockPollInterval   = 2 * time.Second
+	defaultLockTimeout = 10 * time.Minute
+)
+
+// RepoLock is an instance's claim on a repository in the shared work
+// directory, so a daemon and a manual run never work on the same repository
+// at once. It is an operating system lock on the lock file (flock, or
+// LockFileEx on Windows), which the system drops when the process dies, so a
+// lock is never left behind. A nil RepoLock holds nothing.
+type RepoLock struct {
+	file *os.File
+}
+
+func repoLockPath(workDir, owner, repo string) string {
+	return filepath.Join(workDir, owner, repo+".lock")
+}
+
+// lockRepo takes the lock of a repository, waiting up to timeout for another
+// instance to release it. The lock file stays in place; it only tells waiting
+// instances who holds the lock.
+func lockRepo(ctx context.Context, workDir, owner, repo string, timeout time.Duration) (*RepoLock, error) {
+	path := repoLockPath(workDir, owner, repo)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	holder := fmt.Sprintf("pid %d on %s since %s", os.Getpid(), host, time.Now().Format(time.RFC3339))
+	deadline := time.Now().Add(timeout)
+	waiting := false
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open lock file: %w", err)
+		}
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			if err := f.Truncate(0); err == nil {
+				f.WriteAt([]byte(holder+"\n"), 0)
+			}
+			return &RepoLock{file: f}, nil
+		}
+		f.Close()
+
+		current := "another instance"
+		if data, err := os.ReadFile(path); err == nil && len(data) > 0 {
+			current = strings.TrimSpace(string(data))
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s/%s is in use by %s", owner, repo, current)
+		}
+		if !waiting {
+			fmt.Printf(T("lock.waiting"), owner, repo, current)
+			waiting = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// Release gives the repository back to other instances. Closing the file
+// drops the lock.
+func (l *RepoLock) Release() {
+	if l == nil {
+		return
+	}
+	l.file.Close()
+}
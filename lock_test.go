@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLockRepo(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	lock, err := lockRepo(ctx, dir, "owner", "repo", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lockRepo(ctx, dir, "owner", "repo", 0); err == nil {
+		t.Fatal("second lockRepo while the lock is held succeeded")
+	}
+	other, err := lockRepo(ctx, dir, "owner", "other", 0)
+	if err != nil {
+		t.Fatalf("lockRepo of another repository: %v", err)
+	}
+	other.Release()
+
+	lock.Release()
+	lock, err = lockRepo(ctx, dir, "owner", "repo", 0)
+	if err != nil {
+		t.Fatalf("lockRepo after Release: %v", err)
+	}
+	lock.Release()
+}
@@ -2,58 +2,264 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
+	"unicode"
 )
 
 const Version = "v1.3.5"
 
 type Config struct {
-	RepoOwner    string `json:"repo_owner"`
-	RepoName     string `json:"repo_name"`
-	RepoURL      string `json:"repo_url"`
-	GithubToken  string `json:"github_token"`
-	AIService    string `json:"ai_service"`
-	AIAPIKey     string `json:"ai_api_key"`
-	AIModel      string `json:"ai_model"`
-	OllamaURL    string `json:"ollama_url"`
-	WorkDir      string `json:"work_dir"`
-}
-
-func parseRepoURL(url string) (owner, repo string, err error) {
+	RepoOwner           string                `json:"repo_owner"`
+	RepoName            string                `json:"repo_name"`
+	RepoURL             string                `json:"repo_url"`
+	GithubToken         string                `json:"github_token"`
+	AIService           string                `json:"ai_service"`
+	AIAPIKey            string                `json:"ai_api_key"`
+	AIModel             string                `json:"ai_model"`
+	OllamaURL           string                `json:"ollama_url"`
+	WorkDir             string                `json:"work_dir"`
+	Provider            string                `json:"provider"`
+	ProviderURL         string                `json:"provider_url"`
+	BranchTemplate      string                `json:"branch_template"`
+	SelfReview          bool                  `json:"self_review"`
+	ReviewRetries       int                   `json:"review_retries"`
+	DashboardAddr       string                `json:"dashboard_addr"`
+	PollInterval        string                `json:"poll_interval"`
+	Preflight           bool                  `json:"preflight"`
+	Reviewers           []string              `json:"reviewers"`
+	Assignees           []string              `json:"assignees"`
+	Memory              bool                  `json:"memory"`
+	MaxIssueBodyChars   int                   `json:"max_issue_body_chars"`
+	VisionModel         string                `json:"vision_model"`
+	ProtectedPaths      []string              `json:"protected_paths"`
+	ProtectedPathPolicy string                `json:"protected_path_policy"`
+	SyntaxCheck         bool                  `json:"syntax_check"`
+	NotifyWebhook       string                `json:"notify_webhook"`
+	NoConfigWrite       bool                  `json:"no_config_write,omitempty"`
+	Repos               []string              `json:"repos,omitempty"`
+	DuplicateCheck      bool                  `json:"duplicate_check"`
+	DuplicateThreshold  float64               `json:"duplicate_threshold"`
+	CloseDuplicates     bool                  `json:"close_duplicates"`
+	GenerateTests       bool                  `json:"generate_tests"`
+	ContextBudget       int                   `json:"context_budget"`
+	SummaryModel        string                `json:"summary_model"`
+	TUI                 bool                  `json:"tui"`
+	OllamaNumCtx        int                   `json:"ollama_num_ctx"`
+	OllamaTemperature   float64               `json:"ollama_temperature"`
+	OllamaTopP          float64               `json:"ollama_top_p"`
+	OllamaKeepAlive     string                `json:"ollama_keep_alive"`
+	OllamaJSON          bool                  `json:"ollama_json"`
+	Milestone           string                `json:"milestone"`
+	ProjectStatus       string                `json:"project_status"`
+	Currency            string                `json:"currency"`
+	CurrencyRate        float64               `json:"currency_rate,omitempty"`
+	PricingFile         string                `json:"pricing_file,omitempty"`
+	Pricing             map[string]ModelPrice `json:"pricing,omitempty"`
+	SetupCommands       []string              `json:"setup_commands,omitempty"`
+	TestEnv             []string              `json:"test_env,omitempty"`
+	CommentCommands     bool                  `json:"comment_commands"`
+	CommandUsers        []string              `json:"command_users,omitempty"`
+	RequireCommand      bool                  `json:"require_command"`
+	AITimeout           string                `json:"ai_timeout,omitempty"`
+	GithubTimeout       string                `json:"github_timeout,omitempty"`
+	RegionEdits         bool                  `json:"region_edits"`
+	CodeOwners          bool                  `json:"codeowners"`
+	GroupIssues         bool                  `json:"group_issues"`
+	UseGhAuth           bool                  `json:"use_gh_auth"`
+	AIRPM               int                   `json:"ai_rpm"`
+	AITPM               int                   `json:"ai_tpm"`
+	AIRetries           int                   `json:"ai_retries"`
+	FeaturePlans        bool                  `json:"feature_plans"`
+	FeatureLabels       []string              `json:"feature_labels"`
+	ExportPatch         string                `json:"export_patch"`
+	PatchOnly           bool                  `json:"patch_only"`
+	AssigneeFilter      string                `json:"assignee"`
+	SelfAssign          bool                  `json:"self_assign"`
+	SelfAssignUser      string                `json:"self_assign_user"`
+	NoCache             bool                  `json:"no_cache,omitempty"`
+	CacheTTL            string                `json:"cache_ttl,omitempty"`
+	GitProtocol         string                `json:"git_protocol,omitempty"`
+	SSHKey              string                `json:"ssh_key,omitempty"`
+	CloseMinScore       int                   `json:"close_min_score"`
+	ClosePolicy         string                `json:"close_policy"`
+	CommentTemplates    string                `json:"comment_templates,omitempty"`
+	CommentLanguage     string                `json:"comment_language,omitempty"`
+	HTTPProxy           string                `json:"http_proxy,omitempty"`
+	NoProxy             []string              `json:"no_proxy,omitempty"`
+	CABundle            string                `json:"ca_bundle,omitempty"`
+	ClientCert          string                `json:"client_cert,omitempty"`
+	ClientKey           string                `json:"client_key,omitempty"`
+	ContextMaxBytes     int                   `json:"context_max_bytes"`
+	IssueSort           string                `json:"sort,omitempty"`
+	CommitFormat        string                `json:"commit_format"`
+	SimpleModel         string                `json:"simple_model"`
+	ComplexModel        string                `json:"complex_model"`
+	Rebase              bool                  `json:"rebase"`
+	ResolveConflicts    bool                  `json:"resolve_conflicts"`
+	Lang                string                `json:"lang,omitempty"`
+	AuditLog            string                `json:"audit_log,omitempty"` // JSONL log of side effects, "off" disables it
+	MaxDiffLines        int                   `json:"max_diff_lines"`
+	MaxDiffFiles        int                   `json:"max_diff_files"`
+	DiffLimitPolicy     string                `json:"diff_limit_policy"`
+	MaxReferences       int                   `json:"max_references"`
+	OllamaPull          string                `json:"ollama_pull"`
+	Label               string                `json:"label,omitempty"`
+	Org                 string                `json:"org,omitempty"`
+	OrgTopic            string                `json:"org_topic,omitempty"`
+	OrgLanguage         string                `json:"org_language,omitempty"`
+	PlanFirst           bool                  `json:"plan_first"`
+	TranslateIssues     bool                  `json:"translate_issues"`
+	StructuredOutput    string                `json:"structured_output"`
+	ScaffoldEmptyRepos  string                `json:"scaffold_empty_repos"`
+	Reproduce           bool                  `json:"reproduce"`
+	LockTimeout         string                `json:"lock_timeout,omitempty"`
+	KeepClones          bool                  `json:"keep_clones"`
+	SystemPrompt        string                `json:"system_prompt,omitempty"`
+	Instructions        []string              `json:"instructions,omitempty"`
+	ThreadReplies       bool                  `json:"thread_replies"`
+	OfflineAI           bool                  `json:"offline_ai"`
+	AllowHosts          []string              `json:"allow_hosts,omitempty"`
+	BodyOverflow        string                `json:"body_overflow"`
+	WaitForChecks       bool                  `json:"wait_for_checks"`
+	ChecksTimeout       string                `json:"checks_timeout,omitempty"`
+	LabelPriorities     map[string]int        `json:"label_priorities,omitempty"`
+	TokenCheck          bool                  `json:"token_check"`
+	BedrockRegion       string                `json:"bedrock_region,omitempty"`
+	AWSProfile          string                `json:"aws_profile,omitempty"`
+	UpdatePRs           bool                  `json:"update_prs"`
+	CodeMap             bool                  `json:"code_map"`
+	SkipHandledCheck    bool                  `json:"skip_handled_check"`
+	HFEndpointURL       string                `json:"hf_endpoint_url,omitempty"`
+	TrustedAuthors      []string              `json:"trusted_authors,omitempty"`
+	BlockedAuthors      []string              `json:"blocked_authors,omitempty"`
+	TrustedOrg          string                `json:"trusted_org,omitempty"`
+
+	ReuseClones            bool     `json:"reuse_clones"`
+	ContextMaxFiles        int      `json:"context_max_files"`
+	ContextMaxFileKB       int      `json:"context_max_file_kb"`
+	ContextMaxCharsPerFile int      `json:"context_max_chars_per_file"`
+	EmailTo                []string `json:"email_to,omitempty"`
+	EmailFrom              string   `json:"email_from,omitempty"`
+	SMTPHost               string   `json:"smtp_host,omitempty"`
+	SMTPPort               int      `json:"smtp_port"`
+	SMTPUsername           string   `json:"smtp_username,omitempty"`
+	SMTPPassword           string   `json:"smtp_password,omitempty"`
+	EmailInterval          string   `json:"email_interval,omitempty"`
+	ChangelogEntry         bool     `json:"changelog_entry"`
+	ClarifyLocally         bool     `json:"clarify_locally"`
+	RunGitHooks            bool     `json:"run_git_hooks"`
+	ParallelFiles          bool     `json:"parallel_files"`
+	StyleGuides            bool     `json:"style_guides"`
+	FormatCode             bool     `json:"format_code"`
+	MockFixFile            string   `json:"mock_fix_file,omitempty"`
+	BotName                string   `json:"bot_name,omitempty"`
+	BotEmail               string   `json:"bot_email,omitempty"`
+	BotSignature           string   `json:"bot_signature,omitempty"`
+	IssueQuery             string   `json:"query,omitempty"`
+	QuestionReminder       string   `json:"question_reminder,omitempty"`
+	QuestionClose          string   `json:"question_close,omitempty"`
+	AllowedCommands        []string `json:"allowed_commands,omitempty"`
+	VerifierModel          string   `json:"verifier_model,omitempty"`
+	VerifierService        string   `json:"verifier_service,omitempty"`
+	VerifierAPIKey         string   `json:"verifier_api_key,omitempty"`
+	APIAddr                string   `json:"api_addr,omitempty"`
+	APIToken               string   `json:"api_token,omitempty"`
+}
+
+func parseRepoURL(repoURL string) (owner, repo string, err error) {
 	// Remove .git suffix if present
-	url = strings.TrimSuffix(url, ".git")
+	repoURL = strings.TrimSuffix(repoURL, ".git")
 	
-	// Handle various GitHub URL formats:
+	// Handle various URL formats:
 	// https://github.com/owner/repo
 	// git@github.com:owner/repo
 	// github.com/owner/repo
+	// https://gitea.example.com:3000/owner/repo
 	
-	if strings.Contains(url, "github.com") {
-		// Extract owner/repo part
-		parts := strings.Split(url, "github.com")
-		if len(parts) < 2 {
-			return "", "", fmt.Errorf("invalid GitHub URL format")
-		}
-		
-		path := strings.TrimPrefix(parts[1], "/")
-		path = strings.TrimPrefix(path, ":")
-		
-		pathParts := strings.Split(path, "/")
-		if len(pathParts) < 2 {
-			return "", "", fmt.Errorf("invalid repository path")
+	var path string
+	if strings.Contains(repoURL, "://") {
+		u, err := url.Parse(repoURL)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid repository URL: %w", err)
 		}
-		
-		return pathParts[0], pathParts[1], nil
+		path = u.Path
+	} else if at := strings.Index(repoURL, "@"); at != -1 && strings.Contains(repoURL, ":") {
+		// scp-like syntax: git@host:owner/repo
+		path = repoURL[strings.Index(repoURL[at:], ":")+at+1:]
+	} else if slash := strings.Index(repoURL, "/"); slash != -1 {
+		// host/owner/repo
+		path = repoURL[slash:]
+	} else {
+		return "", "", fmt.Errorf("invalid repository URL format")
+	}
+	
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(pathParts) < 2 || pathParts[0] == "" || pathParts[1] == "" {
+		return "", "", fmt.Errorf("invalid repository path")
 	}
 	
-	return "", "", fmt.Errorf("only GitHub repositories are supported")
+	return pathParts[0], pathParts[1], nil
+}
+
+// repoHostURL returns the scheme and host of a repository URL (e.g. https://gitea.example.com)
+func repoHostURL(repoURL string) string {
+	if !strings.Contains(repoURL, "://") {
+		// scp-like and bare host URLs are assumed to be served over https
+		host := repoURL
+		if at := strings.Index(host, "@"); at != -1 {
+			host = host[at+1:]
+		}
+		host = strings.FieldsFunc(host, func(r rune) bool { return r == ':' || r == '/' })[0]
+		return "https://" + host
+	}
+
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// setRepoHost points the provider at the host of a repository URL: github for
+// github.com, gitea with the host as provider_url for any other host. An
+// explicit provider_url for the same host is kept.
+func setRepoHost(config *Config, repoURL string) {
+	host := repoHostURL(repoURL)
+	u, err := url.Parse(host)
+	if host == "" || err != nil {
+		return
+	}
+	if strings.EqualFold(u.Hostname(), "github.com") {
+		config.Provider = "github"
+		config.ProviderURL = ""
+		return
+	}
+	config.Provider = "gitea"
+	if current, err := url.Parse(config.ProviderURL); err != nil || !strings.EqualFold(current.Host, u.Host) {
+		config.ProviderURL = host
+	}
+}
+
+// isRepoURL distinguishes full repository URLs from the owner/repo shorthand
+func isRepoURL(input string) bool {
+	if strings.Contains(input, "://") || strings.Contains(input, "@") {
+		return true
+	}
+	return strings.Count(input, "/") >= 2 && strings.Contains(strings.Split(input, "/")[0], ".")
 }
 
 func getConfigPath() string {
@@ -61,6 +267,15 @@ func getConfigPath() string {
 	return filepath.Join(homeDir, ".mr-code-fixer.json")
 }
 
+// getDataDir is where the bot keeps state between runs (memory, workspace)
+func getDataDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ".mr-code-fixer"
+	}
+	return filepath.Join(homeDir, ".mr-code-fixer")
+}
+
 func getDefaultWorkDir() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -71,10 +286,49 @@ func getDefaultWorkDir() string {
 
 func loadConfig() Config {
 	config := Config{
-		AIService: "groq",
-		AIModel:   "llama-3.3-70b-versatile",
-		OllamaURL: "http://localhost:11434",
-		WorkDir:   getDefaultWorkDir(),
+		AIService:           "groq",
+		AIModel:             "llama-3.3-70b-versatile",
+		OllamaURL:           "http://localhost:11434",
+		WorkDir:             getDefaultWorkDir(),
+		Provider:            "github",
+		BranchTemplate:      "fix/{number}-{slug}",
+		ReviewRetries:       1,
+		PollInterval:        "10m",
+		Preflight:           true,
+		MaxIssueBodyChars:   8000,
+		ProtectedPaths:      defaultProtectedPaths,
+		ProtectedPathPolicy: "reject",
+		SyntaxCheck:         true,
+		DuplicateThreshold:  0.6,
+		TUI:                 true,
+		OllamaNumCtx:        16384,
+		OllamaTemperature:   0.2,
+		OllamaJSON:          true,
+		Currency:            "SEK",
+		CommentCommands:     true,
+		CodeOwners:          true,
+		AIRetries:           defaultAIRetries,
+		FeatureLabels:       defaultFeatureLabels,
+		CloseMinScore:       defaultCloseMinScore,
+		ClosePolicy:         closeOnMerge,
+		ContextMaxBytes:     defaultContextMaxBytes,
+		CommitFormat:        commitFormatPlain,
+		MaxDiffLines:        defaultMaxDiffLines,
+		MaxDiffFiles:        defaultMaxDiffFiles,
+		DiffLimitPolicy:     diffLimitShrink,
+		MaxReferences:       defaultMaxReferences,
+		OllamaPull:          ollamaPullAsk,
+		StructuredOutput:    structuredAuto,
+		ScaffoldEmptyRepos:  scaffoldNever,
+		BodyOverflow:        overflowComments,
+		TokenCheck:          true,
+
+		ContextMaxFiles:        defaultContextMaxFiles,
+		ContextMaxFileKB:       defaultContextMaxFileKB,
+		ContextMaxCharsPerFile: defaultContextMaxCharsPerFile,
+		SMTPPort:               587,
+		ClarifyLocally:         true,
+		StyleGuides:            true,
 	}
 
 	configPath := getConfigPath()
@@ -83,11 +337,19 @@ func loadConfig() Config {
 		json.Unmarshal(data, &config)
 	}
 
+	if err := applyEnvConfig(&config); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
 	return config
 }
 
 func saveConfig(config Config) error {
 	configPath := getConfigPath()
+	// Tokens from the GitHub CLI are looked up on every run, never stored
+	if config.UseGhAuth {
+		config.GithubToken = ""
+	}
 	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return err
@@ -95,6 +357,34 @@ func saveConfig(config Config) error {
 	return os.WriteFile(configPath, data, 0600)
 }
 
+// saveConfigSettings writes the given settings, by JSON name, into the config
+// file and leaves the rest of it alone. Unlike saveConfig it never writes
+// values that came from flags or the environment, such as tokens.
+func saveConfigSettings(settings map[string]interface{}) error {
+	configPath := getConfigPath()
+	file := make(map[string]json.RawMessage)
+	if data, err := os.ReadFile(configPath); err == nil {
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("reading %s: %w", configPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	for name, value := range settings {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		file[name] = raw
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(configPath, data, 0600)
+}
+
 func prompt(label string, defaultValue string) string {
 	reader := bufio.NewReader(os.Stdin)
 	if defaultValue != "" {
@@ -180,6 +470,9 @@ func selectIssue(issues []Issue) *Issue {
 
 	for i, issue := range issues {
 		fmt.Printf("  \033[1;36m%d.\033[0m \033[1m#%d\033[0m - %s\n", i+1, issue.Number, issue.Title)
+		if signals := issueSignals(issue); signals != "" {
+			fmt.Printf("     \033[33m%s\033[0m\n", signals)
+		}
 		if len(issue.Body) > 80 {
 			fmt.Printf("     \033[90m%s...\033[0m\n", issue.Body[:80])
 		} else if issue.Body != "" {
@@ -189,12 +482,12 @@ func selectIssue(issues []Issue) *Issue {
 	}
 
 	for {
-		fmt.Printf("\n\033[1m→\033[0m Select issue to fix (\033[36m1-%d\033[0m, or \033[33m0\033[0m to fix all) [\033[32m1\033[0m]: ", len(issues))
+		fmt.Printf(T("select.prompt"), len(issues))
 		choice := prompt("", "1")
 		
 		num, err := strconv.Atoi(choice)
 		if err != nil || num < 0 || num > len(issues) {
-			fmt.Println("\033[31m✗\033[0m Invalid selection. Please try again.")
+			fmt.Println(T("select.invalid"))
 			continue
 		}
 
@@ -214,6 +507,9 @@ func selectIssueWithSettings(issues []Issue, config *Config, analytics *SessionA
 	fmt.Println()
 	for i, issue := range issues {
 		fmt.Printf("  \033[1;36m%d.\033[0m \033[1m#%d\033[0m - %s\n", i+1, issue.Number, issue.Title)
+		if signals := issueSignals(issue); signals != "" {
+			fmt.Printf("     \033[33m%s\033[0m\n", signals)
+		}
 		if len(issue.Body) > 80 {
 			fmt.Printf("     \033[90m%s...\033[0m\n", issue.Body[:80])
 		} else if issue.Body != "" {
@@ -223,24 +519,24 @@ func selectIssueWithSettings(issues []Issue, config *Config, analytics *SessionA
 	}
 
 	for {
-		fmt.Printf("\n\033[1m→\033[0m Select issue (\033[36m1-%d\033[0m, \033[33m0\033[0m=fix all, \033[35mS\033[0m=settings, \033[90mQ\033[0m=quit) [\033[32m1\033[0m]: ", len(issues))
+		fmt.Printf(T("select.prompt_settings"), len(issues))
 		choice := strings.ToLower(strings.TrimSpace(prompt("", "1")))
 		
 		// Handle special commands
 		if choice == "s" {
 			*config = interactiveSetup()
-			fmt.Println("\n\033[32m✓\033[0m Settings updated. Please restart the application.")
+			fmt.Println(T("run.settings_updated"))
 			return nil
 		}
 		
 		if choice == "q" {
-			fmt.Println("Exiting...")
+			fmt.Println(T("run.exiting"))
 			return nil
 		}
 		
 		num, err := strconv.Atoi(choice)
 		if err != nil || num < 0 || num > len(issues) {
-			fmt.Println("\033[31m✗\033[0m Invalid selection. Please try again.")
+			fmt.Println(T("select.invalid"))
 			continue
 		}
 
@@ -254,127 +550,352 @@ func selectIssueWithSettings(issues []Issue, config *Config, analytics *SessionA
 }
 
 func interactiveSetup() Config {
-	fmt.Println("=== Mr. Code Fixer - Interactive Setup ===")
+	fmt.Println(T("setup.title"))
 	fmt.Println()
 	
 	config := loadConfig()
 
-	fmt.Println("GitHub Repository:")
-	repoInput := prompt("Repository URL or owner/repo", config.RepoURL)
+	fmt.Println(T("setup.repository"))
+	repoInput := prompt(T("setup.repo_url"), config.RepoURL)
 	
 	// Try to parse as URL first, then fall back to owner/repo format
-	if strings.Contains(repoInput, "github.com") || strings.Contains(repoInput, "/") {
-		if strings.Contains(repoInput, "github.com") {
+	if isRepoURL(repoInput) || strings.Contains(repoInput, "/") {
+		if isRepoURL(repoInput) {
 			// It's a URL
 			config.RepoURL = repoInput
 			owner, repo, err := parseRepoURL(repoInput)
 			if err != nil {
 				fmt.Printf("Warning: Could not parse URL: %v\n", err)
-				config.RepoOwner = prompt("Repository Owner", config.RepoOwner)
-				config.RepoName = prompt("Repository Name", config.RepoName)
+				config.RepoOwner = prompt(T("setup.owner"), config.RepoOwner)
+				config.RepoName = prompt(T("setup.name"), config.RepoName)
 			} else {
 				config.RepoOwner = owner
 				config.RepoName = repo
 			}
+			
+			// Self-hosted instances (Gitea/Forgejo) need the provider configured
+			if host := repoHostURL(repoInput); host != "" && host != "https://github.com" {
+				config.Provider = promptWithOptions("Hosting provider (1=github, 2=gitea)", []string{"github", "gitea"}, "gitea")
+				config.ProviderURL = host
+			} else {
+				config.Provider = "github"
+				config.ProviderURL = ""
+			}
 		} else {
 			// It's owner/repo format
 			parts := strings.Split(repoInput, "/")
 			if len(parts) == 2 {
 				config.RepoOwner = parts[0]
 				config.RepoName = parts[1]
-				config.RepoURL = fmt.Sprintf("https://github.com/%s/%s", parts[0], parts[1])
+				config.RepoURL = fmt.Sprintf("%s/%s/%s", providerBaseURL(config), parts[0], parts[1])
 			} else {
-				config.RepoOwner = prompt("Repository Owner", config.RepoOwner)
-				config.RepoName = prompt("Repository Name", config.RepoName)
+				config.RepoOwner = prompt(T("setup.owner"), config.RepoOwner)
+				config.RepoName = prompt(T("setup.name"), config.RepoName)
 			}
 		}
 	} else {
-		config.RepoOwner = prompt("Repository Owner", config.RepoOwner)
-		config.RepoName = prompt("Repository Name", config.RepoName)
+		config.RepoOwner = prompt(T("setup.owner"), config.RepoOwner)
+		config.RepoName = prompt(T("setup.name"), config.RepoName)
 	}
 	
-	config.GithubToken = promptSecret("GitHub Token", config.GithubToken)
+	if config.Provider == "gitea" {
+		config.GithubToken = promptSecret("Gitea Access Token", config.GithubToken)
+	} else if token, err := ghAuthToken(ghHost(config)); err == nil && (config.GithubToken == "" || config.UseGhAuth) {
+		// Already logged in with the GitHub CLI: no need for a separate token
+		answer := prompt(T("setup.use_gh"), T("answer.yes"))
+		config.UseGhAuth = isYes(answer)
+		if config.UseGhAuth {
+			config.GithubToken = token
+		} else {
+			config.GithubToken = promptSecret("GitHub Token", "")
+		}
+	} else {
+		config.GithubToken = promptSecret("GitHub Token", config.GithubToken)
+	}
 
-	fmt.Println("\nAI Service Settings:")
-	config.AIService = prompt("AI Service (chatgpt/grok/ollama)", config.AIService)
+	fmt.Println(T("setup.ai"))
+	config.AIService = prompt(T("setup.ai_service"), config.AIService)
 	
 	if config.AIService == "chatgpt" || config.AIService == "openai" {
 		config.AIAPIKey = promptSecret("OpenAI API Key", config.AIAPIKey)
 		
 		// Fetch available models
-		fmt.Println("Fetching available models...")
+		fmt.Println(T("setup.fetching_models"))
 		client := NewOpenAIClient(config.AIAPIKey, "")
 		models, err := client.GetAvailableModels()
 		if err == nil && len(models) > 0 {
-			fmt.Println("Available models:")
+			fmt.Println(T("setup.available_models"))
 			for i, model := range models {
 				fmt.Printf("  %d. %s\n", i+1, model)
 			}
-			config.AIModel = promptWithOptions("Select model", models, config.AIModel)
+			config.AIModel = promptWithOptions(T("setup.select_model"), models, config.AIModel)
 		} else {
-			config.AIModel = prompt("AI Model", config.AIModel)
+			config.AIModel = prompt(T("setup.model"), config.AIModel)
 		}
 	} else if config.AIService == "grok" {
 		config.AIAPIKey = promptSecret("Grok API Key", config.AIAPIKey)
 		
 		// Fetch available models
-		fmt.Println("Fetching available models...")
+		fmt.Println(T("setup.fetching_models"))
 		client := NewXAIClient(config.AIAPIKey, "")
 		models, err := client.GetAvailableModels()
 		if err == nil && len(models) > 0 {
-			fmt.Println("Available models:")
+			fmt.Println(T("setup.available_models"))
 			for i, model := range models {
 				fmt.Printf("  %d. %s\n", i+1, model)
 			}
-			config.AIModel = promptWithOptions("Select model", models, config.AIModel)
+			config.AIModel = promptWithOptions(T("setup.select_model"), models, config.AIModel)
 		} else {
 			config.AIModel = prompt("AI Model (grok-beta)", "grok-beta")
 		}
+	} else if config.AIService == "mistral" || config.AIService == "deepseek" {
+		var client *CompatibleClient
+		if config.AIService == "mistral" {
+			config.AIAPIKey = promptSecret("Mistral API Key", config.AIAPIKey)
+			client = NewMistralClient(config.AIAPIKey, "")
+		} else {
+			config.AIAPIKey = promptSecret("DeepSeek API Key", config.AIAPIKey)
+			client = NewDeepSeekClient(config.AIAPIKey, "")
+		}
+		
+		// Fetch available models
+		fmt.Println(T("setup.fetching_models"))
+		models, err := client.GetAvailableModels()
+		if err == nil && len(models) > 0 {
+			fmt.Println(T("setup.available_models"))
+			for i, model := range models {
+				fmt.Printf("  %d. %s\n", i+1, model)
+			}
+			config.AIModel = promptWithOptions(T("setup.select_model"), models, config.AIModel)
+		} else {
+			config.AIModel = prompt(T("setup.model"), client.model)
+		}
+	} else if config.AIService == "huggingface" {
+		config.AIAPIKey = promptSecret("Hugging Face Token", config.AIAPIKey)
+		config.HFEndpointURL = prompt("Inference Endpoint URL (empty for serverless models and your deployed endpoints)", config.HFEndpointURL)
+		
+		// Fetch available models
+		fmt.Println(T("setup.fetching_models"))
+		client := NewHuggingFaceClient(config.AIAPIKey, "", config.HFEndpointURL)
+		models, err := client.GetAvailableModels()
+		if err == nil && len(models) > 0 {
+			fmt.Println(T("setup.available_models"))
+			for i, model := range models {
+				fmt.Printf("  %d. %s\n", i+1, model)
+			}
+			config.AIModel = promptWithOptions(T("setup.select_model"), models, config.AIModel)
+		} else {
+			config.AIModel = prompt(T("setup.model"), client.model)
+		}
+	} else if config.AIService == "mock" {
+		config.MockFixFile = prompt("Fix JSON file (empty for a canned fix)", config.MockFixFile)
+	} else if config.AIService == "bedrock" {
+		config.BedrockRegion = prompt("AWS Region", bedrockRegion(config))
+		config.AWSProfile = prompt("AWS Profile (empty for environment credentials)", config.AWSProfile)
+		
+		// Fetch available models
+		fmt.Println(T("setup.fetching_models"))
+		client := NewBedrockClient(config.BedrockRegion, config.AWSProfile, "")
+		models, _ := client.GetAvailableModels()
+		fmt.Println(T("setup.available_models"))
+		for i, model := range models {
+			fmt.Printf("  %d. %s\n", i+1, model)
+		}
+		config.AIModel = promptWithOptions(T("setup.select_model"), models, config.AIModel)
 	} else {
 		config.OllamaURL = prompt("Ollama URL", config.OllamaURL)
 		
 		// Fetch available models
-		fmt.Println("Fetching available local models...")
+		fmt.Println(T("setup.fetching_local_models"))
 		client := NewOllamaClient(config.OllamaURL, "")
 		models, err := client.GetAvailableModels()
 		if err == nil && len(models) > 0 {
-			fmt.Println("Available models:")
+			fmt.Println(T("setup.available_models"))
 			for i, model := range models {
 				fmt.Printf("  %d. %s\n", i+1, model)
 			}
-			config.AIModel = promptWithOptions("Select model", models, config.AIModel)
+			config.AIModel = promptWithOptions(T("setup.select_model"), models, config.AIModel)
 		} else {
-			config.AIModel = prompt("AI Model", config.AIModel)
+			config.AIModel = prompt(T("setup.model"), config.AIModel)
 		}
 	}
 
-	fmt.Println("\nWorking Directory:")
-	fmt.Printf("  (Repos will be cloned to: %s/<owner>/<repo>)\n", config.WorkDir)
-	config.WorkDir = prompt("Work Directory", config.WorkDir)
+	fmt.Println(T("setup.workdir_title"))
+	fmt.Printf(T("setup.workdir_hint"), config.WorkDir)
+	config.WorkDir = prompt(T("setup.workdir"), config.WorkDir)
 
 	// Save config for next time
-	if err := saveConfig(config); err != nil {
+	if config.NoConfigWrite {
+		fmt.Println(T("setup.not_saved"))
+	} else if err := saveConfig(config); err != nil {
 		fmt.Printf("Warning: Could not save config: %v\n", err)
 	} else {
-		fmt.Printf("\nConfiguration saved to: %s\n", getConfigPath())
+		fmt.Printf(T("setup.saved"), getConfigPath())
 	}
 
 	return config
 }
 
-func parseFlags(config *Config) {
+// listFlag is a flag.Value for comma-separated lists
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// registerFlags defines the settings shared by every command on fs. It
+// returns where the --repo-url value ends up.
+func registerFlags(config *Config, fs *flag.FlagSet) *string {
 	var repoURL string
-	flag.StringVar(&repoURL, "repo-url", "", "GitHub repository URL (e.g., https://github.com/owner/repo)")
-	flag.StringVar(&config.RepoOwner, "owner", config.RepoOwner, "GitHub repository owner")
-	flag.StringVar(&config.RepoName, "repo", config.RepoName, "GitHub repository name")
-	flag.StringVar(&config.GithubToken, "github-token", config.GithubToken, "GitHub personal access token")
-	flag.StringVar(&config.AIService, "ai-service", config.AIService, "AI service to use: chatgpt/grok/ollama")
-	flag.StringVar(&config.AIAPIKey, "ai-key", config.AIAPIKey, "API key for AI service")
-	flag.StringVar(&config.AIModel, "ai-model", config.AIModel, "AI model to use")
-	flag.StringVar(&config.OllamaURL, "ollama-url", config.OllamaURL, "Ollama API URL")
-	flag.StringVar(&config.WorkDir, "work-dir", config.WorkDir, "Working directory for cloning repos")
-
-	flag.Parse()
+	fs.StringVar(&repoURL, "repo-url", "", "Repository URL (e.g., https://github.com/owner/repo)")
+	fs.StringVar(&config.RepoOwner, "owner", config.RepoOwner, "GitHub repository owner")
+	fs.StringVar(&config.RepoName, "repo", config.RepoName, "GitHub repository name")
+	fs.StringVar(&config.GithubToken, "github-token", config.GithubToken, "GitHub personal access token (or Gitea access token)")
+	fs.BoolVar(&config.UseGhAuth, "use-gh-auth", config.UseGhAuth, "Use the token of the GitHub CLI login (gh auth token) instead of a stored token")
+	fs.StringVar(&config.Provider, "provider", config.Provider, "Hosting provider: github/gitea")
+	fs.StringVar(&config.ProviderURL, "provider-url", config.ProviderURL, "Base URL of a self-hosted provider (e.g., https://gitea.example.com)")
+	fs.StringVar(&config.HTTPProxy, "http-proxy", config.HTTPProxy, "Proxy for all HTTP(S) requests and git (default: HTTPS_PROXY/HTTP_PROXY from the environment)")
+	fs.Var((*listFlag)(&config.NoProxy), "no-proxy", "Comma-separated hosts or domains reached without the proxy (localhost always is)")
+	fs.BoolVar(&config.OfflineAI, "offline-ai", config.OfflineAI, "Only talk to the Ollama server and the git host; any other request of the bot fails and is audited (setup and test commands are limited on a best-effort basis)")
+	fs.Var((*listFlag)(&config.AllowHosts), "allow-hosts", "Comma-separated extra hosts --offline-ai may reach (e.g. an internal webhook)")
+	fs.StringVar(&config.CABundle, "ca-bundle", config.CABundle, "PEM file with extra CA certificates to trust, e.g. a corporate root")
+	fs.StringVar(&config.ClientCert, "client-cert", config.ClientCert, "PEM client certificate for mutual TLS")
+	fs.StringVar(&config.ClientKey, "client-key", config.ClientKey, "PEM private key for --client-cert")
+	fs.StringVar(&config.GitProtocol, "git-protocol", config.GitProtocol, "Clone and push over https (token) or ssh (default: ssh for SSH repo URLs or when --ssh-key is set)")
+	fs.StringVar(&config.SSHKey, "ssh-key", config.SSHKey, "Private key for SSH, e.g. a deploy key ({owner} and {repo} are replaced per repository)")
+	fs.StringVar(&config.AIService, "ai-service", config.AIService, "AI service to use: chatgpt/grok/mistral/deepseek/huggingface/bedrock/ollama/mock")
+	fs.StringVar(&config.MockFixFile, "mock-fix-file", config.MockFixFile, "File with the fix the mock AI service returns, in the JSON format of an AI fix (default: a canned fix)")
+	fs.StringVar(&config.HFEndpointURL, "hf-endpoint-url", config.HFEndpointURL, "URL of a Hugging Face Inference Endpoint or other TGI server (default: serverless inference)")
+	fs.StringVar(&config.BedrockRegion, "bedrock-region", config.BedrockRegion, "AWS region of Bedrock (default: AWS_REGION, else us-east-1)")
+	fs.StringVar(&config.AWSProfile, "aws-profile", config.AWSProfile, "AWS profile whose credentials sign Bedrock requests (default: environment, AWS_PROFILE or default)")
+	fs.StringVar(&config.AIAPIKey, "ai-key", config.AIAPIKey, "API key for AI service")
+	fs.StringVar(&config.AIModel, "ai-model", config.AIModel, "AI model to use")
+	fs.StringVar(&config.OllamaURL, "ollama-url", config.OllamaURL, "Ollama API URL")
+	fs.StringVar(&config.BodyOverflow, "body-overflow", config.BodyOverflow, "Where sections of oversized PR descriptions go: comments or gist")
+	fs.StringVar(&config.ScaffoldEmptyRepos, "scaffold-empty", config.ScaffoldEmptyRepos, "Create an initial commit in empty repositories: ask/always/never")
+	fs.StringVar(&config.OllamaPull, "ollama-pull", config.OllamaPull, "Pull missing Ollama models: ask/always/never")
+	fs.IntVar(&config.OllamaNumCtx, "ollama-num-ctx", config.OllamaNumCtx, "Ollama context window in tokens (0 = model default)")
+	fs.Float64Var(&config.OllamaTemperature, "ollama-temperature", config.OllamaTemperature, "Ollama sampling temperature")
+	fs.Float64Var(&config.OllamaTopP, "ollama-top-p", config.OllamaTopP, "Ollama top_p (0 = model default)")
+	fs.StringVar(&config.OllamaKeepAlive, "ollama-keep-alive", config.OllamaKeepAlive, "How long Ollama keeps the model loaded (e.g. 10m, -1 for forever)")
+	fs.StringVar(&config.SystemPrompt, "system-prompt", config.SystemPrompt, "Replace the built-in system prompt for fixes (repositories can set their own)")
+	fs.StringVar(&config.StructuredOutput, "structured-output", config.StructuredOutput, "How JSON replies are requested: auto, schema, json or off")
+	fs.BoolVar(&config.OllamaJSON, "ollama-json", config.OllamaJSON, "Use Ollama's JSON output mode for structured replies")
+	fs.StringVar(&config.AITimeout, "ai-timeout", config.AITimeout, "Timeout for a single AI request (e.g. 10m, 0 = none; default 2m, 5m for Ollama)")
+	fs.BoolVar(&config.NoCache, "no-cache", config.NoCache, "Always ask the AI again instead of reusing a cached fix for an unchanged issue")
+	fs.StringVar(&config.CacheTTL, "cache-ttl", config.CacheTTL, "How long cached fixes are reused (e.g. 2h, 0 = forever; default 24h)")
+	fs.StringVar(&config.GithubTimeout, "github-timeout", config.GithubTimeout, "Timeout for GitHub/Gitea API requests (e.g. 1m, 0 = none; default 30s)")
+	fs.IntVar(&config.AIRPM, "ai-rpm", config.AIRPM, "Maximum AI requests per minute (0 = unlimited)")
+	fs.IntVar(&config.AITPM, "ai-tpm", config.AITPM, "Maximum estimated AI prompt tokens per minute (0 = unlimited)")
+	fs.IntVar(&config.AIRetries, "ai-retries", config.AIRetries, "Retries with exponential backoff when the AI service answers 429 Too Many Requests")
+	fs.StringVar(&config.WorkDir, "work-dir", config.WorkDir, "Working directory for cloning repos")
+	fs.BoolVar(&config.KeepClones, "keep-clones", config.KeepClones, "Keep each job's clone in the work directory instead of removing it afterwards")
+	fs.BoolVar(&config.ReuseClones, "reuse-clones", config.ReuseClones, "Update one clone per repository with fetch and reset instead of cloning for every job")
+	fs.BoolVar(&config.RunGitHooks, "run-git-hooks", config.RunGitHooks, "Run the target repository's git hooks (e.g. pre-commit) on commits and pushes in the clone")
+	fs.StringVar(&config.LockTimeout, "lock-timeout", config.LockTimeout, "How long to wait for another instance working on the same repository (default 10m)")
+	fs.StringVar(&config.BranchTemplate, "branch-template", config.BranchTemplate, "Branch name template ({number}, {slug})")
+	fs.StringVar(&config.ExportPatch, "export-patch", config.ExportPatch, "Also write each fix as a git format-patch file into this directory")
+	fs.BoolVar(&config.PatchOnly, "patch-only", config.PatchOnly, "With --export-patch: only write the patch, don't push or open a PR")
+	fs.BoolVar(&config.SelfReview, "self-review", config.SelfReview, "Have the AI review its own diff before creating a PR")
+	fs.IntVar(&config.ReviewRetries, "review-retries", config.ReviewRetries, "Number of revisions to attempt when self-review or the syntax check rejects a fix")
+	fs.StringVar(&config.VerifierModel, "verifier-model", config.VerifierModel, "Second model that must agree a fix resolves the issue before a PR is created (e.g., gpt-4o-mini)")
+	fs.StringVar(&config.VerifierService, "verifier-service", config.VerifierService, "AI service of the verifier model (defaults to the main AI service)")
+	fs.StringVar(&config.VerifierAPIKey, "verifier-key", config.VerifierAPIKey, "API key for the verifier service (defaults to --ai-key for the same service)")
+	fs.BoolVar(&config.SyntaxCheck, "syntax-check", config.SyntaxCheck, "Check generated files for syntax errors before writing them")
+	fs.Var((*listFlag)(&config.TrustedAuthors), "trusted-authors", "Comma-separated users whose issues are fixed automatically; others need a maintainer to pick them")
+	fs.Var((*listFlag)(&config.BlockedAuthors), "blocked-authors", "Comma-separated users whose issues are never fixed automatically")
+	fs.StringVar(&config.TrustedOrg, "trusted-org", config.TrustedOrg, "Also trust issues from members of this organization")
+	fs.BoolVar(&config.SkipHandledCheck, "skip-handled-check", config.SkipHandledCheck, "Don't read issue comments to skip issues the bot already answered; every open issue is a candidate")
+	fs.BoolVar(&config.CodeMap, "code-map", config.CodeMap, "Show the AI each file's declarations and function signatures instead of a plain directory listing")
+	fs.BoolVar(&config.UpdatePRs, "update-prs", config.UpdatePRs, "Regenerate the bot's open PR of a reprocessed issue instead of opening another")
+	fs.BoolVar(&config.TokenCheck, "token-check", config.TokenCheck, "Check at startup that the token may comment, open pull requests and push")
+	fs.Var((*listFlag)(&config.Reviewers), "reviewers", "Comma-separated users (or org/team) to request review from on created PRs")
+	fs.BoolVar(&config.CodeOwners, "codeowners", config.CodeOwners, "Request review from the CODEOWNERS of the changed files")
+	fs.BoolVar(&config.GroupIssues, "group-issues", config.GroupIssues, "When fixing several issues, fix related ones together in a single PR")
+	fs.BoolVar(&config.TranslateIssues, "translate-issues", config.TranslateIssues, "Translate non-English issues for the analysis and reply in the reporter's language")
+	fs.BoolVar(&config.PlanFirst, "plan-first", config.PlanFirst, "Post a fix plan on every issue and only write code after a 👍 or /approve")
+	fs.BoolVar(&config.FeaturePlans, "feature-plans", config.FeaturePlans, "Post an implementation plan on feature requests and only write code after a 👍 or /approve")
+	fs.Var((*listFlag)(&config.FeatureLabels), "feature-labels", "Comma-separated labels that mark an issue as a feature request")
+	fs.Var((*listFlag)(&config.Assignees), "assignees", "Comma-separated users to assign created PRs to")
+	fs.Var((*listFlag)(&config.AllowedCommands), "allowed-commands", "Comma-separated executables setup and test commands may run, including in package.json scripts (e.g. \"go,npm,jest,pytest\"; default: any)")
+	fs.Var((*listFlag)(&config.SetupCommands), "setup-commands", "Comma-separated commands run in the clone before tests (e.g. \"npm ci,go mod download\")")
+	fs.BoolVar(&config.Memory, "memory", config.Memory, "Remember repository architecture and previous fixes across runs")
+	fs.IntVar(&config.MaxIssueBodyChars, "max-issue-body", config.MaxIssueBodyChars, "Truncate issue bodies longer than this many characters (0 = no limit)")
+	fs.StringVar(&config.VisionModel, "vision-model", config.VisionModel, "Vision-capable model used to describe images attached to issues (e.g., gpt-4o, grok-vision-beta)")
+	fs.IntVar(&config.MaxReferences, "max-references", config.MaxReferences, "Most referenced issues, PRs and commits to read as context (0 = off)")
+	fs.IntVar(&config.MaxDiffLines, "max-diff-lines", config.MaxDiffLines, "Most lines a fix may change before diff-limit applies (0 = no limit)")
+	fs.IntVar(&config.MaxDiffFiles, "max-diff-files", config.MaxDiffFiles, "Most files a fix may change before diff-limit applies (0 = no limit)")
+	fs.StringVar(&config.DiffLimitPolicy, "diff-limit", config.DiffLimitPolicy, "What to do with a fix over the size limits: shrink/confirm/downgrade")
+	fs.StringVar(&config.ProtectedPathPolicy, "protected-paths", config.ProtectedPathPolicy, "What to do when a fix touches a protected path: reject/confirm")
+	fs.StringVar(&config.AuditLog, "audit-log", config.AuditLog, "Audit log of every comment, PR, push and AI call (default ~/.mr-code-fixer/audit.jsonl, \"off\" to disable)")
+	fs.StringVar(&config.NotifyWebhook, "notify-webhook", config.NotifyWebhook, "Slack or Discord webhook URL for notifications")
+	fs.Var((*listFlag)(&config.EmailTo), "email-to", "Comma-separated addresses that get a summary email after batch runs and daily in serve mode")
+	fs.StringVar(&config.EmailFrom, "email-from", config.EmailFrom, "Sender of summary emails (default: smtp_username)")
+	fs.StringVar(&config.SMTPHost, "smtp-host", config.SMTPHost, "SMTP server for summary emails")
+	fs.IntVar(&config.SMTPPort, "smtp-port", config.SMTPPort, "SMTP port (465 for TLS from the start, otherwise STARTTLS when offered)")
+	fs.StringVar(&config.SMTPUsername, "smtp-username", config.SMTPUsername, "SMTP login (set the password with smtp_password or MRCF_SMTP_PASSWORD)")
+	fs.BoolVar(&config.DuplicateCheck, "duplicate-check", config.DuplicateCheck, "Detect issues that duplicate an existing open or recently closed issue")
+	fs.BoolVar(&config.CloseDuplicates, "close-duplicates", config.CloseDuplicates, "Close issues detected as duplicates instead of only commenting")
+	fs.StringVar(&config.CommitFormat, "commit-format", config.CommitFormat, "Commit message format: plain or conventional (Conventional Commits)")
+	fs.BoolVar(&config.ChangelogEntry, "changelog-entry", config.ChangelogEntry, "Add a changelog entry or fragment to fixes in repositories that keep a changelog")
+	fs.BoolVar(&config.Rebase, "rebase", config.Rebase, "Rebase the fix onto the latest default branch before pushing")
+	fs.BoolVar(&config.ResolveConflicts, "resolve-conflicts", config.ResolveConflicts, "Let the AI resolve conflicts found while rebasing")
+	fs.BoolVar(&config.Reproduce, "reproduce", config.Reproduce, "For issues with reproduction steps, confirm the bug with a failing test before fixing it")
+	fs.BoolVar(&config.GenerateTests, "generate-tests", config.GenerateTests, "Ask the AI to add tests that reproduce the issue and verify the fix")
+	fs.BoolVar(&config.StyleGuides, "style-guides", config.StyleGuides, "Show the AI the repository's CONTRIBUTING.md and formatter and linter configs")
+	fs.BoolVar(&config.FormatCode, "format-code", config.FormatCode, "Run the repository's formatters (gofmt, prettier, black, ruff, clang-format) on fixed files")
+	fs.BoolVar(&config.ParallelFiles, "parallel-files", config.ParallelFiles, "Plan each fix first and write the files of independent changes in parallel requests")
+	fs.IntVar(&config.ContextBudget, "context-budget", config.ContextBudget, "Characters of full file content to send; less relevant files beyond this are summarized (0 = no limit, the default)")
+	fs.BoolVar(&config.RegionEdits, "region-edits", config.RegionEdits, "For files too large to send in full, let the AI pick and edit individual functions")
+	fs.IntVar(&config.ContextMaxBytes, "context-max-bytes", config.ContextMaxBytes, "Maximum bytes of file content read into the context (0 = no limit)")
+	fs.IntVar(&config.ContextMaxFiles, "context-max-files", config.ContextMaxFiles, "Maximum files from the relevance ranking included in the context")
+	fs.IntVar(&config.ContextMaxFileKB, "context-max-file-kb", config.ContextMaxFileKB, "Source files larger than this many KB are left out of the context (0 = no limit)")
+	fs.IntVar(&config.ContextMaxCharsPerFile, "context-max-chars-per-file", config.ContextMaxCharsPerFile, "Characters of each file shown to the AI before it is truncated or outlined (0 = no limit)")
+	fs.StringVar(&config.SimpleModel, "simple-model", config.SimpleModel, "Cheaper model used for issues estimated to be simple (defaults to the main model)")
+	fs.StringVar(&config.ComplexModel, "complex-model", config.ComplexModel, "Stronger model used for issues estimated to be complex (defaults to the main model)")
+	fs.StringVar(&config.SummaryModel, "summary-model", config.SummaryModel, "Cheaper model used to summarize files in large repositories (defaults to the main model)")
+	fs.StringVar(&config.Milestone, "milestone", config.Milestone, "Only process issues in this milestone (title, number, * for any, none for no milestone)")
+	fs.StringVar(&config.IssueSort, "sort", config.IssueSort, "Order issues by community signal: reactions, comments or oldest (default: newest first)")
+	fs.StringVar(&config.Label, "label", config.Label, "Only process issues with this label, e.g. one that marks issues for the bot")
+	fs.Var((*priorityFlag)(&config.LabelPriorities), "label-priorities", "Process issues by label severity, 1 first, e.g. \"critical=1,bug=2,enhancement=5\"")
+	fs.StringVar(&config.IssueQuery, "query", config.IssueQuery, "GitHub search query selecting the issues to process, e.g. \"label:bug -label:wontfix created:>2024-01-01\" (default: all open issues)")
+	fs.StringVar(&config.AssigneeFilter, "assignee", config.AssigneeFilter, "Only process issues assigned to this user (login, me, * for any, none for unassigned)")
+	fs.StringVar(&config.CommentTemplates, "comment-templates", config.CommentTemplates, "Directory with comment templates overriding the built-in ones")
+	fs.StringVar(&config.Lang, "lang", config.Lang, "Language of the command-line interface (built in: en, sv; default: from LANG)")
+	fs.StringVar(&config.CommentLanguage, "comment-language", config.CommentLanguage, "Language of the bot's issue comments (built in: en, de)")
+	fs.StringVar(&config.ClosePolicy, "close-policy", config.ClosePolicy, "When to close fixed issues: never, on_merge or on_high_confidence")
+	fs.BoolVar(&config.WaitForChecks, "wait-for-checks", config.WaitForChecks, "Before closing an issue on high confidence, wait for the checks on the PR to pass")
+	fs.StringVar(&config.ChecksTimeout, "checks-timeout", config.ChecksTimeout, "How long --wait-for-checks waits (e.g. 1h, 0 = no limit; default 30m)")
+	fs.IntVar(&config.CloseMinScore, "close-min-score", config.CloseMinScore, "Calibrated confidence score (0-100) a fix needs before the issue is closed with on_high_confidence")
+	fs.BoolVar(&config.SelfAssign, "self-assign", config.SelfAssign, "Assign the issue to the bot while working on it")
+	fs.StringVar(&config.SelfAssignUser, "self-assign-user", config.SelfAssignUser, "Account to assign with --self-assign (default: the token's account)")
+	fs.StringVar(&config.ProjectStatus, "project-status", config.ProjectStatus, "Project board column to move the issue card to when a PR is created (e.g. \"In review\")")
+	fs.StringVar(&config.Currency, "currency", config.Currency, "Currency for cost estimates (USD, EUR, GBP, SEK, NOK, DKK or any code with currency_rate)")
+	fs.StringVar(&config.PricingFile, "pricing-file", config.PricingFile, "JSON file with per-model prices per 1K tokens in USD (default ~/.mr-code-fixer/pricing.json)")
+	fs.BoolVar(&config.Preflight, "preflight", config.Preflight, "Show a token/cost estimate and ask for confirmation before each AI call")
+	fs.BoolVar(&config.ClarifyLocally, "clarify-locally", config.ClarifyLocally, "Offer to answer the AI's questions in the terminal before posting them to the issue")
+	fs.StringVar(&config.BotName, "bot-name", config.BotName, "Name the bot commits and signs comments with (default \""+defaultBotName+"\")")
+	fs.StringVar(&config.BotEmail, "bot-email", config.BotEmail, "Email the bot commits with (default \""+defaultBotEmail+"\")")
+	fs.StringVar(&config.BotSignature, "bot-signature", config.BotSignature, "Markdown footer of the bot's comments and pull requests (default: the bot's name)")
+	fs.BoolVar(&config.NoConfigWrite, "no-config-write", config.NoConfigWrite, "Never write ~/.mr-code-fixer.json (for containers)")
+	return &repoURL
+}
+
+func parseFlags(config *Config, fs *flag.FlagSet, args []string) {
+	repoURLFlag := registerFlags(config, fs)
+	fs.Usage = func() { printUsage(fs) }
+	fs.Parse(args)
+	repoURL := *repoURLFlag
 
 	// If repo URL provided, parse it
 	if repoURL != "" {
@@ -384,15 +905,31 @@ func parseFlags(config *Config) {
 			config.RepoOwner = owner
 			config.RepoName = repo
 		}
+		setRepoHost(config, repoURL)
 	}
 
 	// Override from env vars if not set via flags
-	if config.GithubToken == "" {
+	if config.GithubToken == "" && !config.UseGhAuth {
 		config.GithubToken = os.Getenv("GITHUB_TOKEN")
 	}
+	resolveGithubToken(config)
+	if config.AIAPIKey == "" && config.AIService == "huggingface" {
+		config.AIAPIKey = os.Getenv("HF_TOKEN")
+	}
 	if config.AIAPIKey == "" {
 		config.AIAPIKey = os.Getenv("GROQ_API_KEY")
 	}
+
+	if err := configureNetwork(*config); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if err := configureAudit(*config); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if err := setLanguage(config.Lang); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	configureIdentity(*config)
 }
 
 func validateConfig(config Config) error {
@@ -400,26 +937,247 @@ func validateConfig(config Config) error {
 		return fmt.Errorf("repository owner and name are required")
 	}
 	if config.GithubToken == "" {
-		return fmt.Errorf("GitHub token is required")
+		return fmt.Errorf("GitHub token is required (set github_token or GITHUB_TOKEN, or log in with `gh auth login`)")
+	}
+	if config.Provider != "github" && config.Provider != "gitea" {
+		return fmt.Errorf("unsupported provider: %s", config.Provider)
+	}
+	if config.Provider == "gitea" && config.ProviderURL == "" {
+		return fmt.Errorf("provider URL is required for gitea")
+	}
+	if config.DuplicateThreshold <= 0 || config.DuplicateThreshold > 1 {
+		return fmt.Errorf("duplicate threshold must be between 0 and 1")
+	}
+	for name, value := range map[string]string{"ai_timeout": config.AITimeout, "github_timeout": config.GithubTimeout, "cache_ttl": config.CacheTTL, "checks_timeout": config.ChecksTimeout, "email_interval": config.EmailInterval, "question_reminder": config.QuestionReminder, "question_close": config.QuestionClose} {
+		if value == "" {
+			continue
+		}
+		if timeout, err := time.ParseDuration(value); err != nil || timeout < 0 {
+			return fmt.Errorf("invalid %s %q (use a duration like 90s or 10m)", name, value)
+		}
+	}
+	if config.GitProtocol != "" && config.GitProtocol != "https" && config.GitProtocol != "ssh" {
+		return fmt.Errorf("git protocol must be https or ssh")
+	}
+	if config.PatchOnly && config.ExportPatch == "" {
+		return fmt.Errorf("patch_only needs export_patch (the directory to write patches to)")
+	}
+	if config.ClosePolicy != closeNever && config.ClosePolicy != closeOnMerge && config.ClosePolicy != closeOnHighConfidence {
+		return fmt.Errorf("close policy must be never, on_merge or on_high_confidence")
+	}
+	if config.CommitFormat != commitFormatPlain && config.CommitFormat != commitFormatConventional {
+		return fmt.Errorf("commit format must be plain or conventional")
 	}
-	if (config.AIService == "chatgpt" || config.AIService == "openai" || config.AIService == "grok") && config.AIAPIKey == "" {
+	if config.CloseMinScore < 0 || config.CloseMinScore > 100 {
+		return fmt.Errorf("close min score must be between 0 and 100")
+	}
+	if config.IssueQuery != "" && config.Provider != "github" {
+		return fmt.Errorf("query needs provider github; %s has no issue search syntax", config.Provider)
+	}
+	if config.IssueSort != "" && !slices.Contains(issueSorts, config.IssueSort) {
+		return fmt.Errorf("sort must be one of %s", strings.Join(issueSorts, ", "))
+	}
+	for label, priority := range config.LabelPriorities {
+		if priority < 1 {
+			return fmt.Errorf("label_priorities: priority of %q must be 1 or higher", label)
+		}
+	}
+	if config.ContextMaxBytes < 0 || config.ContextMaxFileKB < 0 || config.ContextMaxCharsPerFile < 0 {
+		return fmt.Errorf("context_max_bytes, context_max_file_kb and context_max_chars_per_file cannot be negative")
+	}
+	if len(config.EmailTo) > 0 && config.SMTPHost == "" {
+		return fmt.Errorf("email_to needs smtp_host")
+	}
+	if config.ContextMaxFiles < 1 {
+		return fmt.Errorf("context_max_files must be at least 1")
+	}
+	if config.AIRPM < 0 || config.AITPM < 0 || config.AIRetries < 0 {
+		return fmt.Errorf("ai_rpm, ai_tpm and ai_retries cannot be negative")
+	}
+	if config.ScaffoldEmptyRepos != scaffoldAsk && config.ScaffoldEmptyRepos != scaffoldAlways && config.ScaffoldEmptyRepos != scaffoldNever {
+		return fmt.Errorf("scaffold empty repos must be ask, always or never")
+	}
+	if config.BodyOverflow != overflowComments && config.BodyOverflow != overflowGist {
+		return fmt.Errorf("body overflow must be comments or gist")
+	}
+	if config.OllamaPull != ollamaPullAsk && config.OllamaPull != ollamaPullAlways && config.OllamaPull != ollamaPullNever {
+		return fmt.Errorf("ollama pull must be ask, always or never")
+	}
+	if config.MaxReferences < 0 {
+		return fmt.Errorf("max_references cannot be negative")
+	}
+	if config.MaxDiffLines < 0 || config.MaxDiffFiles < 0 {
+		return fmt.Errorf("max_diff_lines and max_diff_files cannot be negative")
+	}
+	if config.DiffLimitPolicy != diffLimitShrink && config.DiffLimitPolicy != diffLimitConfirm && config.DiffLimitPolicy != diffLimitDowngrade {
+		return fmt.Errorf("diff limit policy must be shrink, confirm or downgrade")
+	}
+	if !slices.Contains(structuredOutputModes, config.StructuredOutput) {
+		return fmt.Errorf("structured output must be one of %s", strings.Join(structuredOutputModes, ", "))
+	}
+	if config.ProtectedPathPolicy != "reject" && config.ProtectedPathPolicy != "confirm" {
+		return fmt.Errorf("protected path policy must be reject or confirm")
+	}
+	if needsAPIKey(config) && config.AIAPIKey == "" {
 		return fmt.Errorf("%s API key is required", config.AIService)
 	}
+	if config.VerifierModel != "" {
+		verifier := verifierConfig(config)
+		if verifier.AIService == config.AIService && verifier.AIModel == config.AIModel {
+			return fmt.Errorf("verifier_model must be a different model than ai_model")
+		}
+		if needsAPIKey(verifier) && verifier.AIAPIKey == "" {
+			return fmt.Errorf("verifier_api_key is required for %s", verifier.AIService)
+		}
+	}
+	if config.AIService == "mock" && config.MockFixFile != "" && !fileExists(config.MockFixFile) {
+		return fmt.Errorf("mock fix file %s does not exist", config.MockFixFile)
+	}
 	return nil
 }
 
+// needsAPIKey reports whether the configured AI service needs ai_api_key
+func needsAPIKey(config Config) bool {
+	switch config.AIService {
+	case "chatgpt", "openai", "grok", "mistral", "deepseek":
+		return true
+	case "huggingface":
+		return config.HFEndpointURL == ""
+	}
+	return false
+}
+
+// Default HTTP timeouts, overridable with ai_timeout and github_timeout
+const (
+	defaultAITimeout     = 120 * time.Second
+	defaultOllamaTimeout = 300 * time.Second // Local models are slower
+	defaultGithubTimeout = 30 * time.Second
+)
+
+// timeoutSetting parses a timeout such as "10m". Empty means fallback and "0" disables the timeout.
+func timeoutSetting(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return fallback
+	}
+	return timeout
+}
+
+// signalContext returns a context that is cancelled on the first Ctrl-C (or SIGTERM),
+// stopping running AI, API and git calls. A second Ctrl-C exits immediately.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		fmt.Println("\n⏹  Cancelling... press Ctrl-C again to quit immediately")
+		cancel()
+	}()
+
+	return ctx, cancel
+}
+
 func main() {
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	// "help serve" is "serve -h"
+	if len(os.Args) > 2 && os.Args[1] == "help" {
+		if command := findCommand(os.Args[2]); command != nil && !command.NoFlags {
+			os.Args = []string{os.Args[0], command.Name, "-h"}
+		}
+	}
+
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "version", "--version", "-version", "-v":
+			printVersion()
+			return
+		case "help", "--help", "-help", "-h":
+			if err := helpCommand(os.Args[2:]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "completion":
+			if err := completionCommand(os.Args[2:]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "serve":
+			if err := serveCommand(ctx, os.Args[2:]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "rollback":
+			if err := rollbackCommand(ctx, os.Args[2:]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "batch":
+			if err := batchCommand(ctx, os.Args[2:]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "history":
+			if err := historyCommand(ctx, os.Args[2:]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "stats":
+			if err := statsCommand(ctx, os.Args[2:]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "analyze":
+			if err := analyzeCommand(ctx, os.Args[2:]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "review":
+			if err := reviewCommand(ctx, os.Args[2:]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "bench":
+			if err := benchCommand(ctx, os.Args[2:]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "resume-issue":
+			if err := resumeIssueCommand(ctx, os.Args[2:]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		}
+	}
+
 	// Check if running in interactive mode
 	interactive := len(os.Args) == 1
 
 	var config Config
 	
 	if interactive {
+		// Pick the language before the first prompt, including the setup wizard
+		if err := setLanguage(loadConfig().Lang); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+
 		// Check if config exists
 		configPath := getConfigPath()
 		if _, err := os.Stat(configPath); err == nil {
 			// Config exists - just load it
 			config = loadConfig()
+			resolveGithubToken(&config)
+		} else if envConfig := loadConfig(); validateConfig(withGithubToken(envConfig)) == nil {
+			// Fully configured through MRCF_* environment variables
+			config = withGithubToken(envConfig)
 		} else {
 			// No config - run full setup
 			config = interactiveSetup()
@@ -429,7 +1187,7 @@ func main() {
 		config = loadConfig()
 		
 		// Parse command line flags to override config
-		parseFlags(&config)
+		parseFlags(&config, flag.CommandLine, os.Args[1:])
 	}
 
 	// Validate configuration
@@ -437,155 +1195,245 @@ func main() {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+	if interactive {
+		if err := configureNetwork(config); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if err := configureAudit(config); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
 
 	// Run the fixer
-	if err := run(config); err != nil {
+	if err := run(ctx, config); err != nil {
 		log.Fatalf("Error: %v", err)
 	}
 }
 
-func run(config Config) error {
-	// Show welcome banner
-	fmt.Println("\n╔════════════════════════════════════════════════════════════════╗")
-	fmt.Printf("║         🤖 Mr. Code Fixer - Ready to Help! %-19s║\n", Version)
-	fmt.Println("╚════════════════════════════════════════════════════════════════╝")
-	fmt.Printf("\n📦 Repository: \033[1m%s/%s\033[0m", config.RepoOwner, config.RepoName)
-	fmt.Printf("\n🧠 AI Service: \033[1m%s\033[0m (model: \033[36m%s\033[0m)\n\n", config.AIService, config.AIModel)
-
-	// Initialize analytics
-	analytics := NewSessionAnalytics()
+// newAIClient creates the configured AI client with analytics attached
+func newAIClient(config Config, analytics *SessionAnalytics) AIClient {
+	// All clients of a service share one rate limiter, also across repositories in batch mode
+	scheduler := schedulerFor(config.AIService, config.AIRPM, config.AITPM, config.AIRetries)
 
-	// Initialize GitHub client
-	ghClient := NewGitHubClient(config.GithubToken, config.RepoOwner, config.RepoName)
+	// Fixes for unchanged prompts are reused from disk unless --no-cache
+	var cache *ResponseCache
+	if !config.NoCache {
+		cache = NewResponseCache(getCacheDir(), timeoutSetting(config.CacheTTL, defaultCacheTTL))
+		cache.Prune()
+	}
 
-	// Initialize AI client with analytics
-	var aiClient AIClient
 	if config.AIService == "chatgpt" || config.AIService == "openai" {
 		client := NewOpenAIClient(config.AIAPIKey, config.AIModel)
 		client.SetAnalytics(analytics)
-		aiClient = client
+		client.SetTimeout(timeoutSetting(config.AITimeout, defaultAITimeout))
+		client.SetScheduler(scheduler)
+		client.SetCache(cache)
+		client.SetStructuredOutput(structuredOutputMode(config))
+		return client
 	} else if config.AIService == "grok" {
 		client := NewXAIClient(config.AIAPIKey, config.AIModel)
 		client.SetAnalytics(analytics)
-		aiClient = client
-	} else {
-		client := NewOllamaClient(config.OllamaURL, config.AIModel)
+		client.SetTimeout(timeoutSetting(config.AITimeout, defaultAITimeout))
+		client.SetScheduler(scheduler)
+		client.SetCache(cache)
+		client.SetStructuredOutput(structuredOutputMode(config))
+		return client
+	} else if config.AIService == "mistral" || config.AIService == "deepseek" {
+		client := NewMistralClient(config.AIAPIKey, config.AIModel)
+		if config.AIService == "deepseek" {
+			client = NewDeepSeekClient(config.AIAPIKey, config.AIModel)
+		}
+		client.SetAnalytics(analytics)
+		client.SetTimeout(timeoutSetting(config.AITimeout, defaultAITimeout))
+		client.SetScheduler(scheduler)
+		client.SetCache(cache)
+		client.SetStructuredOutput(structuredOutputMode(config))
+		return client
+	} else if config.AIService == "huggingface" {
+		client := NewHuggingFaceClient(config.AIAPIKey, config.AIModel, config.HFEndpointURL)
 		client.SetAnalytics(analytics)
-		aiClient = client
+		client.SetTimeout(timeoutSetting(config.AITimeout, defaultAITimeout))
+		client.SetScheduler(scheduler)
+		client.SetCache(cache)
+		client.SetStructuredOutput(structuredOutputMode(config))
+		return client
+	} else if config.AIService == "bedrock" {
+		client := NewBedrockClient(bedrockRegion(config), config.AWSProfile, config.AIModel)
+		client.SetAnalytics(analytics)
+		client.SetTimeout(timeoutSetting(config.AITimeout, defaultAITimeout))
+		client.SetScheduler(scheduler)
+		client.SetCache(cache)
+		return client
+	} else if config.AIService == "mock" {
+		// Canned answers need no limiter or cache
+		client := NewMockClient(config.MockFixFile)
+		client.SetAnalytics(analytics)
+		return client
+	}
+
+	client := NewOllamaClient(config.OllamaURL, config.AIModel)
+	client.SetAnalytics(analytics)
+	client.SetTimeout(timeoutSetting(config.AITimeout, defaultOllamaTimeout))
+	client.SetScheduler(scheduler)
+	client.SetCache(cache)
+	client.SetOptions(&OllamaOptions{
+		NumCtx:      config.OllamaNumCtx,
+		Temperature: config.OllamaTemperature,
+		TopP:        config.OllamaTopP,
+	}, config.OllamaKeepAlive)
+	client.SetStructuredOutput(structuredOutputMode(config))
+	return client
+}
+
+// newSummaryClient returns the client used for file summaries: the cheaper
+// summary model when configured, otherwise the main client
+func newSummaryClient(config Config, analytics *SessionAnalytics, aiClient AIClient) AIClient {
+	if config.SummaryModel == "" || config.SummaryModel == config.AIModel {
+		return aiClient
 	}
+	config.AIModel = config.SummaryModel
+	return newAIClient(config, analytics)
+}
+
+func run(ctx context.Context, config Config) error {
+	// Show welcome banner
+	fmt.Println("\n╔════════════════════════════════════════════════════════════════╗")
+	fmt.Println(boxTitle(fmt.Sprintf(T("banner.title"), Version)))
+	fmt.Println("╚════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("\n📦 Repository: \033[1m%s/%s\033[0m", config.RepoOwner, config.RepoName)
+	fmt.Printf(T("run.ai_service"), config.AIService, config.AIModel)
+
+	// Initialize analytics
+	analytics := NewSessionAnalytics(NewPricing(config))
+
+	// Initialize hosting provider client (GitHub or Gitea)
+	provider := newHostingProvider(config)
+
+	// A missing Ollama server or model should fail now, not halfway through a fix
+	if err := checkOllama(ctx, config); err != nil {
+		return err
+	}
+	if err := checkTokenAccess(ctx, config, provider); err != nil {
+		return err
+	}
+	if err := identifyBotAccount(ctx, provider); err != nil {
+		return err
+	}
+
+	// Initialize AI client with analytics
+	aiClient := newAIClient(config, analytics)
+
+	// Close issues whose fix PRs were merged since the last run
+	watchMergedPRs(ctx, config, provider)
 
 	// Fetch all open issues
-	fmt.Print("🔍 Fetching open issues")
+	fmt.Print(T("run.fetching"))
 	for i := 0; i < 3; i++ {
 		fmt.Print(".")
 	}
 	fmt.Println()
-	issues, err := ghClient.GetOpenIssues(100) // Get up to 100 issues
+	issues, err := provider.GetOpenIssues(ctx, 100) // Get up to 100 issues
 	if err != nil {
-		fmt.Printf("\n\033[31m✗ Error fetching issues:\033[0m %v\n\n", err)
+		fmt.Printf(T("run.fetch_error"), err)
 		
 		// Offer to review settings
-		fmt.Println("This might be due to incorrect configuration.")
-		response := prompt("Would you like to review settings? (yes/no)", "yes")
-		if strings.ToLower(response) == "yes" || strings.ToLower(response) == "y" {
+		fmt.Println(T("run.config_hint"))
+		response := prompt(T("run.review_settings"), T("answer.yes"))
+		if isYes(response) {
 			config = interactiveSetup()
 			// Retry with new config
-			return run(config)
+			return run(ctx, config)
 		}
 		return fmt.Errorf("failed to fetch issues: %w", err)
 	}
 
 	if len(issues) == 0 {
-		fmt.Println("No open issues found.")
+		fmt.Println(T("run.no_issues"))
 		return nil
 	}
 
 	// Filter out issues the bot has already responded to
-	fmt.Print("📝 Loading issues")
+	fmt.Print(T("run.loading"))
 	for i := 0; i < 3; i++ {
 		fmt.Print(".")
 	}
-	fmt.Println("\n")
+	fmt.Println()
 	
-	var unhandledIssues []Issue
-	for _, issue := range issues {
-		comments, err := ghClient.GetIssueComments(issue.Number)
-		if err != nil {
-			// If we can't check, include it to be safe
-			unhandledIssues = append(unhandledIssues, issue)
-			continue
-		}
-		
-		// Check if bot's comment is the last one
-		// If there are new comments after bot's response, process the issue again
-		needsProcessing := true
-		lastBotCommentIndex := -1
-		
-		// Find the last bot comment
-		for i, comment := range comments {
-			if strings.Contains(comment.Body, "Mr. Code Fixer") || 
-			   strings.Contains(comment.Body, "🤖") {
-				lastBotCommentIndex = i
-			}
-		}
-		
-		// If bot commented and it's still the last comment, skip
-		if lastBotCommentIndex != -1 && lastBotCommentIndex == len(comments)-1 {
-			needsProcessing = false
-		}
-		
-		if needsProcessing {
-			unhandledIssues = append(unhandledIssues, issue)
-		}
-	}
+	unhandledIssues := filterUnhandledIssues(ctx, config, provider, issues)
+	prioritizeIssues(unhandledIssues, config.LabelPriorities)
 	
 	if len(unhandledIssues) == 0 {
-		fmt.Println("\n✓ All open issues have already been handled by the bot!")
+		fmt.Println(T("run.all_handled"))
 		return nil
 	}
 	
 	if len(issues) != len(unhandledIssues) {
-		fmt.Printf("✓ Found %d new issue(s) (skipped %d already handled)\n", 
+		fmt.Printf(T("run.found_new"), 
 			len(unhandledIssues), len(issues)-len(unhandledIssues))
 	}
 
 	fmt.Printf("\n\033[1m📦 %s/%s\033[0m\n", config.RepoOwner, config.RepoName)
 
-	// Let user select which issue(s) to fix (with settings option)
-	selectedIssue := selectIssueWithSettings(unhandledIssues, &config, analytics)
-	
-	// If user chose settings, the config has been updated and we should restart
-	if selectedIssue == nil {
-		return nil // User chose to exit or settings were changed
+	var issuesToProcess []Issue
+
+	// Full-screen browser when the terminal supports it
+	if config.TUI {
+		if selected, result, ok := browseIssues(unhandledIssues, &config); ok {
+			switch result {
+			case browserQuit:
+				fmt.Println(T("run.exiting"))
+				return nil
+			case browserSetup:
+				config = interactiveSetup()
+				fmt.Println(T("run.settings_updated"))
+				return nil
+			}
+
+			if len(selected) > 1 {
+				analytics.PrintCostEstimate(len(selected), config.AIService, config.AIModel)
+				confirm := prompt(fmt.Sprintf(T("run.confirm_selected"), len(selected)), T("answer.no"))
+				if !isYes(confirm) {
+					fmt.Println(T("run.cancelled"))
+					return nil
+				}
+			}
+			issuesToProcess = selected
+		}
 	}
 
-	var issuesToProcess []Issue
-	if selectedIssue.Number == -1 {
-		// Special case: user chose to fix all
-		analytics.PrintCostEstimate(len(unhandledIssues), config.AIService)
-		
-		confirm := prompt(fmt.Sprintf("Fix all %d issues? (yes/no)", len(unhandledIssues)), "no")
-		if strings.ToLower(confirm) != "yes" && strings.ToLower(confirm) != "y" {
-			fmt.Println("Cancelled.")
-			return nil
+	// Line-based picker (with settings option)
+	if issuesToProcess == nil {
+		issuesToProcess = pickIssues(ctx, unhandledIssues, &config, analytics, newAuthorTrust(config, provider))
+		if issuesToProcess == nil {
+			return nil // User chose to exit or settings were changed
 		}
-		issuesToProcess = unhandledIssues
-	} else {
-		issuesToProcess = []Issue{*selectedIssue}
+	}
+
+	// Related issues share one branch and PR instead of conflicting with each other
+	if config.GroupIssues && len(issuesToProcess) > 1 {
+		issuesToProcess = groupRelatedIssues(ctx, aiClient, issuesToProcess)
 	}
 
 	// Process each issue
 	fmt.Println("\n" + strings.Repeat("─", 66))
 	for _, issue := range issuesToProcess {
-		fmt.Printf("\n\n🔧 Processing Issue #%d: \033[1m%s\033[0m\n", issue.Number, issue.Title)
+		if ctx.Err() != nil {
+			fmt.Println(T("run.cancelled_remaining"))
+			break
+		}
+		fmt.Printf(T("run.processing"), issueRefs(issue), issue.Title)
 		fmt.Println(strings.Repeat("─", 66))
 		
-		if err := processIssue(config, ghClient, aiClient, issue, analytics); err != nil {
-			fmt.Printf("Failed to process issue #%d: %v\n\n", issue.Number, err)
+		if err := processIssue(ctx, config, provider, aiClient, issue, analytics); err != nil {
+			if errors.Is(err, errIssueSkipped) {
+				fmt.Printf(T("run.skipped"), issue.Number)
+				continue
+			}
+			fmt.Printf(T("run.failed"), issue.Number, err)
 			
-			if len(issuesToProcess) > 1 {
-				cont := prompt("Continue with next issue? (yes/no)", "yes")
-				if strings.ToLower(cont) != "yes" && strings.ToLower(cont) != "y" {
+			if len(issuesToProcess) > 1 && ctx.Err() == nil {
+				cont := prompt(T("run.continue"), T("answer.yes"))
+				if !isYes(cont) {
 					analytics.PrintSummary()
 					return fmt.Errorf("stopped processing issues")
 				}
@@ -593,7 +1441,7 @@ func run(config Config) error {
 			continue
 		}
 		
-		fmt.Printf("✓ Successfully processed issue #%d\n", issue.Number)
+		fmt.Printf(T("run.succeeded"), issue.Number)
 	}
 
 	// Print session summary
@@ -603,164 +1451,514 @@ func run(config Config) error {
 	return nil
 }
 
-func processIssue(config Config, ghClient *GitHubClient, aiClient AIClient, issue Issue, analytics *SessionAnalytics) error {
-	// Check if issue is too vague before processing
-	if isIssueTooVague(issue) {
-		fmt.Println("\n⚠ Issue description is too vague to fix automatically.")
-		fmt.Println("Posting request for more details...")
-		
-		questionComment := `## ❓ Need More Information
+// pickIssues asks for an issue number in the classic line-based picker.
+// It returns nil when the user quit or changed settings.
+func pickIssues(ctx context.Context, unhandledIssues []Issue, config *Config, analytics *SessionAnalytics, trust *authorTrust) []Issue {
+	selectedIssue := selectIssueWithSettings(unhandledIssues, config, analytics)
+	if selectedIssue == nil {
+		return nil
+	}
 
-Hi! I'd love to help fix this issue, but I need more details to understand what's wrong.
+	if selectedIssue.Number != -1 {
+		return []Issue{*selectedIssue}
+	}
 
-Please provide:
+	// Special case: user chose to fix all. Issues from untrusted authors
+	// must be picked one at a time.
+	issues := trust.trustedIssues(ctx, unhandledIssues)
+	if len(issues) == 0 {
+		fmt.Println(T("run.cancelled"))
+		return nil
+	}
+	analytics.PrintCostEstimate(len(issues), config.AIService, config.AIModel)
+	
+	confirm := prompt(fmt.Sprintf(T("run.confirm_all"), len(issues)), T("answer.no"))
+	if !isYes(confirm) {
+		fmt.Println(T("run.cancelled"))
+		return nil
+	}
+	return issues
+}
 
-1. **What's the expected behavior?** What should happen?
-2. **What's the actual behavior?** What's currently happening instead?
-3. **Steps to reproduce:** How can I see this problem?
-4. **Any error messages?** Copy-paste any errors from console/logs
-5. **Which file(s) are affected?** (e.g., src/main.js or components/Login.tsx)
+// prepareRepoContext loads the repository settings and gathers the files, hints
+// and memory the AI needs for an issue in a freshly cloned repository
+func prepareRepoContext(ctx context.Context, config Config, provider HostingProvider, gitOps *GitOps, aiClient AIClient, memory *RepoMemory, issue Issue) (*RepoConfig, *RepoContext, error) {
+	// Maintainer settings from .mr-code-fixer.yml in the target repo
+	repoConfig, err := loadRepoConfig(gitOps.repoPath)
+	if err != nil {
+		fmt.Printf("Warning: Ignoring invalid repository settings: %v\n", err)
+	}
 
-The more details you provide, the better I can help! 🙏
+	// Read relevant files from the repository
+	repoContext, err := gitOps.GetRepoContext(ctx, issue.Title, issue.Body, contextLimits(config))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read repo context: %w", err)
+	}
+	if config.CodeMap {
+		// Declarations per file tell the AI what exists, not just which files do
+		repoContext.Structure = gitOps.buildCodeMap(config.ContextMaxFileKB * 1024)
+	}
+	gitOps.addStackTraceContext(repoContext, issue.Body, contextLimits(config))
+	gitOps.addContextFiles(repoContext, repoConfig.Context)
+	repoContext.References = fetchReferences(ctx, config, provider, gitOps, issue)
+	repoContext.Hints = repoConfig.Hints
+	applyPromptSettings(repoContext, config, repoConfig, gitOps.repoPath)
+	repoContext.GenerateTests = config.GenerateTests
+	repoContext.ConventionalCommits = config.CommitFormat == commitFormatConventional
+	if config.ChangelogEntry {
+		repoContext.Changelog = detectChangelog(gitOps.repoPath)
+	}
+
+	fmt.Printf(T("process.context"), repoContext.FileCount)
+	printContextReport(repoContext, config.ContextMaxBytes)
+
+	if config.Memory {
+		if memory.Architecture == "" {
+			fmt.Println(T("process.learning"))
+			if err := learnRepoMemory(ctx, aiClient, memory, repoContext); err != nil {
+				fmt.Printf("Warning: Could not build repository memory: %v\n", err)
+			}
+		}
+		repoContext.Memory = memory.PromptSection()
+	}
 
----
+	return repoConfig, repoContext, nil
+}
+
+func processIssue(ctx context.Context, config Config, provider HostingProvider, aiClient AIClient, issue Issue, analytics *SessionAnalytics) (err error) {
+	// Remember the outcome for future runs on this repository and for the history report
+	memory := loadRepoMemory(config.RepoOwner, config.RepoName)
+	outcome := FixOutcome{IssueNumber: issue.Number, Title: issue.Title}
+	startCost := analytics.Snapshot().EstimatedCost
+	defer func() {
+		if errors.Is(err, errIssueSkipped) {
+			return
+		}
+		if err != nil {
+			outcome.Result = "failed"
+			outcome.Notes = err.Error()
+		}
+		recordHistory(config, outcome, analytics.Snapshot().EstimatedCost-startCost, analytics.Pricing.Currency)
+		if !config.Memory {
+			return
+		}
+		memory.RecordOutcome(outcome)
+		if saveErr := memory.Save(); saveErr != nil {
+			fmt.Printf("Warning: Could not save repository memory: %v\n", saveErr)
+		}
+	}()
+
+	notifier := NewNotifier(config)
+
+	// Describe attached screenshots and trim huge pasted logs
+	issue = prepareIssue(ctx, config, analytics, issue)
+
+	// Analyze non-English issues in English, but answer the reporter in their language
+	if config.TranslateIssues {
+		translated, language, err := translateIssue(ctx, aiClient, issue)
+		if err != nil {
+			fmt.Printf("Warning: Could not translate the issue: %v\n", err)
+		} else if language != "" {
+			fmt.Printf(T("process.translated"), issue.Number, language)
+			issue = translated
+			provider = &translatingProvider{HostingProvider: provider, aiClient: aiClient, issue: issue.Number, language: language}
+		}
+	}
+
+	// Duplicate bug reports should point at the original instead of producing another PR
+	if config.DuplicateCheck && len(issue.Related) == 0 {
+		match, err := checkDuplicate(ctx, config, provider, issue)
+		if err != nil {
+			fmt.Printf("Warning: Could not check for duplicates: %v\n", err)
+		} else if match != nil {
+			fmt.Printf(T("process.duplicate"), issue.Number, match.Issue.Number, match.Score*100)
+			if err := provider.AddIssueComment(ctx, issue.Number, duplicateComment(match, config.CloseDuplicates)); err != nil {
+				return fmt.Errorf("failed to add comment: %w", err)
+			}
+			if config.CloseDuplicates {
+				if err := provider.CloseIssue(ctx, issue.Number); err != nil {
+					fmt.Printf("Warning: Could not close issue: %v\n", err)
+				}
+			}
+			analytics.RecordIssueHandled()
+			notifier.Notify("🔁", issue, fmt.Sprintf("Marked as a duplicate of #%d.", match.Issue.Number))
+			outcome.Result = "duplicate"
+			outcome.Notes = fmt.Sprintf("duplicate of #%d", match.Issue.Number)
+			return nil
+		}
+	}
 
-<sub>🤖 Mr. Code Fixer - I need clear information to create good fixes</sub>`
+	// Check if issue is too vague before processing
+	if isIssueTooVague(issue) {
+		fmt.Println(T("process.vague"))
+		fmt.Println(T("process.posting_request"))
+		
+		questionComment := renderComment(config, commentNeedInfo, CommentData{Issue: issue})
 		
-		if err := ghClient.AddIssueComment(issue.Number, questionComment); err != nil {
+		if err := provider.AddIssueComment(ctx, issue.Number, questionComment); err != nil {
 			return fmt.Errorf("failed to post comment: %w", err)
 		}
 		
 		analytics.RecordQuestionAsked()
-		fmt.Printf("✓ Posted request for more information on issue #%d\n", issue.Number)
+		notifier.Notify("❓", issue, "Issue is too vague, asked the reporter for more details.")
+		outcome.Result = "question"
+		outcome.Notes = "issue too vague"
+		fmt.Printf(T("process.posted_request"), issue.Number)
 		return nil
 	}
 
+	// Feature requests, and every issue with plan_first, get a plan a maintainer
+	// approves before any code is written
+	postPlan := false
+	feature := isFeatureRequest(config, issue)
+	if config.PlanFirst || (config.FeaturePlans && feature) {
+		plan, err := featurePlanStatus(ctx, config, provider, issue)
+		if err != nil {
+			return fmt.Errorf("failed to check the implementation plan: %w", err)
+		}
+		switch {
+		case plan.Plan == "":
+			postPlan = true
+		case plan.ApprovedBy == "":
+			fmt.Printf(T("process.plan_waiting"), issue.Number)
+			return errIssueSkipped
+		default:
+			fmt.Printf(T("process.plan_approved"), plan.ApprovedBy)
+			issue.Body += approvedPlanSection(plan)
+		}
+	}
+
+	// Show others the issue is being worked on; unassign again unless a PR came out of it
+	if config.SelfAssign {
+		if assigned := selfAssign(ctx, config, provider, issue); len(assigned) > 0 {
+			defer func() {
+				if outcome.Result != "pr_created" && outcome.Result != "pr_updated" {
+					selfUnassign(ctx, provider, assigned)
+				}
+			}()
+		}
+	}
+
 	// Clone repository
-	gitOps, err := NewGitOps(config.WorkDir, config.RepoOwner, config.RepoName, config.GithubToken)
+	gitOps, err := newRepoGitOps(ctx, config, provider)
 	if err != nil {
 		return fmt.Errorf("failed to initialize git: %w", err)
 	}
 	defer gitOps.Cleanup()
 
-	if err := gitOps.Clone(); err != nil {
+	if err := cloneRepo(ctx, config, gitOps); err != nil {
 		return fmt.Errorf("failed to clone repo: %w", err)
 	}
 
-	// Read relevant files from the repository
-	repoContext, err := gitOps.GetRepoContext(issue.Title, issue.Body)
+	repoConfig, repoContext, err := prepareRepoContext(ctx, config, provider, gitOps, aiClient, memory, issue)
 	if err != nil {
-		return fmt.Errorf("failed to read repo context: %w", err)
+		return err
+	}
+
+	// Show what will be sent to the AI and what it will roughly cost, when
+	// someone is there to answer
+	if config.Preflight && stdinIsTerminal() && !confirmPreflight(config, analytics.Pricing, issue, repoContext) {
+		return errIssueSkipped
+	}
+
+	if postPlan {
+		fmt.Println(T("process.plan_drafting"))
+		plan, err := generatePlan(ctx, aiClient, issue, repoContext, feature)
+		if err != nil {
+			return fmt.Errorf("failed to draft implementation plan: %w", err)
+		}
+		if err := provider.AddIssueComment(ctx, issue.Number, planComment(plan, feature)); err != nil {
+			return fmt.Errorf("failed to post implementation plan: %w", err)
+		}
+		analytics.RecordIssueHandled()
+		notifier.Notify("📝", issue, "Posted an implementation plan for approval.")
+		outcome.Result = "plan_posted"
+		outcome.Notes = plan
+		fmt.Printf(T("process.plan_posted"), issue.Number)
+		return nil
 	}
-	
-	fmt.Printf("Analyzed %d relevant files from repository\n", repoContext.FileCount)
+
+	// Large repositories: keep the most relevant files in full and summarize the rest
+	if config.ContextBudget > 0 {
+		summarizeContext(ctx, newSummaryClient(config, analytics, aiClient), repoContext, config.ContextBudget)
+	}
+
+	// Files too large for the prompt: show and edit only the regions the fix needs
+	if config.RegionEdits {
+		selectRegions(ctx, aiClient, issue, repoContext)
+	}
+
+	// Cheap model for trivial issues, premium model for complex ones
+	aiClient = routeModel(config, analytics, aiClient, issue, repoContext)
+
+	// Protected paths (defaults, config and repository settings), also checked
+	// while a parallel fix is planned
+	var confirm func(path, pattern string) bool
+	if config.ProtectedPathPolicy == "confirm" {
+		confirm = confirmProtectedPath
+	}
+	gitOps.guard = NewPathGuard(append(append([]string{}, config.ProtectedPaths...), repoConfig.ProtectedPaths...), confirm)
 
 	// Ask AI to analyze and fix the issue
-	fmt.Println("Analyzing issue with AI...")
-	fix, err := aiClient.AnalyzeAndFix(issue, repoContext)
+	fmt.Println(T("process.analyzing"))
+	fix, err := generateFix(ctx, config, aiClient, gitOps.repoPath, gitOps.guard, issue, repoContext)
 	if err != nil {
 		return fmt.Errorf("AI analysis failed: %w", err)
 	}
 
+	// Whoever runs the bot often knows the answers; ask them before the issue
+	if fix.NeedsMoreInfo && len(fix.Questions) > 0 && config.ClarifyLocally && stdinIsTerminal() {
+		issue, fix, err = clarifyLocally(ctx, aiClient, issue, repoContext, fix)
+		if err != nil {
+			return fmt.Errorf("AI analysis failed: %w", err)
+		}
+	}
+
 	// Check if AI needs more information
 	if fix.NeedsMoreInfo && len(fix.Questions) > 0 {
-		fmt.Println("\n⚠ AI needs more information to fix this issue.")
-		fmt.Println("Posting questions to the issue...")
+		fmt.Println(T("process.needs_info"))
+		fmt.Println(T("process.posting_questions"))
 		
-		questionComment := "I need some clarification to fix this issue:\n\n"
-		for i, q := range fix.Questions {
-			questionComment += fmt.Sprintf("%d. %s\n", i+1, q)
-		}
-		questionComment += "\nPlease provide more details so I can create a proper fix.\n\n---\n*Asked by Mr. Code Fixer*"
+		questionComment := renderComment(config, commentQuestions, CommentData{Issue: issue, Questions: fix.Questions})
 		
-		if err := ghClient.AddIssueComment(issue.Number, questionComment); err != nil {
+		if err := provider.AddIssueComment(ctx, issue.Number, questionComment); err != nil {
 			return fmt.Errorf("failed to post questions: %w", err)
 		}
 		
 		analytics.RecordQuestionAsked()
-		fmt.Printf("✓ Posted %d question(s) to issue #%d\n", len(fix.Questions), issue.Number)
+		notifier.Notify("❓", issue, fmt.Sprintf("Asked %d clarifying question(s).", len(fix.Questions)))
+		outcome.Result = "question"
+		outcome.Notes = strings.Join(fix.Questions, " ")
+		fmt.Printf(T("process.posted_questions"), len(fix.Questions), issue.Number)
 		return nil
 	}
 
 	// Check if AI determined this is not a code fix (e.g., question, discussion, etc.)
 	if len(fix.FileChanges) == 0 {
-		fmt.Println("\n💬 This issue doesn't require code changes.")
+		fmt.Println(T("process.no_code"))
 		
-		responseComment := fmt.Sprintf(`## 💬 Response
-
-%s
-
-This issue appears to be a question or discussion rather than a bug or feature requiring code changes. If you need specific code modifications, please provide more details about what changes you'd like to see.
-
----
-
-<sub>🤖 Mr. Code Fixer</sub>`, fix.Explanation)
+		responseComment := renderComment(config, commentResponse, CommentData{Issue: issue, Explanation: fix.Explanation})
 		
-		if err := ghClient.AddIssueComment(issue.Number, responseComment); err != nil {
+		if err := provider.AddIssueComment(ctx, issue.Number, responseComment); err != nil {
 			return fmt.Errorf("failed to post response: %w", err)
 		}
 		
-		// Close the issue since we've responded
-		if err := ghClient.CloseIssue(issue.Number); err != nil {
-			fmt.Printf("Warning: Could not close issue: %v\n", err)
-		} else {
-			fmt.Printf("✓ Issue #%d closed\n", issue.Number)
+		// Close the issue since we've responded, if the close policy doesn't wait for a merge
+		if closesAnsweredIssues(config) {
+			if err := provider.CloseIssue(ctx, issue.Number); err != nil {
+				fmt.Printf("Warning: Could not close issue: %v\n", err)
+			} else {
+				fmt.Printf(T("process.closed"), issue.Number)
+			}
 		}
 		
 		analytics.RecordIssueHandled()
-		fmt.Printf("✓ Posted response explaining no code changes needed\n")
+		outcome.Result = "answered"
+		fmt.Printf(T("process.answered"))
 		return nil
 	}
 
-	// Create a branch with sanitized issue title
-	branchName := createBranchName(issue)
-	if err := gitOps.CreateBranch(branchName); err != nil {
+	// Regenerate the bot's open PR for the issue, if any, instead of opening another.
+	// Otherwise create a branch with sanitized issue title, avoiding existing remote branches.
+	var branchName string
+	existingPR := findBotPR(ctx, config, provider, issue)
+	if existingPR != nil {
+		branchName = existingPR.Head.Ref
+		fmt.Printf(T("process.updating_pr"), existingPR.Number, branchName)
+	} else {
+		branchName = gitOps.UniqueBranchName(ctx, createBranchName(issue, config.BranchTemplate))
+	}
+	if err := gitOps.CreateBranch(ctx, branchName); err != nil {
 		return fmt.Errorf("failed to create branch: %w", err)
 	}
 
-	// Apply the changes
-	fmt.Printf("Applying %d file change(s)...\n", len(fix.FileChanges))
+	// Make sure generated files parse before they are written
+	if config.SyntaxCheck {
+		fix, err = syntaxCheckFix(ctx, config, aiClient, issue, repoContext, fix)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Refuse changes to protected paths (defaults, config and repository settings)
 	for _, change := range fix.FileChanges {
-		if err := gitOps.ApplyFileChange(change); err != nil {
-			return fmt.Errorf("failed to apply changes to %s: %w", change.FilePath, err)
+		for _, path := range change.Paths() {
+			if err := gitOps.guard.Check(path); err != nil {
+				return err
+			}
 		}
-		fmt.Printf("  ✓ Modified %s\n", change.FilePath)
 	}
 
-	// Run tests if available
-	fmt.Println("\n🧪 Checking for tests...")
 	testRunner := NewTestRunner(gitOps.repoPath)
+	testRunner.Commands = repoConfig.ValidationCommands()
+	testRunner.Setup = append(append([]string{}, config.SetupCommands...), repoConfig.Setup...)
+	testRunner.Env = expandTestEnv(config.TestEnv)
+	testRunner.Secrets = testEnvValues(testRunner.Env)
+	testRunner.Allowed = config.AllowedCommands
+	if config.OfflineAI {
+		testRunner.Env = append(offlineTestEnv(), testRunner.Env...)
+	}
+
+	// Confirm the bug with a failing test first, so the fix can be verified against it
+	var repro *Reproduction
+	if config.Reproduce && hasReproductionSteps(issue) {
+		repro = reproduceIssue(ctx, gitOps, aiClient, issue, repoContext, testRunner)
+	}
+
+	// Apply the changes
+	if err := applyFix(gitOps, fix); err != nil {
+		return err
+	}
+
+	// Let the AI critique its own diff before anything reaches GitHub
+	var review *ReviewResult
+	if config.SelfReview {
+		fix, review, err = selfReviewFix(ctx, config, gitOps, aiClient, issue, repoContext, fix)
+		if err != nil {
+			return err
+		}
+	}
+
+	// A huge diff for a small issue is a red flag: shrink, confirm or downgrade it
+	fix, overLimit, err := limitDiffSize(ctx, config, gitOps, aiClient, issue, repoContext, fix)
+	if err != nil {
+		return err
+	}
+
+	// Record the final fix in the changelog, in the form the repository uses
+	if repoContext.Changelog != nil {
+		if change, ok := changelogChange(gitOps.repoPath, repoContext.Changelog, issue, fix); ok {
+			if err := gitOps.ApplyFileChange(change); err != nil {
+				fmt.Printf("Warning: Could not add the changelog entry: %v\n", err)
+			} else {
+				fix.FileChanges = append(fix.FileChanges, change)
+				fmt.Printf("📝 Changelog entry added to %s\n", change.FilePath)
+			}
+		}
+	}
+
+	// Format the written files the way the project's CI checks them
+	if config.FormatCode {
+		formatFix(testRunner, fix)
+	}
+
+	// The reproduction has to pass now; it is committed with the fix as evidence
+	if repro != nil {
+		result := repro.Verify(gitOps, testRunner)
+		if !result.Passed {
+			fmt.Println(T("repro.still_failing"))
+			fmt.Println(tailText(result.Output, 3000))
+			notifier.Notify("❌", issue, fmt.Sprintf("The fix does not make the reproduction `%s` pass, no PR created.", repro.Command))
+			return fmt.Errorf("reproduction still fails after applying changes")
+		}
+		fmt.Println(T("repro.verified"))
+		if !slices.ContainsFunc(fix.FileChanges, func(change FileChange) bool { return change.FilePath == repro.Path }) {
+			fix.FileChanges = append(fix.FileChanges, FileChange{FilePath: repro.Path, Content: repro.Content, Summary: "Test reproducing the issue"})
+			fix.TestFiles = append(fix.TestFiles, repro.Path)
+		}
+	}
+
+	// Run tests if available
+	fmt.Println(T("process.checking_tests"))
 	testResult := testRunner.Execute()
 	
-	if testResult.Command != "" {
-		fmt.Printf("Found test command: %s\n", testResult.Command)
+	if testResult.SetupFailed {
+		fmt.Printf(T("process.setup_failed"), testResult.Command)
+		fmt.Println(testResult.Output)
+	} else if testResult.Refused {
+		fmt.Printf(T("process.command_refused"), testResult.Command)
+	} else if testResult.Command != "" {
+		fmt.Printf(T("process.test_command"), testResult.Command)
 		
 		if !testResult.Passed {
-			fmt.Println("\n❌ Tests failed! Not creating PR.")
-			fmt.Println("Test output:")
+			fmt.Println(T("process.tests_failed"))
+			notifier.Notify("❌", issue, fmt.Sprintf("Fix failed validation (`%s`), no PR created.", testResult.Command))
+			fmt.Println(T("process.test_output"))
 			fmt.Println(testResult.Output)
 			
 			// Rollback by not proceeding - cleanup will happen via defer
 			return fmt.Errorf("tests failed after applying changes")
 		}
-		fmt.Println("✓ All tests passed!")
+		fmt.Println(T("process.tests_passed"))
 	} else {
-		fmt.Println("No tests detected - proceeding without test validation")
+		fmt.Println(T("process.no_tests"))
+		if len(fix.TestFiles) > 0 {
+			fmt.Println(T("process.tests_not_run"))
+		}
+	}
+
+	// A second model, which did not write the fix, has to agree that it resolves the issue
+	var verification *ReviewResult
+	if config.VerifierModel != "" {
+		verification, err = verifyAppliedFix(ctx, config, analytics, gitOps, issue, repoContext)
+		if err != nil {
+			notifier.Notify("❌", issue, fmt.Sprintf("The verifier %s did not agree with the fix, no PR created.", config.VerifierModel))
+			return err
+		}
 	}
 
 	// Commit changes
-	commitMsg := fmt.Sprintf("Fix #%d: %s\n\n%s", issue.Number, issue.Title, fix.Explanation)
-	if err := gitOps.CommitChanges(commitMsg); err != nil {
+	commitMsg := buildCommitMessage(config, issue, fix)
+	if err := gitOps.CommitChanges(ctx, commitMsg); err != nil {
 		return fmt.Errorf("failed to commit changes: %w", err)
 	}
 
-	// Push branch
-	if err := gitOps.Push(branchName); err != nil {
-		return fmt.Errorf("failed to push branch: %w", err)
+	// Weigh the AI's own confidence against what was actually verified
+	signals := ConfidenceSignals{
+		AIConfidence:   fix.Confidence,
+		SyntaxChecked:  config.SyntaxCheck,
+		ReviewApproved: review != nil && review.Approved,
+		Verified:       verification != nil,
+		TestsRun:       testResult.Command != "" && !testResult.SetupFailed && !testResult.Refused,
+		TestsPassed:    testResult.Passed,
+		SetupFailed:    testResult.SetupFailed,
+		OverDiffLimit:  overLimit,
+		Reproduced:     repro != nil,
+	}
+	var changes *ChangeStats
+	if stats, paths, err := gitOps.CommitStats(ctx); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	} else {
+		signals.LinesChanged = stats.Lines()
+		signals.Dependencies = changedDependencyFiles(paths)
+		changes = &stats
+		outcome.Changes = changes
+		analytics.RecordChanges(issue.Number, stats)
+	}
+	score := calibrateConfidence(signals)
+	closeIssue := config.ClosePolicy == closeOnHighConfidence && score.Score >= config.CloseMinScore
+	fmt.Printf(T("process.score"), score)
+
+	// Replay the fix on the latest default branch so the PR does not conflict right away
+	var rebase *RebaseResult
+	if config.Rebase {
+		rebase = rebaseFix(ctx, config, gitOps, aiClient, testRunner, issue, fix)
+		// A conflict resolved by the AI needs a human look before the issue is closed
+		if rebase != nil && len(rebase.Conflicts) > 0 {
+			closeIssue = false
+		}
 	}
 
-	// Create pull request with detailed technical description
-	prTitle := fmt.Sprintf("Fix #%d: %s", issue.Number, issue.Title)
+	// Write the fix as a patch file, e.g. for review by email or without push rights
+	if config.ExportPatch != "" {
+		name := fmt.Sprintf("%s-%d-%s", config.RepoName, issue.Number, slugify(issue.Title, 40))
+		patchPath, err := gitOps.ExportPatch(ctx, config.ExportPatch, name)
+		if err != nil {
+			return fmt.Errorf("failed to export patch: %w", err)
+		}
+		fmt.Printf(T("process.patch"), patchPath)
+
+		if config.PatchOnly {
+			analytics.RecordIssueHandled()
+			outcome.Result = "patch_exported"
+			outcome.Notes = patchPath
+			notifier.Notify("📄", issue, fmt.Sprintf("Fix exported to %s, no PR created.", patchPath))
+			return nil
+		}
+	}
+
+	// Describe the pull request in detail
+	prTitle := fmt.Sprintf("Fix %s: %s", issueRefs(issue), issue.Title)
 	confidenceNote := ""
 	if fix.Confidence == "high" {
 		confidenceNote = "✅ **High confidence** - This fix should resolve the issue."
@@ -769,40 +1967,84 @@ This issue appears to be a question or discussion rather than a bug or feature r
 	} else {
 		confidenceNote = "⚠️ **Low confidence** - This is a best attempt, please review thoroughly."
 	}
+	if overLimit {
+		confidenceNote += fmt.Sprintf("\n\n📏 **Large change** - This fix is over the size limit of %s, check that every part of it is needed.", diffLimitText(config))
+	}
 	
-	// Build detailed file changes list
+	// One line per file up front; the full explanation is collapsed below it
 	fileChangesList := ""
 	for _, change := range fix.FileChanges {
-		fileChangesList += fmt.Sprintf("- `%s`\n", change.FilePath)
+		fileChangesList += fmt.Sprintf("- %s", change.Describe())
+		if summary := strings.Join(strings.Fields(change.Summary), " "); summary != "" {
+			fileChangesList += ": " + summary
+		}
+		fileChangesList += "\n"
 	}
 	
 	// Add test results to PR body
 	testSection := ""
-	if testResult.Command != "" {
+	if testResult.SetupFailed {
+		testSection = fmt.Sprintf("\n### ⚠️ Tests Not Run\n\nThe test setup command `%s` failed, so this fix has not been validated.\n\n<details>\n<summary>Setup output</summary>\n\n```\n%s\n```\n</details>\n", testResult.Command, tailText(testResult.Output, 3000))
+	} else if testResult.Refused {
+		testSection = fmt.Sprintf("\n### ⚠️ Tests Not Run\n\n`%s` runs commands outside the allowed commands, so this fix has not been validated.\n\n```\n%s\n```\n", testResult.Command, tailText(testResult.Output, 3000))
+	} else if testResult.Command != "" {
 		if testResult.Passed {
 			testSection = "\n### ✅ Tests Passed\n\nAll existing tests passed after applying the changes.\n"
 		}
 	}
+	if len(fix.TestFiles) > 0 {
+		testSection += "\n### 🧪 Tests Added\n\n"
+		for _, path := range fix.TestFiles {
+			testSection += fmt.Sprintf("- `%s`\n", path)
+		}
+		if testResult.SetupFailed {
+			testSection += "\nThese have not been run because the test setup failed.\n"
+		} else if testResult.Refused {
+			testSection += "\nThese have not been run because the test command was refused.\n"
+		} else if testResult.Command != "" {
+			testSection += fmt.Sprintf("\nThese ran as part of `%s`.\n", testResult.Command)
+		} else {
+			testSection += "\nNo test command was detected, so these have not been run.\n"
+		}
+	}
 	
-	prBody := fmt.Sprintf(`## 🔧 Automated Fix
-
-Fixes #%d
+	if repro != nil {
+		testSection = repro.prSection() + testSection
+	}
+	
+	// Add self-review summary to PR body
+	reviewSection := ""
+	if review != nil && review.Summary != "" {
+		reviewSection = fmt.Sprintf("\n### 🔍 Self-Review\n\n%s\n", review.Summary)
+	}
+	if verification != nil {
+		reviewSection += fmt.Sprintf("\n### 🔎 Independent Verification\n\n`%s`, which did not write this fix, agrees that it resolves the issue: %s\n", config.VerifierModel, verification.Summary)
+	}
 
-**Confidence Level:** %s
+	// Tell reviewers what the base branch requires before merging
+	protectionNote := ""
+	if protection, err := provider.GetBranchProtection(ctx, gitOps.DefaultBranch); err != nil {
+		fmt.Printf("Warning: Could not read branch protection: %v\n", err)
+	} else {
+		protectionNote = protectionSection(protection, gitOps.DefaultBranch, closeIssue && config.WaitForChecks)
+	}
 
-### 📋 Analysis
+	prBody := fmt.Sprintf(`## 🔧 Automated Fix
 
 %s
 
-### 🔨 Technical Details
+**Confidence Level:** %s
+**Confidence Score:** %s
 
-This PR addresses the issue by making targeted changes to the codebase. The modifications were determined through analysis of the repository structure, issue description, and relevant code context.
+### 📋 Changes
 
-**Modified Files:**
 %s
-**Approach:**
-The fix was generated by analyzing the issue requirements and applying best practices for the detected programming language and framework. All changes maintain backward compatibility where possible and follow the existing code style.
+<details>
+<summary>Full explanation</summary>
+
 %s
+</details>
+%s%s%s%s
 **Testing Recommendations:**
 - Verify the fix addresses the reported issue
 - Check for any unintended side effects
@@ -811,90 +2053,166 @@ The fix was generated by analyzing the issue requirements and applying best prac
 
 ---
 
-<sub>🤖 This PR was automatically generated by [Mr. Code Fixer](https://github.com/pefman/Mr-Code-Fixer) - an AI-powered issue resolution bot</sub>`,
-		issue.Number, confidenceNote, fix.Explanation, fileChangesList, testSection)
-	
-	prURL, err := ghClient.CreatePullRequest(prTitle, prBody, branchName, gitOps.DefaultBranch)
-	if err != nil {
-		return fmt.Errorf("failed to create pull request: %w", err)
+%s`,
+		fixesLines(issue, closingKeyword(config)), confidenceNote, score, fileChangesList, fix.Explanation, testSection, reviewSection, rebase.prSection(), protectionNote,
+		bot.footer("This PR was automatically generated by "+bot.link()+" - an AI-powered issue resolution bot"))
+
+	revision := 0
+	if existingPR != nil {
+		prBody, revision = revisedPRBody(existingPR.Body, prBody, changes, time.Now())
+	}
+
+	// Save progress so a failed push or pull request can be resumed with resume-issue
+	state := &PipelineState{
+		Repo:        config.RepoOwner + "/" + config.RepoName,
+		Issue:       issue,
+		Related:     issue.Related,
+		Stage:       stageCommitted,
+		Branch:      branchName,
+		BaseBranch:  gitOps.DefaultBranch,
+		Bundle:      filepath.Join(getStateDir(config.RepoOwner, config.RepoName), fmt.Sprintf("issue-%d.bundle", issue.Number)),
+		PRTitle:     prTitle,
+		PRBody:      prBody,
+		Explanation: fix.Explanation,
+		Confidence:  fix.Confidence,
+		Score:       score.Score,
+		CloseIssue:  closeIssue,
+		Changes:     changes,
+	}
+	if existingPR != nil {
+		state.UpdatePR, state.UpdateSHA, state.Revision = existingPR.Number, existingPR.Head.SHA, revision
+	}
+	for _, change := range fix.FileChanges {
+		state.Files = append(state.Files, change.Paths()...)
+	}
+	if err := gitOps.SaveBundle(ctx, state.Bundle, branchName); err != nil {
+		fmt.Printf("Warning: Could not save progress: %v\n", err)
+	} else if err := state.Save(config); err != nil {
+		fmt.Printf("Warning: Could not save progress: %v\n", err)
 	}
 
-	analytics.RecordPRCreated()
-	analytics.RecordIssueHandled()
-	fmt.Printf("✓ Pull request created: %s\n", prURL)
+	return publishFix(ctx, config, provider, gitOps, state, analytics, notifier, &outcome)
+}
 
-	// If high confidence, close the issue with a detailed comment
-	if fix.Confidence == "high" {
-		fmt.Println("Closing issue (high confidence fix)...")
-		
-		// Create user-friendly explanation
-		fileList := ""
-		for i, change := range fix.FileChanges {
-			if i < 3 { // Show first 3 files
-				fileList += fmt.Sprintf("`%s`", change.FilePath)
-				if i < len(fix.FileChanges)-1 && i < 2 {
-					fileList += ", "
-				}
-			}
+// applyFix writes every file change of a fix into the working tree
+func applyFix(gitOps *GitOps, fix *Fix) error {
+	fmt.Printf(T("process.applying"), len(fix.FileChanges))
+	for _, change := range fix.FileChanges {
+		if err := gitOps.ApplyFileChange(change); err != nil {
+			return fmt.Errorf("failed to apply changes to %s: %w", change.FilePath, err)
 		}
-		if len(fix.FileChanges) > 3 {
-			fileList += fmt.Sprintf(" and %d more", len(fix.FileChanges)-3)
+		switch change.Operation {
+		case fileDelete:
+			fmt.Printf(T("process.deleted"), change.FilePath)
+		case fileRename:
+			fmt.Printf(T("process.renamed"), change.FilePath, change.NewPath)
+		default:
+			fmt.Printf(T("process.modified"), change.FilePath)
 		}
-		
-		closeComment := fmt.Sprintf(`## ✅ Issue Resolved!
+	}
+	return nil
+}
 
-Great news! I've analyzed this issue and created a fix that should resolve the problem.
+// selfReviewFix runs the AI review pass over the applied diff, revising the
+// fix up to config.ReviewRetries times. Returns the (possibly revised) fix.
+func selfReviewFix(ctx context.Context, config Config, gitOps *GitOps, aiClient AIClient, issue Issue, repoContext *RepoContext, fix *Fix) (*Fix, *ReviewResult, error) {
+	for attempt := 0; ; attempt++ {
+		diff, err := gitOps.Diff(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
 
-**What I did:**
-%s
+		fmt.Println(T("process.reviewing"))
+		review, err := reviewFix(ctx, aiClient, issue, repoContext, diff)
+		if err != nil {
+			// A broken review call shouldn't block an otherwise valid fix
+			fmt.Printf("Warning: Self-review failed: %v\n", err)
+			return fix, nil, nil
+		}
 
-**Files modified:** %s
+		if review.Approved {
+			fmt.Println(T("process.review_approved"))
+			return fix, review, nil
+		}
 
-**Next steps:**
-I've created a pull request with the changes: %s
+		fmt.Println(T("process.review_problems"))
+		for _, concern := range review.Concerns {
+			fmt.Printf("  - %s\n", concern)
+		}
 
-Please review the PR to make sure everything looks good. The fix has been implemented with high confidence, but it's always good to double-check before merging. If you notice any issues or have questions about the approach, feel free to comment on the PR!
+		if attempt >= config.ReviewRetries {
+			return nil, nil, fmt.Errorf("self-review rejected the fix: %s", review.Summary)
+		}
 
----
+		fmt.Println(T("process.revising"))
+		revised, err := reviseFix(ctx, aiClient, issue, repoContext, diff, review.Concerns)
+		if err != nil {
+			return nil, nil, fmt.Errorf("AI revision failed: %w", err)
+		}
+		if len(revised.FileChanges) == 0 {
+			return nil, nil, fmt.Errorf("AI revision produced no file changes")
+		}
+		if config.SyntaxCheck {
+			if problems := checkFixSyntax(revised); len(problems) > 0 {
+				return nil, nil, fmt.Errorf("revised fix has syntax errors: %s", strings.Join(problems, "; "))
+			}
+		}
 
-<sub>🤖 Fixed automatically by Mr. Code Fixer</sub>`,
-			fix.Explanation, fileList, prURL)
-		
-		if err := ghClient.AddIssueComment(issue.Number, closeComment); err != nil {
-			fmt.Printf("Warning: Could not add closing comment: %v\n", err)
+		if err := gitOps.ResetChanges(ctx); err != nil {
+			return nil, nil, err
 		}
-		
-		if err := ghClient.CloseIssue(issue.Number); err != nil {
-			fmt.Printf("Warning: Could not close issue: %v\n", err)
-		} else {
-			fmt.Printf("✓ Issue #%d closed\n", issue.Number)
+		if err := applyFix(gitOps, revised); err != nil {
+			return nil, nil, err
 		}
+		fix = revised
 	}
+}
 
-	return nil
+func createBranchName(issue Issue, template string) string {
+	if template == "" {
+		template = "fix/{number}-{slug}"
+	}
+
+	name := strings.ReplaceAll(template, "{number}", strconv.Itoa(issue.Number))
+	name = strings.ReplaceAll(name, "{slug}", slugify(issue.Title, 40))
+	return sanitizeBranchName(name)
 }
 
-func createBranchName(issue Issue) string {
-	// Sanitize issue title for branch name
-	title := strings.ToLower(issue.Title)
-	title = strings.ReplaceAll(title, " ", "-")
-	title = strings.ReplaceAll(title, "?", "")
-	title = strings.ReplaceAll(title, "!", "")
-	title = strings.ReplaceAll(title, ".", "")
-	title = strings.ReplaceAll(title, ",", "")
-	title = strings.ReplaceAll(title, "'", "")
-	title = strings.ReplaceAll(title, "\"", "")
-	
-	// Limit length
-	if len(title) > 40 {
-		title = title[:40]
+// slugify turns an issue title into a branch-safe slug. Letters and digits
+// from any script are kept so non-ASCII titles don't collapse to nothing.
+func slugify(title string, maxLen int) string {
+	var slug strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			slug.WriteRune(r)
+			lastDash = false
+		} else if !lastDash {
+			slug.WriteRune('-')
+			lastDash = true
+		}
 	}
-	
-	return fmt.Sprintf("fix/%d-%s", issue.Number, title)
+
+	// Limit length without cutting a multi-byte character in half
+	runes := []rune(strings.Trim(slug.String(), "-"))
+	if len(runes) > maxLen {
+		runes = runes[:maxLen]
+	}
+
+	result := strings.Trim(string(runes), "-")
+	if result == "" {
+		return "issue"
+	}
+	return result
 }
 
 // isIssueTooVague checks if an issue lacks sufficient detail to fix
 func isIssueTooVague(issue Issue) bool {
+	// Filled-in issue forms have the structure vague reports lack, even with short answers
+	if parseIssueForm(issue.Body) != nil {
+		return false
+	}
+
 	combined := strings.ToLower(issue.Title + " " + issue.Body)
 	
 	// Vague phrases that indicate lack of detail
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxMemoryOutcomes bounds how many previous fixes are remembered per repo
+const maxMemoryOutcomes = 20
+
+// RepoMemory is compact knowledge about a repository carried across runs
+type RepoMemory struct {
+	Architecture string       `json:"architecture"`
+	Conventions  []string     `json:"conventions"`
+	Outcomes     []FixOutcome `json:"outcomes"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+	path         string
+}
+
+// FixOutcome records what happened when the bot handled an issue
+type FixOutcome struct {
+	IssueNumber int          `json:"issue_number"`
+	Title       string       `json:"title"`
+	Result      string       `json:"result"` // "pr_created", "pr_updated", "question", "answered", "duplicate", "failed", "rolled_back", "plan_posted", "patch_exported", "analyzed"
+	PRURL       string       `json:"pr_url,omitempty"`
+	Confidence  string       `json:"confidence,omitempty"` // The AI's confidence in a fix it opened a PR for
+	Score       int          `json:"score,omitempty"`      // The calibrated confidence score of that fix
+	Changes     *ChangeStats `json:"changes,omitempty"`    // Size of the committed fix
+	Notes       string       `json:"notes,omitempty"`
+	Time        time.Time    `json:"time"`
+}
+
+func getMemoryPath(owner, repo string) string {
+	return filepath.Join(getDataDir(), "memory", owner, repo+".json")
+}
+
+// loadRepoMemory reads the stored memory for a repository, or returns an empty one
+func loadRepoMemory(owner, repo string) *RepoMemory {
+	memory := &RepoMemory{path: getMemoryPath(owner, repo)}
+
+	data, err := os.ReadFile(memory.path)
+	if err == nil {
+		json.Unmarshal(data, memory)
+	}
+
+	return memory
+}
+
+func (m *RepoMemory) Save() error {
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+
+	m.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.path, data, 0600)
+}
+
+// RecordOutcome remembers the result of handling an issue, keeping only recent ones
+func (m *RepoMemory) RecordOutcome(outcome FixOutcome) {
+	outcome.Time = time.Now()
+	if len(outcome.Notes) > 300 {
+		outcome.Notes = outcome.Notes[:300] + "..."
+	}
+
+	m.Outcomes = append(m.Outcomes, outcome)
+	if len(m.Outcomes) > maxMemoryOutcomes {
+		m.Outcomes = m.Outcomes[len(m.Outcomes)-maxMemoryOutcomes:]
+	}
+}
+
+// PromptSection renders the memory as a prompt section, empty if nothing is known yet
+func (m *RepoMemory) PromptSection() string {
+	if m.Architecture == "" && len(m.Conventions) == 0 && len(m.Outcomes) == 0 {
+		return ""
+	}
+
+	var section strings.Builder
+	section.WriteString("# Repository Memory (from previous runs)\n\n")
+
+	if m.Architecture != "" {
+		section.WriteString("## Architecture\n")
+		section.WriteString(m.Architecture)
+		section.WriteString("\n\n")
+	}
+
+	if len(m.Conventions) > 0 {
+		section.WriteString("## Coding Conventions\n")
+		for _, convention := range m.Conventions {
+			section.WriteString(fmt.Sprintf("- %s\n", convention))
+		}
+		section.WriteString("\n")
+	}
+
+	if len(m.Outcomes) > 0 {
+		section.WriteString("## Previous Fixes (avoid repeating failed approaches)\n")
+		for _, outcome := range m.Outcomes {
+			line := fmt.Sprintf("- #%d %q: %s", outcome.IssueNumber, outcome.Title, outcome.Result)
+			if outcome.Notes != "" {
+				line += " - " + outcome.Notes
+			}
+			section.WriteString(line + "\n")
+		}
+		section.WriteString("\n")
+	}
+
+	return section.String()
+}
+
+// learnRepoMemory asks the AI for a compact architecture summary and the coding conventions
+func learnRepoMemory(ctx context.Context, aiClient AIClient, memory *RepoMemory, repoContext *RepoContext) error {
+	var prompt strings.Builder
+	prompt.WriteString("# Repository Structure\n```\n")
+	prompt.WriteString(repoContext.Structure)
+	prompt.WriteString("\n```\n\n")
+
+	for path, content := range repoContext.Files {
+		if len(content) > 3000 {
+			content = content[:3000] + "\n... (truncated)"
+		}
+		prompt.WriteString(fmt.Sprintf("### %s\n```\n%s\n```\n\n", path, content))
+	}
+
+	prompt.WriteString(`# Task
+
+Summarize this repository for a developer who will fix issues in it later. Your response MUST be in the following JSON format:
+
+{
+  "architecture": "A compact summary (max 8 sentences) of the components, how they interact and where key logic lives",
+  "conventions": ["Coding convention observed in the code, e.g. error handling style, naming, test layout"]
+}
+
+List at most 10 conventions. Return valid JSON only, no markdown code blocks.`)
+
+	response, err := completeJSON(ctx, aiClient, "You are an expert software architect. Describe codebases concisely.", prompt.String())
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Architecture string   `json:"architecture"`
+		Conventions  []string `json:"conventions"`
+	}
+	if err := json.Unmarshal([]byte(cleanJSONResponse(response)), &result); err != nil {
+		return fmt.Errorf("failed to parse memory response: %w", err)
+	}
+
+	memory.Architecture = result.Architecture
+	memory.Conventions = result.Conventions
+	return nil
+}
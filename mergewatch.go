@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Close policies: when the bot closes the issues its PRs fix
+const (
+	closeNever            = "never"              // Issues are left for humans to close
+	closeOnMerge          = "on_merge"           // Closed once the fix PR is merged
+	closeOnHighConfidence = "on_high_confidence" // Closed when the PR opens, if the confidence score is high enough
+)
+
+// closingKeyword links a PR to its issues. Hosts close issues referenced with
+// "Fixes" on merge, which the never policy must avoid.
+func closingKeyword(config Config) string {
+	if config.ClosePolicy == closeNever {
+		return "Refs"
+	}
+	return "Fixes"
+}
+
+// closesAnsweredIssues reports whether an issue answered without code changes
+// is closed right away. Only on_high_confidence closes issues without a
+// merge; under on_merge no PR will ever be merged for it.
+func closesAnsweredIssues(config Config) bool {
+	return config.ClosePolicy == closeOnHighConfidence
+}
+
+// watchMergedPRs records whether the repository's bot PRs have been merged or
+// closed since the last check, for the acceptance statistics, and with the
+// on_merge policy closes the issues of merged ones. It works from the fix
+// history, so PRs opened by earlier runs are covered too; hosts that already
+// closed the issue on merge are left alone.
+func watchMergedPRs(ctx context.Context, config Config, provider HostingProvider) {
+	records, err := loadHistory()
+	if err != nil {
+		fmt.Printf("Warning: Could not read history to check merged PRs: %v\n", err)
+		return
+	}
+
+	repo := config.RepoOwner + "/" + config.RepoName
+	changed := false
+	for i := range records {
+		record := &records[i]
+		if ctx.Err() != nil {
+			break
+		}
+		if !strings.EqualFold(record.Repo, repo) || record.PRURL == "" || record.PRState == "closed" {
+			continue
+		}
+		if record.PRState == "merged" && (record.IssuesClosed || config.ClosePolicy != closeOnMerge) {
+			continue
+		}
+		match := prNumberPattern.FindStringSubmatch(record.PRURL)
+		if match == nil {
+			continue
+		}
+		number, _ := strconv.Atoi(match[1])
+
+		pr, err := provider.GetPullRequest(ctx, number)
+		if err != nil {
+			fmt.Printf("Warning: Could not check %s: %v\n", record.PRURL, err)
+			continue
+		}
+		switch {
+		case pr.Merged:
+			record.PRState = "merged"
+			if config.ClosePolicy == closeOnMerge {
+				record.IssuesClosed = closeMergedIssues(ctx, config, provider, pr)
+			}
+			changed = true
+		case pr.State == "closed":
+			// Closed without merging: the issue stays open
+			record.PRState = "closed"
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := saveHistory(records); err != nil {
+			fmt.Printf("Warning: Could not update history: %v\n", err)
+		}
+	}
+}
+
+// closeMergedIssues closes every still open issue a merged PR fixes and reports
+// whether all of them are closed now
+func closeMergedIssues(ctx context.Context, config Config, provider HostingProvider, pr *PullRequest) bool {
+	done := true
+	for _, match := range prIssueRefPattern.FindAllStringSubmatch(pr.Body, -1) {
+		number, _ := strconv.Atoi(match[1])
+		issue, err := provider.GetIssue(ctx, number)
+		if err != nil {
+			fmt.Printf("Warning: Could not check issue #%d: %v\n", number, err)
+			done = false
+			continue
+		}
+		if issue.State == "closed" {
+			continue
+		}
+
+		if err := provider.AddIssueComment(ctx, number, renderComment(config, commentMerged, CommentData{Issue: *issue, PRURL: pr.HTMLURL})); err != nil {
+			fmt.Printf("Warning: Could not comment on issue #%d: %v\n", number, err)
+		}
+		if err := provider.CloseIssue(ctx, number); err != nil {
+			fmt.Printf("Warning: Could not close issue #%d: %v\n", number, err)
+			done = false
+			continue
+		}
+		fmt.Printf("✓ Issue #%d closed (PR #%d merged)\n", number, pr.Number)
+	}
+	return done
+}
@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+func TestClosesAnsweredIssues(t *testing.T) {
+	tests := map[string]bool{
+		closeOnMerge:          false,
+		closeNever:            false,
+		closeOnHighConfidence: true,
+	}
+	for policy, want := range tests {
+		if got := closesAnsweredIssues(Config{ClosePolicy: policy}); got != want {
+			t.Errorf("closesAnsweredIssues(%s) = %v, want %v", policy, got, want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// mockReply answers every JSON request of the mock service other than a fix.
+// Its fields satisfy the replies the pipeline parses: the self-review
+// approves, a pull request review finds nothing, and anything else gets its
+// zero values.
+const mockReply = `{"approved": true, "concerns": [], "summary": "Reply from the mock AI service.", "verdict": "looks_good"}`
+
+// MockClient is the "mock" AI service. It answers without a network or an API
+// key, for integration tests and demos of the whole pipeline: its fix is
+// read from a file in the format of a real fix reply, or, without a file,
+// adds a note about the issue to mock-fixes/.
+type MockClient struct {
+	fixFile   string
+	analytics *SessionAnalytics
+
+	mu    sync.Mutex
+	issue Issue // Last issue fixed, whose fix revisions return again
+}
+
+func NewMockClient(fixFile string) *MockClient {
+	return &MockClient{fixFile: fixFile}
+}
+
+func (m *MockClient) SetAnalytics(analytics *SessionAnalytics) {
+	m.analytics = analytics
+}
+
+// fixReply returns the canned fix reply for an issue
+func (m *MockClient) fixReply(issue Issue) (string, error) {
+	if m.fixFile != "" {
+		data, err := os.ReadFile(m.fixFile)
+		if err != nil {
+			return "", fmt.Errorf("reading mock fix: %w", err)
+		}
+		return string(data), nil
+	}
+
+	reply, err := json.Marshal(map[string]interface{}{
+		"confidence":  "high",
+		"explanation": fmt.Sprintf("Mock fix for #%d. The mock AI service adds a note instead of changing code.", issue.Number),
+		"files": []map[string]string{{
+			"operation": fileCreate,
+			"path":      fmt.Sprintf("mock-fixes/issue-%d.md", issue.Number),
+			"content":   fmt.Sprintf("# %s\n\nMock fix for #%d.\n", issue.Title, issue.Number),
+			"summary":   "Note about the issue from the mock AI service",
+		}},
+	})
+	return string(reply), err
+}
+
+func (m *MockClient) record() {
+	if m.analytics != nil {
+		m.analytics.RecordAPICall("mock", "mock", 0, 0)
+	}
+}
+
+func (m *MockClient) AnalyzeAndFix(ctx context.Context, issue Issue, repoContext *RepoContext) (*Fix, error) {
+	m.record()
+	m.mu.Lock()
+	m.issue = issue
+	m.mu.Unlock()
+	reply, err := m.fixReply(issue)
+	if err != nil {
+		return nil, err
+	}
+	g := &OpenAIClient{}
+	return g.parseFix(reply, repoContext)
+}
+
+// Complete answers plain-text requests such as summaries and plans
+func (m *MockClient) Complete(ctx context.Context, systemPrompt, prompt string) (string, error) {
+	m.record()
+	return "Reply from the mock AI service.", nil
+}
+
+// CompleteJSON answers every JSON request with mockReply
+func (m *MockClient) CompleteJSON(ctx context.Context, systemPrompt, prompt string) (string, error) {
+	m.record()
+	return mockReply, nil
+}
+
+// CompleteSchema answers requests for a fix, such as revisions, with the
+// canned fix of the last issue and others like CompleteJSON
+func (m *MockClient) CompleteSchema(ctx context.Context, systemPrompt, prompt string, schema *JSONSchema) (string, error) {
+	if schema != fixSchema {
+		return m.CompleteJSON(ctx, systemPrompt, prompt)
+	}
+	m.record()
+	m.mu.Lock()
+	issue := m.issue
+	m.mu.Unlock()
+	return m.fixReply(issue)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// systemCABundles are where the system's trusted certificates are usually
+// kept, as found by crypto/x509 on Linux and the BSDs
+var systemCABundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+	"/usr/local/share/certs/ca-root-nss.crt",
+	"/usr/local/etc/openssl/cert.pem",
+}
+
+// gitCABundles caches the combined bundle written for each ca_bundle
+var (
+	gitCABundles   = make(map[string]string)
+	gitCABundlesMu sync.Mutex
+)
+
+// configureNetwork applies the proxy, CA bundle and client certificate settings
+// to http.DefaultTransport, which every HTTP client of the bot (GitHub, Gitea,
+// the AI services, notifications, attachments) uses.
+func configureNetwork(config Config) error {
+	if config.OfflineAI {
+		if err := checkOffline(config); err != nil {
+			return err
+		}
+		// Wrap whatever the other settings produce. A proxy from the
+		// environment would be another destination, so only http_proxy counts.
+		defer func() {
+			transport := baseTransport()
+			if config.HTTPProxy == "" {
+				transport = transport.Clone()
+				transport.Proxy = nil
+			}
+			http.DefaultTransport = &allowlistTransport{next: transport, allowed: offlineHosts(config)}
+		}()
+	}
+	if config.HTTPProxy == "" && config.CABundle == "" && config.ClientCert == "" {
+		return nil
+	}
+
+	transport := baseTransport().Clone()
+
+	if config.HTTPProxy != "" {
+		proxyURL, err := url.Parse(config.HTTPProxy)
+		if err != nil || proxyURL.Host == "" {
+			return fmt.Errorf("invalid http_proxy %q", config.HTTPProxy)
+		}
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			if bypassProxy(req.URL.Hostname(), config.NoProxy) {
+				return nil, nil
+			}
+			return proxyURL, nil
+		}
+	}
+
+	tlsConfig, err := clientTLSConfig(config)
+	if err != nil {
+		return err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	http.DefaultTransport = transport
+	return nil
+}
+
+// clientTLSConfig is the TLS configuration of outbound connections: the
+// system roots plus ca_bundle, and the client certificate if there is one
+func clientTLSConfig(config Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.CABundle != "" {
+		pem, err := os.ReadFile(config.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("reading ca_bundle: %w", err)
+		}
+		// Add to the system roots so public endpoints keep working
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_bundle %s contains no PEM certificates", config.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.ClientCert != "" {
+		if config.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert needs client_key")
+		}
+		cert, err := tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// baseTransport is the *http.Transport under the offline allowlist, if any
+func baseTransport() *http.Transport {
+	if allowlist, ok := http.DefaultTransport.(*allowlistTransport); ok {
+		return allowlist.next.(*http.Transport)
+	}
+	return http.DefaultTransport.(*http.Transport)
+}
+
+// bypassProxy reports hosts that are reached directly: loopback (e.g. a local
+// Ollama) and everything matching no_proxy, by exact name or domain suffix
+func bypassProxy(host string, noProxy []string) bool {
+	if host == "localhost" {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "*" {
+			return true
+		}
+		// "example.com", ".example.com" and "*.example.com" all cover subdomains
+		entry = strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		if entry != "" && (host == entry || strings.HasSuffix(host, "."+entry)) {
+			return true
+		}
+	}
+	return false
+}
+
+// networkGitEnv passes the same settings to git for HTTPS clones and pushes
+func networkGitEnv(config Config) []string {
+	var env []string
+	if config.HTTPProxy != "" {
+		env = append(env, "HTTPS_PROXY="+config.HTTPProxy, "HTTP_PROXY="+config.HTTPProxy)
+		if len(config.NoProxy) > 0 {
+			env = append(env, "NO_PROXY="+strings.Join(config.NoProxy, ","))
+		}
+	}
+	if config.OfflineAI && config.HTTPProxy == "" {
+		// Empty values override proxies from the environment
+		env = append(env, "HTTPS_PROXY=", "HTTP_PROXY=", "ALL_PROXY=", "https_proxy=", "http_proxy=", "all_proxy=")
+	}
+	if config.CABundle != "" {
+		env = append(env, "GIT_SSL_CAINFO="+gitCABundle(config.CABundle))
+	}
+	if config.ClientCert != "" {
+		env = append(env, "GIT_SSL_CERT="+config.ClientCert, "GIT_SSL_KEY="+config.ClientKey)
+	}
+	return env
+}
+
+// gitCABundle is the file git should trust for ca_bundle. GIT_SSL_CAINFO
+// replaces git's certificates instead of adding to them, so the system
+// certificates are written to a temporary file together with ca_bundle, as
+// the HTTP clients trust both. Without a system bundle to find, ca_bundle is
+// used as it is and has to contain every certificate git needs.
+func gitCABundle(caBundle string) string {
+	gitCABundlesMu.Lock()
+	defer gitCABundlesMu.Unlock()
+	if combined, ok := gitCABundles[caBundle]; ok {
+		return combined
+	}
+
+	combined, err := writeCombinedCABundle(caBundle)
+	if err != nil {
+		fmt.Printf("Warning: git will trust only the certificates in ca_bundle: %v\n", err)
+		combined = caBundle
+	}
+	gitCABundles[caBundle] = combined
+	return combined
+}
+
+// writeCombinedCABundle writes the system bundle followed by caBundle to a
+// temporary file and returns its path
+func writeCombinedCABundle(caBundle string) (string, error) {
+	extra, err := os.ReadFile(caBundle)
+	if err != nil {
+		return "", fmt.Errorf("reading ca_bundle: %w", err)
+	}
+
+	candidates := systemCABundles
+	if file := os.Getenv("SSL_CERT_FILE"); file != "" {
+		candidates = append([]string{file}, candidates...)
+	}
+	var system []byte
+	for _, file := range candidates {
+		if system, err = os.ReadFile(file); err == nil && len(system) > 0 {
+			break
+		}
+		system = nil
+	}
+	if system == nil {
+		return "", fmt.Errorf("no system certificate bundle found")
+	}
+
+	file, err := os.CreateTemp("", "mr-code-fixer-ca-*.pem")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	combined := append(append(system, '\n'), extra...)
+	if _, err := file.Write(combined); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
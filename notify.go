@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Notifier posts bot activity to a Slack, Discord or generic JSON webhook
+type Notifier struct {
+	webhookURL string
+	repo       string
+	client     *http.Client
+}
+
+// NewNotifier returns nil when no webhook is configured; a nil Notifier ignores events
+func NewNotifier(config Config) *Notifier {
+	if config.NotifyWebhook == "" {
+		return nil
+	}
+	return &Notifier{
+		webhookURL: config.NotifyWebhook,
+		repo:       config.RepoOwner + "/" + config.RepoName,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Notify sends a message for an issue. Failures are reported but never stop the pipeline.
+func (n *Notifier) Notify(emoji string, issue Issue, message string) {
+	if n == nil {
+		return
+	}
+
+	text := fmt.Sprintf("%s *%s* #%d %s\n%s", emoji, n.repo, issue.Number, issue.Title, message)
+	if issue.HTMLURL != "" {
+		text += "\n" + issue.HTMLURL
+	}
+
+	if err := n.send(text); err != nil {
+		fmt.Printf("Warning: Could not send notification: %v\n", err)
+	}
+}
+
+func (n *Notifier) send(text string) error {
+	// Discord expects "content", Slack and most other webhooks "text"
+	var payload map[string]string
+	if strings.Contains(n.webhookURL, "discord.com/api/webhooks") || strings.Contains(n.webhookURL, "discordapp.com/api/webhooks") {
+		payload = map[string]string{"content": strings.ReplaceAll(text, "*", "**")}
+	} else {
+		payload = map[string]string{"text": text}
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", n.webhookURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// The error names the webhook URL, whose path is the secret
+		urlErr.URL = req.URL.Scheme + "://" + req.URL.Host + "/..."
+	}
+	recordHTTPAudit(auditNotify, "webhook", n.repo, req, auditSummary(text), resp, err)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("webhook error: %s - %s", resp.Status, string(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// errOutboundBlocked marks a request offline_ai refused to send
+var errOutboundBlocked = errors.New("outbound request blocked by offline_ai")
+
+// offlineHosts is the allowlist of offline_ai: the Ollama server, the
+// hosting provider (its web and git host, and the API host of GitHub) and
+// whatever allow_hosts adds
+func offlineHosts(config Config) []string {
+	var hosts []string
+	add := func(rawURL string) {
+		if u, err := url.Parse(rawURL); err == nil && u.Hostname() != "" && !slices.Contains(hosts, strings.ToLower(u.Hostname())) {
+			hosts = append(hosts, strings.ToLower(u.Hostname()))
+		}
+	}
+
+	add(config.OllamaURL)
+	add(providerBaseURL(config))
+	if config.Provider != "gitea" {
+		add("https://api.github.com")
+	}
+	if config.RepoURL != "" {
+		add(repoHostURL(config.RepoURL))
+	}
+	for _, host := range config.AllowHosts {
+		if host = strings.TrimSpace(host); host != "" {
+			add("https://" + host)
+		}
+	}
+	return hosts
+}
+
+// checkOffline refuses settings that would talk to anything but the allowlist,
+// so offline_ai fails at startup rather than halfway through a fix
+func checkOffline(config Config) error {
+	if config.AIService != "ollama" && config.AIService != "mock" {
+		return fmt.Errorf("offline_ai needs ai_service ollama or mock, not %s", config.AIService)
+	}
+	if service := verifierConfig(config).AIService; config.VerifierModel != "" && service != "ollama" && service != "mock" {
+		return fmt.Errorf("offline_ai needs verifier_service ollama or mock, not %s", service)
+	}
+	if config.NotifyWebhook != "" {
+		if u, err := url.Parse(config.NotifyWebhook); err != nil || !slices.Contains(offlineHosts(config), strings.ToLower(u.Hostname())) {
+			return fmt.Errorf("offline_ai: notify_webhook points outside the allowlist (add its host to allow_hosts or remove it)")
+		}
+	}
+	if len(config.EmailTo) > 0 && config.SMTPHost != "" && !slices.Contains(offlineHosts(config), strings.ToLower(config.SMTPHost)) {
+		return fmt.Errorf("offline_ai: smtp_host is not in the allowlist (add it to allow_hosts or remove email_to)")
+	}
+	if config.HTTPProxy != "" {
+		// Requests would leave through the proxy, whatever their destination
+		if u, err := url.Parse(config.HTTPProxy); err != nil || !slices.Contains(offlineHosts(config), strings.ToLower(u.Hostname())) {
+			return fmt.Errorf("offline_ai: http_proxy is not in the allowlist (add its host to allow_hosts or remove it)")
+		}
+	}
+	return nil
+}
+
+// allowlistTransport sends requests only to allowlisted hosts. Anything else
+// fails before a connection is made and is recorded in the audit log.
+type allowlistTransport struct {
+	next    http.RoundTripper
+	allowed []string
+}
+
+func (t *allowlistTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Hostname())
+	if !slices.Contains(t.allowed, host) {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		err := fmt.Errorf("%w: %s is not in the allowlist (%s)", errOutboundBlocked, host, strings.Join(t.allowed, ", "))
+		recordAudit(auditNetwork, "blocked", "", req.Method+" "+req.URL.Host+req.URL.Path, "", err)
+		fmt.Printf("\033[31m✗ Blocked request to %s (offline_ai)\033[0m\n", host)
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// offlineTestEnv points the proxies and package managers of setup and test
+// commands at nothing, so builds use local caches instead of the internet.
+// test_env comes after it and can open a way to an internal mirror.
+func offlineTestEnv() []string {
+	blackhole := "http://127.0.0.1:9"
+	return []string{
+		"HTTP_PROXY=" + blackhole, "HTTPS_PROXY=" + blackhole, "ALL_PROXY=" + blackhole,
+		"http_proxy=" + blackhole, "https_proxy=" + blackhole, "all_proxy=" + blackhole,
+		"NO_PROXY=localhost,127.0.0.1,::1", "no_proxy=localhost,127.0.0.1,::1",
+		"GOPROXY=off", "npm_config_offline=true", "PIP_NO_INDEX=1",
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestCheckOfflineEmail(t *testing.T) {
+	config := Config{
+		AIService: "ollama",
+		OllamaURL: "http://localhost:11434",
+		EmailTo:   []string{"ops@example.com"},
+		SMTPHost:  "smtp.example.com",
+		SMTPPort:  587,
+	}
+	if err := checkOffline(config); err == nil {
+		t.Error("email report to a host outside the allowlist accepted")
+	}
+
+	config.AllowHosts = []string{"SMTP.example.com"}
+	if err := checkOffline(config); err != nil {
+		t.Errorf("email report to an allowlisted host refused: %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// What to do when a configured Ollama model is not installed
+const (
+	ollamaPullAsk    = "ask"    // Ask before pulling it
+	ollamaPullAlways = "always" // Pull it without asking
+	ollamaPullNever  = "never"  // Stop with an error
+)
+
+// ollamaHealthTimeout bounds the reachability check, so a missing server is
+// reported right away instead of after the long generate timeout
+const ollamaHealthTimeout = 5 * time.Second
+
+// Version returns the version of the Ollama server, failing if it is unreachable
+func (o *OllamaClient) Version(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, ollamaHealthTimeout)
+	defer cancel()
+
+	var result struct {
+		Version string `json:"version"`
+	}
+	if err := o.getJSON(ctx, "/api/version", &result); err != nil {
+		return "", err
+	}
+	return result.Version, nil
+}
+
+// LocalModels lists the installed models. Unlike GetAvailableModels it fails
+// instead of suggesting defaults.
+func (o *OllamaClient) LocalModels(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, ollamaHealthTimeout)
+	defer cancel()
+
+	var result struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := o.getJSON(ctx, "/api/tags", &result); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, 0, len(result.Models))
+	for _, m := range result.Models {
+		models = append(models, m.Name)
+	}
+	return models, nil
+}
+
+func (o *OllamaClient) getJSON(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", o.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Ollama API error: %s - %s", resp.Status, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// ollamaPullStatus is one line of the streamed /api/pull response
+type ollamaPullStatus struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest"`
+	Total     int64  `json:"total"`
+	Completed int64  `json:"completed"`
+	Error     string `json:"error"`
+}
+
+// Pull downloads a model, calling progress for every status update
+func (o *OllamaClient) Pull(ctx context.Context, model string, progress func(ollamaPullStatus)) error {
+	jsonData, err := json.Marshal(map[string]interface{}{"model": model, "stream": true})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/api/pull", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Downloads take as long as they take; Ctrl-C cancels them through ctx
+	resp, err := (&http.Client{Transport: o.client.Transport}).Do(req)
+	recordHTTPAudit(auditAI, "pull_model", "", req, model, resp, err)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Ollama API error: %s - %s", resp.Status, string(body))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var status ollamaPullStatus
+		if err := json.Unmarshal(scanner.Bytes(), &status); err != nil {
+			continue
+		}
+		if status.Error != "" {
+			return fmt.Errorf("pulling %s failed: %s", model, status.Error)
+		}
+		progress(status)
+		if status.Status == "success" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("pulling %s ended without success", model)
+}
+
+// hasOllamaModel reports whether model is installed; a name without a tag
+// means ":latest", as in the ollama CLI
+func hasOllamaModel(installed []string, model string) bool {
+	if !strings.Contains(model, ":") {
+		model += ":latest"
+	}
+	return slices.Contains(installed, model)
+}
+
+// ollamaModels returns the distinct models the configuration uses
+func ollamaModels(config Config) []string {
+	var models []string
+	verifier := ""
+	if config.VerifierModel != "" && verifierConfig(config).AIService == "ollama" {
+		verifier = config.VerifierModel
+	}
+	for _, model := range []string{config.AIModel, config.SimpleModel, config.ComplexModel, config.SummaryModel, config.VisionModel, verifier} {
+		if model != "" && !slices.Contains(models, model) {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// printPullProgress renders pull progress on a single line
+func printPullProgress(model string) func(ollamaPullStatus) {
+	last := ""
+	return func(status ollamaPullStatus) {
+		line := fmt.Sprintf("⬇️  %s: %s", model, status.Status)
+		if status.Total > 0 {
+			line = fmt.Sprintf("⬇️  %s: downloading %d%% (%.1f / %.1f GB)", model, status.Completed*100/status.Total, float64(status.Completed)/1e9, float64(status.Total)/1e9)
+		}
+		if line != last {
+			fmt.Printf("\r\033[K%s", line)
+			last = line
+		}
+		if status.Status == "success" {
+			fmt.Println()
+		}
+	}
+}
+
+// checkOllama makes sure the Ollama server answers and every configured model
+// is installed before the pipeline starts; otherwise a missing model only
+// shows up as a 404 in the middle of a fix. Missing models are pulled
+// according to ollama_pull.
+func checkOllama(ctx context.Context, config Config) error {
+	if config.AIService != "ollama" {
+		return nil
+	}
+
+	client := NewOllamaClient(config.OllamaURL, "")
+	version, err := client.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("Ollama is not reachable at %s: %v\n  Start it with `ollama serve`, or point ollama_url / --ollama-url at the right server", config.OllamaURL, err)
+	}
+
+	installed, err := client.LocalModels(ctx)
+	if err != nil {
+		return fmt.Errorf("could not list Ollama models: %w", err)
+	}
+
+	for _, model := range ollamaModels(config) {
+		if hasOllamaModel(installed, model) {
+			continue
+		}
+
+		fmt.Printf(T("ollama.missing"), model, config.OllamaURL)
+		pull := config.OllamaPull
+		if config.OfflineAI {
+			// A pull makes the Ollama server download from its registry
+			pull = ollamaPullNever
+		}
+		switch pull {
+		case ollamaPullNever:
+			return fmt.Errorf("Ollama model %s is not installed (run `ollama pull %s`)", model, model)
+		case ollamaPullAsk:
+			if !isYes(prompt(T("ollama.pull"), T("answer.yes"))) {
+				return fmt.Errorf("Ollama model %s is not installed (run `ollama pull %s`)", model, model)
+			}
+		}
+
+		if err := client.Pull(ctx, model, printPullProgress(model)); err != nil {
+			fmt.Println()
+			return err
+		}
+		fmt.Printf(T("ollama.pulled"), model)
+	}
+
+	fmt.Printf(T("ollama.ready"), version, config.OllamaURL)
+	return nil
+}
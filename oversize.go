@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxBodyChars keeps PR descriptions and comments under GitHub's limit of
+// 65536 characters, which is answered with a 422. Bytes are counted, so
+// multi-byte characters only add margin.
+const maxBodyChars = 65000
+
+// Where sections that do not fit into a pull request description go
+const (
+	overflowComments = "comments" // Collapsed sections in comments on the pull request
+	overflowGist     = "gist"     // A secret gist linked from the description (GitHub only)
+)
+
+var detailsPattern = regexp.MustCompile(`(?s)<details>\s*<summary>(.*?)</summary>(.*?)</details>`)
+
+// overflowPart is a collapsed section moved out of an oversized body
+type overflowPart struct {
+	Title   string
+	Content string
+}
+
+// splitBody moves the largest collapsed sections out of body until it fits
+// into limit, replacing each with the note returned for it. A body that is
+// still too long, e.g. because of a huge explanation, is cut off.
+func splitBody(body string, limit int, note func(part overflowPart) string) string {
+	for len(body) > limit {
+		largest := []int(nil)
+		for _, match := range detailsPattern.FindAllStringSubmatchIndex(body, -1) {
+			if largest == nil || match[1]-match[0] > largest[1]-largest[0] {
+				largest = match
+			}
+		}
+		// Moving a section must save more than the note costs
+		if largest == nil || largest[1]-largest[0] < 500 {
+			break
+		}
+
+		part := overflowPart{
+			Title:   strings.TrimSpace(body[largest[2]:largest[3]]),
+			Content: strings.TrimSpace(body[largest[4]:largest[5]]),
+		}
+		replacement := fmt.Sprintf("<details>\n<summary>%s</summary>\n\n%s\n</details>", part.Title, note(part))
+		body = body[:largest[0]] + replacement + body[largest[1]:]
+	}
+
+	if len(body) > limit {
+		cut := strings.ToValidUTF8(body[:limit-200], "")
+		body = cut + "\n\n… _(cut off: the rest does not fit into the size limit of the description)_"
+	}
+	return body
+}
+
+// fitPRBody makes a pull request description fit the size limit. With
+// body_overflow gist the sections moved out are uploaded as secret gists;
+// otherwise, or if that fails, they are returned to be posted as comments
+// once the pull request exists.
+func fitPRBody(ctx context.Context, config Config, provider HostingProvider, body string) (string, []overflowPart) {
+	var comments []overflowPart
+	fitted := splitBody(body, maxBodyChars, func(part overflowPart) string {
+		if config.BodyOverflow == overflowGist {
+			name := slugify(part.Title, 40) + ".md"
+			url, err := provider.CreateGist(ctx, fmt.Sprintf("%s/%s: %s", config.RepoOwner, config.RepoName, part.Title), name, part.Content)
+			if err == nil {
+				return fmt.Sprintf("Too long for the description, see [%s](%s).", name, url)
+			}
+			fmt.Printf("Warning: Could not create a gist, the section goes into a comment instead: %v\n", err)
+		}
+		comments = append(comments, part)
+		return "Too long for the description, posted as a comment below."
+	})
+	if len(fitted) < len(body) {
+		fmt.Printf("📏 The description was %d characters, over the limit of %d; long sections were moved out\n", len(body), maxBodyChars)
+	}
+	return fitted, comments
+}
+
+// postOverflow posts sections moved out of a pull request description as
+// comments on it, split into as many comments as the size limit needs
+func postOverflow(ctx context.Context, provider HostingProvider, number int, parts []overflowPart) {
+	for _, part := range parts {
+		chunks := chunkText(part.Content, maxBodyChars-1000)
+		for i, chunk := range chunks {
+			title := part.Title
+			if len(chunks) > 1 {
+				title += fmt.Sprintf(" (part %d of %d)", i+1, len(chunks))
+			}
+			comment := fmt.Sprintf("<details>\n<summary>%s</summary>\n\n%s\n</details>\n\n---\n\n%s", title, chunk, bot.signature())
+			if err := provider.AddIssueComment(ctx, number, comment); err != nil {
+				fmt.Printf("Warning: Could not post %q: %v\n", title, err)
+			}
+		}
+	}
+}
+
+// chunkText splits text at line breaks into chunks of at most size bytes. A
+// code block cut in two is closed at the end of one chunk and reopened in
+// the next.
+func chunkText(text string, size int) []string {
+	var chunks []string
+	var chunk strings.Builder
+	inFence := false
+	flush := func() {
+		if inFence {
+			chunk.WriteString("```\n")
+		}
+		chunks = append(chunks, strings.TrimRight(chunk.String(), "\n"))
+		chunk.Reset()
+		if inFence {
+			chunk.WriteString("```\n")
+		}
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for len(line) > size-10 {
+			// A single huge line, e.g. minified output
+			cut := strings.ToValidUTF8(line[:size-10], "")
+			if chunk.Len() > 0 {
+				flush()
+			}
+			chunk.WriteString(cut + "\n")
+			flush()
+			line = line[len(cut):]
+		}
+		if chunk.Len()+len(line)+4 > size {
+			flush()
+		}
+		chunk.WriteString(line)
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+	}
+	if strings.TrimSpace(chunk.String()) != "" {
+		chunks = append(chunks, strings.TrimRight(chunk.String(), "\n"))
+	}
+	return chunks
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// parallelFileWorkers bounds how many files of one fix are generated at once
+const parallelFileWorkers = 4
+
+// FilePlan is one file of a planned fix, with what has to change in it
+type FilePlan struct {
+	Path         string `json:"path"`
+	Operation    string `json:"operation"`
+	NewPath      string `json:"new_path"`
+	Instructions string `json:"instructions"`
+	Test         bool   `json:"test"`
+}
+
+// FixPlan is the AI's plan for a fix: which files change how, and whether
+// each can be written without seeing the new content of the others
+type FixPlan struct {
+	Confidence    string     `json:"confidence"`
+	NeedsMoreInfo bool       `json:"needs_more_info"`
+	Questions     []string   `json:"questions"`
+	Explanation   string     `json:"explanation"`
+	Independent   bool       `json:"independent"`
+	Files         []FilePlan `json:"files"`
+}
+
+// needsContent reports whether the AI has to write the file's new content
+func (f FilePlan) needsContent() bool {
+	return f.Operation != fileDelete && (f.Operation != fileRename || f.Instructions != "")
+}
+
+// generateFix asks the AI for a fix. With parallel_files, the fix is planned
+// first and the files of a plan whose changes are independent are written by
+// concurrent requests; other plans fall back to a single request.
+func generateFix(ctx context.Context, config Config, aiClient AIClient, repoPath string, guard *PathGuard, issue Issue, repoContext *RepoContext) (*Fix, error) {
+	// The mock service has a single canned fix and nothing to plan
+	if !config.ParallelFiles || config.AIService == "mock" {
+		return aiClient.AnalyzeAndFix(ctx, issue, repoContext)
+	}
+
+	plan, err := planFix(ctx, aiClient, repoPath, guard, issue, repoContext)
+	if err != nil {
+		fmt.Printf("Warning: Could not plan the fix, generating it in one request: %v\n", err)
+		return aiClient.AnalyzeAndFix(ctx, issue, repoContext)
+	}
+	if plan.NeedsMoreInfo && len(plan.Questions) > 0 {
+		return &Fix{Confidence: plan.Confidence, NeedsMoreInfo: true, Questions: plan.Questions, Explanation: plan.Explanation}, nil
+	}
+	if len(plan.Files) == 0 {
+		return &Fix{Confidence: plan.Confidence, Explanation: plan.Explanation}, nil
+	}
+
+	writes := 0
+	for _, file := range plan.Files {
+		if file.needsContent() {
+			writes++
+		}
+	}
+	if !plan.Independent || writes < 2 {
+		return aiClient.AnalyzeAndFix(ctx, issue, repoContext)
+	}
+
+	fmt.Printf("⚡ Writing %d independent files in parallel...\n", writes)
+	return writePlannedFiles(ctx, aiClient, repoPath, issue, repoContext, plan)
+}
+
+// planFix asks the AI which files the fix changes and whether they depend
+// on each other. Every planned path has to pass the path guard and stay in
+// the clone, since the files are read for the prompts that write them.
+func planFix(ctx context.Context, aiClient AIClient, repoPath string, guard *PathGuard, issue Issue, repoContext *RepoContext) (*FixPlan, error) {
+	var prompt strings.Builder
+	writeIssueContext(&prompt, issue, repoContext)
+
+	prompt.WriteString(`# Task
+
+Plan the fix for this issue. Do NOT write any code yet: each file will be written separately from your plan. Respond in this JSON format:
+
+{
+  "confidence": "high|medium|low",
+  "needs_more_info": false,
+  "questions": [],
+  "explanation": "Brief explanation of what the fix does",
+  "independent": true,
+  "files": [
+    {
+      "path": "relative/path/to/file.ext",
+      "operation": "create|modify|delete|rename",
+      "new_path": "",
+      "instructions": "Exactly what to change in this file, naming every function, type and signature other files rely on",
+      "test": false
+    }
+  ]
+}
+
+Instructions:
+- If you need more information, set "needs_more_info" to true and list specific "questions" to ask in the issue
+- Set "independent" to true only if every file can be written correctly from its instructions alone, without seeing the new content of the other files; a new function used by another file is fine if the instructions give its exact signature
+- Only include files that need to be modified, created, deleted or renamed
+- For a rename that keeps the content, leave "instructions" empty`)
+	if repoContext.GenerateTests {
+		prompt.WriteString(`
+- Also plan tests that reproduce the issue and verify the fix, with "test": true, using the project's test framework and layout`)
+	}
+	prompt.WriteString("\n- Return valid JSON only, no markdown code blocks")
+
+	response, err := completeJSON(ctx, aiClient, systemPrompt(repoContext), prompt.String())
+	if err != nil {
+		return nil, err
+	}
+	var plan FixPlan
+	if err := json.Unmarshal([]byte(cleanJSONResponse(response)), &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse fix plan: %w", err)
+	}
+	for i, file := range plan.Files {
+		operation, err := fileOperation(file.Operation)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Path, err)
+		}
+		if operation == fileRename && file.NewPath == "" {
+			return nil, fmt.Errorf("%s: rename without a new_path", file.Path)
+		}
+		for _, path := range []string{file.Path, file.NewPath} {
+			if path == "" {
+				continue
+			}
+			if err := guard.Check(path); err != nil {
+				return nil, err
+			}
+			if err := checkContained(repoPath, path); err != nil {
+				return nil, err
+			}
+		}
+		plan.Files[i].Operation = operation
+	}
+	return &plan, nil
+}
+
+// writePlannedFiles writes the files of an independent plan concurrently and
+// merges them into one fix. Any failed file fails the fix, since a partial
+// fix would not compile or make sense.
+func writePlannedFiles(ctx context.Context, aiClient AIClient, repoPath string, issue Issue, repoContext *RepoContext, plan *FixPlan) (*Fix, error) {
+	changes := make([]FileChange, len(plan.Files))
+	errs := make([]error, len(plan.Files))
+
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < parallelFileWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				changes[i], errs[i] = writePlannedFile(ctx, aiClient, repoPath, issue, repoContext, plan, plan.Files[i])
+			}
+		}()
+	}
+	for i, file := range plan.Files {
+		if !file.needsContent() {
+			changes[i] = FileChange{FilePath: file.Path, Operation: file.Operation, NewPath: file.NewPath}
+			continue
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	fix := &Fix{Confidence: plan.Confidence, Explanation: plan.Explanation}
+	for i, file := range plan.Files {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("writing %s: %w", file.Path, errs[i])
+		}
+		fix.FileChanges = append(fix.FileChanges, changes[i])
+		if file.Test {
+			fix.TestFiles = append(fix.TestFiles, file.Path)
+		}
+	}
+	return fix, nil
+}
+
+// writePlannedFile asks the AI for the complete new content of one planned
+// file. The prompt shows the whole file, however large, since it is the only
+// one the request writes.
+func writePlannedFile(ctx context.Context, aiClient AIClient, repoPath string, issue Issue, repoContext *RepoContext, plan *FixPlan, file FilePlan) (FileChange, error) {
+	var prompt strings.Builder
+	writeIssueContext(&prompt, issue, repoContext)
+
+	prompt.WriteString("# Plan\n\n" + plan.Explanation + "\n\n")
+	for _, other := range plan.Files {
+		prompt.WriteString(fmt.Sprintf("- `%s` (%s): %s\n", other.Path, other.Operation, other.Instructions))
+	}
+
+	content, ok := repoContext.Files[file.Path]
+	if !ok {
+		if data, err := os.ReadFile(filepath.Join(repoPath, filepath.FromSlash(file.Path))); err == nil {
+			content, ok = string(data), true
+		}
+	}
+	if ok {
+		prompt.WriteString(fmt.Sprintf("\n# Current Content of %s\n```\n%s\n```\n", file.Path, content))
+	}
+
+	target := file.Path
+	if file.Operation == fileRename {
+		target = file.NewPath
+	}
+	prompt.WriteString(fmt.Sprintf(`
+# Task
+
+Write the complete new content of %s following the plan. The other files are written separately from the same plan, so rely only on what it says about them. Respond in this JSON format:
+
+{
+  "content": "complete file content with the change applied",
+  "summary": "One line for reviewers on what changed in this file and why"
+}
+
+Return valid JSON only, no markdown code blocks.`, target))
+
+	response, err := completeJSON(ctx, aiClient, systemPrompt(repoContext), prompt.String())
+	if err != nil {
+		return FileChange{}, err
+	}
+	var result struct {
+		Content string `json:"content"`
+		Summary string `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(cleanJSONResponse(response)), &result); err != nil {
+		return FileChange{}, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+	if strings.TrimSpace(result.Content) == "" {
+		return FileChange{}, fmt.Errorf("the AI returned no content")
+	}
+	return FileChange{FilePath: file.Path, Content: result.Content, Operation: file.Operation, NewPath: file.NewPath, Summary: result.Summary}, nil
+}
+
+// writeIssueContext adds the issue and the repository to a prompt
+func writeIssueContext(prompt *strings.Builder, issue Issue, repoContext *RepoContext) {
+	prompt.WriteString("# Issue\n\n")
+	prompt.WriteString(fmt.Sprintf("**Title:** %s\n\n", issue.Title))
+	prompt.WriteString(fmt.Sprintf("**Description:**\n%s\n\n", issue.Body))
+	if len(repoContext.References) > 0 {
+		prompt.WriteString(formatReferences(repoContext.References))
+	}
+	if repoContext.Memory != "" {
+		prompt.WriteString(repoContext.Memory)
+	}
+	prompt.WriteString(instructionsSection(repoContext.Instructions))
+	prompt.WriteString(styleSection(repoContext.StyleGuides))
+	writeRepoOverview(prompt, repoContext)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckContained(t *testing.T) {
+	repo := t.TempDir()
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(repo, "link")); err != nil {
+		t.Skipf("symbolic links not available: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(repo, "src", "secret.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"src/main.go", true},
+		{"src/new/dir/file.go", true},
+		{"link/secret", false},
+		{"link/new.go", false},
+		{"src/secret.go", false},
+	}
+	for _, tt := range tests {
+		if err := checkContained(repo, tt.path); (err == nil) != tt.ok {
+			t.Errorf("checkContained(%q) = %v, want ok %v", tt.path, err, tt.ok)
+		}
+	}
+}
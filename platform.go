@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// windowsReservedChars can't appear in Windows file names, and so neither in
+// paths written to the clone nor in branch names, which git stores as files
+const windowsReservedChars = `<>:"|?*`
+
+// windowsReservedName reports whether a path component is a DOS device name
+// such as CON or LPT1, which Windows reserves with any extension
+func windowsReservedName(name string) bool {
+	base, _, _ := strings.Cut(strings.ToUpper(name), ".")
+	base = strings.TrimRight(base, " ")
+	switch base {
+	case "CON", "PRN", "AUX", "NUL":
+		return true
+	}
+	if len(base) == 4 && (strings.HasPrefix(base, "COM") || strings.HasPrefix(base, "LPT")) {
+		return base[3] >= '1' && base[3] <= '9'
+	}
+	return false
+}
+
+// sanitizeBranchName makes a rendered branch template valid both as a git ref
+// and as a file name on Windows, replacing what is not with dashes
+func sanitizeBranchName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f || strings.ContainsRune(` ~^:?*[\`+windowsReservedChars, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	for strings.Contains(name, "..") {
+		name = strings.ReplaceAll(name, "..", ".")
+	}
+	name = strings.ReplaceAll(name, "@{", "-{")
+
+	var components []string
+	for _, component := range strings.Split(name, "/") {
+		component = strings.TrimSuffix(strings.Trim(component, ". "), ".lock")
+		if component == "" {
+			continue
+		}
+		if windowsReservedName(component) {
+			base, ext, _ := strings.Cut(component, ".")
+			component = base + "-"
+			if ext != "" {
+				component += "." + ext
+			}
+		}
+		components = append(components, component)
+	}
+	if len(components) == 0 {
+		return "fix"
+	}
+	return strings.Join(components, "/")
+}
+
+// normalizeRepoPath turns a path relative to the clone into the slash-separated
+// form used in prompts, pull requests and protected path patterns
+func normalizeRepoPath(relPath string) string {
+	return path.Clean(filepath.ToSlash(relPath))
+}
+
+// pythonCandidates are the names a Python interpreter goes by, in order of
+// preference. Windows installs "python" and the "py" launcher, not "python3".
+var pythonCandidates = []string{"python3", "python", "py"}
+
+// lookTool finds an installed tool, trying the other names of the Python
+// interpreter when a Python is asked for
+func lookTool(name string) (string, error) {
+	if name != "python" && name != "python3" {
+		return exec.LookPath(name)
+	}
+	var firstErr error
+	for _, candidate := range pythonCandidates {
+		if _, err := exec.LookPath(candidate); err == nil {
+			return candidate, nil
+		} else if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return "", firstErr
+}
+
+// pythonCommand is the installed Python interpreter, "python" if none is found
+func pythonCommand() string {
+	if name, err := lookTool("python"); err == nil {
+		return name
+	}
+	return "python"
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// platformCommand builds a command from a program and its arguments that runs
+// in dir
+func platformCommand(dir string, parts []string) *exec.Cmd {
+	cmd := exec.Command(parts[0], parts[1:]...)
+	cmd.Dir = dir
+	return cmd
+}
+
+// checkPlatformPath rejects repository paths this system can't create. Unix
+// file systems take anything git does.
+func checkPlatformPath(path string) error {
+	return nil
+}
+
+// tryLockFile takes an exclusive flock on f without waiting. It reports false
+// when another process holds it.
+func tryLockFile(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	return err == nil, err
+}
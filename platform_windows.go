@@ -0,0 +1,83 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// LockFileEx flags and the error it fails with when the range is locked
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+// platformCommand builds a command from a program and its arguments that runs
+// in dir. Programs are resolved with PATHEXT, so "npm" finds npm.cmd, and
+// relative programs such as ./gradlew are looked up in dir. Batch files can't
+// be started directly and run through cmd /c.
+func platformCommand(dir string, parts []string) *exec.Cmd {
+	name := parts[0]
+	if !filepath.IsAbs(name) && strings.ContainsAny(name, `/\`) {
+		name = filepath.Join(dir, name)
+	}
+	if resolved, err := exec.LookPath(name); err == nil {
+		name = resolved
+	}
+
+	var cmd *exec.Cmd
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".cmd", ".bat":
+		shell := os.Getenv("ComSpec")
+		if shell == "" {
+			shell = "cmd.exe"
+		}
+		cmd = exec.Command(shell, append([]string{"/c", name}, parts[1:]...)...)
+	default:
+		cmd = exec.Command(name, parts[1:]...)
+	}
+	cmd.Dir = dir
+	return cmd
+}
+
+// checkPlatformPath rejects repository paths Windows can't create: reserved
+// characters, device names such as CON, and names ending in a dot or space
+func checkPlatformPath(path string) error {
+	for _, component := range strings.Split(filepath.ToSlash(path), "/") {
+		if strings.ContainsAny(component, windowsReservedChars) {
+			return fmt.Errorf("%s: %q contains a character Windows does not allow in file names", path, component)
+		}
+		if windowsReservedName(component) {
+			return fmt.Errorf("%s: %q is a reserved name on Windows", path, component)
+		}
+		if component != "." && component != ".." && strings.TrimRight(component, ". ") != component {
+			return fmt.Errorf("%s: %q ends in a dot or space, which Windows does not allow", path, component)
+		}
+	}
+	return nil
+}
+
+// tryLockFile takes an exclusive LockFileEx lock on f without waiting. It
+// reports false when another process holds it. The locked byte lies far past
+// the end of the file, since Windows locks also block reading the range and
+// waiting instances read who holds the lock.
+func tryLockFile(f *os.File) (bool, error) {
+	overlapped := syscall.Overlapped{OffsetHigh: 0x7fffffff}
+	r, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r != 0 {
+		return true, nil
+	}
+	if err == errorLockViolation {
+		return false, nil
+	}
+	return false, err
+}
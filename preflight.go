@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// errIssueSkipped is returned when the operator declines to process an issue
+var errIssueSkipped = errors.New("skipped by user")
+
+// Expected completion size, as the AI returns full file contents (capped by max_tokens)
+const maxOutputTokens = 8000
+
+// Expected size of a file summary: at most 5 short lines
+const summaryOutputTokens = 150
+
+// PreflightEstimate describes what an AI call for an issue will send and roughly cost
+type PreflightEstimate struct {
+	Files        []string
+	FileSizes    map[string]int
+	Summarized   int // Files summarized by an extra AI call before the fix
+	InputTokens  int
+	OutputTokens int
+	Cost         float64
+}
+
+// estimateIssue builds the prompt that would be sent and estimates its size and cost
+func estimateIssue(config Config, pricing *Pricing, issue Issue, repoContext *RepoContext) *PreflightEstimate {
+	g := &OpenAIClient{}
+	promptText := systemPrompt(repoContext) + g.buildPrompt(issue, repoContext)
+
+	estimate := &PreflightEstimate{
+		FileSizes:   make(map[string]int),
+		InputTokens: estimateTokens(promptText),
+	}
+
+	for path, content := range repoContext.Files {
+		estimate.Files = append(estimate.Files, path)
+		estimate.FileSizes[path] = len(content)
+	}
+	sort.Strings(estimate.Files)
+
+	// Files beyond context_budget are summarized first, one request each, and
+	// only their summaries are part of the fix prompt
+	summaryModel := config.SummaryModel
+	if summaryModel == "" {
+		summaryModel = config.AIModel
+	}
+	for _, path := range filesToSummarize(repoContext, config.ContextBudget) {
+		content := repoContext.Files[path]
+		estimate.Summarized++
+		estimate.InputTokens -= estimateTokens(repoContext.promptContent(content))
+		estimate.InputTokens += summaryOutputTokens
+		estimate.Cost += pricing.Cost(config.AIService, summaryModel, estimateTokens(content[:min(len(content), maxSummaryInputChars)]), summaryOutputTokens)
+	}
+
+	// The reply rewrites whole files, so assume about a quarter of the prompt comes back
+	estimate.OutputTokens = estimate.InputTokens / 4
+	if estimate.OutputTokens > maxOutputTokens {
+		estimate.OutputTokens = maxOutputTokens
+	}
+
+	estimate.Cost += pricing.Cost(config.AIService, config.AIModel, estimate.InputTokens, estimate.OutputTokens)
+	if config.SelfReview {
+		// Self-review sends roughly the diff plus structure; count it as a second, smaller request
+		estimate.Cost += pricing.Cost(config.AIService, config.AIModel, estimate.InputTokens/3, 500)
+	}
+	if config.VerifierModel != "" {
+		// Verification sends about as much as the self-review, to the verifier
+		verifier := verifierConfig(config)
+		estimate.Cost += pricing.Cost(verifier.AIService, verifier.AIModel, estimate.InputTokens/3, 500)
+	}
+
+	return estimate
+}
+
+// confirmPreflight prints the estimate for an issue and asks whether to proceed
+func confirmPreflight(config Config, pricing *Pricing, issue Issue, repoContext *RepoContext) bool {
+	estimate := estimateIssue(config, pricing, issue, repoContext)
+
+	fmt.Printf(T("preflight.title"), issue.Number)
+	fmt.Printf(T("preflight.files"), len(estimate.Files))
+	for _, path := range estimate.Files {
+		fmt.Printf("     \033[90m- %s (%.1f KB)\033[0m\n", path, float64(estimate.FileSizes[path])/1024)
+	}
+	if estimate.Summarized > 0 {
+		fmt.Printf(T("preflight.summaries"), estimate.Summarized)
+	}
+	fmt.Printf(T("preflight.prompt_tokens"), estimate.InputTokens)
+	fmt.Printf(T("preflight.response_tokens"), estimate.OutputTokens)
+	if estimate.Cost > 0 {
+		fmt.Printf(T("preflight.cost"), config.AIModel, pricing.Format(estimate.Cost))
+	} else {
+		fmt.Printf(T("preflight.free"))
+	}
+
+	return isYes(prompt(T("preflight.proceed"), T("answer.yes")))
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ModelPrice is the price of a model per 1K tokens, in USD
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// defaultModelPrices are approximate list prices per 1K tokens in USD.
+// Override or extend them with "pricing" in the config or a pricing file.
+var defaultModelPrices = map[string]ModelPrice{
+	"gpt-4o":                {0.0025, 0.01},
+	"gpt-4o-mini":           {0.00015, 0.0006},
+	"gpt-4.1":               {0.002, 0.008},
+	"gpt-4.1-mini":          {0.0004, 0.0016},
+	"gpt-4-turbo":           {0.01, 0.03},
+	"gpt-4":                 {0.03, 0.06},
+	"gpt-3.5-turbo":         {0.0005, 0.0015},
+	"grok-beta":             {0.005, 0.015},
+	"grok-2":                {0.002, 0.01},
+	"grok-3":                {0.003, 0.015},
+	"grok-3-mini":           {0.0003, 0.0005},
+	"grok-4-fast-reasoning": {0.0002, 0.0005},
+	"grok-code-fast-1":      {0.0002, 0.0015},
+	"codestral-latest":      {0.0003, 0.0009},
+	"mistral-large-latest":  {0.002, 0.006},
+	"mistral-small-latest":  {0.0001, 0.0003},
+	"deepseek-chat":         {0.00027, 0.0011},
+	"deepseek-reasoner":     {0.00055, 0.00219},
+
+	// AWS Bedrock, on-demand prices in us-east-1
+	"anthropic.claude-3-5-sonnet": {0.003, 0.015},
+	"anthropic.claude-3-7-sonnet": {0.003, 0.015},
+	"anthropic.claude-sonnet-4":   {0.003, 0.015},
+	"anthropic.claude-3-5-haiku":  {0.0008, 0.004},
+	"anthropic.claude-3-haiku":    {0.00025, 0.00125},
+	"meta.llama3-1-70b-instruct":  {0.00072, 0.00072},
+	"meta.llama3-1-8b-instruct":   {0.00022, 0.00022},
+	"meta.llama3-3-70b-instruct":  {0.00072, 0.00072},
+}
+
+// serviceFallbackPrices are used for models that are not in the table
+var serviceFallbackPrices = map[string]ModelPrice{
+	"chatgpt":  {0.0025, 0.01},
+	"openai":   {0.0025, 0.01},
+	"grok":     {0.003, 0.015},
+	"xai":      {0.003, 0.015},
+	"mistral":  {0.002, 0.006},
+	"deepseek": {0.00027, 0.0011},
+	"bedrock":  {0.003, 0.015},
+}
+
+// currencyRates are approximate units of each currency per USD
+var currencyRates = map[string]float64{
+	"USD": 1,
+	"EUR": 0.92,
+	"GBP": 0.79,
+	"SEK": 10.5,
+	"NOK": 10.8,
+	"DKK": 6.9,
+}
+
+// PricingFile is the format of an updatable pricing JSON file
+type PricingFile struct {
+	Models map[string]ModelPrice `json:"models"`
+}
+
+// Pricing turns token counts into costs in the configured currency
+type Pricing struct {
+	Currency string
+	rate     float64
+	models   map[string]ModelPrice
+}
+
+func getDefaultPricingPath() string {
+	return filepath.Join(getDataDir(), "pricing.json")
+}
+
+// NewPricing builds the pricing table: built-in prices, then the pricing file,
+// then "pricing" entries from the config
+func NewPricing(config Config) *Pricing {
+	p := &Pricing{
+		Currency: strings.ToUpper(config.Currency),
+		rate:     config.CurrencyRate,
+		models:   make(map[string]ModelPrice),
+	}
+	if p.Currency == "" {
+		p.Currency = "USD"
+	}
+	if p.rate <= 0 {
+		rate, ok := currencyRates[p.Currency]
+		if !ok {
+			fmt.Printf("Warning: No exchange rate known for %s, set currency_rate; showing USD amounts\n", p.Currency)
+			p.Currency, rate = "USD", 1
+		}
+		p.rate = rate
+	}
+
+	for model, price := range defaultModelPrices {
+		p.models[model] = price
+	}
+
+	pricingFile := config.PricingFile
+	if pricingFile == "" {
+		pricingFile = getDefaultPricingPath()
+	}
+	if data, err := os.ReadFile(pricingFile); err == nil {
+		var file PricingFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			fmt.Printf("Warning: Ignoring invalid pricing file %s: %v\n", pricingFile, err)
+		} else {
+			for model, price := range file.Models {
+				p.models[model] = price
+			}
+		}
+	} else if config.PricingFile != "" {
+		fmt.Printf("Warning: Could not read pricing file: %v\n", err)
+	}
+
+	for model, price := range config.Pricing {
+		p.models[model] = price
+	}
+
+	return p
+}
+
+// price finds the price for a model: exact match, then the longest known
+// prefix (so dated versions like gpt-4o-2024-08-06 match), then the service default
+func (p *Pricing) price(service, model string) ModelPrice {
+	if price, ok := p.models[model]; ok {
+		return price
+	}
+
+	best := ""
+	for known := range p.models {
+		if strings.HasPrefix(model, known) && len(known) > len(best) {
+			best = known
+		}
+	}
+	if best != "" {
+		return p.models[best]
+	}
+
+	return serviceFallbackPrices[service]
+}
+
+// Cost returns the cost of a request in the configured currency. Local models
+// and the mock service are free.
+func (p *Pricing) Cost(service, model string, inputTokens, outputTokens int) float64 {
+	if service == "ollama" || service == "mock" {
+		return 0
+	}
+	price := p.price(service, model)
+	usd := float64(inputTokens)/1000*price.Input + float64(outputTokens)/1000*price.Output
+	return usd * p.rate
+}
+
+// Format renders an amount with the currency code
+func (p *Pricing) Format(amount float64) string {
+	return fmt.Sprintf("%.4f %s", amount, p.Currency)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// issueSorts are the accepted values of the sort setting
+var issueSorts = []string{"reactions", "comments", "oldest"}
+
+// thumbsUp is the number of 👍 reactions on an issue, 0 when unknown
+func (i Issue) thumbsUp() int {
+	if i.Reactions == nil {
+		return 0
+	}
+	return i.Reactions.PlusOne
+}
+
+// created parses the issue's creation time, zero when unknown
+func (i Issue) created() time.Time {
+	t, _ := time.Parse(time.RFC3339, i.CreatedAt)
+	return t
+}
+
+// sortIssues orders issues by community signal so the AI budget goes to the
+// issues people care about. Ties keep the provider's order. An empty mode
+// leaves the list as it is (newest first).
+func sortIssues(issues []Issue, mode string) {
+	var less func(a, b Issue) bool
+	switch mode {
+	case "reactions":
+		less = func(a, b Issue) bool {
+			if a.thumbsUp() != b.thumbsUp() {
+				return a.thumbsUp() > b.thumbsUp()
+			}
+			return a.Comments > b.Comments
+		}
+	case "comments":
+		less = func(a, b Issue) bool { return a.Comments > b.Comments }
+	case "oldest":
+		less = func(a, b Issue) bool {
+			// Issues without a known creation time go last
+			if a.created().IsZero() || b.created().IsZero() {
+				return !a.created().IsZero() && b.created().IsZero()
+			}
+			return a.created().Before(b.created())
+		}
+	default:
+		return
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return less(issues[i], issues[j]) })
+}
+
+// issueSignals summarizes reactions, comments and age for issue lists, e.g.
+// "👍 12 · 💬 4 · 3d old"
+func issueSignals(issue Issue) string {
+	var parts []string
+	if n := issue.thumbsUp(); n > 0 {
+		parts = append(parts, fmt.Sprintf("👍 %d", n))
+	}
+	if issue.Comments > 0 {
+		parts = append(parts, fmt.Sprintf("💬 %d", issue.Comments))
+	}
+	if created := issue.created(); !created.IsZero() {
+		parts = append(parts, formatAge(time.Since(created))+" old")
+	}
+	return strings.Join(parts, " · ")
+}
+
+// formatAge renders a duration in the largest fitting unit
+func formatAge(age time.Duration) string {
+	switch {
+	case age >= 365*24*time.Hour:
+		return fmt.Sprintf("%dy", int(age.Hours()/24/365))
+	case age >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	case age >= time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	}
+}
+
+// labelPriority is the priority of an issue's most severe mapped label, 1
+// being the most urgent, and 0 when none of its labels has a priority
+func labelPriority(issue Issue, priorities map[string]int) int {
+	priority := 0
+	for _, label := range issue.Labels {
+		for name, p := range priorities {
+			if strings.EqualFold(name, label.Name) && (priority == 0 || p < priority) {
+				priority = p
+			}
+		}
+	}
+	return priority
+}
+
+// prioritizeIssues orders issues by label_priorities, so "fix all" and serve
+// mode spend the budget on critical bugs before cosmetic requests. Issues
+// without a mapped label go last, and ties keep the order of the sort setting.
+func prioritizeIssues(issues []Issue, priorities map[string]int) {
+	if len(priorities) == 0 {
+		return
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := labelPriority(issues[i], priorities), labelPriority(issues[j], priorities)
+		if a == 0 || b == 0 {
+			return a != 0 && b == 0
+		}
+		return a < b
+	})
+}
+
+// priorityFlag is a flag.Value for label=priority lists such as
+// "critical=1,bug=2,enhancement=5"
+type priorityFlag map[string]int
+
+func (p *priorityFlag) String() string {
+	var items []string
+	for label, priority := range *p {
+		items = append(items, fmt.Sprintf("%s=%d", label, priority))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+func (p *priorityFlag) Set(value string) error {
+	priorities := make(map[string]int)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		label, number, ok := strings.Cut(item, "=")
+		priority, err := strconv.Atoi(strings.TrimSpace(number))
+		if !ok || err != nil || strings.TrimSpace(label) == "" {
+			return fmt.Errorf("invalid priority %q, expected label=number", item)
+		}
+		priorities[strings.TrimSpace(label)] = priority
+	}
+	*p = priorities
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// projectStatusField is the single-select field GitHub project boards use for columns
+const projectStatusField = "Status"
+
+const projectItemsQuery = `query($owner: String!, $repo: String!, $number: Int!, $field: String!) {
+  repository(owner: $owner, name: $repo) {
+    issue(number: $number) {
+      projectItems(first: 20) {
+        nodes {
+          id
+          project {
+            id
+            title
+            field(name: $field) {
+              ... on ProjectV2SingleSelectField {
+                id
+                options { id name }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+const updateProjectItemMutation = `mutation($project: ID!, $item: ID!, $field: ID!, $option: String!) {
+  updateProjectV2ItemFieldValue(input: {projectId: $project, itemId: $item, fieldId: $field, value: {singleSelectOptionId: $option}}) {
+    projectV2Item { id }
+  }
+}`
+
+// graphQL runs a GitHub GraphQL query and decodes its data into out
+func (g *GitHubClient) graphQL(ctx context.Context, query string, variables map[string]interface{}, out interface{}, action string) error {
+	var resp struct {
+		Data   interface{} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	resp.Data = out
+
+	payload := map[string]interface{}{"query": query, "variables": variables}
+	if err := g.request(ctx, "POST", g.baseURL+"/graphql", payload, &resp, http.StatusOK, action); err != nil {
+		return err
+	}
+
+	if len(resp.Errors) > 0 {
+		var messages []string
+		for _, e := range resp.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("GitHub GraphQL error %s: %s", action, strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// SetProjectStatus moves the issue's cards on all linked project boards (Projects v2)
+// to the Status column with the given name
+func (g *GitHubClient) SetProjectStatus(ctx context.Context, issueNumber int, status string) error {
+	var data struct {
+		Repository struct {
+			Issue struct {
+				ProjectItems struct {
+					Nodes []struct {
+						ID      string `json:"id"`
+						Project struct {
+							ID    string `json:"id"`
+							Title string `json:"title"`
+							Field *struct {
+								ID      string `json:"id"`
+								Options []struct {
+									ID   string `json:"id"`
+									Name string `json:"name"`
+								} `json:"options"`
+							} `json:"field"`
+						} `json:"project"`
+					} `json:"nodes"`
+				} `json:"projectItems"`
+			} `json:"issue"`
+		} `json:"repository"`
+	}
+
+	variables := map[string]interface{}{
+		"owner":  g.owner,
+		"repo":   g.repo,
+		"number": issueNumber,
+		"field":  projectStatusField,
+	}
+	if err := g.graphQL(ctx, projectItemsQuery, variables, &data, "fetching project items"); err != nil {
+		return err
+	}
+
+	items := data.Repository.Issue.ProjectItems.Nodes
+	if len(items) == 0 {
+		return nil // Not on any board
+	}
+
+	for _, item := range items {
+		field := item.Project.Field
+		if field == nil || field.ID == "" {
+			fmt.Printf("Warning: Project %q has no %s field\n", item.Project.Title, projectStatusField)
+			continue
+		}
+
+		optionID := ""
+		for _, option := range field.Options {
+			if strings.EqualFold(option.Name, status) {
+				optionID = option.ID
+				break
+			}
+		}
+		if optionID == "" {
+			fmt.Printf("Warning: Project %q has no %q column\n", item.Project.Title, status)
+			continue
+		}
+
+		mutationVars := map[string]interface{}{
+			"project": item.Project.ID,
+			"item":    item.ID,
+			"field":   field.ID,
+			"option":  optionID,
+		}
+		if err := g.graphQL(ctx, updateProjectItemMutation, mutationVars, nil, "moving project card"); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Moved issue card to %q on project %q\n", status, item.Project.Title)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// repoInstructionFiles are Markdown files in the target repository with
+// instructions for the AI, for standards too long for .mr-code-fixer.yml
+var repoInstructionFiles = []string{".mr-code-fixer.md", ".github/mr-code-fixer.md"}
+
+// maxInstructionChars keeps an instructions file from crowding out the code
+const maxInstructionChars = 8000
+
+// loadRepoInstructions reads the instructions file from a clone, or returns ""
+func loadRepoInstructions(repoPath string) string {
+	for _, name := range repoInstructionFiles {
+		data, err := os.ReadFile(filepath.Join(repoPath, name))
+		if err != nil {
+			continue
+		}
+		instructions := strings.TrimSpace(string(data))
+		if len([]rune(instructions)) > maxInstructionChars {
+			fmt.Printf("Warning: %s is longer than %d characters and was truncated\n", name, maxInstructionChars)
+			instructions = truncateRunes(instructions, maxInstructionChars)
+		}
+		fmt.Printf("📄 Loaded instructions for the AI from %s\n", name)
+		return instructions
+	}
+	return ""
+}
+
+// applyPromptSettings puts the custom system prompt and the project
+// instructions into the context. The configured system prompt replaces the
+// built-in one; the repository's own is added to it, since whoever can push
+// to the repository must not be able to replace the bot's instructions.
+// Instructions from all sources are combined.
+func applyPromptSettings(repoContext *RepoContext, config Config, repoConfig *RepoConfig, repoPath string) {
+	repoContext.SystemPrompt = strings.TrimSpace(config.SystemPrompt)
+	if repoPrompt := strings.TrimSpace(strings.Join(repoConfig.SystemPrompt, "\n")); repoPrompt != "" {
+		repoContext.SystemPrompt = systemPrompt(repoContext) + "\n\n" + repoPrompt
+	}
+
+	repoContext.Instructions = append(append([]string{}, config.Instructions...), repoConfig.Instructions...)
+	if instructions := loadRepoInstructions(repoPath); instructions != "" {
+		repoContext.Instructions = append(repoContext.Instructions, instructions)
+	}
+	if config.StyleGuides {
+		repoContext.StyleGuides = loadStyleGuides(repoPath)
+	}
+}
+
+// systemPrompt is the system prompt for requests that produce or plan a fix
+func systemPrompt(repoContext *RepoContext) string {
+	if repoContext == nil || repoContext.SystemPrompt == "" {
+		return fixSystemPrompt
+	}
+	return repoContext.SystemPrompt
+}
+
+// instructionsSection renders the project instructions for the prompt
+func instructionsSection(instructions []string) string {
+	if len(instructions) == 0 {
+		return ""
+	}
+	var section strings.Builder
+	section.WriteString("# Project Instructions\n\nThe maintainers require every change to follow these rules. They take precedence over general best practices:\n\n")
+	for _, instruction := range instructions {
+		if strings.Contains(instruction, "\n") {
+			section.WriteString("\n" + instruction + "\n\n")
+		} else {
+			section.WriteString(fmt.Sprintf("- %s\n", instruction))
+		}
+	}
+	section.WriteString("\n")
+	return section.String()
+}
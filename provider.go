@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// HostingProvider is the code hosting service the bot works against.
+// GitHubClient and GiteaClient (also used for Forgejo) implement it.
+type HostingProvider interface {
+	GetOpenIssues(ctx context.Context, maxIssues int) ([]Issue, error)
+	GetClosedIssues(ctx context.Context, maxIssues int) ([]Issue, error)
+	GetIssue(ctx context.Context, number int) (*Issue, error)
+	GetIssueComments(ctx context.Context, issueNumber int) ([]Comment, error)
+	AddIssueComment(ctx context.Context, issueNumber int, comment string) error
+	EditIssueComment(ctx context.Context, commentID int, comment string) error
+	GetCommentReactions(ctx context.Context, commentID int) ([]Reaction, error)
+	IsCollaborator(ctx context.Context, login string) (bool, error)
+	IsOrgMember(ctx context.Context, org, login string) (bool, error)
+	CloseIssue(ctx context.Context, issueNumber int) error
+	ReopenIssue(ctx context.Context, issueNumber int) error
+	CreatePullRequest(ctx context.Context, title, body, head, base string) (*PullRequest, error)
+	GetPullRequest(ctx context.Context, number int) (*PullRequest, error)
+	GetPullRequestDiff(ctx context.Context, number int) (string, error)
+	ClosePullRequest(ctx context.Context, number int) error
+	UpdatePullRequest(ctx context.Context, number int, title, body string) error
+	FindPullRequest(ctx context.Context, head string) (*PullRequest, error)
+	GetOpenPullRequests(ctx context.Context, maxPRs int) ([]PullRequest, error)
+	DeleteBranch(ctx context.Context, branch string) error
+	RequestReviewers(ctx context.Context, prNumber int, reviewers []string) error
+	AddAssignees(ctx context.Context, issueNumber int, assignees []string) error
+	RemoveAssignees(ctx context.Context, issueNumber int, assignees []string) error
+	GetAuthenticatedUser(ctx context.Context) (string, error)
+	SetMilestone(ctx context.Context, issueNumber int, milestone *Milestone) error
+	SetProjectStatus(ctx context.Context, issueNumber int, status string) error
+	CloneURL() string
+	GetDefaultBranch(ctx context.Context) (string, error)
+	ListOrgRepos(ctx context.Context, org string) ([]Repository, error)
+	CreateGist(ctx context.Context, description, filename, content string) (string, error)
+	GetBranchProtection(ctx context.Context, branch string) (*BranchProtection, error)
+	GetChecks(ctx context.Context, ref string) ([]CheckRun, error)
+	CheckTokenAccess(ctx context.Context) (*TokenAccess, error)
+}
+
+// Repository is a repository of an organization or user, as listed for org mode
+type Repository struct {
+	Name       string   `json:"name"`
+	FullName   string   `json:"full_name"`
+	Language   string   `json:"language"`
+	Topics     []string `json:"topics"`
+	Archived   bool     `json:"archived"`
+	Fork       bool     `json:"fork"`
+	HasIssues  bool     `json:"has_issues"`
+	OpenIssues int      `json:"open_issues_count"`
+}
+
+// newHostingProvider creates the client for the configured provider, with
+// every mutation recorded in the audit log
+func newHostingProvider(config Config) HostingProvider {
+	timeout := timeoutSetting(config.GithubTimeout, defaultGithubTimeout)
+	if config.Provider == "gitea" {
+		client := NewGiteaClient(config.ProviderURL, config.GithubToken, config.RepoOwner, config.RepoName)
+		client.SetTimeout(timeout)
+		client.SetPRDefaults(config.Reviewers, config.Assignees)
+		client.SetMilestoneFilter(config.Milestone)
+		client.SetAssigneeFilter(config.AssigneeFilter)
+		client.SetLabelFilter(config.Label)
+		client.SetIssueSort(config.IssueSort)
+		return &auditedProvider{HostingProvider: client, repo: config.RepoOwner + "/" + config.RepoName}
+	}
+
+	client := NewGitHubClient(config.GithubToken, config.RepoOwner, config.RepoName)
+	client.SetTimeout(timeout)
+	client.SetPRDefaults(config.Reviewers, config.Assignees)
+	client.SetMilestoneFilter(config.Milestone)
+	client.SetAssigneeFilter(config.AssigneeFilter)
+	client.SetLabelFilter(config.Label)
+	client.SetIssueSort(config.IssueSort)
+	client.SetSearchQuery(config.IssueQuery)
+	return &auditedProvider{HostingProvider: client, repo: config.RepoOwner + "/" + config.RepoName}
+}
+
+// providerBaseURL returns the web URL of the configured provider
+func providerBaseURL(config Config) string {
+	if config.Provider == "gitea" && config.ProviderURL != "" {
+		return strings.TrimSuffix(config.ProviderURL, "/")
+	}
+	return "https://github.com"
+}
+
+// httpsCloneURL builds an https clone URL. The token is not part of it; git
+// gets it from gitAuthEnv.
+func httpsCloneURL(baseURL, owner, repo string) string {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil || u.Host == "" {
+		return fmt.Sprintf("https://github.com/%s/%s.git", owner, repo)
+	}
+
+	u.Path = fmt.Sprintf("%s/%s/%s.git", u.Path, owner, repo)
+	return u.String()
+}
+
+// filterByAssignee keeps issues assigned to login, or unassigned ones for
+// "none", or assigned ones for "*"
+func filterByAssignee(issues []Issue, login string) []Issue {
+	var filtered []Issue
+	for _, issue := range issues {
+		keep := false
+		switch login {
+		case "none":
+			keep = len(issue.Assignees) == 0
+		case "*":
+			keep = len(issue.Assignees) > 0
+		default:
+			for _, user := range issue.Assignees {
+				if strings.EqualFold(user.Login, login) {
+					keep = true
+				}
+			}
+		}
+		if keep {
+			filtered = append(filtered, issue)
+		}
+	}
+	return filtered
+}
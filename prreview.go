@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// prReviewMarker tags review comments with the head commit they reviewed,
+// as in "<!-- mr-code-fixer:review 1a2b3c... -->"
+const prReviewMarker = "<!-- mr-code-fixer:review "
+
+// PRReview is the AI's review of a pull request
+type PRReview struct {
+	Summary      string       `json:"summary"`
+	Bugs         []ReviewNote `json:"potential_bugs"`
+	MissingTests []string     `json:"missing_tests"`
+	Style        []ReviewNote `json:"style_issues"`
+	Verdict      string       `json:"verdict"` // "looks_good", "needs_changes" or "needs_discussion"
+}
+
+// ReviewNote is one finding of a review, tied to a place in the diff
+type ReviewNote struct {
+	Path     string `json:"path"`
+	Location string `json:"location"` // Function or line, if known
+	Note     string `json:"note"`
+}
+
+// reviewVerdicts render a review's verdict
+var reviewVerdicts = map[string]string{
+	"looks_good":       "✅ Looks good",
+	"needs_changes":    "🛠️ Needs changes",
+	"needs_discussion": "💬 Needs discussion",
+}
+
+// reviewCommand reviews open pull requests by people, or one given pull
+// request, and posts the review as a comment. Nothing is committed or pushed.
+func reviewCommand(ctx context.Context, args []string) error {
+	config := loadConfig()
+
+	// Allow the PR number before the flags: review 42 --repo foo
+	var prNumber int
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		number, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+		if err != nil {
+			return fmt.Errorf("invalid pull request number %q", args[0])
+		}
+		prNumber = number
+		args = args[1:]
+	}
+
+	var printOnly, force bool
+	maxPRs := 10
+	fs := flag.NewFlagSet("review", flag.ExitOnError)
+	fs.IntVar(&prNumber, "pr", prNumber, "Pull request to review (default: every open pull request by a person)")
+	fs.BoolVar(&printOnly, "print", false, "Print the reviews instead of posting them")
+	fs.BoolVar(&force, "force", false, "Review again even if the latest commit was already reviewed")
+	fs.IntVar(&maxPRs, "max", maxPRs, "Most pull requests to review in one run")
+	parseFlags(&config, fs, args)
+
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+
+	analytics := NewSessionAnalytics(NewPricing(config))
+	provider := newHostingProvider(config)
+	if err := checkOllama(ctx, config); err != nil {
+		return err
+	}
+	if err := identifyBotAccount(ctx, provider); err != nil {
+		return err
+	}
+	aiClient := newAIClient(config, analytics)
+
+	var prs []PullRequest
+	if prNumber > 0 {
+		pr, err := provider.GetPullRequest(ctx, prNumber)
+		if err != nil {
+			return fmt.Errorf("failed to fetch pull request #%d: %w", prNumber, err)
+		}
+		prs = append(prs, *pr)
+	} else {
+		open, err := provider.GetOpenPullRequests(ctx, 100)
+		if err != nil {
+			return fmt.Errorf("failed to fetch pull requests: %w", err)
+		}
+		for _, pr := range open {
+			if pr.Draft || isBotPR(&pr) || strings.HasSuffix(pr.User.Login, "[bot]") {
+				continue
+			}
+			if !force && alreadyReviewed(ctx, provider, pr) {
+				continue
+			}
+			prs = append(prs, pr)
+		}
+		if len(prs) > maxPRs {
+			fmt.Printf("Reviewing %d of %d pull requests (--max)\n", maxPRs, len(prs))
+			prs = prs[:maxPRs]
+		}
+	}
+	if len(prs) == 0 {
+		fmt.Println("✓ No pull requests to review")
+		return nil
+	}
+
+	gitOps, err := newRepoGitOps(ctx, config, provider)
+	if err != nil {
+		return fmt.Errorf("failed to initialize git: %w", err)
+	}
+	defer gitOps.Cleanup()
+	if err := cloneRepo(ctx, config, gitOps); err != nil {
+		return fmt.Errorf("failed to clone repo: %w", err)
+	}
+	memory := loadRepoMemory(config.RepoOwner, config.RepoName)
+
+	for _, pr := range prs {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := reviewPullRequest(ctx, config, provider, gitOps, aiClient, memory, pr, printOnly); err != nil {
+			fmt.Printf("Failed to review #%d: %v\n", pr.Number, err)
+		}
+	}
+
+	analytics.PrintSummary()
+	return nil
+}
+
+// reviewPullRequest reviews one pull request and posts or prints the review
+func reviewPullRequest(ctx context.Context, config Config, provider HostingProvider, gitOps *GitOps, aiClient AIClient, memory *RepoMemory, pr PullRequest, printOnly bool) error {
+	fmt.Printf("\n🔍 Reviewing #%d: %s (@%s)\n", pr.Number, pr.Title, pr.User.Login)
+
+	diff, err := provider.GetPullRequestDiff(ctx, pr.Number)
+	if err != nil {
+		return fmt.Errorf("fetching diff: %w", err)
+	}
+	if strings.TrimSpace(diff) == "" {
+		fmt.Println("  No changes, skipping")
+		return nil
+	}
+
+	// The changed files lead the context, and the issues the description
+	// links to come along as references
+	changed := diffFiles(diff)
+	issue := Issue{
+		Number: pr.Number,
+		Title:  pr.Title,
+		Body:   pr.Body + "\n\nChanged files: " + strings.Join(changed, " "),
+	}
+	_, repoContext, err := prepareRepoContext(ctx, config, provider, gitOps, aiClient, memory, issue)
+	if err != nil {
+		return err
+	}
+
+	review, err := reviewPRDiff(ctx, aiClient, pr, repoContext, diff)
+	if err != nil {
+		return fmt.Errorf("AI review failed: %w", err)
+	}
+	comment := prReviewComment(review, pr)
+
+	if printOnly {
+		fmt.Println()
+		fmt.Println(comment)
+		return nil
+	}
+	if err := provider.AddIssueComment(ctx, pr.Number, comment); err != nil {
+		return fmt.Errorf("posting review: %w", err)
+	}
+	fmt.Printf("✓ Posted review on #%d\n", pr.Number)
+	return nil
+}
+
+// alreadyReviewed reports whether the pull request's latest commit already
+// has a review comment
+func alreadyReviewed(ctx context.Context, provider HostingProvider, pr PullRequest) bool {
+	comments, err := provider.GetIssueComments(ctx, pr.Number)
+	if err != nil {
+		return false
+	}
+	for _, comment := range comments {
+		if isBotComment(comment) && strings.Contains(comment.Body, prReviewMarker+pr.Head.SHA+" -->") {
+			return true
+		}
+	}
+	return false
+}
+
+// diffFiles lists the files a unified diff changes
+func diffFiles(diff string) []string {
+	var files []string
+	for _, line := range strings.Split(diff, "\n") {
+		if path, ok := strings.CutPrefix(line, "+++ b/"); ok {
+			files = append(files, strings.TrimSpace(path))
+		}
+	}
+	return files
+}
+
+// reviewPRDiff asks the AI to review a pull request's diff
+func reviewPRDiff(ctx context.Context, aiClient AIClient, pr PullRequest, repoContext *RepoContext, diff string) (*PRReview, error) {
+	var prompt strings.Builder
+	prompt.WriteString("# Pull Request\n\n")
+	prompt.WriteString(fmt.Sprintf("**Title:** %s\n\n", pr.Title))
+	prompt.WriteString(fmt.Sprintf("**Description:**\n%s\n\n", pr.Body))
+	if len(repoContext.References) > 0 {
+		prompt.WriteString(formatReferences(repoContext.References))
+	}
+	if repoContext.Memory != "" {
+		prompt.WriteString(repoContext.Memory)
+	}
+	prompt.WriteString(instructionsSection(repoContext.Instructions))
+	prompt.WriteString(styleSection(repoContext.StyleGuides))
+	writeRepoOverview(&prompt, repoContext)
+
+	prompt.WriteString("# Diff\n```diff\n")
+	prompt.WriteString(truncateDiff(diff, maxReviewDiffChars))
+	prompt.WriteString("\n```\n\n")
+
+	prompt.WriteString(`# Task
+
+Review this pull request as a senior maintainer of the repository would. The repository files shown are from the default branch, before the change. Check the diff against the description and the issues it references, and look for:
+- Bugs: wrong logic, unhandled errors or edge cases, calls to functions that do not exist, broken existing behavior
+- Missing tests: changed behavior that no test in the diff covers
+- Style: deviations from the conventions of the surrounding code, the project instructions and the code style configuration
+
+Respond in this JSON format:
+
+{
+  "summary": "One or two sentences on what the pull request does and how well",
+  "potential_bugs": [{"path": "file/in/the/diff.ext", "location": "function or line, or empty", "note": "the problem and how to fix it"}],
+  "missing_tests": ["behavior that should be tested"],
+  "style_issues": [{"path": "file/in/the/diff.ext", "location": "", "note": "the deviation"}],
+  "verdict": "looks_good|needs_changes|needs_discussion"
+}
+
+Only report real findings in code the diff changes; empty lists are fine. Return valid JSON only, no markdown code blocks.`)
+
+	response, err := completeJSON(ctx, aiClient, reviewSystemPrompt, prompt.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var review PRReview
+	if err := json.Unmarshal([]byte(cleanJSONResponse(response)), &review); err != nil {
+		return nil, fmt.Errorf("failed to parse review: %w", err)
+	}
+	return &review, nil
+}
+
+// prReviewComment renders a review for the pull request
+func prReviewComment(review *PRReview, pr PullRequest) string {
+	var comment strings.Builder
+	comment.WriteString("## 🔍 Automated Review\n\n")
+	if verdict, ok := reviewVerdicts[review.Verdict]; ok {
+		comment.WriteString(fmt.Sprintf("**%s**\n\n", verdict))
+	}
+	if review.Summary != "" {
+		comment.WriteString(review.Summary + "\n\n")
+	}
+
+	writeNotes := func(heading string, notes []ReviewNote) {
+		if len(notes) == 0 {
+			return
+		}
+		comment.WriteString("### " + heading + "\n\n")
+		for _, note := range notes {
+			place := ""
+			if note.Path != "" {
+				place = fmt.Sprintf("`%s`", note.Path)
+				if note.Location != "" {
+					place += fmt.Sprintf(" (`%s`)", note.Location)
+				}
+				place += ": "
+			}
+			comment.WriteString(fmt.Sprintf("- %s%s\n", place, note.Note))
+		}
+		comment.WriteString("\n")
+	}
+	writeNotes("Potential Bugs", review.Bugs)
+
+	if len(review.MissingTests) > 0 {
+		comment.WriteString("### Missing Tests\n\n")
+		for _, test := range review.MissingTests {
+			comment.WriteString(fmt.Sprintf("- %s\n", test))
+		}
+		comment.WriteString("\n")
+	}
+	writeNotes("Style", review.Style)
+
+	if len(review.Bugs) == 0 && len(review.MissingTests) == 0 && len(review.Style) == 0 {
+		comment.WriteString("No findings.\n\n")
+	}
+
+	commit := pr.Head.SHA
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	comment.WriteString(fmt.Sprintf("%s%s -->\n\n---\n\n%s", prReviewMarker, pr.Head.SHA, bot.footer(fmt.Sprintf("Reviewed at %s by %s. This review is a second pair of eyes, not an approval.", commit, bot.Name))))
+	return comment.String()
+}
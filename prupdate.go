@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// revisionsMarker starts the list of revisions in the description of a bot
+// PR that was regenerated
+const revisionsMarker = "<!-- mr-code-fixer:revisions -->"
+
+// isBotPR reports whether a pull request was opened by the bot: by its
+// account and with its marker, since anyone can copy the marker into a PR
+// whose branch would then be force-pushed
+func isBotPR(pr *PullRequest) bool {
+	return isBotAccount(pr.User.Login) && isBotText(pr.Body)
+}
+
+// findBotPR returns the open pull request the bot opened for an issue earlier,
+// so a reprocessed issue updates it instead of opening another. The fix
+// history is searched first; without a record, e.g. on another machine, the
+// branch the template gives the issue is looked up.
+func findBotPR(ctx context.Context, config Config, provider HostingProvider, issue Issue) *PullRequest {
+	if !config.UpdatePRs {
+		return nil
+	}
+
+	repo := config.RepoOwner + "/" + config.RepoName
+	if records, err := loadHistory(); err == nil {
+		for i := len(records) - 1; i >= 0; i-- {
+			record := records[i]
+			if !strings.EqualFold(record.Repo, repo) || record.IssueNumber != issue.Number || record.PRURL == "" {
+				continue
+			}
+			if record.PRState != "open" {
+				break // The latest PR was merged or closed, so a new one is due
+			}
+			match := prNumberPattern.FindStringSubmatch(record.PRURL)
+			if match == nil {
+				break
+			}
+			number, _ := strconv.Atoi(match[1])
+			if pr, err := provider.GetPullRequest(ctx, number); err == nil && pr.State == "open" && !pr.Merged && isBotPR(pr) {
+				return pr
+			}
+			break
+		}
+	}
+
+	pr, err := provider.FindPullRequest(ctx, createBranchName(issue, config.BranchTemplate))
+	if err != nil {
+		fmt.Printf("Warning: Could not look for an earlier pull request: %v\n", err)
+		return nil
+	}
+	if pr != nil && isBotPR(pr) {
+		return pr
+	}
+	return nil
+}
+
+// revisedPRBody carries the list of revisions of an updated PR over from its
+// old description into the new one, adding an entry for this revision
+func revisedPRBody(oldBody, newBody string, changes *ChangeStats, now time.Time) (string, int) {
+	var entries []string
+	if _, section, ok := strings.Cut(oldBody, revisionsMarker); ok {
+		for _, line := range strings.Split(section, "\n") {
+			if strings.HasPrefix(line, "- ") {
+				entries = append(entries, line)
+			} else if strings.HasPrefix(line, "---") {
+				break
+			}
+		}
+	}
+	if len(entries) == 0 {
+		entries = append(entries, "- **Revision 1:** the original fix")
+	}
+
+	revision := len(entries) + 1
+	entry := fmt.Sprintf("- **Revision %d** (%s): regenerated after new information on the issue", revision, now.Format("2006-01-02"))
+	if changes != nil {
+		entry += fmt.Sprintf(", %s", changes)
+	}
+	entries = append(entries, entry)
+
+	section := fmt.Sprintf("\n### 🔁 Revisions\n\n%s\n%s\n", revisionsMarker, strings.Join(entries, "\n"))
+
+	// Above the footer, which starts at the last rule
+	if i := strings.LastIndex(newBody, "\n---\n"); i != -1 {
+		return newBody[:i] + section + newBody[i:], revision
+	}
+	return newBody + "\n" + section, revision
+}
+
+// revisionComment tells reviewers of an updated PR that its branch was replaced
+func revisionComment(branch string, revision int) string {
+	return fmt.Sprintf("🔁 The issue got new information, so I regenerated the fix and force-pushed `%s` (revision %d). The description lists the revisions.\n\n%s", branch, revision, bot.signature())
+}
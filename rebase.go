@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const conflictSystemPrompt = "You are an expert software developer resolving git merge conflicts. Respond in a structured JSON format."
+
+// maxConflictFiles is how many conflicted files the AI is asked to resolve
+// before the rebase is given up
+const maxConflictFiles = 5
+
+// RebaseResult describes how the fix branch was brought up to date
+type RebaseResult struct {
+	Behind    int      // Commits on the default branch since the clone
+	Conflicts []string // Files that conflicted with those commits
+	Resolved  bool     // The AI resolved all conflicts
+	Retested  bool     // The tests passed again on the resolved branch
+}
+
+// Conflicted reports whether the branch still conflicts with the default branch
+func (r *RebaseResult) Conflicted() bool {
+	return r != nil && len(r.Conflicts) > 0 && !r.Resolved
+}
+
+// prSection explains conflicts in the pull request description
+func (r *RebaseResult) prSection() string {
+	if r == nil || len(r.Conflicts) == 0 {
+		return ""
+	}
+
+	var files strings.Builder
+	for _, path := range r.Conflicts {
+		files.WriteString(fmt.Sprintf("- `%s`\n", path))
+	}
+	if r.Resolved {
+		tests := "No tests could be run on the result"
+		if r.Retested {
+			tests = "The tests were run again on the result and passed"
+		}
+		return fmt.Sprintf("\n### 🔀 Conflicts Resolved\n\n%d new commit(s) on the default branch conflicted with this fix. The AI resolved the conflicts in these files. %s, but please review them closely:\n\n%s", r.Behind, tests, files.String())
+	}
+	return fmt.Sprintf("\n### ⚠️ Merge Conflicts\n\nThis fix conflicts with %d newer commit(s) on the default branch and could not be rebased automatically. These files need a manual resolution:\n\n%s", r.Behind, files.String())
+}
+
+// Rebase fetches the default branch and replays the fix on top of it. On a
+// conflict the rebase is left in progress and the conflicted paths are returned.
+func (g *GitOps) Rebase(ctx context.Context) (int, []string, error) {
+	if err := g.runGitCommand(ctx, "fetch", "--quiet", "origin", g.DefaultBranch); err != nil {
+		return 0, nil, fmt.Errorf("failed to fetch %s: %w", g.DefaultBranch, err)
+	}
+
+	upstream := "origin/" + g.DefaultBranch
+	output, err := g.gitOutput(ctx, "rev-list", "--count", "HEAD.."+upstream)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to compare with %s: %w", upstream, err)
+	}
+	behind, _ := strconv.Atoi(strings.TrimSpace(output))
+	if behind == 0 {
+		return 0, nil, nil
+	}
+
+	if err := g.runGitCommand(ctx, "rebase", "--quiet", upstream); err == nil {
+		return behind, nil, nil
+	}
+
+	conflicts, err := g.conflictedFiles(ctx)
+	if err != nil || len(conflicts) == 0 {
+		g.AbortRebase(ctx)
+		return behind, nil, fmt.Errorf("rebase onto %s failed", upstream)
+	}
+	return behind, conflicts, nil
+}
+
+func (g *GitOps) conflictedFiles(ctx context.Context) ([]string, error) {
+	output, err := g.gitOutput(ctx, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(output), nil
+}
+
+// ContinueRebase stages the resolved files and finishes the rebase
+func (g *GitOps) ContinueRebase(ctx context.Context, paths []string) error {
+	if err := g.runGitCommand(ctx, append([]string{"add", "--"}, paths...)...); err != nil {
+		return fmt.Errorf("failed to stage resolved files: %w", err)
+	}
+	if err := g.runGitCommand(ctx, "-c", "core.editor=true", "rebase", "--continue"); err != nil {
+		return fmt.Errorf("failed to continue rebase: %w", err)
+	}
+	return nil
+}
+
+// AbortRebase puts the branch back as it was before Rebase
+func (g *GitOps) AbortRebase(ctx context.Context) {
+	if err := g.runGitCommand(ctx, "rebase", "--abort"); err != nil {
+		fmt.Printf("Warning: Could not abort rebase: %v\n", err)
+	}
+}
+
+// rebaseFix brings the committed fix up to date with the default branch so
+// the pull request does not conflict the moment it is opened. Conflicts are
+// handed to the AI when resolve_conflicts is on, and the tests are run again
+// on its resolution. Otherwise, or if either fails, the fix is kept as it was
+// and the conflict is reported.
+func rebaseFix(ctx context.Context, config Config, gitOps *GitOps, aiClient AIClient, testRunner *TestRunner, issue Issue, fix *Fix) *RebaseResult {
+	head, err := gitOps.gitOutput(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		fmt.Printf("Warning: Could not rebase onto %s: %v\n", gitOps.DefaultBranch, err)
+		return nil
+	}
+	head = strings.TrimSpace(head)
+
+	behind, conflicts, err := gitOps.Rebase(ctx)
+	if err != nil {
+		fmt.Printf("Warning: Could not rebase onto %s: %v\n", gitOps.DefaultBranch, err)
+		return nil
+	}
+	if behind == 0 {
+		return nil
+	}
+
+	result := &RebaseResult{Behind: behind, Conflicts: conflicts}
+	if len(conflicts) == 0 {
+		fmt.Printf("✓ Rebased onto %d new commit(s) on %s\n", behind, gitOps.DefaultBranch)
+		return result
+	}
+
+	fmt.Printf("⚠️  Fix conflicts with %d new commit(s) on %s: %s\n", behind, gitOps.DefaultBranch, strings.Join(conflicts, ", "))
+	if config.ResolveConflicts && len(conflicts) <= maxConflictFiles {
+		fmt.Println("🔀 Asking the AI to resolve the conflicts...")
+		if err := resolveConflicts(ctx, gitOps, aiClient, issue, fix, conflicts); err != nil {
+			fmt.Printf("Warning: Could not resolve conflicts: %v\n", err)
+			gitOps.AbortRebase(ctx)
+		} else {
+			fmt.Println("✓ Conflicts resolved, running the tests again")
+			tested := testRunner.Execute()
+			if tested.Command == "" || tested.SetupFailed || tested.Refused || tested.Passed {
+				result.Resolved = true
+				result.Retested = tested.Command != "" && tested.Passed
+				return result
+			}
+			fmt.Println("⚠️  Tests failed on the resolved conflicts")
+			fmt.Println(tailText(tested.Output, 3000))
+			if err := gitOps.runGitCommand(ctx, "reset", "--hard", "--quiet", head); err != nil {
+				fmt.Printf("Warning: Could not restore the fix: %v\n", err)
+			}
+		}
+	} else {
+		gitOps.AbortRebase(ctx)
+	}
+	fmt.Println("⚠️  Keeping the fix on the old base; the pull request will be marked as conflicted")
+	return result
+}
+
+// resolveConflicts asks the AI for the merged content of each conflicted file
+// and completes the rebase
+func resolveConflicts(ctx context.Context, gitOps *GitOps, aiClient AIClient, issue Issue, fix *Fix, conflicts []string) error {
+	for _, path := range conflicts {
+		// Upstream commits may have made the path a link out of the clone
+		if err := checkContained(gitOps.repoPath, path); err != nil {
+			return err
+		}
+		fullPath := filepath.Join(gitOps.repoPath, path)
+		content, err := os.ReadFile(fullPath)
+		if err != nil {
+			return err
+		}
+
+		prompt := fmt.Sprintf(`# Merge Conflict
+
+A fix for issue "%s" is being rebased onto newer commits of the default branch, and the file below conflicts.
+In each conflict block, the part between <<<<<<< and ======= is the newer default branch and the part between ======= and >>>>>>> is the fix.
+
+**What the fix does:** %s
+
+### %s
+`+"```"+`
+%s
+`+"```"+`
+
+# Task
+
+Resolve every conflict so the file keeps the newer upstream changes AND still applies the fix. Your response MUST be in the following JSON format:
+
+{
+  "content": "complete file content with all conflicts resolved and no conflict markers"
+}
+
+Return valid JSON only, no markdown code blocks.`, issue.Title, fix.Explanation, path, string(content))
+
+		response, err := completeJSON(ctx, aiClient, conflictSystemPrompt, prompt)
+		if err != nil {
+			return err
+		}
+
+		var result struct {
+			Content string `json:"content"`
+		}
+		if err := json.Unmarshal([]byte(cleanJSONResponse(response)), &result); err != nil {
+			return fmt.Errorf("failed to parse resolution of %s: %w", path, err)
+		}
+		if result.Content == "" || strings.Contains(result.Content, "<<<<<<<") || strings.Contains(result.Content, ">>>>>>>") {
+			return fmt.Errorf("resolution of %s still contains conflicts", path)
+		}
+
+		if err := gitOps.ApplyFileChange(FileChange{FilePath: path, Content: result.Content}); err != nil {
+			return err
+		}
+	}
+
+	return gitOps.ContinueRebase(ctx, conflicts)
+}
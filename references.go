@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxReferences is how many referenced issues, pull requests and
+// commits are fetched for one issue
+const defaultMaxReferences = 5
+
+// Size limits of a reference in the prompt
+const (
+	maxReferenceBodyChars = 1500
+	maxReferenceDiffChars = 4000
+)
+
+var (
+	// #123, owner/repo#123 and links to issues, pull requests and commits
+	issueRefPattern   = regexp.MustCompile(`(?:^|[^\w&#/])(?:([\w.-]+/[\w.-]+))?#(\d+)\b`)
+	issueURLPattern   = regexp.MustCompile(`https?://[^\s/]+/([\w.-]+)/([\w.-]+)/(?:issues|pulls?)/(\d+)`)
+	commitURLPattern  = regexp.MustCompile(`https?://[^\s/]+/([\w.-]+)/([\w.-]+)/commits?/([0-9a-f]{7,40})\b`)
+	commitHashPattern = regexp.MustCompile(`\b[0-9a-f]{7,40}\b`)
+)
+
+// Reference is an issue, pull request or commit mentioned in the issue, with
+// what the AI needs to know about it
+type Reference struct {
+	Kind  string // "issue", "pull request" or "commit"
+	ID    string // "#123" or an abbreviated commit hash
+	Title string
+	State string // open, closed or merged; empty for commits
+	Body  string // Description or commit message, trimmed
+	Diff  string // Changes of pull requests and commits, trimmed
+}
+
+// issueReferences finds the issue and pull request numbers and the commit
+// hashes an issue body mentions, in order of appearance. Mentions of other
+// repositories are ignored.
+func issueReferences(config Config, body string) ([]int, []string) {
+	sameRepo := func(owner, repo string) bool {
+		return strings.EqualFold(owner, config.RepoOwner) && strings.EqualFold(strings.TrimSuffix(repo, ".git"), config.RepoName)
+	}
+
+	type mention struct {
+		at     int
+		number int
+		commit string
+	}
+	var mentions []mention
+	for _, match := range issueRefPattern.FindAllStringSubmatchIndex(body, -1) {
+		if match[2] >= 0 {
+			owner, repo, _ := strings.Cut(body[match[2]:match[3]], "/")
+			if !sameRepo(owner, repo) {
+				continue
+			}
+		}
+		number, _ := strconv.Atoi(body[match[4]:match[5]])
+		mentions = append(mentions, mention{at: match[4], number: number})
+	}
+	for _, match := range issueURLPattern.FindAllStringSubmatchIndex(body, -1) {
+		if sameRepo(body[match[2]:match[3]], body[match[4]:match[5]]) {
+			number, _ := strconv.Atoi(body[match[6]:match[7]])
+			mentions = append(mentions, mention{at: match[0], number: number})
+		}
+	}
+	for _, match := range commitURLPattern.FindAllStringSubmatchIndex(body, -1) {
+		if sameRepo(body[match[2]:match[3]], body[match[4]:match[5]]) {
+			mentions = append(mentions, mention{at: match[0], commit: body[match[6]:match[7]]})
+		}
+	}
+	for _, match := range commitHashPattern.FindAllStringIndex(body, -1) {
+		hash := body[match[0]:match[1]]
+		// Plain numbers and words like "deadbeef" are too likely to be something else
+		if strings.ContainsAny(hash, "abcdef") && strings.ContainsAny(hash, "0123456789") {
+			mentions = append(mentions, mention{at: match[0], commit: hash})
+		}
+	}
+	sort.SliceStable(mentions, func(i, j int) bool { return mentions[i].at < mentions[j].at })
+
+	var numbers []int
+	var commits []string
+	seen := make(map[string]bool)
+	for _, m := range mentions {
+		key := m.commit
+		if m.commit == "" {
+			key = "#" + strconv.Itoa(m.number)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if m.commit != "" {
+			commits = append(commits, m.commit)
+		} else if m.number > 0 {
+			numbers = append(numbers, m.number)
+		}
+	}
+	return numbers, commits
+}
+
+// fetchReferences loads up to max_references issues, pull requests and commits
+// mentioned in the issue. Cross-references are often where the reproduction
+// details live. Lookups that fail are skipped.
+func fetchReferences(ctx context.Context, config Config, provider HostingProvider, gitOps *GitOps, issue Issue) []Reference {
+	if config.MaxReferences <= 0 {
+		return nil
+	}
+
+	// The issue itself and issues fixed together with it are already in the prompt
+	skip := map[int]bool{issue.Number: true}
+	for _, related := range issue.Related {
+		skip[related.Number] = true
+	}
+
+	numbers, commits := issueReferences(config, issue.Body)
+	var refs []Reference
+	for _, number := range numbers {
+		if len(refs) >= config.MaxReferences {
+			break
+		}
+		if skip[number] {
+			continue
+		}
+		ref, err := fetchIssueReference(ctx, provider, number)
+		if err != nil {
+			fmt.Printf("Warning: Could not fetch #%d: %v\n", number, err)
+			continue
+		}
+		refs = append(refs, *ref)
+	}
+	seenCommits := make(map[string]bool) // The same commit may be mentioned by short and full hash
+	for _, hash := range commits {
+		if len(refs) >= config.MaxReferences {
+			break
+		}
+		// Hex strings that are not commits of this repository are simply not found
+		ref, err := gitOps.ShowCommit(ctx, hash)
+		if err != nil || seenCommits[ref.ID] {
+			continue
+		}
+		seenCommits[ref.ID] = true
+		refs = append(refs, *ref)
+	}
+
+	if len(refs) > 0 {
+		var ids []string
+		for _, ref := range refs {
+			ids = append(ids, ref.ID)
+		}
+		fmt.Printf("🔗 Read %d referenced issue(s), pull request(s) and commit(s): %s\n", len(refs), strings.Join(ids, ", "))
+	}
+	return refs
+}
+
+// fetchIssueReference loads an issue, or a pull request with its diff; GitHub
+// and Gitea share one number space for both
+func fetchIssueReference(ctx context.Context, provider HostingProvider, number int) (*Reference, error) {
+	issue, err := provider.GetIssue(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := &Reference{
+		Kind:  "issue",
+		ID:    fmt.Sprintf("#%d", number),
+		Title: issue.Title,
+		State: issue.State,
+		Body:  truncateIssueBody(issue.Body, maxReferenceBodyChars),
+	}
+	if issue.PullRequest == nil {
+		return ref, nil
+	}
+
+	ref.Kind = "pull request"
+	if pr, err := provider.GetPullRequest(ctx, number); err == nil && pr.Merged {
+		ref.State = "merged"
+	}
+	diff, err := provider.GetPullRequestDiff(ctx, number)
+	if err != nil {
+		fmt.Printf("Warning: Could not fetch the diff of #%d: %v\n", number, err)
+	} else {
+		ref.Diff = truncateDiff(diff, maxReferenceDiffChars)
+	}
+	return ref, nil
+}
+
+// ShowCommit reads a commit of the cloned repository as a reference
+func (g *GitOps) ShowCommit(ctx context.Context, hash string) (*Reference, error) {
+	full, err := g.gitOutput(ctx, "rev-parse", "--verify", "--quiet", hash+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown commit %s", hash)
+	}
+	full = strings.TrimSpace(full)
+
+	message, err := g.gitOutput(ctx, "log", "-1", "--format=%B", full)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := g.gitOutput(ctx, "show", "--format=", "--patch", full)
+	if err != nil {
+		return nil, err
+	}
+
+	title, body, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return &Reference{
+		Kind:  "commit",
+		ID:    full[:min(len(full), 12)],
+		Title: title,
+		Body:  truncateIssueBody(strings.TrimSpace(body), maxReferenceBodyChars),
+		Diff:  truncateDiff(diff, maxReferenceDiffChars),
+	}, nil
+}
+
+// truncateDiff keeps the start of a diff, cut at a line boundary
+func truncateDiff(diff string, maxChars int) string {
+	if len(diff) <= maxChars {
+		return diff
+	}
+	cut := diff[:maxChars]
+	if i := strings.LastIndex(cut, "\n"); i > 0 {
+		cut = cut[:i+1]
+	}
+	return cut + fmt.Sprintf("... (diff truncated, %d more characters)\n", len(diff)-len(cut))
+}
+
+// formatReferences renders the references for the prompt
+func formatReferences(refs []Reference) string {
+	var section strings.Builder
+	section.WriteString("## Referenced Issues, Pull Requests and Commits\n\nThe issue mentions these; they may hold reproduction details or show related earlier changes:\n\n")
+	for _, ref := range refs {
+		heading := fmt.Sprintf("### %s %s: %s", ref.Kind, ref.ID, ref.Title)
+		if ref.State != "" {
+			heading += fmt.Sprintf(" (%s)", ref.State)
+		}
+		section.WriteString(heading + "\n")
+		if ref.Body != "" {
+			section.WriteString(ref.Body + "\n")
+		}
+		if ref.Diff != "" {
+			section.WriteString("```diff\n" + ref.Diff)
+			if !strings.HasSuffix(ref.Diff, "\n") {
+				section.WriteString("\n")
+			}
+			section.WriteString("```\n")
+		}
+		section.WriteString("\n")
+	}
+	return section.String()
+}
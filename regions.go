@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Limits for region-targeted editing of large files
+const (
+	maxSelectedRegions  = 8
+	maxRegionChars      = 15000 // Regions larger than this are not offered for editing
+	maxOutlineLineChars = 120
+)
+
+// CodeRegion is a top-level declaration (function, method, class, type) in a file
+type CodeRegion struct {
+	Name  string
+	Start int    // First line, 1-based, including a leading doc comment
+	End   int    // Last line, inclusive
+	Text  string // Source of the region, filled in for selected regions
+}
+
+// RegionEdit replaces one region of a large file
+type RegionEdit struct {
+	Path    string
+	Region  string
+	Content string
+}
+
+var (
+	// Definition lines in common languages: def/class/function/fn/func/... name
+	regionDefPattern = regexp.MustCompile(`^(\s*)(?:(?:export|default|public|private|protected|internal|static|async|abstract|final|override|open|sealed|pub(?:\([^)]*\))?|unsafe|extern)\s+)*(?:def|class|function|fn|func|fun|interface|struct|enum|impl|trait|module|object)\s+([A-Za-z_$][\w$]*)`)
+	// JavaScript/TypeScript arrow functions: const name = (...) => / async x =>
+	regionArrowPattern = regexp.MustCompile(`^(\s*)(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::\s*[^=]+)?=>|[A-Za-z_$][\w$]*\s*=>)`)
+)
+
+// findRegions splits a file into named regions: Go files by their AST,
+// other languages by definition lines and indentation
+func findRegions(path, content string) []CodeRegion {
+	if strings.ToLower(filepath.Ext(path)) == ".go" {
+		if regions := findGoRegions(path, content); regions != nil {
+			return regions
+		}
+	}
+	return findIndentRegions(content)
+}
+
+func findGoRegions(path, content string) []CodeRegion {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	var regions []CodeRegion
+	for _, decl := range file.Decls {
+		start := decl.Pos()
+		var name string
+
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			name = d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name = receiverTypeName(d.Recv.List[0].Type) + "." + name
+			}
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT || len(d.Specs) == 0 {
+				continue
+			}
+			switch spec := d.Specs[0].(type) {
+			case *ast.TypeSpec:
+				name = "type " + spec.Name.Name
+			case *ast.ValueSpec:
+				name = d.Tok.String() + " " + spec.Names[0].Name
+			}
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		}
+
+		if name != "" {
+			regions = append(regions, CodeRegion{
+				Name:  name,
+				Start: fset.Position(start).Line,
+				End:   fset.Position(decl.End()).Line,
+			})
+		}
+	}
+	return uniqueRegionNames(regions)
+}
+
+// receiverTypeName returns T for receivers of type T, *T or T[P]
+func receiverTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return "?"
+}
+
+// findIndentRegions finds definitions by pattern; a region ends where the next
+// definition at the same or a lower indentation starts. Nested definitions are
+// named after their parent, e.g. "UserService.save".
+func findIndentRegions(content string) []CodeRegion {
+	lines := strings.Split(content, "\n")
+
+	type def struct {
+		name   string
+		indent int
+		line   int
+	}
+	var defs []def
+	for i, line := range lines {
+		match := regionDefPattern.FindStringSubmatch(line)
+		if match == nil {
+			match = regionArrowPattern.FindStringSubmatch(line)
+		}
+		if match == nil {
+			continue
+		}
+		defs = append(defs, def{name: match[2], indent: len(strings.ReplaceAll(match[1], "\t", "    ")), line: i + 1})
+	}
+
+	var regions []CodeRegion
+	var parents []int // Indexes into defs of enclosing definitions
+	for i, d := range defs {
+		end := len(lines)
+		for _, next := range defs[i+1:] {
+			if next.indent <= d.indent {
+				end = next.line - 1
+				break
+			}
+		}
+		// Don't count blank lines between regions
+		for end > d.line && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+
+		for len(parents) > 0 && defs[parents[len(parents)-1]].indent >= d.indent {
+			parents = parents[:len(parents)-1]
+		}
+		name := d.name
+		if len(parents) > 0 {
+			name = defs[parents[len(parents)-1]].name + "." + name
+		}
+		parents = append(parents, i)
+
+		regions = append(regions, CodeRegion{Name: name, Start: d.line, End: end})
+	}
+	return uniqueRegionNames(regions)
+}
+
+// uniqueRegionNames suffixes repeated names (overloads, redefinitions) with #2, #3, ...
+func uniqueRegionNames(regions []CodeRegion) []CodeRegion {
+	seen := make(map[string]int)
+	for i := range regions {
+		seen[regions[i].Name]++
+		if n := seen[regions[i].Name]; n > 1 {
+			regions[i].Name = fmt.Sprintf("%s#%d", regions[i].Name, n)
+		}
+	}
+	return regions
+}
+
+// regionOutline lists the regions of a file with their line ranges and first line
+func regionOutline(content string, regions []CodeRegion) string {
+	lines := strings.Split(content, "\n")
+	var outline strings.Builder
+	for _, region := range regions {
+		signature := ""
+		for i := region.Start; i <= region.End && i <= len(lines); i++ {
+			line := strings.TrimSpace(lines[i-1])
+			if line != "" && !strings.HasPrefix(line, "//") && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "*") && !strings.HasPrefix(line, "/*") {
+				signature = truncateRunes(line, maxOutlineLineChars)
+				break
+			}
+		}
+		outline.WriteString(fmt.Sprintf("- %s (lines %d-%d): %s\n", region.Name, region.Start, region.End, signature))
+	}
+	return outline.String()
+}
+
+// regionText returns the source lines of a region
+func regionText(content string, region CodeRegion) string {
+	lines := strings.Split(content, "\n")
+	if region.Start < 1 || region.End > len(lines) || region.Start > region.End {
+		return ""
+	}
+	return strings.Join(lines[region.Start-1:region.End], "\n")
+}
+
+// selectRegions asks the AI which regions of each file too large for the prompt
+// it needs to see, so only those are sent in full and can be edited in place
+func selectRegions(ctx context.Context, aiClient AIClient, issue Issue, repoContext *RepoContext) {
+	outlines := make(map[string][]CodeRegion)
+	var paths []string
+	for _, path := range repoContext.Ranked {
+		content, ok := repoContext.Files[path]
+		if !ok || !repoContext.truncates(content) {
+			continue
+		}
+		if regions := findRegions(path, content); len(regions) > 1 {
+			outlines[path] = regions
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+
+	fmt.Printf("🧩 %d large file(s), asking which regions are relevant...\n", len(paths))
+
+	var prompt strings.Builder
+	prompt.WriteString("# Issue\n\n")
+	prompt.WriteString(fmt.Sprintf("**Title:** %s\n\n", issue.Title))
+	prompt.WriteString(fmt.Sprintf("**Description:**\n%s\n\n", issue.Body))
+	prompt.WriteString("# Large Files\n\nThese files are too large to show in full. Each is listed as an outline of its top-level regions:\n\n")
+	for _, path := range paths {
+		prompt.WriteString(fmt.Sprintf("## %s\n%s\n", path, regionOutline(repoContext.Files[path], outlines[path])))
+	}
+	prompt.WriteString(fmt.Sprintf(`# Task
+
+Pick the regions you need to read or change to fix the issue (at most %d). Your response MUST be in the following JSON format:
+
+{
+  "regions": [
+    {"path": "relative/path/to/file.ext", "name": "exact region name from the outline"}
+  ]
+}
+
+Return an empty list if none of these files are relevant. Return valid JSON only, no markdown code blocks.`, maxSelectedRegions))
+
+	response, err := completeJSON(ctx, aiClient, fixSystemPrompt, prompt.String())
+	if err != nil {
+		fmt.Printf("Warning: Could not select regions, large files stay truncated: %v\n", err)
+		return
+	}
+
+	var result struct {
+		Regions []struct {
+			Path string `json:"path"`
+			Name string `json:"name"`
+		} `json:"regions"`
+	}
+	if err := json.Unmarshal([]byte(cleanJSONResponse(response)), &result); err != nil {
+		fmt.Printf("Warning: Could not parse region selection: %v\n", err)
+		return
+	}
+
+	if repoContext.Regions == nil {
+		repoContext.Regions = make(map[string][]CodeRegion)
+	}
+	selected := 0
+	for _, choice := range result.Regions {
+		if selected >= maxSelectedRegions {
+			break
+		}
+		for _, region := range outlines[choice.Path] {
+			if region.Name != choice.Name {
+				continue
+			}
+			region.Text = regionText(repoContext.Files[choice.Path], region)
+			if len(region.Text) > maxRegionChars {
+				fmt.Printf("Warning: Region %s in %s is too large to edit in place\n", region.Name, choice.Path)
+				break
+			}
+			repoContext.Regions[choice.Path] = append(repoContext.Regions[choice.Path], region)
+			selected++
+			break
+		}
+	}
+
+	if selected > 0 {
+		fmt.Printf("✓ Showing %d region(s) of large files in full\n", selected)
+	}
+}
+
+// spliceRegionEdits turns region edits into whole-file changes by replacing each
+// region in the original content. Regions are located again by name so line
+// numbers always refer to the original file.
+func spliceRegionEdits(fix *Fix, edits []RegionEdit, repoContext *RepoContext) error {
+	byPath := make(map[string][]RegionEdit)
+	var paths []string
+	for _, edit := range edits {
+		if byPath[edit.Path] == nil {
+			paths = append(paths, edit.Path)
+		}
+		byPath[edit.Path] = append(byPath[edit.Path], edit)
+	}
+
+	for _, path := range paths {
+		original, ok := repoContext.Files[path]
+		if !ok {
+			return fmt.Errorf("region edit for %s, which is not in the context", path)
+		}
+		regions := findRegions(path, original)
+
+		type span struct {
+			start, end int
+			content    string
+		}
+		var spans []span
+		for _, edit := range byPath[path] {
+			found := false
+			for _, region := range regions {
+				if region.Name == edit.Region {
+					spans = append(spans, span{region.Start, region.End, edit.Content})
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("region %q not found in %s", edit.Region, path)
+			}
+		}
+
+		// Replace from the bottom up so earlier line numbers stay valid
+		sort.Slice(spans, func(i, j int) bool { return spans[i].start > spans[j].start })
+		lines := strings.Split(original, "\n")
+		for i, s := range spans {
+			if i > 0 && s.end >= spans[i-1].start {
+				return fmt.Errorf("overlapping region edits in %s", path)
+			}
+			replacement := strings.Split(strings.TrimRight(s.content, "\n"), "\n")
+			lines = append(lines[:s.start-1], append(replacement, lines[s.end:]...)...)
+		}
+		content := strings.Join(lines, "\n")
+
+		// A full-file change for the same path wins over region edits
+		replaced := false
+		for _, change := range fix.FileChanges {
+			if change.FilePath == path {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			fix.FileChanges = append(fix.FileChanges, FileChange{FilePath: path, Content: content})
+		}
+		fmt.Printf("🧩 Spliced %d region edit(s) into %s\n", len(spans), path)
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// repoConfigFiles are the names checked for maintainer settings in the target repository
+var repoConfigFiles = []string{".mr-code-fixer.yml", ".mr-code-fixer.yaml"}
+
+// RepoConfig holds the settings maintainers declare in .mr-code-fixer.yml:
+//
+//	setup: go mod download
+//	build: go build ./...
+//	test:
+//	  - go test ./...
+//	lint: golangci-lint run
+//	protected_paths:
+//	  - migrations/
+//	context:
+//	  - docs/ARCHITECTURE.md
+//	hints:
+//	  - HTTP handlers live in internal/api, business logic in internal/core
+//	instructions:
+//	  - Never modify files in db/migrations
+//	  - Target Go 1.21 and use log/slog for logging
+//	system_prompt: You are a senior Go developer on a payments team.
+type RepoConfig struct {
+	Setup          []string // Commands run before validation, e.g. to fetch dependencies
+	Build          []string
+	Test           []string
+	Lint           []string
+	ProtectedPaths []string
+	Context        []string // Files or globs always included in the AI context
+	Hints          []string // Free-text notes for the AI
+	Instructions   []string // Rules every fix must follow
+	SystemPrompt   []string // Replaces the system prompt; lines are joined
+}
+
+// loadRepoConfig reads the repo config from a clone. A missing file yields an empty config.
+func loadRepoConfig(repoPath string) (*RepoConfig, error) {
+	for _, name := range repoConfigFiles {
+		data, err := os.ReadFile(filepath.Join(repoPath, name))
+		if err != nil {
+			continue
+		}
+
+		values, err := parseSimpleYAML(string(data))
+		if err != nil {
+			return &RepoConfig{}, fmt.Errorf("%s: %w", name, err)
+		}
+
+		fmt.Printf("📄 Loaded repository settings from %s\n", name)
+		return &RepoConfig{
+			Setup:          values["setup"],
+			Build:          values["build"],
+			Test:           values["test"],
+			Lint:           values["lint"],
+			ProtectedPaths: values["protected_paths"],
+			Context:        values["context"],
+			Hints:          values["hints"],
+			Instructions:   values["instructions"],
+			SystemPrompt:   values["system_prompt"],
+		}, nil
+	}
+
+	return &RepoConfig{}, nil
+}
+
+// ValidationCommands returns the declared commands in execution order: build, lint, test
+func (r *RepoConfig) ValidationCommands() []string {
+	var commands []string
+	commands = append(commands, r.Build...)
+	commands = append(commands, r.Lint...)
+	commands = append(commands, r.Test...)
+	return commands
+}
+
+// parseSimpleYAML parses the small YAML subset used by the repo config: top-level
+// keys with a scalar, an inline [a, b] list, or a block list of "- item" lines.
+// Every value is returned as a list.
+func parseSimpleYAML(data string) (map[string][]string, error) {
+	values := make(map[string][]string)
+	currentKey := ""
+
+	for lineNum, line := range strings.Split(data, "\n") {
+		line = stripYAMLComment(line)
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if currentKey == "" {
+				return nil, fmt.Errorf("line %d: list item without a key", lineNum+1)
+			}
+			item := unquoteYAML(strings.TrimSpace(strings.TrimPrefix(trimmed, "-")))
+			if item != "" {
+				values[currentKey] = append(values[currentKey], item)
+			}
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			return nil, fmt.Errorf("line %d: nested mappings are not supported", lineNum+1)
+		}
+
+		colon := strings.Index(trimmed, ":")
+		if colon == -1 {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", lineNum+1)
+		}
+
+		currentKey = strings.TrimSpace(trimmed[:colon])
+		value := strings.TrimSpace(trimmed[colon+1:])
+		values[currentKey] = nil
+
+		if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = unquoteYAML(strings.TrimSpace(item)); item != "" {
+					values[currentKey] = append(values[currentKey], item)
+				}
+			}
+		} else if value != "" {
+			values[currentKey] = []string{unquoteYAML(value)}
+		}
+	}
+
+	return values, nil
+}
+
+// stripYAMLComment removes a trailing # comment that is not inside quotes
+func stripYAMLComment(line string) string {
+	inSingle, inDouble := false, false
+	for i, r := range line {
+		switch {
+		case r == '\'' && !inDouble:
+			inSingle = !inSingle
+		case r == '"' && !inSingle:
+			inDouble = !inDouble
+		case r == '#' && !inSingle && !inDouble && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return strings.TrimRight(line, " \t\r")
+}
+
+func unquoteYAML(value string) string {
+	if len(value) >= 2 {
+		if (value[0] == '"' && value[len(value)-1] == '"') || (value[0] == '\'' && value[len(value)-1] == '\'') {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
+
+// matchesPathPattern reports whether a repo-relative path matches a pattern.
+// Patterns ending in "/" match whole directories; others are globs matched
+// against the full path and the base name.
+func matchesPathPattern(path, pattern string) bool {
+	path = filepath.ToSlash(filepath.Clean(path))
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(path+"/", pattern)
+	}
+	if matched, _ := filepath.Match(pattern, path); matched {
+		return true
+	}
+	if !strings.Contains(pattern, "/") {
+		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
+			return true
+		}
+	}
+	return path == pattern
+}
+
+// addContextFiles pins the files matched by the repo config's context globs into the AI context
+func (g *GitOps) addContextFiles(repoContext *RepoContext, patterns []string) {
+	var pinned []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(g.repoPath, pattern))
+		if err != nil {
+			fmt.Printf("Warning: Invalid context pattern %q: %v\n", pattern, err)
+			continue
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || info.IsDir() || info.Size() > 100*1024 {
+				continue
+			}
+			relPath, _ := filepath.Rel(g.repoPath, match)
+			if content, err := os.ReadFile(match); err == nil {
+				repoContext.Files[filepath.ToSlash(relPath)] = string(content)
+				pinned = append(pinned, filepath.ToSlash(relPath))
+			}
+		}
+	}
+
+	// Pinned files rank first so they are never summarized away
+	ranked := pinned
+	for _, path := range repoContext.Ranked {
+		if !slices.Contains(pinned, path) {
+			ranked = append(ranked, path)
+		}
+	}
+	repoContext.Ranked = ranked
+	repoContext.FileCount = len(repoContext.Files)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// reproductionMarkers are phrases issues with reproduction steps contain
+var reproductionMarkers = []string{
+	"steps to reproduce", "to reproduce", "how to reproduce", "reproduction", "repro steps",
+	"expected behavior", "expected behaviour", "expected result", "actual behavior", "actual result",
+}
+
+// reproductionCommands are the shapes a reproduction command may have, by the
+// tool the project's tests run with. Only the test subcommand of a runner is
+// allowed, and no option that runs another program in its place.
+var reproductionCommands = map[string][][]string{
+	"go":     {{"go", "test"}},
+	"node":   {{"npx", "jest"}, {"npx", "vitest"}, {"npx", "mocha"}},
+	"pytest": {{"pytest"}, {"python", "-m", "pytest"}, {"python3", "-m", "pytest"}, {"py", "-m", "pytest"}},
+	"cargo":  {{"cargo", "test"}},
+}
+
+// reproductionForbiddenArgs make go test run another program with the test
+// binary; they are matched with one or two leading dashes
+var reproductionForbiddenArgs = []string{"-exec", "-toolexec"}
+
+// Reproduction is a test the AI wrote to reproduce the issue, confirmed to
+// fail before the fix
+type Reproduction struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Command string `json:"command"`
+	Before  string `json:"-"` // Output of the failing run on the unfixed code
+}
+
+// hasReproductionSteps reports whether the issue describes how to trigger the bug
+func hasReproductionSteps(issue Issue) bool {
+	body := strings.ToLower(issue.Body)
+	for _, marker := range reproductionMarkers {
+		if strings.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// reproduceIssue asks the AI for a test that reproduces the issue, writes it
+// and checks that it fails on the unfixed code. It returns nil when no
+// reproduction could be confirmed; the fix then goes ahead unverified.
+func reproduceIssue(ctx context.Context, gitOps *GitOps, aiClient AIClient, issue Issue, repoContext *RepoContext, testRunner *TestRunner) *Reproduction {
+	commands := testRunner.TestCommands()
+	if len(commands) == 0 {
+		fmt.Println(T("repro.no_tests"))
+		return nil
+	}
+
+	fmt.Println(T("repro.writing"))
+	repro, err := generateReproduction(ctx, aiClient, issue, repoContext, commands)
+	if err != nil {
+		fmt.Printf("Warning: Could not write a reproduction: %v\n", err)
+		return nil
+	}
+	if !filepath.IsLocal(repro.Path) {
+		fmt.Printf("Warning: Ignoring reproduction outside the repository: %s\n", repro.Path)
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(gitOps.repoPath, repro.Path)); err == nil {
+		fmt.Printf("Warning: Ignoring reproduction that would overwrite %s\n", repro.Path)
+		return nil
+	}
+	if err := repro.write(gitOps); err != nil {
+		fmt.Printf("Warning: Could not write the reproduction: %v\n", err)
+		return nil
+	}
+
+	result := testRunner.Run(repro.Command)
+	if result.SetupFailed || result.Refused {
+		fmt.Printf(T("process.setup_failed"), result.Command)
+		repro.remove(gitOps)
+		return nil
+	}
+	if result.Passed {
+		fmt.Printf(T("repro.not_reproduced"), repro.Path)
+		repro.remove(gitOps)
+		return nil
+	}
+
+	repro.Before = result.Output
+	fmt.Printf(T("repro.reproduced"), repro.Path)
+	return repro
+}
+
+// generateReproduction asks the AI for a failing test and the command that runs
+// it. The command must use the same tool as the project's own tests, so the AI
+// cannot run arbitrary programs.
+func generateReproduction(ctx context.Context, aiClient AIClient, issue Issue, repoContext *RepoContext, commands []string) (*Reproduction, error) {
+	g := &OpenAIClient{}
+	var prompt strings.Builder
+	prompt.WriteString(g.buildPrompt(issue, repoContext))
+	prompt.WriteString(fmt.Sprintf(`
+
+# Different Task: Reproduce the Bug
+
+Do NOT fix the issue. Write one automated test that reproduces it: it must FAIL on the current code because of the reported bug, and PASS once the bug is fixed.
+
+- Use the project's existing test framework, file naming and directory layout
+- Test only the reported behavior, with the inputs from the reproduction steps
+- The project's tests are run with: %s
+- Give a command that runs just this test, using the same tool (e.g. "go test ./parser -run TestIssue12" or "npx jest tests/issue-12.test.js")
+
+Respond in JSON format:
+{
+  "path": "relative/path/to/test_file",
+  "content": "complete content of the test file",
+  "command": "command that runs only this test"
+}
+
+Return valid JSON only, no markdown code blocks.`, strings.Join(commands, " && ")))
+
+	response, err := completeJSON(ctx, aiClient, systemPrompt(repoContext), prompt.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var repro Reproduction
+	if err := json.Unmarshal([]byte(cleanJSONResponse(response)), &repro); err != nil {
+		return nil, fmt.Errorf("failed to parse reproduction: %w", err)
+	}
+	if repro.Path == "" || repro.Content == "" || repro.Command == "" {
+		return nil, fmt.Errorf("incomplete reproduction")
+	}
+
+	if err := checkReproductionCommand(repro.Command, commands); err != nil {
+		return nil, err
+	}
+	return &repro, nil
+}
+
+// checkReproductionCommand refuses a reproduction command that is not the
+// test command of a tool the project's tests use, e.g. "go test ./x -run T"
+// for "go test ./..." or "npx jest x.test.js" for "npm test"
+func checkReproductionCommand(command string, projectCommands []string) error {
+	parts, err := splitCommand(command)
+	if err != nil {
+		return err
+	}
+	for _, project := range projectCommands {
+		projectParts, err := splitCommand(project)
+		if err != nil {
+			continue
+		}
+		for _, shape := range reproductionCommands[testTool(projectParts)] {
+			if len(parts) < len(shape) || !slices.Equal(parts[:len(shape)], shape) {
+				continue
+			}
+			for _, arg := range parts[len(shape):] {
+				name, _, _ := strings.Cut(arg, "=")
+				name = "-" + strings.TrimLeft(name, "-")
+				if slices.Contains(reproductionForbiddenArgs, name) {
+					return fmt.Errorf("reproduction command %q uses %s", command, name)
+				}
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("reproduction command %q is not a test command of the project's test tool", command)
+}
+
+// testTool is the tool a test command runs tests with, "" if it is none
+// that reproductions support
+func testTool(parts []string) string {
+	if len(parts) == 0 {
+		return ""
+	}
+	name := executableName(parts[0])
+	switch {
+	case name == "go" && len(parts) > 1 && parts[1] == "test":
+		return "go"
+	case name == "npx" || slices.Contains(packageManagers, name):
+		return "node"
+	case name == "pytest" || slices.Contains(pythonLaunchers, name) && len(parts) > 2 && parts[1] == "-m" && parts[2] == "pytest":
+		return "pytest"
+	case name == "cargo" && len(parts) > 1 && parts[1] == "test":
+		return "cargo"
+	}
+	return ""
+}
+
+func (r *Reproduction) write(gitOps *GitOps) error {
+	return gitOps.ApplyFileChange(FileChange{FilePath: r.Path, Content: r.Content})
+}
+
+func (r *Reproduction) remove(gitOps *GitOps) {
+	os.Remove(filepath.Join(gitOps.repoPath, r.Path))
+}
+
+// Verify runs the reproduction against the fixed code. The file is written
+// again first, since shrinking or revising the fix resets the working tree.
+func (r *Reproduction) Verify(gitOps *GitOps, testRunner *TestRunner) *TestResult {
+	if err := r.write(gitOps); err != nil {
+		return &TestResult{Passed: false, Output: err.Error(), Command: r.Command}
+	}
+	fmt.Println(T("repro.verifying"))
+	return testRunner.Run(r.Command)
+}
+
+// prSection shows the reproduction as evidence in the pull request
+func (r *Reproduction) prSection() string {
+	return fmt.Sprintf("\n### 🐞 Bug Reproduced and Verified\n\n`%s` reproduces the issue: `%s` failed before the fix and passes with it.\n\n<details>\n<summary>Output before the fix</summary>\n\n```\n%s\n```\n</details>\n", r.Path, r.Command, tailText(r.Before, 3000))
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestCheckReproductionCommand(t *testing.T) {
+	tests := []struct {
+		command  string
+		projects []string
+		ok       bool
+	}{
+		{"go test ./parser -run TestIssue12", []string{"go test ./..."}, true},
+		{"go test -exec ./evil ./parser", []string{"go test ./..."}, false},
+		{"go test --toolexec=evil ./parser", []string{"go test ./..."}, false},
+		{"go run ./evil", []string{"go test ./..."}, false},
+		{"go vet ./...", []string{"go test ./..."}, false},
+		{"npx jest tests/issue-12.test.js", []string{"npm test"}, true},
+		{"npx vitest run tests/issue.test.ts", []string{"pnpm test"}, true},
+		{"npx evil", []string{"npm test"}, false},
+		{"npx --package=evil jest", []string{"npm test"}, false},
+		{"npm x evil", []string{"npm test"}, false},
+		{"npm exec evil", []string{"npm test"}, false},
+		{"npx jest x.test.js", []string{"go test ./..."}, false},
+		{"pytest tests/test_issue.py", []string{"python3 -m pytest"}, true},
+		{"python3 -m pytest tests/test_issue.py", []string{"pytest"}, true},
+		{"python3 evil.py", []string{"python3 -m pytest"}, false},
+		{"cargo test issue_12", []string{"cargo test"}, true},
+		{"cargo run", []string{"cargo test"}, false},
+		{"go test ./... && curl evil", []string{"go test ./..."}, false},
+	}
+	for _, tt := range tests {
+		err := checkReproductionCommand(tt.command, tt.projects)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkReproductionCommand(%q, %q) = %v, want ok %v", tt.command, tt.projects, err, tt.ok)
+		}
+	}
+}
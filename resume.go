@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pipeline stages after which a fix can be resumed
+const (
+	stageCommitted = "committed" // Committed locally, not pushed yet
+	stagePushed    = "pushed"    // Branch pushed, pull request not created yet
+)
+
+// PipelineState is everything needed to finish a committed fix, saved before
+// the push and pull request steps so a failure there does not lose the work
+type PipelineState struct {
+	Repo        string       `json:"repo"` // owner/name
+	Issue       Issue        `json:"issue"`
+	Related     []Issue      `json:"related,omitempty"` // Issue.Related is not serialized
+	Stage       string       `json:"stage"`
+	Branch      string       `json:"branch"`
+	BaseBranch  string       `json:"base_branch"`
+	Bundle      string       `json:"bundle"` // git bundle with the fix commit
+	PRTitle     string       `json:"pr_title"`
+	PRBody      string       `json:"pr_body"`
+	Explanation string       `json:"explanation"`
+	Confidence  string       `json:"confidence"`
+	Files       []string     `json:"files"`
+	Score       int          `json:"score"`
+	CloseIssue  bool         `json:"close_issue"`
+	Changes     *ChangeStats `json:"changes,omitempty"`
+	UpdatePR    int          `json:"update_pr,omitempty"`  // Earlier bot PR the fix replaces, 0 for a new PR
+	UpdateSHA   string       `json:"update_sha,omitempty"` // Head of UpdatePR that the push may replace
+	Revision    int          `json:"revision,omitempty"`   // Revision number of UpdatePR
+	SavedAt     time.Time    `json:"saved_at"`
+}
+
+func getStateDir(owner, repo string) string {
+	return filepath.Join(getDataDir(), "state", owner, repo)
+}
+
+func getStatePath(owner, repo string, issueNumber int) string {
+	return filepath.Join(getStateDir(owner, repo), fmt.Sprintf("issue-%d.json", issueNumber))
+}
+
+// fix rebuilds the parts of the fix that are used after the pull request is created
+func (s *PipelineState) fix() *Fix {
+	fix := &Fix{Explanation: s.Explanation, Confidence: s.Confidence}
+	for _, path := range s.Files {
+		fix.FileChanges = append(fix.FileChanges, FileChange{FilePath: path})
+	}
+	return fix
+}
+
+func (s *PipelineState) issue() Issue {
+	issue := s.Issue
+	issue.Related = s.Related
+	return issue
+}
+
+func (s *PipelineState) Save(config Config) error {
+	s.SavedAt = time.Now()
+	path := getStatePath(config.RepoOwner, config.RepoName, s.Issue.Number)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Remove deletes the saved state and its bundle once the pull request exists
+func (s *PipelineState) Remove(config Config) {
+	os.Remove(getStatePath(config.RepoOwner, config.RepoName, s.Issue.Number))
+	if s.Bundle != "" {
+		os.Remove(s.Bundle)
+	}
+}
+
+func loadPipelineState(config Config, issueNumber int) (*PipelineState, error) {
+	data, err := os.ReadFile(getStatePath(config.RepoOwner, config.RepoName, issueNumber))
+	if err != nil {
+		return nil, err
+	}
+
+	var state PipelineState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid saved state for issue #%d: %w", issueNumber, err)
+	}
+	return &state, nil
+}
+
+// listPipelineStates returns the unfinished fixes saved for the configured repository
+func listPipelineStates(config Config) []*PipelineState {
+	entries, err := os.ReadDir(getStateDir(config.RepoOwner, config.RepoName))
+	if err != nil {
+		return nil
+	}
+
+	var states []*PipelineState
+	for _, entry := range entries {
+		name := strings.TrimSuffix(strings.TrimPrefix(entry.Name(), "issue-"), ".json")
+		number, err := strconv.Atoi(name)
+		if err != nil || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if state, err := loadPipelineState(config, number); err == nil {
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
+// publishFix pushes a committed fix and opens its pull request. The state is
+// saved before each step, so after a failure resume-issue continues from the
+// last step that succeeded.
+func publishFix(ctx context.Context, config Config, provider HostingProvider, gitOps *GitOps, state *PipelineState, analytics *SessionAnalytics, notifier *Notifier, outcome *FixOutcome) error {
+	issue := state.issue()
+	fix := state.fix()
+	resumeHint := fmt.Sprintf("run `mr-code-fixer resume-issue %d` to continue", issue.Number)
+
+	if state.Stage == stageCommitted {
+		push := func() error { return gitOps.Push(ctx, state.Branch) }
+		if state.UpdatePR != 0 {
+			push = func() error {
+				// The PR may have changed hands since it was picked for the update
+				pr, err := provider.GetPullRequest(ctx, state.UpdatePR)
+				if err != nil {
+					return err
+				}
+				if !isBotPR(pr) || pr.Head.Ref != state.Branch {
+					return fmt.Errorf("pull request #%d is not the bot's pull request for %s, refusing to force-push", state.UpdatePR, state.Branch)
+				}
+				return gitOps.ForcePush(ctx, state.Branch, state.UpdateSHA)
+			}
+		}
+		if err := push(); err != nil {
+			return fmt.Errorf("failed to push branch: %w (%s)", err, resumeHint)
+		}
+		state.Stage = stagePushed
+		if err := state.Save(config); err != nil {
+			fmt.Printf("Warning: Could not save progress: %v\n", err)
+		}
+	}
+
+	// Long test output can push the description over the size limit
+	body, overflow := fitPRBody(ctx, config, provider, state.PRBody)
+	var pr *PullRequest
+	var err error
+	if state.UpdatePR != 0 {
+		if err := provider.UpdatePullRequest(ctx, state.UpdatePR, state.PRTitle, body); err != nil {
+			return fmt.Errorf("failed to update pull request: %w (%s)", err, resumeHint)
+		}
+		if pr, err = provider.GetPullRequest(ctx, state.UpdatePR); err != nil {
+			return fmt.Errorf("failed to fetch pull request: %w (%s)", err, resumeHint)
+		}
+		if err := provider.AddIssueComment(ctx, pr.Number, revisionComment(state.Branch, state.Revision)); err != nil {
+			fmt.Printf("Warning: Could not comment on the pull request: %v\n", err)
+		}
+	} else if pr, err = provider.CreatePullRequest(ctx, state.PRTitle, body, state.Branch, state.BaseBranch); err != nil {
+		return fmt.Errorf("failed to create pull request: %w (%s)", err, resumeHint)
+	}
+	state.Remove(config)
+	prURL := pr.HTMLURL
+	postOverflow(ctx, provider, pr.Number, overflow)
+
+	// Get the PR in front of the people who own the changed code
+	if config.CodeOwners {
+		requestCodeOwnerReview(ctx, config, provider, gitOps, pr, fix)
+	}
+
+	// Keep the PR on the issue's milestone and move the board card along
+	if issue.Milestone != nil {
+		if err := provider.SetMilestone(ctx, pr.Number, issue.Milestone); err != nil {
+			fmt.Printf("Warning: Could not set milestone: %v\n", err)
+		}
+	}
+	if config.ProjectStatus != "" {
+		for _, member := range groupIssues(issue) {
+			if err := provider.SetProjectStatus(ctx, member.Number, config.ProjectStatus); err != nil {
+				fmt.Printf("Warning: Could not update project board: %v\n", err)
+			}
+		}
+	}
+
+	for range groupIssues(issue) {
+		analytics.RecordIssueHandled()
+	}
+	outcome.PRURL = prURL
+	outcome.Confidence = fix.Confidence
+	outcome.Score = state.Score
+	outcome.Notes = fix.Explanation
+	if state.UpdatePR != 0 {
+		outcome.Result = "pr_updated"
+		fmt.Printf(T("process.pr_updated"), prURL, state.Revision)
+		notifier.Notify("🔁", issue, fmt.Sprintf("Updated pull request to revision %d (%s confidence, score %d/100): %s", state.Revision, fix.Confidence, state.Score, prURL))
+	} else {
+		analytics.RecordPRCreated(prURL)
+		outcome.Result = "pr_created"
+		fmt.Printf(T("process.pr_created"), prURL)
+		notifier.Notify("🔧", issue, fmt.Sprintf("Opened pull request (%s confidence, score %d/100): %s", fix.Confidence, state.Score, prURL))
+	}
+
+	// A high score says nothing about CI, so optionally let the checks decide too
+	if state.CloseIssue && !checksAllowClose(ctx, config, provider, pr, state.BaseBranch) {
+		state.CloseIssue = false
+	}
+
+	// Issues fixed along with this one point at the shared PR so they are not picked up again
+	if !state.CloseIssue {
+		for _, related := range issue.Related {
+			if err := provider.AddIssueComment(ctx, related.Number, groupedComment(issue, prURL)); err != nil {
+				fmt.Printf("Warning: Could not comment on issue #%d: %v\n", related.Number, err)
+			}
+		}
+	}
+
+	// If the calibrated score is high enough, close the issue with a detailed comment
+	if state.CloseIssue {
+		fmt.Printf("Closing issue (confidence score %d/100)...\n", state.Score)
+
+		closeComment := renderComment(config, commentResolved, CommentData{Issue: issue, Explanation: fix.Explanation, PRURL: prURL}.withFiles(fix))
+
+		for _, member := range groupIssues(issue) {
+			if err := provider.AddIssueComment(ctx, member.Number, closeComment); err != nil {
+				fmt.Printf("Warning: Could not add closing comment: %v\n", err)
+			}
+
+			if err := provider.CloseIssue(ctx, member.Number); err != nil {
+				fmt.Printf("Warning: Could not close issue: %v\n", err)
+			} else {
+				fmt.Printf(T("process.closed"), member.Number)
+			}
+		}
+	}
+
+	return nil
+}
+
+// resumeIssueCommand finishes a fix whose push or pull request creation failed
+func resumeIssueCommand(ctx context.Context, args []string) error {
+	config := loadConfig()
+
+	// Allow the issue number before the flags: resume-issue 123 --repo foo
+	var issueNumber int
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		number, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+		if err != nil {
+			return fmt.Errorf("invalid issue number %q", args[0])
+		}
+		issueNumber = number
+		args = args[1:]
+	}
+
+	var discard bool
+	fs := flag.NewFlagSet("resume-issue", flag.ExitOnError)
+	fs.BoolVar(&discard, "discard", false, "Delete the saved progress instead of resuming it")
+	parseFlags(&config, fs, args)
+
+	if issueNumber == 0 && fs.NArg() > 0 {
+		number, err := strconv.Atoi(strings.TrimPrefix(fs.Arg(0), "#"))
+		if err != nil {
+			return fmt.Errorf("invalid issue number %q", fs.Arg(0))
+		}
+		issueNumber = number
+	}
+
+	if issueNumber == 0 {
+		states := listPipelineStates(config)
+		if len(states) == 0 {
+			fmt.Printf("No unfinished fixes for %s/%s\n", config.RepoOwner, config.RepoName)
+			return nil
+		}
+		fmt.Printf("Unfinished fixes for %s/%s:\n", config.RepoOwner, config.RepoName)
+		for _, state := range states {
+			fmt.Printf("  #%-5d %-9s %s  (%s, saved %s)\n", state.Issue.Number, state.Stage, state.Issue.Title, state.Branch, state.SavedAt.Format("2006-01-02 15:04"))
+		}
+		return nil
+	}
+
+	state, err := loadPipelineState(config, issueNumber)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no unfinished fix saved for issue #%d in %s/%s", issueNumber, config.RepoOwner, config.RepoName)
+	}
+	if err != nil {
+		return err
+	}
+
+	if discard {
+		state.Remove(config)
+		fmt.Printf("✓ Discarded the saved fix for issue #%d\n", issueNumber)
+		return nil
+	}
+
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+
+	analytics := NewSessionAnalytics(NewPricing(config))
+	provider := newHostingProvider(config)
+	if err := identifyBotAccount(ctx, provider); err != nil {
+		return err
+	}
+	notifier := NewNotifier(config)
+	outcome := FixOutcome{IssueNumber: issueNumber, Title: state.Issue.Title, Changes: state.Changes}
+	if state.Changes != nil {
+		analytics.RecordChanges(issueNumber, *state.Changes)
+	}
+
+	fmt.Printf("▶️  Resuming issue #%d from stage %q (branch %s)\n", issueNumber, state.Stage, state.Branch)
+
+	gitOps, err := newRepoGitOps(ctx, config, provider)
+	if err != nil {
+		return fmt.Errorf("failed to initialize git: %w", err)
+	}
+	defer gitOps.Cleanup()
+
+	if err := cloneRepo(ctx, config, gitOps); err != nil {
+		return fmt.Errorf("failed to clone repo: %w", err)
+	}
+	if state.Stage == stageCommitted {
+		if err := gitOps.RestoreBundle(ctx, state.Bundle, state.Branch); err != nil {
+			return err
+		}
+	}
+
+	if err := publishFix(ctx, config, provider, gitOps, state, analytics, notifier, &outcome); err != nil {
+		return err
+	}
+
+	recordHistory(config, outcome, analytics.Snapshot().EstimatedCost, analytics.Pricing.Currency)
+	if config.Memory {
+		memory := loadRepoMemory(config.RepoOwner, config.RepoName)
+		memory.RecordOutcome(outcome)
+		if err := memory.Save(); err != nil {
+			fmt.Printf("Warning: Could not save repository memory: %v\n", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const reviewSystemPrompt = "You are a meticulous senior code reviewer. Review proposed fixes critically and respond in a structured JSON format."
+
+// maxReviewDiffChars keeps the review prompt within a sane size for big diffs
+const maxReviewDiffChars = 20000
+
+// ReviewResult is the outcome of the AI's self-review of its own diff
+type ReviewResult struct {
+	Approved bool     `json:"approved"`
+	Concerns []string `json:"concerns"`
+	Summary  string   `json:"summary"`
+}
+
+// reviewFix asks the AI to critique the diff it produced for an issue
+func reviewFix(ctx context.Context, aiClient AIClient, issue Issue, repoContext *RepoContext, diff string) (*ReviewResult, error) {
+	if len(diff) > maxReviewDiffChars {
+		diff = diff[:maxReviewDiffChars] + "\n... (truncated)"
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("# Issue\n\n")
+	prompt.WriteString(fmt.Sprintf("**Title:** %s\n\n", issue.Title))
+	prompt.WriteString(fmt.Sprintf("**Description:**\n%s\n\n", issue.Body))
+
+	prompt.WriteString("# Repository Structure\n```\n")
+	prompt.WriteString(repoContext.Structure)
+	prompt.WriteString("\n```\n\n")
+	prompt.WriteString(instructionsSection(repoContext.Instructions))
+	prompt.WriteString(styleSection(repoContext.StyleGuides))
+
+	prompt.WriteString("# Proposed Diff\n```diff\n")
+	prompt.WriteString(diff)
+	prompt.WriteString("\n```\n\n")
+
+	prompt.WriteString(`# Task
+
+Review the proposed diff against the issue. Check specifically for:
+- Calls to functions, methods, packages or APIs that do not exist in this repository or its dependencies
+- Existing functionality that was removed or broken unintentionally
+- Changes that do not actually address the issue
+- Syntax errors or obviously incomplete code
+- Violations of the project instructions, if any are given
+
+Your response MUST be in the following JSON format:
+
+{
+  "approved": true,
+  "concerns": ["specific problem found in the diff"],
+  "summary": "One or two sentences summarizing the review"
+}
+
+Only set "approved" to false for real problems, not style nitpicks. Return valid JSON only, no markdown code blocks.`)
+
+	response, err := completeJSON(ctx, aiClient, reviewSystemPrompt, prompt.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var result ReviewResult
+	if err := json.Unmarshal([]byte(cleanJSONResponse(response)), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse review response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// reviseFix asks the AI for a new fix that addresses the problems found in a
+// previous attempt. diff may be empty when the attempt was never applied.
+func reviseFix(ctx context.Context, aiClient AIClient, issue Issue, repoContext *RepoContext, diff string, problems []string) (*Fix, error) {
+	if len(diff) > maxReviewDiffChars {
+		diff = diff[:maxReviewDiffChars] + "\n... (truncated)"
+	}
+
+	g := &OpenAIClient{}
+	var prompt strings.Builder
+	prompt.WriteString(g.buildPrompt(issue, repoContext))
+	prompt.WriteString("\n\n# Previous Attempt (rejected)\n")
+	if diff != "" {
+		prompt.WriteString("```diff\n")
+		prompt.WriteString(diff)
+		prompt.WriteString("\n```\n\n")
+	}
+	prompt.WriteString("Problems found:\n")
+	for _, problem := range problems {
+		prompt.WriteString(fmt.Sprintf("- %s\n", problem))
+	}
+	prompt.WriteString("\nProvide a corrected fix that addresses these problems, using the same JSON format.")
+
+	response, err := completeFix(ctx, aiClient, systemPrompt(repoContext), prompt.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return g.parseFix(response, repoContext)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// rollbackMarker tags the bot's rollback comment so the issue counts as unhandled again
+const rollbackMarker = "<!-- mr-code-fixer:rollback -->"
+
+var (
+	prIssueRefPattern   = regexp.MustCompile(`(?i)\b(?:fixes|closes|resolves)\s+#(\d+)`)
+	prIssueTitlePattern = regexp.MustCompile(`^Fix #(\d+):`)
+)
+
+// linkedIssueNumber finds the issue a bot PR fixes from its body or title
+func linkedIssueNumber(pr *PullRequest) int {
+	match := prIssueRefPattern.FindStringSubmatch(pr.Body)
+	if match == nil {
+		match = prIssueTitlePattern.FindStringSubmatch(pr.Title)
+	}
+	if match == nil {
+		return 0
+	}
+	number, _ := strconv.Atoi(match[1])
+	return number
+}
+
+// rollbackCommand undoes a bot PR: closes it, deletes its branch and reopens the issue
+func rollbackCommand(ctx context.Context, args []string) error {
+	config := loadConfig()
+
+	var prNumber, issueNumber int
+	var reason string
+	var force bool
+	fs := flag.NewFlagSet("rollback", flag.ExitOnError)
+	fs.IntVar(&prNumber, "pr", 0, "Number of the bot's pull request to roll back")
+	fs.IntVar(&issueNumber, "issue", 0, "Issue to reopen (default: the issue the PR fixes)")
+	fs.StringVar(&reason, "reason", "", "Explanation posted on the reopened issue")
+	fs.BoolVar(&force, "force", false, "Roll back even if the PR was not created by the bot")
+	parseFlags(&config, fs, args)
+
+	if prNumber <= 0 {
+		return fmt.Errorf("--pr is required")
+	}
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+
+	provider := newHostingProvider(config)
+
+	pr, err := provider.GetPullRequest(ctx, prNumber)
+	if err != nil {
+		return err
+	}
+	if pr.Merged {
+		return fmt.Errorf("PR #%d is already merged; revert the merge commit instead", prNumber)
+	}
+	if !force {
+		if err := identifyBotAccount(ctx, provider); err != nil {
+			return err
+		}
+		if !isBotPR(pr) {
+			return fmt.Errorf("PR #%d was not created by %s (use --force to roll back anyway)", prNumber, bot.Name)
+		}
+	}
+
+	if issueNumber == 0 {
+		issueNumber = linkedIssueNumber(pr)
+	}
+
+	fmt.Printf("⏪ Rolling back PR #%d: %s\n", pr.Number, pr.Title)
+
+	if pr.State != "closed" {
+		if err := provider.ClosePullRequest(ctx, pr.Number); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Closed PR #%d\n", pr.Number)
+	}
+
+	if pr.Head.Ref != "" {
+		deleteRollbackBranch(ctx, config, provider, pr)
+	}
+
+	if issueNumber == 0 {
+		fmt.Println("Warning: Could not find the linked issue; use --issue to reopen it")
+		return nil
+	}
+
+	if err := provider.ReopenIssue(ctx, issueNumber); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Reopened issue #%d\n", issueNumber)
+
+	if err := provider.AddIssueComment(ctx, issueNumber, rollbackComment(pr, reason)); err != nil {
+		return err
+	}
+
+	// Remember that this approach was rejected
+	if config.Memory {
+		memory := loadRepoMemory(config.RepoOwner, config.RepoName)
+		memory.RecordOutcome(FixOutcome{
+			IssueNumber: issueNumber,
+			Title:       pr.Title,
+			Result:      "rolled_back",
+			PRURL:       pr.HTMLURL,
+			Notes:       reason,
+		})
+		if err := memory.Save(); err != nil {
+			fmt.Printf("Warning: Could not save repository memory: %v\n", err)
+		}
+	}
+
+	fmt.Printf("✓ Issue #%d will be picked up again on the next run\n", issueNumber)
+	return nil
+}
+
+// deleteRollbackBranch deletes the branch of a rolled back PR. Only a branch
+// of this repository is deleted: a fork's branch of the same name would be
+// another branch here. The default branch is never deleted.
+func deleteRollbackBranch(ctx context.Context, config Config, provider HostingProvider, pr *PullRequest) {
+	branch := pr.Head.Ref
+	if !strings.EqualFold(pr.Head.Repo.FullName, config.RepoOwner+"/"+config.RepoName) {
+		fmt.Printf("Keeping branch %s: it is not in %s/%s\n", branch, config.RepoOwner, config.RepoName)
+		return
+	}
+	defaultBranch, err := provider.GetDefaultBranch(ctx)
+	if err != nil {
+		fmt.Printf("Warning: Could not look up the default branch, keeping %s: %v\n", branch, err)
+		return
+	}
+	if branch == defaultBranch {
+		fmt.Printf("Keeping branch %s: it is the default branch\n", branch)
+		return
+	}
+	if err := provider.DeleteBranch(ctx, branch); err != nil {
+		fmt.Printf("Warning: Could not delete branch %s: %v\n", branch, err)
+		return
+	}
+	fmt.Printf("✓ Deleted branch %s\n", branch)
+}
+
+func rollbackComment(pr *PullRequest, reason string) string {
+	comment := fmt.Sprintf(`%s
+🤖 **%s - Fix Rolled Back**
+
+My previous fix in #%d was rolled back, so I've closed it and reopened this issue.
+`, rollbackMarker, bot.Name, pr.Number)
+
+	if reason != "" {
+		comment += fmt.Sprintf("\n**Reason:** %s\n", reason)
+	}
+
+	comment += "\nThis issue is open for another attempt. Any extra details you can add will help me get it right next time. 🙏\n" + botMarker
+	return comment
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Issue complexity levels used for model routing
+const (
+	complexitySimple   = "simple"
+	complexityStandard = "standard"
+	complexityComplex  = "complex"
+)
+
+// ComplexityEstimate is how hard an issue looks before any AI call
+type ComplexityEstimate struct {
+	Level   string
+	Points  int
+	Factors []string
+}
+
+func (c ComplexityEstimate) String() string {
+	return fmt.Sprintf("%s (%s)", c.Level, strings.Join(c.Factors, ", "))
+}
+
+// estimateComplexity scores an issue on signals that are free to compute: how
+// much the reporter wrote, how many files match it, whether it comes with a
+// stack trace and how many issues are fixed together
+func estimateComplexity(issue Issue, repoContext *RepoContext) ComplexityEstimate {
+	var estimate ComplexityEstimate
+	add := func(points int, factor string) {
+		estimate.Points += points
+		estimate.Factors = append(estimate.Factors, factor)
+	}
+
+	switch length := len(issue.Body); {
+	case length < 400:
+		add(0, fmt.Sprintf("short report, %d chars", length))
+	case length < 2000:
+		add(1, fmt.Sprintf("%d chars", length))
+	default:
+		add(2, fmt.Sprintf("long report, %d chars", length))
+	}
+
+	switch candidates := repoContext.Candidates; {
+	case candidates <= 5:
+		add(0, fmt.Sprintf("%d candidate files", candidates))
+	case candidates <= 25:
+		add(1, fmt.Sprintf("%d candidate files", candidates))
+	default:
+		add(2, fmt.Sprintf("%d candidate files", candidates))
+	}
+
+	if len(repoContext.Traces) > 0 {
+		add(1, "stack trace")
+	}
+	if len(issue.Related) > 0 {
+		add(1, fmt.Sprintf("%d related issues", len(issue.Related)))
+	}
+
+	switch {
+	case estimate.Points <= 1:
+		estimate.Level = complexitySimple
+	case estimate.Points >= 4:
+		estimate.Level = complexityComplex
+	default:
+		estimate.Level = complexityStandard
+	}
+	return estimate
+}
+
+// routeModel picks the model for fixing an issue: simple_model for trivial
+// issues, complex_model for hard ones and the main model otherwise. It returns
+// the main client when routing is off or the level maps to the main model.
+func routeModel(config Config, analytics *SessionAnalytics, aiClient AIClient, issue Issue, repoContext *RepoContext) AIClient {
+	if config.SimpleModel == "" && config.ComplexModel == "" {
+		return aiClient
+	}
+
+	estimate := estimateComplexity(issue, repoContext)
+	model := config.AIModel
+	switch {
+	case estimate.Level == complexitySimple && config.SimpleModel != "":
+		model = config.SimpleModel
+	case estimate.Level == complexityComplex && config.ComplexModel != "":
+		model = config.ComplexModel
+	}
+	fmt.Printf("🧭 Complexity: %s → %s\n", estimate, model)
+
+	if model == config.AIModel {
+		return aiClient
+	}
+	config.AIModel = model
+	return newAIClient(config, analytics)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Retry policy for rate-limited AI requests
+const (
+	defaultAIRetries = 5
+	retryBaseDelay   = 2 * time.Second
+	retryMaxDelay    = 2 * time.Minute
+)
+
+// rateWindow is the period RPM and TPM limits are counted over
+const rateWindow = time.Minute
+
+// requestStamp is one request counted against the rate limits
+type requestStamp struct {
+	at     time.Time
+	tokens int
+}
+
+// RequestScheduler paces requests to one AI provider: it keeps requests and
+// tokens per minute under the configured limits, queues callers that would
+// exceed them, and retries with exponential backoff when the provider answers
+// 429 Too Many Requests. One scheduler is shared by every client of a service.
+type RequestScheduler struct {
+	name       string
+	rpm        int // Requests per minute, 0 = unlimited
+	tpm        int // Estimated prompt tokens per minute, 0 = unlimited
+	maxRetries int
+
+	mu      sync.Mutex
+	window  []requestStamp
+	waiting int // Callers queued for a slot
+}
+
+var (
+	schedulersMu sync.Mutex
+	schedulers   = make(map[string]*RequestScheduler)
+)
+
+// schedulerFor returns the shared scheduler for an AI service, updating its limits
+func schedulerFor(service string, rpm, tpm, maxRetries int) *RequestScheduler {
+	schedulersMu.Lock()
+	defer schedulersMu.Unlock()
+
+	s, ok := schedulers[service]
+	if !ok {
+		s = &RequestScheduler{name: service}
+		schedulers[service] = s
+	}
+	s.mu.Lock()
+	s.rpm, s.tpm, s.maxRetries = rpm, tpm, maxRetries
+	s.mu.Unlock()
+	return s
+}
+
+// reserve records a request if it fits the limits, otherwise it returns how
+// long to wait until the oldest request leaves the window. Must hold s.mu.
+func (s *RequestScheduler) reserve(tokens int, now time.Time) time.Duration {
+	kept := s.window[:0]
+	for _, stamp := range s.window {
+		if now.Sub(stamp.at) < rateWindow {
+			kept = append(kept, stamp)
+		}
+	}
+	s.window = kept
+
+	used := 0
+	for _, stamp := range s.window {
+		used += stamp.tokens
+	}
+
+	full := (s.rpm > 0 && len(s.window) >= s.rpm) ||
+		// A single request larger than the TPM limit is let through on an empty window
+		(s.tpm > 0 && used+tokens > s.tpm && len(s.window) > 0)
+	if !full {
+		s.window = append(s.window, requestStamp{at: now, tokens: tokens})
+		return 0
+	}
+	return s.window[0].at.Add(rateWindow).Sub(now)
+}
+
+// acquire blocks until the request fits the rate limits or ctx is done
+func (s *RequestScheduler) acquire(ctx context.Context, tokens int) error {
+	s.mu.Lock()
+	s.waiting++
+	position := s.waiting
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.waiting--
+		s.mu.Unlock()
+	}()
+
+	for {
+		s.mu.Lock()
+		wait := s.reserve(tokens, time.Now())
+		s.mu.Unlock()
+		if wait <= 0 {
+			return nil
+		}
+
+		fmt.Printf("⏳ %s rate limit reached, waiting %s (queue position %d)\n", s.name, wait.Round(time.Second), position)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Do sends req through the scheduler. tokens is the estimated prompt size used
+// for the TPM limit. A nil scheduler sends the request directly. Every AI
+// request passes here, so this is where they are audited.
+func (s *RequestScheduler) Do(ctx context.Context, client *http.Client, req *http.Request, tokens int) (*http.Response, error) {
+	resp, err := s.do(ctx, client, req, tokens)
+	service := req.URL.Host
+	if s != nil {
+		service = s.name
+	}
+	recordHTTPAudit(auditAI, "request", "", req, fmt.Sprintf("%s, ~%d prompt tokens", service, tokens), resp, err)
+	return resp, err
+}
+
+func (s *RequestScheduler) do(ctx context.Context, client *http.Client, req *http.Request, tokens int) (*http.Response, error) {
+	if s == nil {
+		return client.Do(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := s.acquire(ctx, tokens); err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= s.maxRetries || req.GetBody == nil {
+			return resp, nil
+		}
+
+		// Drain so the connection can be reused, then back off
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		delay := retryDelay(resp.Header.Get("Retry-After"), attempt)
+		fmt.Printf("⏳ %s answered 429 Too Many Requests, retrying in %s (attempt %d/%d)\n", s.name, delay.Round(time.Second), attempt+1, s.maxRetries)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+	}
+}
+
+// retryDelay honours a Retry-After header in seconds, otherwise it backs off
+// exponentially from retryBaseDelay with some jitter
+func retryDelay(retryAfter string, attempt int) time.Duration {
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	delay := retryBaseDelay << attempt
+	if delay > retryMaxDelay || delay <= 0 {
+		delay = retryMaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Timeouts of the dashboard and API servers, so clients that send their
+// request slowly, or never, can't hold connections open
+const (
+	serverReadHeaderTimeout = 10 * time.Second
+	serverReadTimeout       = time.Minute
+	serverWriteTimeout      = time.Minute
+	serverIdleTimeout       = 2 * time.Minute
+)
+
+// serveHTTP serves handler on addr until ctx is done
+func serveHTTP(ctx context.Context, addr string, handler http.Handler) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: serverReadHeaderTimeout,
+		ReadTimeout:       serverReadTimeout,
+		WriteTimeout:      serverWriteTimeout,
+		IdleTimeout:       serverIdleTimeout,
+	}
+	stop := context.AfterFunc(ctx, func() { server.Close() })
+	defer stop()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// serveCommand runs the bot headlessly, polling for new issues on an interval
+func serveCommand(ctx context.Context, args []string) error {
+	config := loadConfig()
+
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.StringVar(&config.DashboardAddr, "dashboard", config.DashboardAddr, "Address for the web dashboard (e.g. :8080 for localhost only, 0.0.0.0:8080 for all interfaces with --api-token), empty to disable")
+	fs.StringVar(&config.APIAddr, "api", config.APIAddr, "Address for the REST API to request fixes (e.g. :8081), empty to disable; needs --api-token")
+	fs.StringVar(&config.APIToken, "api-token", config.APIToken, "Bearer token that requests to the REST API and the dashboard must carry")
+	fs.StringVar(&config.PollInterval, "interval", config.PollInterval, "How often to poll for new issues (e.g. 10m)")
+	fs.BoolVar(&config.CommentCommands, "commands", config.CommentCommands, "Accept /fix, /retry, /explain and /skip commands in issue comments")
+	fs.BoolVar(&config.RequireCommand, "require-command", config.RequireCommand, "Only work on issues where an authorized user commented /fix")
+	fs.BoolVar(&config.ThreadReplies, "thread-replies", config.ThreadReplies, "Answer commands with replies that quote them, and show progress in one status comment")
+	fs.StringVar(&config.EmailInterval, "email-interval", config.EmailInterval, "How often to email a summary when email_to is set (e.g. 12h, 0 = after every cycle with activity; default 24h)")
+	fs.StringVar(&config.QuestionReminder, "question-reminder", config.QuestionReminder, "Remind reporters who haven't answered the bot's questions after this long (e.g. 72h, 0 = never; default 168h)")
+	fs.StringVar(&config.QuestionClose, "question-close", config.QuestionClose, "Close issues whose questions are still unanswered this long after the reminder (e.g. 168h; default: never)")
+	fs.Var((*listFlag)(&config.CommandUsers), "command-users", "Comma-separated users allowed to post commands (default: repository owner, members and collaborators)")
+	parseFlags(&config, fs, args)
+
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+	if config.APIAddr != "" && config.APIToken == "" {
+		return fmt.Errorf("the REST API needs api_token (--api-token or MRCF_API_TOKEN)")
+	}
+	if config.DashboardAddr != "" && !isLoopbackAddr(dashboardListenAddr(config.DashboardAddr)) && config.APIToken == "" {
+		return fmt.Errorf("a dashboard reachable from other machines needs api_token (--api-token or MRCF_API_TOKEN)")
+	}
+
+	// Nobody is around to answer prompts in serve mode
+	config.Preflight = false
+	config.ClarifyLocally = false
+	config.ProtectedPathPolicy = "reject"
+	if config.OllamaPull == ollamaPullAsk {
+		config.OllamaPull = ollamaPullNever
+	}
+	if config.ScaffoldEmptyRepos == scaffoldAsk {
+		config.ScaffoldEmptyRepos = scaffoldNever
+	}
+	if config.DiffLimitPolicy == diffLimitConfirm {
+		config.DiffLimitPolicy = diffLimitDowngrade
+	}
+
+	interval, err := time.ParseDuration(config.PollInterval)
+	if err != nil {
+		return fmt.Errorf("invalid poll interval %q: %w", config.PollInterval, err)
+	}
+
+	return runServe(ctx, config, interval)
+}
+
+func runServe(ctx context.Context, config Config, interval time.Duration) error {
+	analytics := NewSessionAnalytics(NewPricing(config))
+	provider := newHostingProvider(config)
+	if err := checkOllama(ctx, config); err != nil {
+		return err
+	}
+	if err := checkTokenAccess(ctx, config, provider); err != nil {
+		return err
+	}
+	if err := identifyBotAccount(ctx, provider); err != nil {
+		return err
+	}
+	aiClient := newAIClient(config, analytics)
+	dashboard := NewDashboard(config, analytics)
+
+	if config.DashboardAddr != "" {
+		restore := dashboard.CaptureOutput()
+		defer restore()
+
+		addr := dashboardListenAddr(config.DashboardAddr)
+		go func() {
+			if err := serveHTTP(ctx, addr, dashboard.Handler(config.APIToken)); err != nil {
+				fmt.Printf("\033[31m✗ Dashboard stopped:\033[0m %v\n", err)
+			}
+		}()
+		fmt.Printf("📊 Dashboard listening on %s\n", addr)
+	}
+
+	var api *FixAPI
+	if config.APIAddr != "" {
+		api = NewFixAPI(config, dashboard)
+		go func() {
+			if err := serveHTTP(ctx, config.APIAddr, api.Handler()); err != nil {
+				fmt.Printf("\033[31m✗ API stopped:\033[0m %v\n", err)
+			}
+		}()
+		fmt.Printf("🔌 REST API listening on %s\n", config.APIAddr)
+	}
+
+	fmt.Printf("🤖 Mr. Code Fixer %s serving %s/%s (polling every %s)\n",
+		Version, config.RepoOwner, config.RepoName, interval)
+
+	// Issues we already tried, keyed by number with the updated_at seen at the time.
+	// A failed attempt is only retried once the issue changes.
+	attempted := make(map[int]string)
+
+	// Activity since the last summary email
+	emailer := NewEmailer(config)
+	emailInterval := timeoutSetting(config.EmailInterval, defaultEmailInterval)
+	digest := newServeDigest(analytics)
+
+	for {
+		serveCycle(ctx, config, provider, aiClient, analytics, dashboard, attempted, digest)
+		if config.DashboardAddr != "" {
+			dashboard.RefreshPullRequests(ctx, provider)
+		}
+
+		if emailer != nil && time.Since(digest.since) >= emailInterval {
+			if digest.issues > 0 {
+				emailer.Send(digest.report(config, analytics))
+			}
+			digest = newServeDigest(analytics)
+		}
+
+		// Fixes requested through the API run while waiting for the next poll
+		next := time.After(interval)
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				fmt.Println("\nShutting down...")
+				analytics.PrintSummary()
+				return nil
+			case request := <-api.Jobs():
+				api.run(ctx, request, provider, aiClient, analytics, digest)
+			case <-next:
+				break wait
+			}
+		}
+	}
+}
+
+// serveCycle fetches unhandled issues once and processes each of them
+func serveCycle(ctx context.Context, config Config, provider HostingProvider, aiClient AIClient, analytics *SessionAnalytics, dashboard *Dashboard, attempted map[int]string, digest *serveDigest) {
+	fmt.Printf("\n[%s] 🔍 Checking for new issues...\n", time.Now().Format("15:04:05"))
+
+	watchMergedPRs(ctx, config, provider)
+	followUpQuestions(ctx, config, provider)
+
+	issues, err := provider.GetOpenIssues(ctx, 100)
+	if err != nil {
+		fmt.Printf("\033[31m✗ Error fetching issues:\033[0m %v\n", err)
+		return
+	}
+
+	var pending []Issue
+	threads := make(map[int]*commentThread)
+	trust := newAuthorTrust(config, provider)
+	for _, issue := range filterUnhandledIssues(ctx, config, provider, issues) {
+		// Any new comment (including /retry) changes updated_at and allows another attempt
+		if updatedAt, ok := attempted[issue.Number]; ok && updatedAt == issue.UpdatedAt {
+			continue
+		}
+
+		// Issues from untrusted authors wait for a maintainer's /fix
+		commandConfig := config
+		if reason := trust.untrustedReason(ctx, issue); reason != "" {
+			fmt.Printf("⏭️  #%d needs a maintainer's /fix: %s\n", issue.Number, reason)
+			commandConfig.RequireCommand = true
+		}
+		if commandConfig.CommentCommands || commandConfig.RequireCommand {
+			proceed, thread := handleIssueCommand(ctx, commandConfig, provider, aiClient, analytics, issue)
+			if !proceed {
+				attempted[issue.Number] = issue.UpdatedAt
+				continue
+			}
+			threads[issue.Number] = thread
+		}
+		pending = append(pending, issue)
+	}
+	prioritizeIssues(pending, config.LabelPriorities)
+
+	if len(pending) == 0 {
+		fmt.Println("✓ No new issues")
+		return
+	}
+
+	for _, issue := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		attempted[issue.Number] = issue.UpdatedAt
+
+		fmt.Printf("\n🔧 Processing Issue #%d: %s\n", issue.Number, issue.Title)
+		job := dashboard.StartJob(issue)
+		issueProvider := provider
+		if thread := threads[issue.Number]; thread != nil {
+			thread.Update(ctx, "👀 Working on it")
+			issueProvider = thread
+		}
+		err := processIssue(ctx, config, issueProvider, aiClient, issue, analytics)
+		threads[issue.Number].Finish(ctx, err)
+		dashboard.FinishJob(job, err)
+		digest.record(issue, err)
+	}
+}
+
+// serveDigest collects what serve did between two summary emails
+type serveDigest struct {
+	since    time.Time
+	baseline AnalyticsSnapshot // Session counters when the digest started
+	issues   int
+	failures []string
+}
+
+func newServeDigest(analytics *SessionAnalytics) *serveDigest {
+	return &serveDigest{since: time.Now(), baseline: analytics.Snapshot()}
+}
+
+// record prints the outcome of processing an issue and counts it
+func (d *serveDigest) record(issue Issue, err error) {
+	if errors.Is(err, errIssueSkipped) {
+		fmt.Printf("⏭ Skipped issue #%d\n", issue.Number)
+		return
+	}
+	d.issues++
+	if err != nil {
+		d.failures = append(d.failures, fmt.Sprintf("#%d %s: %v", issue.Number, issue.Title, err))
+		fmt.Printf("Failed to process issue #%d: %v\n", issue.Number, err)
+		return
+	}
+	fmt.Printf("✓ Successfully processed issue #%d\n", issue.Number)
+}
+
+// report is the summary email for the activity since the digest started
+func (d *serveDigest) report(config Config, analytics *SessionAnalytics) EmailReport {
+	snapshot := analytics.Snapshot()
+	return EmailReport{
+		Title:    "Serve summary",
+		Since:    d.since,
+		Currency: snapshot.Currency,
+		Repos: []ReportRepo{{
+			Repo:      config.RepoOwner + "/" + config.RepoName,
+			Issues:    d.issues,
+			PRs:       snapshot.PullRequests[len(d.baseline.PullRequests):],
+			Questions: snapshot.QuestionsAsked - d.baseline.QuestionsAsked,
+			Failures:  d.failures,
+			Cost:      snapshot.EstimatedCost - d.baseline.EstimatedCost,
+		}},
+	}
+}
+
+// handleIssueCommand acts on the newest slash command on an issue. It returns
+// whether the issue should go on to be fixed in this cycle and, with
+// thread_replies, the thread the command is answered in.
+func handleIssueCommand(ctx context.Context, config Config, provider HostingProvider, aiClient AIClient, analytics *SessionAnalytics, issue Issue) (bool, *commentThread) {
+	comments, err := provider.GetIssueComments(ctx, issue.Number)
+	if err != nil {
+		fmt.Printf("Warning: Could not read comments on #%d: %v\n", issue.Number, err)
+		return !config.RequireCommand, nil
+	}
+
+	command, trigger, skipped := issueCommand(config, comments)
+	if !config.CommentCommands {
+		command = ""
+	}
+	author := trigger.User.Login
+
+	// Answers to the command quote it and mention its author
+	var thread *commentThread
+	if command != "" && config.ThreadReplies {
+		thread = newCommentThread(provider, issue.Number, trigger)
+		provider = thread
+	}
+
+	switch command {
+	case commandFix, commandRetry, commandApprove:
+		fmt.Printf("💬 @%s asked for /%s on #%d\n", author, command, issue.Number)
+		return true, thread
+	case commandSkip:
+		fmt.Printf("💬 @%s asked to skip #%d\n", author, issue.Number)
+		if err := provider.AddIssueComment(ctx, issue.Number, skipComment(author)); err != nil {
+			fmt.Printf("Warning: Could not acknowledge /skip: %v\n", err)
+		}
+		return false, nil
+	case commandExplain:
+		fmt.Printf("💬 @%s asked for an explanation of #%d\n", author, issue.Number)
+		if err := explainIssue(ctx, config, provider, aiClient, issue, analytics); err != nil {
+			fmt.Printf("Failed to explain issue #%d: %v\n", issue.Number, err)
+		}
+		return false, nil
+	}
+
+	return !skipped && !config.RequireCommand, nil
+}
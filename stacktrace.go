@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Limits for how much of a stack trace is pinned into the context
+const (
+	maxTraceFrames    = 10
+	maxTraceFiles     = 5
+	traceSnippetLines = 15
+)
+
+// StackFrame is a file:line reference found in a stack trace
+type StackFrame struct {
+	File     string // Path as written in the trace
+	Line     int
+	Function string
+}
+
+// TraceLocation is a stack frame resolved to a file in the repository
+type TraceLocation struct {
+	Path     string // Repository-relative path
+	Line     int
+	Function string
+	Snippet  string // Numbered source lines around Line
+}
+
+var (
+	// Go: "main.(*Server).handle(...)" followed by "\t/src/app/server.go:42 +0x1d"
+	goFramePattern = regexp.MustCompile(`^\s+(\S+\.go):(\d+)(?:\s+\+0x[0-9a-f]+)?\s*$`)
+	goFuncPattern  = regexp.MustCompile(`^([\w./*()-]+)\(.*\)$`)
+	// Python: File "app/views.py", line 42, in handler
+	pythonFramePattern = regexp.MustCompile(`File "([^"]+)", line (\d+)(?:, in (\S+))?`)
+	// JavaScript: "at handler (src/app.js:10:5)" or "at src/app.js:10:5"
+	jsFramePattern = regexp.MustCompile(`at (?:(\S+) \()?(?:file://)?([^\s()]+\.(?:js|mjs|cjs|jsx|ts|tsx)):(\d+)(?::\d+)?\)?`)
+	// Java/Kotlin: "at com.example.Foo.bar(Foo.java:42)"
+	javaFramePattern = regexp.MustCompile(`at ([\w$.]+)\.([\w$<>]+)\(([\w$]+\.(?:java|kt)):(\d+)\)`)
+)
+
+// parseStackTraces extracts file:line references from Go panics, Python
+// tracebacks, JavaScript stack traces and Java exceptions
+func parseStackTraces(text string) []StackFrame {
+	var frames []StackFrame
+	lines := strings.Split(strings.ReplaceAll(text, "\r", ""), "\n")
+
+	for i, line := range lines {
+		if m := goFramePattern.FindStringSubmatch(line); m != nil {
+			number, _ := strconv.Atoi(m[2])
+			frame := StackFrame{File: m[1], Line: number}
+			if i > 0 {
+				if fn := goFuncPattern.FindStringSubmatch(strings.TrimSpace(lines[i-1])); fn != nil {
+					frame.Function = fn[1]
+				}
+			}
+			frames = append(frames, frame)
+			continue
+		}
+
+		if m := pythonFramePattern.FindStringSubmatch(line); m != nil {
+			number, _ := strconv.Atoi(m[2])
+			frames = append(frames, StackFrame{File: m[1], Line: number, Function: m[3]})
+			continue
+		}
+
+		if m := javaFramePattern.FindStringSubmatch(line); m != nil {
+			number, _ := strconv.Atoi(m[4])
+			// Turn the package into a directory: com.example.Foo -> com/example/Foo.java
+			className := m[1]
+			if dollar := strings.Index(className, "$"); dollar != -1 {
+				className = className[:dollar]
+			}
+			dir := ""
+			if dot := strings.LastIndex(className, "."); dot != -1 {
+				dir = strings.ReplaceAll(className[:dot], ".", "/") + "/"
+			}
+			frames = append(frames, StackFrame{File: dir + m[3], Line: number, Function: m[1] + "." + m[2]})
+			continue
+		}
+
+		if m := jsFramePattern.FindStringSubmatch(line); m != nil {
+			number, _ := strconv.Atoi(m[3])
+			frames = append(frames, StackFrame{File: m[2], Line: number, Function: m[1]})
+		}
+	}
+
+	return frames
+}
+
+// isThirdPartyFrame reports frames from dependencies or the language runtime
+func isThirdPartyFrame(path string) bool {
+	path = filepath.ToSlash(path)
+	for _, marker := range []string{"node_modules/", "site-packages/", "dist-packages/", "/go/pkg/mod/", "/usr/local/go/", "/usr/lib/", "node:internal", "<frozen"} {
+		if strings.Contains(path, marker) {
+			return true
+		}
+	}
+	return strings.HasPrefix(path, "java.") || strings.HasPrefix(path, "javax.") || strings.HasPrefix(path, "internal/")
+}
+
+// resolveTracePath maps a path from a trace (often absolute, from another machine)
+// to the repository file with the longest matching path suffix
+func resolveTracePath(tracePath string, repoFiles []string) string {
+	tracePath = filepath.ToSlash(tracePath)
+	best := ""
+	for _, file := range repoFiles {
+		if tracePath == file || strings.HasSuffix(tracePath, "/"+file) || strings.HasSuffix(file, "/"+tracePath) {
+			if len(file) > len(best) {
+				best = file
+			}
+		}
+	}
+	return best
+}
+
+// listRepoFiles returns all repository-relative file paths, skipping dependency directories
+func (g *GitOps) listRepoFiles() []string {
+	var files []string
+	filepath.Walk(g.repoPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != g.repoPath && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relPath, _ := filepath.Rel(g.repoPath, path)
+		files = append(files, filepath.ToSlash(relPath))
+		return nil
+	})
+	return files
+}
+
+// addStackTraceContext pins the repository files referenced by stack traces in
+// the issue at the top of the context and records the code around each frame.
+// Files the context limits leave out, because they are too large, binary or
+// generated or would go over the byte cap, only contribute their snippets.
+func (g *GitOps) addStackTraceContext(repoContext *RepoContext, issueBody string, limits ContextLimits) {
+	frames := parseStackTraces(issueBody)
+	if len(frames) == 0 {
+		return
+	}
+
+	total := 0
+	for _, content := range repoContext.Files {
+		total += len(content)
+	}
+
+	repoFiles := g.listRepoFiles()
+	var pinned []string
+	seen := make(map[string]bool)
+	contents := make(map[string]string)
+
+	for _, frame := range frames {
+		if len(repoContext.Traces) >= maxTraceFrames {
+			break
+		}
+		if isThirdPartyFrame(frame.File) {
+			continue
+		}
+		path := resolveTracePath(frame.File, repoFiles)
+		if path == "" {
+			continue
+		}
+		if !seen[path] && len(seen) >= maxTraceFiles {
+			continue
+		}
+
+		if !seen[path] {
+			seen[path] = true
+			file := readContextFile(g.repoPath, path)
+			if file.reason == "unreadable" || file.reason == "binary" {
+				continue
+			}
+			contents[path] = file.content
+			if reason := traceFileExclusion(repoContext, file, limits, total); reason != "" {
+				repoContext.Excluded = append(repoContext.Excluded, ExcludedFile{path, reason})
+			} else {
+				if _, ok := repoContext.Files[path]; !ok {
+					total += len(file.content)
+					repoContext.Files[path] = file.content
+				}
+				pinned = append(pinned, path)
+			}
+		}
+		content, ok := contents[path]
+		if !ok {
+			continue
+		}
+		repoContext.Traces = append(repoContext.Traces, TraceLocation{
+			Path:     path,
+			Line:     frame.Line,
+			Function: frame.Function,
+			Snippet:  numberedSnippet(content, frame.Line, traceSnippetLines),
+		})
+	}
+
+	if len(pinned) == 0 {
+		return
+	}
+
+	// Files from the trace rank above everything else
+	ranked := pinned
+	for _, path := range repoContext.Ranked {
+		if !slices.Contains(pinned, path) {
+			ranked = append(ranked, path)
+		}
+	}
+	repoContext.Ranked = ranked
+	repoContext.FileCount = len(repoContext.Files)
+
+	fmt.Printf("🔎 Found %d stack frame(s) in the issue, pinned %d file(s)\n", len(repoContext.Traces), len(pinned))
+}
+
+// traceFileExclusion is why a file from a stack trace can't be added to the
+// context in full, or "" if it can. Files already in the context stay.
+func traceFileExclusion(repoContext *RepoContext, file contextFile, limits ContextLimits, total int) string {
+	if _, ok := repoContext.Files[file.path]; ok {
+		return ""
+	}
+	switch {
+	case file.reason != "":
+		return file.reason
+	case limits.MaxFileSize > 0 && len(file.content) > limits.MaxFileSize:
+		return "too large"
+	case limits.MaxBytes > 0 && total+len(file.content) > limits.MaxBytes:
+		return "byte cap"
+	}
+	return ""
+}
+
+// numberedSnippet returns the lines around line (1-based) prefixed with line numbers
+func numberedSnippet(content string, line, radius int) string {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	start := line - radius
+	if start < 1 {
+		start = 1
+	}
+	end := line + radius
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	var snippet strings.Builder
+	for i := start; i <= end; i++ {
+		marker := "  "
+		if i == line {
+			marker = "> "
+		}
+		snippet.WriteString(fmt.Sprintf("%s%5d | %s\n", marker, i, lines[i-1]))
+	}
+	return snippet.String()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddStackTraceContextLimits(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"src/small.py": "def small():\n    raise ValueError()\n",
+		"src/big.py":   strings.Repeat("x = 1\n", 2000) + "def big():\n    small()\n",
+	}
+	for path, content := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0755)
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trace := "Traceback (most recent call last):\n" +
+		"  File \"/app/src/big.py\", line 2002, in big\n" +
+		"  File \"/app/src/small.py\", line 2, in small\n" +
+		"ValueError\n"
+
+	tests := []struct {
+		name     string
+		limits   ContextLimits
+		inFiles  []string
+		excluded map[string]string
+	}{
+		{
+			name:    "no limits",
+			inFiles: []string{"src/big.py", "src/small.py"},
+		},
+		{
+			name:     "file size",
+			limits:   ContextLimits{MaxFileSize: 1024},
+			inFiles:  []string{"src/small.py"},
+			excluded: map[string]string{"src/big.py": "too large"},
+		},
+		{
+			name:     "byte cap",
+			limits:   ContextLimits{MaxBytes: 100},
+			inFiles:  []string{"src/small.py"},
+			excluded: map[string]string{"src/big.py": "byte cap"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &GitOps{repoPath: dir}
+			ctx := &RepoContext{Files: make(map[string]string)}
+			g.addStackTraceContext(ctx, trace, tt.limits)
+
+			if len(ctx.Files) != len(tt.inFiles) {
+				t.Errorf("context has %d files, want %v", len(ctx.Files), tt.inFiles)
+			}
+			for _, path := range tt.inFiles {
+				if _, ok := ctx.Files[path]; !ok {
+					t.Errorf("%s is not in the context", path)
+				}
+			}
+			for path, reason := range tt.excluded {
+				found := false
+				for _, excluded := range ctx.Excluded {
+					found = found || excluded == ExcludedFile{path, reason}
+				}
+				if !found {
+					t.Errorf("%s is not excluded as %q: %v", path, reason, ctx.Excluded)
+				}
+			}
+
+			// Every frame keeps its snippet, whether or not its file is included
+			if len(ctx.Traces) != 2 {
+				t.Fatalf("got %d traces, want 2", len(ctx.Traces))
+			}
+			for _, trace := range ctx.Traces {
+				if !strings.Contains(trace.Snippet, "> ") {
+					t.Errorf("trace of %s has no snippet marking line %d", trace.Path, trace.Line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// How replies that must be JSON are requested from the AI service
+const (
+	structuredAuto   = "auto"   // The strongest mode the service supports
+	structuredSchema = "schema" // Constrain fixes to the Fix JSON schema, other replies to JSON
+	structuredJSON   = "json"   // Only require valid JSON
+	structuredOff    = "off"    // Rely on the prompt alone
+)
+
+var structuredOutputModes = []string{structuredAuto, structuredSchema, structuredJSON, structuredOff}
+
+var (
+	// errFormatRejected marks an API error caused by the requested response format
+	errFormatRejected = errors.New("response format rejected")
+	// errReplyTruncated marks a structured reply cut off at the token limit,
+	// which can never be valid JSON
+	errReplyTruncated = errors.New("the AI reply hit the token limit and was cut off")
+)
+
+// JSONSchema is a named JSON schema replies can be constrained to
+type JSONSchema struct {
+	Name   string
+	Schema map[string]interface{}
+}
+
+// fixSchema describes the fix reply the prompt from buildPrompt asks for. It
+// uses the strict subset OpenAI accepts: every property is required and no
+// others are allowed, so optional parts are empty arrays or null.
+var fixSchema = &JSONSchema{
+	Name: "fix",
+	Schema: schemaObject(map[string]interface{}{
+		"confidence":      map[string]interface{}{"type": "string", "enum": []string{"high", "medium", "low"}},
+		"needs_more_info": map[string]interface{}{"type": "boolean"},
+		"questions":       schemaArray(map[string]interface{}{"type": "string"}),
+		"explanation":     map[string]interface{}{"type": "string"},
+		"files":           schemaArray(fileSchema()),
+		"tests":           schemaArray(schemaStrings("path", "content", "summary")),
+		"edits":           schemaArray(schemaStrings("path", "region", "content")),
+		"commit": map[string]interface{}{
+			"anyOf": []interface{}{schemaStrings("type", "scope", "subject", "body"), map[string]interface{}{"type": "null"}},
+		},
+		"changelog": map[string]interface{}{
+			"anyOf": []interface{}{schemaStrings("type", "entry"), map[string]interface{}{"type": "null"}},
+		},
+	}),
+}
+
+// schemaObject is an object schema requiring all of its properties
+func schemaObject(properties map[string]interface{}) map[string]interface{} {
+	required := make([]string, 0, len(properties))
+	for name := range properties {
+		required = append(required, name)
+	}
+	sort.Strings(required)
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// schemaStrings is an object schema with only string properties
+func schemaStrings(names ...string) map[string]interface{} {
+	properties := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		properties[name] = map[string]interface{}{"type": "string"}
+	}
+	return schemaObject(properties)
+}
+
+// fileSchema is a file of a fix: its operation, path, rename destination,
+// content and a one-line summary
+func fileSchema() map[string]interface{} {
+	file := schemaStrings("path", "new_path", "content", "operation", "summary")
+	file["properties"].(map[string]interface{})["operation"] = map[string]interface{}{"type": "string", "enum": fileOperations}
+	return file
+}
+
+func schemaArray(items map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": items}
+}
+
+// schemaCompleter is implemented by clients that can constrain replies to a JSON schema
+type schemaCompleter interface {
+	CompleteSchema(ctx context.Context, systemPrompt, prompt string, schema *JSONSchema) (string, error)
+}
+
+// completeFix asks for a fix, constrained to fixSchema when the client supports it
+func completeFix(ctx context.Context, aiClient AIClient, systemPrompt, prompt string) (string, error) {
+	if client, ok := aiClient.(schemaCompleter); ok {
+		return client.CompleteSchema(ctx, systemPrompt, prompt, fixSchema)
+	}
+	return completeJSON(ctx, aiClient, systemPrompt, prompt)
+}
+
+// structuredOutputMode resolves structured_output for the configured service.
+// "auto" picks what each API is known to support; a format the API rejects
+// anyway is weakened at runtime.
+func structuredOutputMode(config Config) string {
+	if config.StructuredOutput != structuredAuto {
+		return config.StructuredOutput
+	}
+	switch config.AIService {
+	case "deepseek":
+		return structuredJSON
+	case "ollama":
+		if !config.OllamaJSON {
+			return structuredOff
+		}
+	}
+	return structuredSchema
+}
+
+// structuredOutput is a client's structured output mode. The zero value is off.
+type structuredOutput struct {
+	mu   sync.Mutex
+	mode string
+}
+
+func (s *structuredOutput) set(mode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mode = mode
+}
+
+func (s *structuredOutput) get() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mode == "" {
+		return structuredOff
+	}
+	return s.mode
+}
+
+// complete calls request with the current mode. When the API rejects the
+// format, the mode is weakened for this and all later requests and the
+// request is retried.
+func (s *structuredOutput) complete(request func(mode string) (string, error)) (string, error) {
+	for {
+		mode := s.get()
+		response, err := request(mode)
+		if !errors.Is(err, errFormatRejected) || mode == structuredOff {
+			return response, err
+		}
+
+		weaker := structuredJSON
+		if mode != structuredSchema {
+			weaker = structuredOff
+		}
+		fmt.Printf("Warning: The AI service does not accept %s output, falling back to %s: %v\n", mode, weaker, err)
+		s.mu.Lock()
+		if s.mode == mode {
+			s.mode = weaker
+		}
+		s.mu.Unlock()
+	}
+}
+
+// formatError turns an API error into errFormatRejected when a response format
+// was requested and the error is about it
+func formatError(err error, formatted bool, status int, body string) error {
+	body = strings.ToLower(body)
+	if formatted && status == http.StatusBadRequest && (strings.Contains(body, "format") || strings.Contains(body, "schema")) {
+		return fmt.Errorf("%w: %v", errFormatRejected, err)
+	}
+	return err
+}
+
+// OpenAIResponseFormat is the response_format of OpenAI-compatible chat completions
+type OpenAIResponseFormat struct {
+	Type       string            `json:"type"` // "json_object" or "json_schema"
+	JSONSchema *OpenAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type OpenAIJSONSchema struct {
+	Name   string                 `json:"name"`
+	Strict bool                   `json:"strict"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+// openAIResponseFormat returns the response_format for a mode, or nil for none.
+// JSON mode is only used for prompts that mention JSON, which the API requires.
+func openAIResponseFormat(mode string, schema *JSONSchema, text string) *OpenAIResponseFormat {
+	switch {
+	case mode == structuredOff:
+		return nil
+	case mode == structuredSchema && schema != nil:
+		return &OpenAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &OpenAIJSONSchema{Name: schema.Name, Strict: true, Schema: schema.Schema},
+		}
+	case !strings.Contains(strings.ToLower(text), "json"):
+		return nil
+	}
+	return &OpenAIResponseFormat{Type: "json_object"}
+}
+
+// ollamaFormat returns the format of an Ollama generate request for a mode:
+// a JSON schema, "json", or nil for none
+func ollamaFormat(mode string, schema *JSONSchema) interface{} {
+	switch {
+	case mode == structuredOff:
+		return nil
+	case mode == structuredSchema && schema != nil:
+		return schema.Schema
+	}
+	return "json"
+}
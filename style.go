@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// maxStyleGuideChars bounds each style file in the prompt
+	maxStyleGuideChars = 4000
+	// maxStyleGuidesChars bounds all of them together
+	maxStyleGuidesChars = 12000
+)
+
+// styleGuideFiles are the contribution guidelines and formatter and linter
+// configs whose rules generated code has to follow, in prompt order
+var styleGuideFiles = []string{
+	"CONTRIBUTING.md", ".github/CONTRIBUTING.md", "docs/CONTRIBUTING.md",
+	".editorconfig",
+	".golangci.yml", ".golangci.yaml", ".golangci.toml",
+	".eslintrc", ".eslintrc.json", ".eslintrc.js", ".eslintrc.cjs", ".eslintrc.yml", ".eslintrc.yaml", "eslint.config.js", "eslint.config.mjs",
+	".prettierrc", ".prettierrc.json", ".prettierrc.yml", ".prettierrc.yaml", ".prettierrc.js", "prettier.config.js",
+	"ruff.toml", ".ruff.toml", ".flake8", ".rubocop.yml", ".clang-format", "rustfmt.toml", ".rustfmt.toml",
+}
+
+// pyprojectStyleTables are the pyproject.toml tables that configure Python formatters and linters
+var pyprojectStyleTables = []string{"tool.black", "tool.ruff", "tool.isort", "tool.flake8"}
+
+// StyleGuide is a style file of the repository as shown in the prompt
+type StyleGuide struct {
+	Path    string
+	Content string
+}
+
+// loadStyleGuides reads the repository's contribution guidelines and style
+// configs, up to maxStyleGuidesChars in all
+func loadStyleGuides(repoPath string) []StyleGuide {
+	var guides []StyleGuide
+	total := 0
+	add := func(name, content string) {
+		content = strings.TrimSpace(content)
+		if content == "" || total >= maxStyleGuidesChars {
+			return
+		}
+		content = truncateRunes(content, min(maxStyleGuideChars, maxStyleGuidesChars-total))
+		total += len(content)
+		guides = append(guides, StyleGuide{Path: name, Content: content})
+	}
+
+	for _, name := range styleGuideFiles {
+		if data, err := os.ReadFile(filepath.Join(repoPath, filepath.FromSlash(name))); err == nil {
+			add(name, string(data))
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(repoPath, "pyproject.toml")); err == nil {
+		add("pyproject.toml", tomlTables(string(data), pyprojectStyleTables))
+	}
+
+	if len(guides) > 0 {
+		names := make([]string, len(guides))
+		for i, guide := range guides {
+			names[i] = guide.Path
+		}
+		fmt.Printf("📐 Loaded style guides: %s\n", strings.Join(names, ", "))
+	}
+	return guides
+}
+
+// tomlTables returns the tables of a TOML file named by prefixes, with their subtables
+func tomlTables(content string, prefixes []string) string {
+	var tables strings.Builder
+	keep := false
+	for _, line := range strings.Split(content, "\n") {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "[") {
+			name := strings.Trim(trimmed, "[] ")
+			keep = false
+			for _, prefix := range prefixes {
+				if name == prefix || strings.HasPrefix(name, prefix+".") {
+					keep = true
+				}
+			}
+		}
+		if keep {
+			tables.WriteString(line + "\n")
+		}
+	}
+	return tables.String()
+}
+
+// styleSection renders the style guides for the prompt
+func styleSection(guides []StyleGuide) string {
+	if len(guides) == 0 {
+		return ""
+	}
+	var section strings.Builder
+	section.WriteString("# Code Style\n\nFollow the project's contribution guidelines and style configuration below. CI rejects changes that its formatters and linters would change or flag, so match them exactly (indentation, quotes, line length, import order, naming):\n\n")
+	for _, guide := range guides {
+		section.WriteString(fmt.Sprintf("## %s\n```\n%s\n```\n\n", guide.Path, guide.Content))
+	}
+	return section.String()
+}
+
+// codeFormatter is a formatter the bot runs on the files of a fix
+type codeFormatter struct {
+	name       string
+	extensions []string
+	command    func(repoPath string) []string // nil if the repository doesn't use it
+}
+
+// codeFormatters are only run when the repository shows it uses them and
+// the tool is installed; Go is always formatted with gofmt
+var codeFormatters = []codeFormatter{
+	{"gofmt", []string{".go"}, func(string) []string { return []string{"gofmt", "-w"} }},
+	{"prettier", []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".vue", ".css", ".scss", ".json", ".html"}, prettierCommand},
+	{"black", []string{".py"}, func(repoPath string) []string {
+		if hasTOMLTable(repoPath, "pyproject.toml", "tool.black") {
+			return []string{"black", "--quiet"}
+		}
+		return nil
+	}},
+	{"ruff", []string{".py"}, func(repoPath string) []string {
+		if fileExists(filepath.Join(repoPath, "ruff.toml")) || fileExists(filepath.Join(repoPath, ".ruff.toml")) || hasTOMLTable(repoPath, "pyproject.toml", "tool.ruff.format") {
+			return []string{"ruff", "format", "--quiet"}
+		}
+		return nil
+	}},
+	{"clang-format", []string{".c", ".h", ".cpp", ".hpp"}, func(repoPath string) []string {
+		if fileExists(filepath.Join(repoPath, ".clang-format")) {
+			return []string{"clang-format", "-i"}
+		}
+		return nil
+	}},
+}
+
+// prettierCommand runs prettier from the PATH, never the clone's
+// node_modules, whose content comes from the repository
+func prettierCommand(repoPath string) []string {
+	configured := strings.Contains(readFileString(filepath.Join(repoPath, "package.json")), `"prettier"`)
+	for _, name := range []string{".prettierrc", ".prettierrc.json", ".prettierrc.yml", ".prettierrc.yaml", ".prettierrc.js", "prettier.config.js"} {
+		configured = configured || fileExists(filepath.Join(repoPath, name))
+	}
+	if !configured {
+		return nil
+	}
+	return []string{"prettier", "--write"}
+}
+
+// hasTOMLTable reports whether a TOML file in the repository has the table
+func hasTOMLTable(repoPath, name, table string) bool {
+	return tomlTables(readFileString(filepath.Join(repoPath, name)), []string{table}) != ""
+}
+
+// readFileString reads a file, "" if it can't be read
+func readFileString(file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// formatFix runs the repository's formatters over the files a fix writes,
+// in the working tree, so the commit passes the project's format checks.
+// Formatters run like test commands: from the PATH, subject to
+// allowed_commands and with the test environment. A formatter that is
+// missing, refused or fails is reported and skipped.
+func formatFix(testRunner *TestRunner, fix *Fix) {
+	repoPath := testRunner.RepoPath
+	var written []string
+	for _, change := range fix.FileChanges {
+		switch {
+		case change.Operation == fileDelete:
+		case change.Operation == fileRename:
+			written = append(written, change.NewPath)
+		default:
+			written = append(written, change.FilePath)
+		}
+	}
+
+	for _, formatter := range codeFormatters {
+		var files []string
+		for _, file := range written {
+			for _, ext := range formatter.extensions {
+				if strings.EqualFold(path.Ext(file), ext) {
+					files = append(files, filepath.FromSlash(file))
+				}
+			}
+		}
+		if len(files) == 0 {
+			continue
+		}
+		command := formatter.command(repoPath)
+		if command == nil {
+			continue
+		}
+		if _, err := exec.LookPath(command[0]); err != nil {
+			fmt.Printf("Warning: %s is not installed, leaving %d file(s) unformatted\n", formatter.name, len(files))
+			continue
+		}
+
+		if result := testRunner.check(strings.Join(command, " ")); result != nil {
+			continue
+		}
+
+		output, err := testRunner.command(append(command, files...)).CombinedOutput()
+		if err != nil {
+			fmt.Printf("Warning: %s failed: %v\n%s", formatter.name, err, tailText(testRunner.redact(output), 1000))
+			continue
+		}
+		fmt.Printf("🎨 Formatted %d file(s) with %s\n", len(files), formatter.name)
+	}
+}
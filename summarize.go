@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maxSummaryInputChars bounds how much of a single file is sent for summarization
+const maxSummaryInputChars = 20000
+
+const summarySystemPrompt = "You are an expert software developer. Summarize source files concisely so another developer can decide whether and how to change them."
+
+// promptFileSize is roughly how many characters a file takes up in the fix prompt
+func (c *RepoContext) promptFileSize(content string) int {
+	if c.truncates(content) {
+		return c.MaxFileChars
+	}
+	return len(content)
+}
+
+// truncates reports whether the prompt shows only part of a file
+func (c *RepoContext) truncates(content string) bool {
+	return c.MaxFileChars > 0 && len(content) > c.MaxFileChars
+}
+
+// promptContent is a file's content as the prompt shows it
+func (c *RepoContext) promptContent(content string) string {
+	if c.truncates(content) {
+		return content[:c.MaxFileChars] + "\n... (truncated)"
+	}
+	return content
+}
+
+// summarizeContext keeps the most relevant files verbatim until the budget
+// (in characters) is used up and replaces the rest with short AI summaries.
+// Repositories that already fit are left untouched.
+func summarizeContext(ctx context.Context, summarizer AIClient, repoContext *RepoContext, budget int) {
+	toSummarize := filesToSummarize(repoContext, budget)
+	if len(toSummarize) == 0 {
+		return
+	}
+
+	fmt.Printf("📚 Context is %d chars (budget %d), summarizing less relevant files...\n", repoContext.promptSize(), budget)
+
+	if repoContext.Summaries == nil {
+		repoContext.Summaries = make(map[string]string)
+	}
+
+	for _, path := range toSummarize {
+		summary, err := summarizeFile(ctx, summarizer, path, repoContext.Files[path])
+		delete(repoContext.Files, path)
+		if err != nil {
+			fmt.Printf("Warning: Could not summarize %s: %v\n", path, err)
+			continue
+		}
+		repoContext.Summaries[path] = summary
+	}
+
+	fmt.Printf("✓ Kept %d full file(s), summarized %d\n", len(repoContext.Files), len(repoContext.Summaries))
+}
+
+// promptSize is roughly how many characters the files take up in the fix prompt
+func (c *RepoContext) promptSize() int {
+	total := 0
+	for _, content := range c.Files {
+		total += c.promptFileSize(content)
+	}
+	return total
+}
+
+// filesToSummarize lists the files summarizeContext replaces with summaries:
+// the least relevant ones beyond the budget, none if everything fits
+func filesToSummarize(repoContext *RepoContext, budget int) []string {
+	if budget <= 0 || repoContext.promptSize() <= budget {
+		return nil
+	}
+
+	used := 0
+	var toSummarize []string
+	for _, path := range repoContext.Ranked {
+		content, ok := repoContext.Files[path]
+		if !ok {
+			continue
+		}
+		size := repoContext.promptFileSize(content)
+		if used == 0 || used+size <= budget {
+			used += size
+			continue
+		}
+		toSummarize = append(toSummarize, path)
+	}
+	return toSummarize
+}
+
+// summarizeFile asks the AI for a short description of a single file
+func summarizeFile(ctx context.Context, summarizer AIClient, path, content string) (string, error) {
+	if len(content) > maxSummaryInputChars {
+		content = content[:maxSummaryInputChars] + "\n... (truncated)"
+	}
+
+	prompt := fmt.Sprintf(`### %s
+`+"```"+`
+%s
+`+"```"+`
+
+Summarize this file in at most 5 short lines: its purpose, the main types and functions
+(with signatures), and anything notable such as error handling or side effects.
+Reply with plain text only.`, path, content)
+
+	response, err := summarizer.Complete(ctx, summarySystemPrompt, prompt)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response), nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// externalSyntaxCheckers validate languages without a Go parser by shelling out,
+// but only when the tool is installed. The file path is appended as last argument.
+var externalSyntaxCheckers = map[string][]string{
+	".js":  {"node", "--check"},
+	".mjs": {"node", "--check"},
+	".cjs": {"node", "--check"},
+	".py":  {"python3", "-m", "py_compile"},
+	".rb":  {"ruby", "-c"},
+	".php": {"php", "-l"},
+	".sh":  {"bash", "-n"},
+}
+
+// checkSyntax reports a syntax error in generated file content, or nil if it parses
+func checkSyntax(change FileChange) error {
+	ext := strings.ToLower(filepath.Ext(change.FilePath))
+
+	switch ext {
+	case ".go":
+		_, err := parser.ParseFile(token.NewFileSet(), change.FilePath, change.Content, parser.AllErrors)
+		return err
+	case ".json":
+		var v interface{}
+		if err := json.Unmarshal([]byte(change.Content), &v); err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+		return nil
+	case ".yml", ".yaml":
+		return checkYAMLIndentation(change.Content)
+	}
+
+	checker, ok := externalSyntaxCheckers[ext]
+	if !ok {
+		return nil
+	}
+	tool, err := lookTool(checker[0])
+	if err != nil {
+		return nil // Tool not installed, nothing to check with
+	}
+
+	tmpDir, err := os.MkdirTemp("", "mr-code-fixer-syntax-")
+	if err != nil {
+		return nil
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpFile := filepath.Join(tmpDir, filepath.Base(change.FilePath))
+	if err := os.WriteFile(tmpFile, []byte(change.Content), 0644); err != nil {
+		return nil
+	}
+
+	args := append(append([]string{}, checker[1:]...), tmpFile)
+	cmd := platformCommand(tmpDir, append([]string{tool}, args...))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s", strings.TrimSpace(strings.ReplaceAll(string(output), tmpFile, change.FilePath)))
+	}
+	return nil
+}
+
+// checkYAMLIndentation catches the most common broken YAML: tab indentation
+func checkYAMLIndentation(content string) error {
+	for i, line := range strings.Split(content, "\n") {
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if strings.Contains(indent, "\t") {
+			return fmt.Errorf("invalid YAML: tab used for indentation on line %d", i+1)
+		}
+	}
+	return nil
+}
+
+// checkFixSyntax returns one message per generated file that fails to parse
+func checkFixSyntax(fix *Fix) []string {
+	var problems []string
+	for _, change := range fix.FileChanges {
+		if !change.Writes() {
+			continue
+		}
+		change.FilePath = change.TargetPath()
+		if err := checkSyntax(change); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: syntax error: %v", change.FilePath, err))
+		}
+	}
+	return problems
+}
+
+// syntaxCheckFix rejects fixes with unparsable files and asks the AI to correct them,
+// up to config.ReviewRetries times
+func syntaxCheckFix(ctx context.Context, config Config, aiClient AIClient, issue Issue, repoContext *RepoContext, fix *Fix) (*Fix, error) {
+	for attempt := 0; ; attempt++ {
+		problems := checkFixSyntax(fix)
+		if len(problems) == 0 {
+			return fix, nil
+		}
+
+		fmt.Println("⚠ Generated code has syntax errors:")
+		for _, problem := range problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+
+		if attempt >= config.ReviewRetries {
+			return nil, fmt.Errorf("generated code has syntax errors: %s", strings.Join(problems, "; "))
+		}
+
+		fmt.Println("Asking AI to correct the syntax errors...")
+		revised, err := reviseFix(ctx, aiClient, issue, repoContext, "", problems)
+		if err != nil {
+			return nil, fmt.Errorf("AI revision failed: %w", err)
+		}
+		if len(revised.FileChanges) == 0 {
+			return nil, fmt.Errorf("AI revision produced no file changes")
+		}
+		fix = revised
+	}
+}
@@ -5,12 +5,20 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
 // TestRunner detects and runs tests for different project types
 type TestRunner struct {
 	RepoPath string
+	Commands []string // Validation commands from the repository settings; auto-detected when empty
+	Setup    []string // Commands run once before validation, e.g. "npm ci" or "go mod download"
+	Env      []string // Extra KEY=VALUE variables for setup and test commands
+	Allowed  []string // Executables commands may run (allowed_commands); anything when empty
+	Secrets  []string // Values hidden in command output, which ends up in PRs and comments
+
+	setupDone bool // The setup commands already succeeded
 }
 
 func NewTestRunner(repoPath string) *TestRunner {
@@ -32,10 +40,10 @@ func (t *TestRunner) DetectTestCommand() (string, bool) {
 	
 	// Check for requirements.txt or setup.py (Python)
 	if _, err := os.Stat(filepath.Join(t.RepoPath, "requirements.txt")); err == nil {
-		return "python -m pytest", true
+		return pythonCommand() + " -m pytest", true
 	}
 	if _, err := os.Stat(filepath.Join(t.RepoPath, "setup.py")); err == nil {
-		return "python -m pytest", true
+		return pythonCommand() + " -m pytest", true
 	}
 	
 	// Check for Cargo.toml (Rust)
@@ -68,15 +76,15 @@ func (t *TestRunner) RunTests() (bool, string, error) {
 		return true, "No tests detected - skipping", nil
 	}
 	
-	fmt.Printf("\n🧪 Running tests: %s\n", testCmd)
-	
 	// Split command into parts
-	parts := strings.Fields(testCmd)
-	cmd := exec.Command(parts[0], parts[1:]...)
-	cmd.Dir = t.RepoPath
+	parts, err := splitCommand(testCmd)
+	if err != nil {
+		return false, "", err
+	}
+	cmd := t.command(parts)
 	
 	output, err := cmd.CombinedOutput()
-	outputStr := string(output)
+	outputStr := t.redact(output)
 	
 	if err != nil {
 		return false, outputStr, fmt.Errorf("tests failed: %w", err)
@@ -87,12 +95,36 @@ func (t *TestRunner) RunTests() (bool, string, error) {
 
 // TestResult contains the outcome of running tests
 type TestResult struct {
-	Passed  bool
-	Output  string
-	Command string
+	Passed      bool
+	Output      string
+	Command     string
+	SetupFailed bool // A setup command failed, so the tests were not run
+	Refused     bool // Command refused by allowed_commands or not a plain command, so the tests were not run
+}
+
+// check shows what a command will execute, including the package scripts
+// it runs, and returns a refused result if the allowlist does not permit it
+func (t *TestRunner) check(command string) *TestResult {
+	plan, err := t.planCommand(command)
+	for _, script := range plan.Scripts {
+		fmt.Printf("   ↳ %s\n", script)
+	}
+	if err != nil {
+		fmt.Printf("⛔ Refusing to run %s: %v\n", command, err)
+		return &TestResult{Passed: false, Output: fmt.Sprintf("Refused to run %s: %v", command, err), Command: command, Refused: true}
+	}
+	return nil
 }
 
 func (t *TestRunner) Execute() *TestResult {
+	if result := t.runSetup(); result != nil {
+		return result
+	}
+
+	if len(t.Commands) > 0 {
+		return t.runCommands()
+	}
+
 	cmd, found := t.DetectTestCommand()
 	if !found {
 		return &TestResult{
@@ -101,6 +133,10 @@ func (t *TestRunner) Execute() *TestResult {
 			Command: "",
 		}
 	}
+	fmt.Printf("\n🧪 Running tests: %s\n", cmd)
+	if result := t.check(cmd); result != nil {
+		return result
+	}
 	
 	passed, output, _ := t.RunTests()
 	return &TestResult{
@@ -109,3 +145,240 @@ func (t *TestRunner) Execute() *TestResult {
 		Command: cmd,
 	}
 }
+
+// runCommands runs the declared validation commands in order, stopping at the first failure
+func (t *TestRunner) runCommands() *TestResult {
+	var output strings.Builder
+	for _, command := range t.Commands {
+		fmt.Printf("\n🧪 Running: %s\n", command)
+		if result := t.check(command); result != nil {
+			return result
+		}
+		output.WriteString("$ " + command + "\n")
+
+		parts, _ := splitCommand(command)
+		cmd := t.command(parts)
+
+		cmdOutput, err := cmd.CombinedOutput()
+		output.WriteString(t.redact(cmdOutput))
+		if err != nil {
+			return &TestResult{
+				Passed:  false,
+				Output:  output.String(),
+				Command: command,
+			}
+		}
+	}
+
+	return &TestResult{
+		Passed:  true,
+		Output:  output.String(),
+		Command: strings.Join(t.Commands, " && "),
+	}
+}
+
+// Run runs a single command, after the setup commands if they have not run yet
+func (t *TestRunner) Run(command string) *TestResult {
+	if result := t.runSetup(); result != nil {
+		return result
+	}
+
+	fmt.Printf("\n🧪 Running: %s\n", command)
+	if result := t.check(command); result != nil {
+		return result
+	}
+	parts, _ := splitCommand(command)
+	if len(parts) == 0 {
+		return &TestResult{Passed: false, Output: "empty command", Command: command}
+	}
+	output, err := t.command(parts).CombinedOutput()
+	return &TestResult{
+		Passed:  err == nil,
+		Output:  t.redact(output),
+		Command: command,
+	}
+}
+
+// TestCommands returns the validation commands, or the detected test command
+func (t *TestRunner) TestCommands() []string {
+	if len(t.Commands) > 0 {
+		return t.Commands
+	}
+	if cmd, found := t.DetectTestCommand(); found {
+		return []string{cmd}
+	}
+	return nil
+}
+
+// command builds a command that runs in the clone with the configured environment
+func (t *TestRunner) command(parts []string) *exec.Cmd {
+	cmd := platformCommand(t.RepoPath, parts)
+	cmd.Env = append(commandEnv(), t.Env...)
+	return cmd
+}
+
+// botCredentialEnv are variables holding the bot's own credentials, besides
+// everything with the MRCF_ prefix
+var botCredentialEnv = []string{
+	"GITHUB_TOKEN", "GH_TOKEN", "GITEA_TOKEN",
+	"OPENAI_API_KEY", "XAI_API_KEY", "MISTRAL_API_KEY", "DEEPSEEK_API_KEY", "GROQ_API_KEY", "HF_TOKEN",
+	"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN",
+}
+
+// commandEnv is the bot's environment without its credentials. Setup and test
+// commands come from the repository or the AI and must not be able to read
+// them; test_env can still pass a variable on explicitly.
+func commandEnv() []string {
+	var env []string
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		upper := strings.ToUpper(name)
+		if strings.HasPrefix(upper, envPrefix) || slices.Contains(botCredentialEnv, upper) {
+			continue
+		}
+		env = append(env, entry)
+	}
+	return env
+}
+
+// runSetup runs the setup commands in order. It returns a failed result when
+// one of them fails and nil when all succeed.
+func (t *TestRunner) runSetup() *TestResult {
+	if t.setupDone {
+		return nil
+	}
+	var output strings.Builder
+	for _, command := range t.Setup {
+		fmt.Printf("\n📦 Setup: %s\n", command)
+		output.WriteString("$ " + command + "\n")
+
+		if result := t.check(command); result != nil {
+			result.Output = output.String() + result.Output
+			return result
+		}
+		parts, _ := splitCommand(command)
+		if len(parts) == 0 {
+			continue
+		}
+		cmdOutput, err := t.command(parts).CombinedOutput()
+		output.WriteString(t.redact(cmdOutput))
+		if err != nil {
+			output.WriteString(err.Error() + "\n")
+			return &TestResult{
+				Passed:      false,
+				Output:      output.String(),
+				Command:     command,
+				SetupFailed: true,
+			}
+		}
+	}
+	t.setupDone = true
+	return nil
+}
+
+// shellOperators are shell syntax that a command can't use, since commands
+// run without a shell
+const shellOperators = "&|;<>()`$"
+
+// splitCommand splits a setup or test command into its arguments. Single and
+// double quotes group words, and \" is a quote inside double quotes; any
+// other backslash is kept, for Windows paths. Commands run without a shell,
+// so unquoted &&, |, ;, redirections, subshells and $ expansions are refused
+// rather than passed to the program as arguments.
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inWord := false
+	var quote rune
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+				i++
+				current.WriteRune(runes[i])
+			} else if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		case strings.ContainsRune(shellOperators, r):
+			return nil, fmt.Errorf("%q uses %q: commands run without a shell, so shell syntax is not supported; quote it or use a package script", command, string(r))
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("%q has an unterminated %c quote", command, quote)
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// minSecretLength is the shortest value redact hides; shorter values such as
+// "1" or "on" would garble the output without protecting anything
+const minSecretLength = 4
+
+// redact replaces the secret values in command output with ***
+func (t *TestRunner) redact(output []byte) string {
+	text := string(output)
+	for _, secret := range t.Secrets {
+		if len(secret) >= minSecretLength {
+			text = strings.ReplaceAll(text, secret, "***")
+		}
+	}
+	return text
+}
+
+// testEnvValues are the values of KEY=VALUE entries
+func testEnvValues(entries []string) []string {
+	var values []string
+	for _, entry := range entries {
+		if _, value, ok := strings.Cut(entry, "="); ok && value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// expandTestEnv expands ${VAR} references in KEY=VALUE entries from the
+// environment, so secrets like NPM_TOKEN stay out of the config file
+func expandTestEnv(entries []string) []string {
+	var env []string
+	for _, entry := range entries {
+		if !strings.Contains(entry, "=") {
+			fmt.Printf("Warning: Ignoring test_env entry without '=': %s\n", entry)
+			continue
+		}
+		env = append(env, os.ExpandEnv(entry))
+	}
+	return env
+}
+
+// tailText keeps the last maxChars characters of command output, where the error usually is
+func tailText(text string, maxChars int) string {
+	text = strings.TrimSpace(text)
+	if len(text) <= maxChars {
+		return text
+	}
+	return "...\n" + text[len(text)-maxChars:]
+}
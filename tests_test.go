@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{command: "go test ./...", want: []string{"go", "test", "./..."}},
+		{command: "  npm   test  ", want: []string{"npm", "test"}},
+		{command: `pytest -k "not slow"`, want: []string{"pytest", "-k", "not slow"}},
+		{command: `go test -run 'TestA$'`, want: []string{"go", "test", "-run", "TestA$"}},
+		{command: `echo "say \"hi\""`, want: []string{"echo", `say "hi"`}},
+		{command: `echo ""`, want: []string{"echo", ""}},
+		{command: `a"b c"d`, want: []string{"ab cd"}},
+		{command: `.\scripts\test.bat`, want: []string{`.\scripts\test.bat`}},
+		{command: `npm test -- --grep "a && b"`, want: []string{"npm", "test", "--", "--grep", "a && b"}},
+		{command: "", want: nil},
+		{command: "npm ci && npm test", wantErr: true},
+		{command: "make test; rm -rf /", wantErr: true},
+		{command: "go test | tee out", wantErr: true},
+		{command: "go test > out", wantErr: true},
+		{command: "echo $(id)", wantErr: true},
+		{command: "echo `id`", wantErr: true},
+		{command: "echo $HOME", wantErr: true},
+		{command: `pytest -k "unterminated`, wantErr: true},
+		{command: `pytest -k 'unterminated`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := splitCommand(tt.command)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("splitCommand(%q) = %q, want an error", tt.command, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitCommand(%q) failed: %v", tt.command, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("splitCommand(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestTestRunnerRedact(t *testing.T) {
+	runner := &TestRunner{Secrets: testEnvValues([]string{"NPM_TOKEN=npm_abc123", "CI=1", "EMPTY=", "URL=https://user:pw@example.com"})}
+
+	tests := map[string]string{
+		"//registry.npmjs.org/:_authToken=npm_abc123\n": "//registry.npmjs.org/:_authToken=***\n",
+		"fetching https://user:pw@example.com/pkg":      "fetching ***/pkg",
+		"npm_abc123 npm_abc123":                         "*** ***",
+		"CI=1 ok 1 passed":                              "CI=1 ok 1 passed",
+		"nothing secret":                                "nothing secret",
+	}
+	for output, want := range tests {
+		if got := runner.redact([]byte(output)); got != want {
+			t.Errorf("redact(%q) = %q, want %q", output, got, want)
+		}
+	}
+}
+
+func TestTestRunnerHidesCredentials(t *testing.T) {
+	if _, err := exec.LookPath("printenv"); err != nil {
+		t.Skip("printenv not available")
+	}
+	t.Setenv("MRCF_GITHUB_TOKEN", "ghp_secret")
+	t.Setenv("GITHUB_TOKEN", "ghp_secret")
+
+	for _, name := range []string{"MRCF_GITHUB_TOKEN", "GITHUB_TOKEN"} {
+		runner := &TestRunner{RepoPath: t.TempDir(), Setup: []string{"printenv " + name}}
+		result := runner.runSetup()
+		if result == nil || !result.SetupFailed {
+			t.Errorf("setup command saw %s", name)
+		} else if strings.Contains(result.Output, "ghp_secret") {
+			t.Errorf("setup output contains %s: %q", name, result.Output)
+		}
+	}
+
+	// test_env still passes variables on
+	runner := &TestRunner{RepoPath: t.TempDir(), Env: []string{"NPM_TOKEN=npm_abc123"}}
+	if result := runner.Run("printenv NPM_TOKEN"); !result.Passed {
+		t.Errorf("test_env variable not passed on: %q", result.Output)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// statusMarker tags the status comment of a command; the ID of the command's
+// comment is appended so the bot finds its own status comment again
+const statusMarker = "<!-- mr-code-fixer:status"
+
+// maxQuoteLines limits how much of the command is quoted in replies
+const maxQuoteLines = 5
+
+// commentThread answers a comment command: comments on the issue become
+// replies that quote the command and mention its author, and progress is
+// shown in one status comment that is edited in place. Comments on other
+// issues pass through unchanged.
+type commentThread struct {
+	HostingProvider
+	issue    int
+	trigger  Comment
+	statusID int
+	steps    []string
+}
+
+func newCommentThread(provider HostingProvider, issue int, trigger Comment) *commentThread {
+	return &commentThread{HostingProvider: provider, issue: issue, trigger: trigger}
+}
+
+func (t *commentThread) AddIssueComment(ctx context.Context, issueNumber int, comment string) error {
+	if issueNumber == t.issue {
+		comment = t.reply(comment)
+	}
+	return t.HostingProvider.AddIssueComment(ctx, issueNumber, comment)
+}
+
+func (t *commentThread) CreatePullRequest(ctx context.Context, title, body, head, base string) (*PullRequest, error) {
+	pr, err := t.HostingProvider.CreatePullRequest(ctx, title, body, head, base)
+	if err == nil {
+		t.Update(ctx, fmt.Sprintf("🔧 Opened pull request #%d: %s", pr.Number, pr.HTMLURL))
+	}
+	return pr, err
+}
+
+// reply quotes the command above a comment and mentions its author, unless
+// the comment already does
+func (t *commentThread) reply(comment string) string {
+	var reply strings.Builder
+	reply.WriteString(t.quote())
+	if login := t.trigger.User.Login; login != "" && !strings.Contains(comment, "@"+login) {
+		reply.WriteString(fmt.Sprintf("@%s\n\n", login))
+	}
+	reply.WriteString(comment)
+	return reply.String()
+}
+
+// quote renders the start of the command as a Markdown quote. HTML comments
+// are escaped, so hidden markers in the command, such as statusMarker, show
+// as text and don't turn the reply into something the bot looks for.
+func (t *commentThread) quote() string {
+	lines := strings.Split(strings.TrimSpace(t.trigger.Body), "\n")
+	if len(lines) > maxQuoteLines {
+		lines = append(lines[:maxQuoteLines], "…")
+	}
+	var quote strings.Builder
+	for _, line := range lines {
+		line = strings.ReplaceAll(strings.TrimRight(line, "\r"), "<!--", "&lt;!--")
+		quote.WriteString("> " + line + "\n")
+	}
+	quote.WriteString("\n")
+	return quote.String()
+}
+
+// Update adds a step to the status comment, posting it on the first call and
+// editing it afterwards. Failures only cost the status display.
+func (t *commentThread) Update(ctx context.Context, step string) {
+	if t == nil {
+		return
+	}
+	t.steps = append(t.steps, fmt.Sprintf("%s _(%s)_", step, time.Now().Format("15:04")))
+	body := t.reply(t.statusComment())
+
+	if t.statusID != 0 {
+		if err := t.HostingProvider.EditIssueComment(ctx, t.statusID, body); err != nil {
+			fmt.Printf("Warning: Could not update the status comment: %v\n", err)
+		}
+		return
+	}
+
+	if err := t.HostingProvider.AddIssueComment(ctx, t.issue, body); err != nil {
+		fmt.Printf("Warning: Could not post a status comment: %v\n", err)
+		return
+	}
+	// Comments are created without returning them, so look the new one up
+	comments, err := t.HostingProvider.GetIssueComments(ctx, t.issue)
+	if err != nil {
+		fmt.Printf("Warning: Could not find the status comment, later steps are not shown: %v\n", err)
+		return
+	}
+	for i := len(comments) - 1; i >= 0; i-- {
+		if isBotAccount(comments[i].User.Login) && strings.Contains(comments[i].Body, t.marker()) {
+			t.statusID = comments[i].ID
+			break
+		}
+	}
+}
+
+// Finish records how processing the command ended
+func (t *commentThread) Finish(ctx context.Context, err error) {
+	switch {
+	case t == nil:
+	case errors.Is(err, errIssueSkipped):
+		t.Update(ctx, "⏭️ Skipped")
+	case err != nil:
+		t.Update(ctx, fmt.Sprintf("❌ Failed: %s. Comment `/retry` to try again.", auditSummary(err.Error())))
+	default:
+		t.Update(ctx, "✅ Done")
+	}
+}
+
+func (t *commentThread) marker() string {
+	return fmt.Sprintf("%s %d -->", statusMarker, t.trigger.ID)
+}
+
+// statusComment renders the steps so far
+func (t *commentThread) statusComment() string {
+	var comment strings.Builder
+	comment.WriteString("## 🤖 Status\n\n")
+	for _, step := range t.steps {
+		comment.WriteString(fmt.Sprintf("- %s\n", step))
+	}
+	comment.WriteString(fmt.Sprintf("\n%s\n\n---\n\n%s", t.marker(), bot.signature()))
+	return comment.String()
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommentThreadQuoteEscapesMarkers(t *testing.T) {
+	trigger := Comment{ID: 7, User: User{Login: "mallory"}, Body: "/fix\n" + statusMarker + " 7 -->\n" + botMarker}
+	thread := newCommentThread(nil, 1, trigger)
+
+	reply := thread.reply("Working on it")
+	if strings.Contains(reply, "<!--") {
+		t.Errorf("reply contains a hidden marker from the quoted command:\n%s", reply)
+	}
+	if !strings.Contains(reply, "> &lt;!-- mr-code-fixer") {
+		t.Errorf("reply does not show the escaped marker:\n%s", reply)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// Code, links and HTML comments say nothing about the language of the prose around them
+	codeBlockPattern   = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+	urlPattern         = regexp.MustCompile(`https?://\S+`)
+	htmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// englishStopwords are frequent in any English prose and rare elsewhere
+var englishStopwords = map[string]bool{
+	"the": true, "is": true, "and": true, "to": true, "of": true, "it": true,
+	"this": true, "that": true, "when": true, "with": true, "not": true, "for": true,
+	"on": true, "be": true, "are": true, "was": true, "but": true, "have": true,
+	"should": true, "does": true, "doesn't": true, "can't": true, "from": true, "after": true,
+}
+
+// looksNonEnglish is a cheap first guess whether an issue is written in another
+// language, so English issues never cost a translation request. The AI has the
+// final word.
+func looksNonEnglish(text string) bool {
+	text = codeBlockPattern.ReplaceAllString(text, " ")
+	text = urlPattern.ReplaceAllString(text, " ")
+	text = htmlCommentPattern.ReplaceAllString(text, " ")
+
+	letters, foreign := 0, 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if r > unicode.MaxASCII {
+				foreign++
+			}
+		}
+	}
+	// Non-Latin scripts, or heavy use of accented letters
+	if letters > 0 && foreign*5 > letters {
+		return true
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) < 8 {
+		return false
+	}
+	stopwords := 0
+	for _, word := range words {
+		if englishStopwords[word] {
+			stopwords++
+		}
+	}
+	return stopwords*10 < len(words)
+}
+
+// translateIssue translates a non-English issue to English for the analysis.
+// It returns the issue and the language it was written in, empty for English.
+func translateIssue(ctx context.Context, aiClient AIClient, issue Issue) (Issue, string, error) {
+	if !looksNonEnglish(issue.Title + "\n" + issue.Body) {
+		return issue, "", nil
+	}
+
+	prompt := fmt.Sprintf(`Determine the language of this issue report and translate it to English.
+
+Keep the Markdown structure, code blocks, inline code, log output, URLs, file paths and identifiers unchanged.
+
+Title: %s
+
+Body:
+%s
+
+Respond in JSON format:
+{
+  "language": "Name of the language in English, e.g. German",
+  "title": "English title",
+  "body": "English body"
+}
+
+Return valid JSON only, no markdown code blocks.`, issue.Title, issue.Body)
+
+	response, err := completeJSON(ctx, aiClient, "You are a precise technical translator.", prompt)
+	if err != nil {
+		return issue, "", err
+	}
+
+	var result struct {
+		Language string `json:"language"`
+		Title    string `json:"title"`
+		Body     string `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(cleanJSONResponse(response)), &result); err != nil {
+		return issue, "", fmt.Errorf("failed to parse translation: %w", err)
+	}
+	if result.Language == "" || strings.EqualFold(result.Language, "English") || result.Body == "" {
+		return issue, "", nil
+	}
+
+	if result.Title != "" {
+		issue.Title = result.Title
+	}
+	issue.Body = fmt.Sprintf("%s\n\n_(Translated from %s; the reporter will be answered in %s.)_", result.Body, result.Language, result.Language)
+	return issue, result.Language, nil
+}
+
+// translatingProvider posts comments on one issue in the reporter's language.
+// Comments elsewhere, and any a translation fails for, are posted in English.
+type translatingProvider struct {
+	HostingProvider
+	aiClient AIClient
+	issue    int
+	language string
+}
+
+func (p *translatingProvider) AddIssueComment(ctx context.Context, issueNumber int, comment string) error {
+	if issueNumber == p.issue {
+		if translated, err := translateComment(ctx, p.aiClient, comment, p.language); err != nil {
+			fmt.Printf("Warning: Could not translate comment to %s: %v\n", p.language, err)
+		} else {
+			comment = translated
+		}
+	}
+	return p.HostingProvider.AddIssueComment(ctx, issueNumber, comment)
+}
+
+// translateComment translates a bot comment, keeping what the bot parses
+// again later (HTML comment markers, commands) intact
+func translateComment(ctx context.Context, aiClient AIClient, comment, language string) (string, error) {
+	prompt := fmt.Sprintf(`Translate this comment from English to %s.
+
+Keep the Markdown structure. Leave these unchanged: HTML comments (<!-- ... -->), code blocks and inline code, URLs, file paths, @mentions, #issue references, commands such as /approve, and emoji.
+
+%s
+
+Reply with the translated comment only.`, language, comment)
+
+	translated, err := aiClient.Complete(ctx, "You are a precise technical translator.", prompt)
+	if err != nil {
+		return "", err
+	}
+	translated = strings.TrimSpace(translated)
+	if translated == "" {
+		return "", fmt.Errorf("empty translation")
+	}
+	for _, marker := range htmlCommentPattern.FindAllString(comment, -1) {
+		if !strings.Contains(translated, marker) {
+			return "", fmt.Errorf("translation lost the marker %s", marker)
+		}
+	}
+	return translated, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// authorTrust decides whose issues the bot works on without a maintainer
+// choosing them. With neither trusted_authors nor trusted_org configured,
+// everyone but the blocked authors is trusted.
+type authorTrust struct {
+	config   Config
+	provider HostingProvider
+	members  map[string]bool // Membership of trusted_org by login, looked up once
+}
+
+func newAuthorTrust(config Config, provider HostingProvider) *authorTrust {
+	return &authorTrust{config: config, provider: provider, members: make(map[string]bool)}
+}
+
+// untrustedReason explains why an issue's author is not trusted, or returns
+// "" if the bot may pick the issue up on its own
+func (t *authorTrust) untrustedReason(ctx context.Context, issue Issue) string {
+	login := issue.User.Login
+	hasLogin := func(users []string) bool {
+		return slices.ContainsFunc(users, func(user string) bool { return strings.EqualFold(user, login) })
+	}
+
+	if hasLogin(t.config.BlockedAuthors) {
+		return fmt.Sprintf("@%s is blocked", login)
+	}
+	if len(t.config.TrustedAuthors) == 0 && t.config.TrustedOrg == "" {
+		return ""
+	}
+	if strings.EqualFold(login, t.config.RepoOwner) || hasLogin(t.config.TrustedAuthors) {
+		return ""
+	}
+
+	if t.config.TrustedOrg != "" && login != "" {
+		member, known := t.members[strings.ToLower(login)]
+		if !known {
+			var err error
+			member, err = t.provider.IsOrgMember(ctx, t.config.TrustedOrg, login)
+			if err != nil {
+				// Don't cache a failed lookup; the next cycle asks again
+				return fmt.Sprintf("membership of @%s in %s could not be checked: %v", login, t.config.TrustedOrg, err)
+			}
+			t.members[strings.ToLower(login)] = member
+		}
+		if member {
+			return ""
+		}
+		return fmt.Sprintf("@%s is not in trusted_authors or a member of %s", login, t.config.TrustedOrg)
+	}
+	return fmt.Sprintf("@%s is not in trusted_authors", login)
+}
+
+// trustedIssues drops the issues of untrusted authors, printing why
+func (t *authorTrust) trustedIssues(ctx context.Context, issues []Issue) []Issue {
+	var trusted []Issue
+	for _, issue := range issues {
+		if reason := t.untrustedReason(ctx, issue); reason != "" {
+			fmt.Printf("⏭️  Leaving #%d for a maintainer: %s\n", issue.Number, reason)
+			continue
+		}
+		trusted = append(trusted, issue)
+	}
+	return trusted
+}
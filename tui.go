@@ -0,0 +1,449 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// browserResult tells run() what the user chose in the issue browser
+type browserResult int
+
+const (
+	browserQuit browserResult = iota
+	browserFix
+	browserSetup
+)
+
+// browserSetting is a toggle shown in the browser's inline settings panel
+type browserSetting struct {
+	label string
+	name  string // JSON name in the config file
+	value *bool
+}
+
+// issueBrowser is a full-screen issue picker: a scrollable list on top and a
+// preview of the highlighted issue below
+type issueBrowser struct {
+	issues   []Issue
+	config   *Config
+	repo     string
+	cursor   int
+	offset   int
+	selected map[int]bool
+	scroll   int
+	rows     int
+	cols     int
+	message  string
+
+	inSettings    bool
+	settingCursor int
+	settings      []browserSetting
+	saved         map[string]bool // Value of each setting when last saved or opened, by JSON name
+}
+
+// browseIssues lets the user pick issues in a full-screen browser. ok is false
+// when the terminal does not support it and the line-based picker should be used.
+func browseIssues(issues []Issue, config *Config) (selected []Issue, result browserResult, ok bool) {
+	if !stdinIsTerminal() {
+		return nil, browserQuit, false
+	}
+
+	restore, err := makeRaw()
+	if err != nil {
+		return nil, browserQuit, false
+	}
+
+	b := &issueBrowser{
+		issues:   issues,
+		config:   config,
+		repo:     config.RepoOwner + "/" + config.RepoName,
+		selected: make(map[int]bool),
+		settings: []browserSetting{
+			{"Self-review fixes before creating a PR", "self_review", &config.SelfReview},
+			{"Generate tests with each fix", "generate_tests", &config.GenerateTests},
+			{"Check generated files for syntax errors", "syntax_check", &config.SyntaxCheck},
+			{"Detect duplicate issues", "duplicate_check", &config.DuplicateCheck},
+			{"Close detected duplicates", "close_duplicates", &config.CloseDuplicates},
+			{"Remember the repository between runs", "memory", &config.Memory},
+			{"Pre-flight cost estimate before AI calls", "preflight", &config.Preflight},
+		},
+		saved: make(map[string]bool),
+	}
+	for _, setting := range b.settings {
+		b.saved[setting.name] = *setting.value
+	}
+
+	// Alternate screen, hidden cursor
+	fmt.Print("\033[?1049h\033[?25l")
+	defer func() {
+		fmt.Print("\033[?25h\033[?1049l")
+		restore()
+	}()
+
+	buf := make([]byte, 8)
+	for {
+		b.rows, b.cols = terminalSize()
+		b.render()
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return nil, browserQuit, true
+		}
+
+		if b.inSettings {
+			if b.settingsKey(string(buf[:n])) {
+				return nil, browserSetup, true
+			}
+			continue
+		}
+
+		switch key := string(buf[:n]); key {
+		case "q", "\x1b", "\x03":
+			return nil, browserQuit, true
+		case "\r", "\n":
+			return b.selection(), browserFix, true
+		default:
+			b.listKey(key)
+		}
+	}
+}
+
+// selection returns the checked issues, or the highlighted one if none are checked
+func (b *issueBrowser) selection() []Issue {
+	var selected []Issue
+	for i, issue := range b.issues {
+		if b.selected[i] {
+			selected = append(selected, issue)
+		}
+	}
+	if len(selected) == 0 {
+		selected = append(selected, b.issues[b.cursor])
+	}
+	return selected
+}
+
+func (b *issueBrowser) listKey(key string) {
+	b.message = ""
+	previous := b.cursor
+
+	switch key {
+	case "\x1b[A", "k":
+		b.cursor--
+	case "\x1b[B", "j":
+		b.cursor++
+	case "\x1b[H", "g":
+		b.cursor = 0
+	case "\x1b[F", "G":
+		b.cursor = len(b.issues) - 1
+	case "\x1b[5~":
+		b.scroll -= b.previewHeight() / 2
+	case "\x1b[6~":
+		b.scroll += b.previewHeight() / 2
+	case " ":
+		b.selected[b.cursor] = !b.selected[b.cursor]
+		b.cursor++
+	case "a":
+		all := len(b.selected) < len(b.issues)
+		b.selected = make(map[int]bool)
+		if all {
+			for i := range b.issues {
+				b.selected[i] = true
+			}
+		}
+	case "s":
+		b.inSettings = true
+	}
+
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+	if b.cursor >= len(b.issues) {
+		b.cursor = len(b.issues) - 1
+	}
+	if b.cursor != previous {
+		b.scroll = 0
+	}
+	if b.scroll < 0 {
+		b.scroll = 0
+	}
+	for i, checked := range b.selected {
+		if !checked {
+			delete(b.selected, i)
+		}
+	}
+}
+
+// settingsKey handles a key in the settings panel; it returns true when the
+// user asked for the full interactive setup
+func (b *issueBrowser) settingsKey(key string) bool {
+	// The last entry opens the full setup
+	count := len(b.settings) + 1
+
+	switch key {
+	case "\x1b[A", "k":
+		if b.settingCursor > 0 {
+			b.settingCursor--
+		}
+	case "\x1b[B", "j":
+		if b.settingCursor < count-1 {
+			b.settingCursor++
+		}
+	case " ", "\r", "\n":
+		if b.settingCursor == len(b.settings) {
+			return true
+		}
+		setting := b.settings[b.settingCursor]
+		*setting.value = !*setting.value
+	case "s", "q", "\x1b", "\x03":
+		b.inSettings = false
+		b.message = "Settings apply to this session"
+		if !b.config.NoConfigWrite {
+			b.saveSettings()
+		}
+	}
+	return false
+}
+
+// saveSettings writes the toggles the user changed to the config file, and
+// only those: the session's config also holds values from flags and the
+// environment that must not end up in the file
+func (b *issueBrowser) saveSettings() {
+	changed := make(map[string]interface{})
+	for _, setting := range b.settings {
+		if *setting.value != b.saved[setting.name] {
+			changed[setting.name] = *setting.value
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+	if err := saveConfigSettings(changed); err != nil {
+		b.message = fmt.Sprintf("Could not save settings: %v", err)
+		return
+	}
+	for name, value := range changed {
+		b.saved[name] = value.(bool)
+	}
+	b.message = "Settings saved"
+}
+
+func (b *issueBrowser) listHeight() int {
+	height := (b.rows - 4) * 2 / 5
+	if height < 3 {
+		height = 3
+	}
+	if height > len(b.issues) {
+		height = len(b.issues)
+	}
+	return height
+}
+
+func (b *issueBrowser) previewHeight() int {
+	height := b.rows - 4 - b.listHeight()
+	if height < 1 {
+		height = 1
+	}
+	return height
+}
+
+func (b *issueBrowser) render() {
+	var out strings.Builder
+	out.WriteString("\033[H\033[2J")
+
+	line := func(style, text string) {
+		text = truncateRunes(text, b.cols)
+		if style != "" {
+			out.WriteString(style + text + "\033[0m\n")
+		} else {
+			out.WriteString(text + "\n")
+		}
+	}
+
+	header := fmt.Sprintf(" Mr. Code Fixer - %s - %d open issue(s)", b.repo, len(b.issues))
+	if len(b.selected) > 0 {
+		header += fmt.Sprintf(", %d selected", len(b.selected))
+	}
+	line("\033[1;36m", header)
+	line("\033[90m", strings.Repeat("─", b.cols))
+
+	if b.inSettings {
+		b.renderSettings(line)
+	} else {
+		b.renderList(line)
+	}
+
+	out.WriteString("\033[" + fmt.Sprint(b.rows) + ";1H")
+	footer := " ↑/↓ move  space select  a all  enter fix  PgUp/PgDn scroll  s settings  q quit"
+	if b.inSettings {
+		footer = " ↑/↓ move  space toggle  s back"
+	}
+	if b.message != "" {
+		footer = " " + b.message + "  |" + footer
+	}
+	out.WriteString("\033[7m" + truncateRunes(padRunes(footer, b.cols), b.cols) + "\033[0m")
+
+	os.Stdout.WriteString(out.String())
+}
+
+func (b *issueBrowser) renderList(line func(style, text string)) {
+	height := b.listHeight()
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+height {
+		b.offset = b.cursor - height + 1
+	}
+
+	for i := b.offset; i < b.offset+height && i < len(b.issues); i++ {
+		issue := b.issues[i]
+		marker := "  "
+		if i == b.cursor {
+			marker = "› "
+		}
+		check := "[ ]"
+		if b.selected[i] {
+			check = "[x]"
+		}
+		text := fmt.Sprintf("%s%s #%-5d %s", marker, check, issue.Number, issue.Title)
+		if labels := issueLabelNames(issue); labels != "" {
+			text += "  [" + labels + "]"
+		}
+
+		style := ""
+		if i == b.cursor {
+			style = "\033[1;33m"
+		}
+		line(style, text)
+	}
+
+	line("\033[90m", strings.Repeat("─", b.cols))
+
+	// Preview of the highlighted issue
+	issue := b.issues[b.cursor]
+	var preview []string
+	preview = append(preview, fmt.Sprintf("#%d %s", issue.Number, issue.Title))
+	if labels := issueLabelNames(issue); labels != "" {
+		preview = append(preview, "Labels:    "+labels)
+	}
+	if signals := issueSignals(issue); signals != "" {
+		preview = append(preview, "Activity:  "+signals)
+	}
+	if len(issue.Assignees) > 0 {
+		var logins []string
+		for _, user := range issue.Assignees {
+			logins = append(logins, "@"+user.Login)
+		}
+		preview = append(preview, "Assignees: "+strings.Join(logins, ", "))
+	}
+	if issue.HTMLURL != "" {
+		preview = append(preview, issue.HTMLURL)
+	}
+	preview = append(preview, "")
+	body := issue.Body
+	if strings.TrimSpace(body) == "" {
+		body = "(no description)"
+	}
+	preview = append(preview, wrapText(body, b.cols-1)...)
+
+	if max := len(preview) - b.previewHeight(); b.scroll > max {
+		b.scroll = max
+		if b.scroll < 0 {
+			b.scroll = 0
+		}
+	}
+	for i := b.scroll; i < len(preview) && i < b.scroll+b.previewHeight(); i++ {
+		style := ""
+		if i == 0 {
+			style = "\033[1m"
+		}
+		line(style, preview[i])
+	}
+}
+
+func (b *issueBrowser) renderSettings(line func(style, text string)) {
+	line("\033[1m", " Settings")
+	line("", "")
+	for i, setting := range b.settings {
+		value := "off"
+		if *setting.value {
+			value = "on "
+		}
+		style := ""
+		if i == b.settingCursor {
+			style = "\033[1;33m"
+		}
+		line(style, fmt.Sprintf(" %s [%s] %s", cursorMarker(i == b.settingCursor), value, setting.label))
+	}
+	style := ""
+	if b.settingCursor == len(b.settings) {
+		style = "\033[1;33m"
+	}
+	line(style, fmt.Sprintf(" %s Full setup (repository, tokens, AI service)...", cursorMarker(b.settingCursor == len(b.settings))))
+}
+
+func cursorMarker(active bool) string {
+	if active {
+		return "›"
+	}
+	return " "
+}
+
+func issueLabelNames(issue Issue) string {
+	var names []string
+	for _, label := range issue.Labels {
+		names = append(names, label.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// truncateRunes cuts text to at most width runes
+func truncateRunes(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	if width <= 1 {
+		return string(runes[:width])
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+func padRunes(text string, width int) string {
+	if n := len([]rune(text)); n < width {
+		return text + strings.Repeat(" ", width-n)
+	}
+	return text
+}
+
+// wrapText breaks text into lines of at most width runes
+func wrapText(text string, width int) []string {
+	if width < 10 {
+		width = 10
+	}
+	text = strings.ReplaceAll(text, "\r", "")
+	text = strings.ReplaceAll(text, "\t", "    ")
+
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		runes := []rune(paragraph)
+		if len(runes) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		for len(runes) > width {
+			cut := width
+			// Prefer breaking at a space
+			for i := width; i > width/2; i-- {
+				if runes[i] == ' ' {
+					cut = i
+					break
+				}
+			}
+			lines = append(lines, string(runes[:cut]))
+			runes = []rune(strings.TrimLeft(string(runes[cut:]), " "))
+		}
+		lines = append(lines, string(runes))
+	}
+	return lines
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package main
+
+import "errors"
+
+// makeRaw is not implemented on Windows; the line-based issue picker is used instead
+func makeRaw() (func(), error) {
+	return nil, errors.New("interactive browser not supported on Windows")
+}
+
+func terminalSize() (rows, cols int) {
+	return 24, 80
+}
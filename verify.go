@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const verifierSystemPrompt = "You are an independent reviewer checking a bug fix written by another model. Judge whether it resolves the issue without giving it the benefit of the doubt, and respond in a structured JSON format."
+
+// verifierConfig is the configuration of the verifying model: verifier_model
+// on verifier_service, which defaults to ai_service. verifier_api_key defaults
+// to ai_api_key, but only for the same service.
+func verifierConfig(config Config) Config {
+	verifier := config
+	verifier.AIModel = config.VerifierModel
+	if config.VerifierService != "" {
+		verifier.AIService = config.VerifierService
+	}
+	if config.VerifierAPIKey != "" || verifier.AIService != config.AIService {
+		verifier.AIAPIKey = config.VerifierAPIKey
+	}
+	return verifier
+}
+
+// verifyFix asks a model that did not write the fix whether the diff
+// plausibly resolves the issue. Unlike the self-review it is not asked to
+// improve the fix, only to agree or not.
+func verifyFix(ctx context.Context, verifier AIClient, issue Issue, repoContext *RepoContext, diff string) (*ReviewResult, error) {
+	if len(diff) > maxReviewDiffChars {
+		diff = diff[:maxReviewDiffChars] + "\n... (truncated)"
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("# Issue\n\n")
+	prompt.WriteString(fmt.Sprintf("**Title:** %s\n\n", issue.Title))
+	prompt.WriteString(fmt.Sprintf("**Description:**\n%s\n\n", issue.Body))
+
+	prompt.WriteString("# Repository Structure\n```\n")
+	prompt.WriteString(repoContext.Structure)
+	prompt.WriteString("\n```\n\n")
+
+	prompt.WriteString("# Proposed Diff\n```diff\n")
+	prompt.WriteString(diff)
+	prompt.WriteString("\n```\n\n")
+
+	prompt.WriteString(`# Task
+
+Another model wrote this diff to fix the issue above. Decide independently whether it plausibly resolves the issue:
+- Does it change the code the issue is about, or only something near it?
+- Would the reported behavior actually be different after the change?
+- Does it rely on functions, packages or APIs that do not appear to exist?
+- Does it break or remove behavior the issue did not ask to change?
+
+Your response MUST be in the following JSON format:
+
+{
+  "approved": true,
+  "concerns": ["reason the diff may not resolve the issue"],
+  "summary": "One or two sentences on whether and why the diff resolves the issue"
+}
+
+Set "approved" to true only if you are convinced the diff resolves the issue; style does not matter. Return valid JSON only, no markdown code blocks.`)
+
+	response, err := completeJSON(ctx, verifier, verifierSystemPrompt, prompt.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var result ReviewResult
+	if err := json.Unmarshal([]byte(cleanJSONResponse(response)), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse verification response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// verifyAppliedFix has the verifier judge the applied fix and returns an
+// error unless it agrees. A verifier that can't be reached doesn't agree
+// either, so the fix never goes ahead unverified.
+func verifyAppliedFix(ctx context.Context, config Config, analytics *SessionAnalytics, gitOps *GitOps, issue Issue, repoContext *RepoContext) (*ReviewResult, error) {
+	diff, err := gitOps.Diff(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier := verifierConfig(config)
+	fmt.Printf(T("process.verifying"), verifier.AIModel, verifier.AIService)
+	result, err := verifyFix(ctx, newAIClient(verifier, analytics), issue, repoContext, diff)
+	if err != nil {
+		return nil, fmt.Errorf("verification with %s failed: %w", verifier.AIModel, err)
+	}
+
+	if !result.Approved {
+		fmt.Println(T("process.verifier_rejected"))
+		for _, concern := range result.Concerns {
+			fmt.Printf("  - %s\n", concern)
+		}
+		return result, fmt.Errorf("verifier %s does not think the fix resolves the issue: %s", verifier.AIModel, result.Summary)
+	}
+	fmt.Printf(T("process.verifier_approved"), verifier.AIModel)
+	return result, nil
+}